@@ -0,0 +1,461 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"agent-cli/internal/config"
+	"agent-cli/internal/pipeline"
+	"agent-cli/internal/redact"
+	"agent-cli/internal/result"
+	"agent-cli/internal/stats"
+)
+
+const (
+	batchIDLabelKey  = "agent-cli.batch_id"
+	batchRowLabelKey = "agent-cli.batch_row"
+)
+
+type batchOptions struct {
+	Pipeline   string
+	VarsFile   string
+	Rows       []map[string]string
+	Parallel   int
+	JSONOutput bool
+	Model      string
+	Profile    string
+	Debug      bool
+	MaxCostUSD float64
+	MaxTokens  int64
+	Labels     map[string]string
+	Force      bool
+}
+
+// BatchCommand runs one pipeline per row of a vars file, at most --parallel at a time.
+func BatchCommand(ctx context.Context, cwd string, args []string) error {
+	opts, err := parseBatchArgs(cwd, args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.ReadProfile(cwd, opts.Profile)
+	if err != nil {
+		return err
+	}
+
+	if _, err := checkSpendCaps(cwd, cfg.Budget, opts.Force); err != nil {
+		return err
+	}
+
+	if err := cfg.ResolveSecrets(cwd); err != nil {
+		return err
+	}
+	redactor, err := redact.New([]string{cfg.Auth.GitHubToken, cfg.Auth.ClaudeToken}, cfg.Redact.Patterns)
+	if err != nil {
+		return err
+	}
+
+	batchID, err := stats.NewRunID()
+	if err != nil {
+		return err
+	}
+	batch := &stats.BatchRecord{
+		BatchID:   batchID,
+		Timestamp: time.Now().UTC(),
+		CWD:       cwd,
+		Profile:   cfg.Profile,
+		Pipeline:  opts.Pipeline,
+		VarsFile:  opts.VarsFile,
+		Parallel:  opts.Parallel,
+		Runs:      make([]stats.BatchRunRecord, len(opts.Rows)),
+	}
+	for index, row := range opts.Rows {
+		batch.Runs[index] = stats.BatchRunRecord{
+			Row:          index + 1,
+			TemplateVars: row,
+			Status:       stats.RunStatusExecError,
+			ErrorMessage: "not started",
+		}
+	}
+
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+
+	var progressUI *BatchTUI
+	if !opts.JSONOutput {
+		progressUI = NewBatchTUI(runCtx, runOutputWriter, os.Stdin, batch, cancelRun)
+		progressUI.Start()
+	}
+	progressClosed := false
+	closeProgress := func() error {
+		if progressUI == nil || progressClosed {
+			return nil
+		}
+		progressClosed = true
+		progressUI.Finish(batch)
+		return progressUI.Wait()
+	}
+	defer func() {
+		if err := closeProgress(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: render batch ui: %v\n", err)
+		}
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, opts.Parallel)
+	for index, row := range opts.Rows {
+		select {
+		case slots <- struct{}{}:
+		case <-runCtx.Done():
+		}
+		if runCtx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(index int, row map[string]string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			entry := runBatchRow(runCtx, cwd, cfg, redactor, opts, batchID, index, row, progressUI)
+			mu.Lock()
+			batch.Runs[index] = entry
+			mu.Unlock()
+		}(index, row)
+	}
+	wg.Wait()
+
+	failed := 0
+	batch.Status = stats.RunStatusSuccess
+	for _, entry := range batch.Runs {
+		batch.TotalCostUSD += entry.TotalCostUSD
+		if entry.Status != stats.RunStatusSuccess {
+			failed++
+			batch.Status = stats.RunStatusError
+		}
+	}
+	batch.DurationMS = time.Since(batch.Timestamp).Milliseconds()
+
+	if _, err := stats.SaveBatchRecord(config.BatchesDir(cwd), batch); err != nil {
+		return fmt.Errorf("save batch record: %w", err)
+	}
+
+	if opts.JSONOutput {
+		content, err := json.MarshalIndent(batch, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal batch record: %w", err)
+		}
+		fmt.Fprintln(runOutputWriter, string(content))
+	} else if err := closeProgress(); err != nil {
+		return fmt.Errorf("render batch ui: %w", err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("batch %s: %d of %d run(s) failed", batchID, failed, len(batch.Runs))
+	}
+	return nil
+}
+
+// runBatchRow executes one row and summarizes it for the batch record.
+func runBatchRow(
+	ctx context.Context,
+	cwd string,
+	cfg *config.Config,
+	redactor *redact.Redactor,
+	opts *batchOptions,
+	batchID string,
+	index int,
+	row map[string]string,
+	progressUI *BatchTUI,
+) stats.BatchRunRecord {
+	entry := stats.BatchRunRecord{
+		Row:          index + 1,
+		TemplateVars: row,
+		Status:       stats.RunStatusExecError,
+	}
+
+	runRequest := newRunRequest(cwd, cfg, &runOptions{
+		Pipeline:     opts.Pipeline,
+		TemplateVars: row,
+		Model:        opts.Model,
+		Debug:        opts.Debug,
+	})
+	runRequest.Labels = mergeTemplateVars(opts.Labels, map[string]string{
+		batchIDLabelKey:  batchID,
+		batchRowLabelKey: strconv.Itoa(entry.Row),
+	})
+
+	execution := runExecution{
+		CWD:          cwd,
+		Profile:      cfg.Profile,
+		Request:      runRequest,
+		Redactor:     redactor,
+		TemplateVars: row,
+		BatchID:      batchID,
+		Budget:       resolveBudget(cfg.Budget, opts.MaxCostUSD, opts.MaxTokens),
+		Pricing:      statsPricing(cfg.Pricing),
+		Retention:    retentionPolicy(cfg.Retention),
+	}
+	if progressUI != nil {
+		progressUI.StartRow(index)
+		execution.OnEvent = func(event *result.StreamEvent) {
+			progressUI.SendRowEvent(index, event)
+		}
+	}
+
+	// Rows started later count the spend of the rows before them. With --force, BatchCommand
+	// already warned about reached caps when the batch started.
+	var outcome *runOutcome
+	var err error
+	if !opts.Force {
+		_, err = checkSpendCaps(cwd, cfg.Budget, false)
+	}
+	if err == nil {
+		outcome, err = executeRun(ctx, execution)
+	}
+	if err != nil {
+		entry.ErrorMessage = err.Error()
+		if progressUI != nil {
+			progressUI.FinishRow(index, entry)
+		}
+		return entry
+	}
+
+	record := outcome.Record
+	entry.RunID = record.RunID
+	entry.Status = record.Status
+	entry.TotalCostUSD = stats.EffectiveCostUSD(record)
+	entry.ErrorMessage = record.ErrorMessage
+	if record.Pipeline != nil {
+		entry.TerminalNode = record.Pipeline.TerminalNode
+	}
+	if progressUI != nil {
+		progressUI.FinishRow(index, entry)
+	}
+	return entry
+}
+
+func parseBatchArgs(cwd string, args []string) (*batchOptions, error) {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	var varsFile string
+	var parallel int
+	var jsonOutput bool
+	var modelOverride string
+	var profile string
+	var debug bool
+	var maxCostUSD float64
+	var force bool
+	var maxTokens int64
+	var templateVars templateVarValues
+	var labels labelValues
+	fs.StringVar(&varsFile, "vars-file", "", "CSV (header row of variable names) or JSONL file with one run per row")
+	fs.IntVar(&parallel, "parallel", 1, "maximum number of runs in flight")
+	fs.BoolVar(&jsonOutput, "json", false, "print the batch summary record as JSON")
+	fs.StringVar(&modelOverride, "model", "", "model override (sonnet|opus)")
+	fs.StringVar(&profile, "profile", "", "config profile from [profiles.<name>] (default: default_profile)")
+	fs.BoolVar(&debug, "debug", false, "enable debug logs in container entrypoint")
+	fs.Float64Var(&maxCostUSD, "max-cost-usd", 0, "per-run cost cap (default: budget.max_cost_usd)")
+	fs.Int64Var(&maxTokens, "max-tokens", 0, "per-run token cap (default: budget.max_tokens)")
+	fs.BoolVar(&force, "force", false, "start rows even when a daily, weekly or monthly spend cap is reached")
+	fs.Var(&templateVars, "var", "template variable shared by all rows in KEY=VALUE format (rows take precedence)")
+	fs.Var(&labels, "label", "label in KEY=VALUE format recorded on every run of the batch (repeatable)")
+
+	positionals, err := parseInterspersedFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(positionals) != 1 {
+		return nil, errors.New("batch requires exactly one plan path")
+	}
+	if strings.TrimSpace(varsFile) == "" {
+		return nil, errors.New("batch requires --vars-file")
+	}
+	if parallel < 1 {
+		return nil, fmt.Errorf("invalid --parallel %d: must be at least 1", parallel)
+	}
+	if err := validateBudgetFlags(maxCostUSD, maxTokens); err != nil {
+		return nil, err
+	}
+
+	modelOverride = strings.ToLower(strings.TrimSpace(modelOverride))
+	if modelOverride != "" && !config.IsValidDockerModel(modelOverride) {
+		return nil, fmt.Errorf(
+			"invalid --model %q: expected %s or %s",
+			modelOverride,
+			config.DockerModelSonnet,
+			config.DockerModelOpus,
+		)
+	}
+
+	planPath := positionals[0]
+	pathForRecord := planPath
+	if !filepath.IsAbs(pathForRecord) {
+		pathForRecord = filepath.Join(cwd, pathForRecord)
+	}
+	planBytes, err := os.ReadFile(pathForRecord)
+	if err != nil {
+		return nil, fmt.Errorf("read pipeline file %s: %w", planPath, err)
+	}
+	if strings.TrimSpace(string(planBytes)) == "" {
+		return nil, errors.New("pipeline file is empty")
+	}
+
+	varsPath := varsFile
+	if !filepath.IsAbs(varsPath) {
+		varsPath = filepath.Join(cwd, varsPath)
+	}
+	rows, err := loadBatchVarsFile(varsPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --vars-file %s: %w", varsFile, err)
+	}
+
+	// Every row is validated up front so a typo in row 9 does not surface after eight runs were paid for.
+	for index, row := range rows {
+		rows[index] = mergeTemplateVars(templateVars.values, row)
+		_, err := pipeline.ParsePlan(planBytes, pipeline.LoadOptions{
+			WorkspaceDir:  cwd,
+			TemplateVars:  rows[index],
+			FallbackModel: modelOverride,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid pipeline plan %s: %w", index+1, planPath, err)
+		}
+	}
+
+	return &batchOptions{
+		Pipeline:   pathForRecord,
+		VarsFile:   varsPath,
+		Rows:       rows,
+		Parallel:   parallel,
+		JSONOutput: jsonOutput,
+		Model:      modelOverride,
+		Profile:    strings.TrimSpace(profile),
+		Debug:      debug,
+		MaxCostUSD: maxCostUSD,
+		MaxTokens:  maxTokens,
+		Labels:     labels.values,
+		Force:      force,
+	}, nil
+}
+
+// loadBatchVarsFile reads one template variable set per row. Files ending in .csv use the header row
+// as variable names; anything else is read as JSONL with one object per line.
+func loadBatchVarsFile(path string) ([]map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]string
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		rows, err = parseBatchCSV(string(content))
+	} else {
+		rows, err = parseBatchJSONL(string(content))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no rows")
+	}
+	return rows, nil
+}
+
+func parseBatchCSV(content string) ([]map[string]string, error) {
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("missing header row")
+	}
+
+	header := records[0]
+	for index, name := range header {
+		header[index] = strings.TrimSpace(name)
+		if err := validateBatchVarName(header[index]); err != nil {
+			return nil, fmt.Errorf("header: %w", err)
+		}
+	}
+
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for index, name := range header {
+			row[name] = record[index]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseBatchJSONL(content string) ([]map[string]string, error) {
+	rows := make([]map[string]string, 0)
+	for lineIndex, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var object map[string]any
+		if err := json.Unmarshal([]byte(line), &object); err != nil {
+			return nil, fmt.Errorf("line %d: expected a JSON object: %w", lineIndex+1, err)
+		}
+		row := make(map[string]string, len(object))
+		for name, value := range object {
+			if err := validateBatchVarName(name); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineIndex+1, err)
+			}
+			switch typed := value.(type) {
+			case string:
+				row[name] = typed
+			case float64:
+				row[name] = strconv.FormatFloat(typed, 'f', -1, 64)
+			case bool:
+				row[name] = strconv.FormatBool(typed)
+			default:
+				return nil, fmt.Errorf("line %d: %s must be a string, number or boolean", lineIndex+1, name)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func validateBatchVarName(name string) error {
+	if !templateVarNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid variable name %q: expected UPPER_SNAKE (^[A-Z][A-Z0-9_]*$)", name)
+	}
+	return nil
+}
+
+// formatBatchVars renders a row's variables as a compact KEY=VALUE list.
+func formatBatchVars(vars map[string]string) string {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+vars[key])
+	}
+	return strings.Join(pairs, " ")
+}
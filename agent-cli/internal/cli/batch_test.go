@@ -0,0 +1,271 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"agent-cli/internal/config"
+	"agent-cli/internal/result"
+	"agent-cli/internal/runner"
+	"agent-cli/internal/stats"
+)
+
+func TestParseBatchArgsReadsCSVAndJSONL(t *testing.T) {
+	t.Parallel()
+
+	cwd := t.TempDir()
+	writeBatchTestFile(t, cwd, "pipeline.yaml", testPipelinePlan("A_VAR", "B_VAR"))
+	writeBatchTestFile(t, cwd, "tasks.csv", "A_VAR,B_VAR\n1,\"x, y\"\n2,z\n")
+	writeBatchTestFile(t, cwd, "tasks.jsonl", "{\"A_VAR\": 1}\n\n{\"A_VAR\": \"two\", \"B_VAR\": true}\n")
+
+	opts, err := parseBatchArgs(cwd, []string{"pipeline.yaml", "--vars-file", "tasks.csv", "--parallel", "3"})
+	if err != nil {
+		t.Fatalf("parse batch args: %v", err)
+	}
+	if opts.Pipeline != filepath.Join(cwd, "pipeline.yaml") || opts.Parallel != 3 || len(opts.Rows) != 2 {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+	assertTemplateVars(t, opts.Rows[0], map[string]string{"A_VAR": "1", "B_VAR": "x, y"})
+	assertTemplateVars(t, opts.Rows[1], map[string]string{"A_VAR": "2", "B_VAR": "z"})
+
+	opts, err = parseBatchArgs(cwd, []string{"--vars-file", "tasks.jsonl", "--var", "B_VAR=shared", "pipeline.yaml"})
+	if err != nil {
+		t.Fatalf("parse batch args: %v", err)
+	}
+	if opts.Parallel != 1 || len(opts.Rows) != 2 {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+	assertTemplateVars(t, opts.Rows[0], map[string]string{"A_VAR": "1", "B_VAR": "shared"})
+	assertTemplateVars(t, opts.Rows[1], map[string]string{"A_VAR": "two", "B_VAR": "true"})
+}
+
+func TestParseBatchArgsErrors(t *testing.T) {
+	t.Parallel()
+
+	cwd := t.TempDir()
+	writeBatchTestFile(t, cwd, "pipeline.yaml", testPipelinePlan("A_VAR"))
+	writeBatchTestFile(t, cwd, "tasks.csv", "A_VAR\n1\n")
+	writeBatchTestFile(t, cwd, "missing-var.jsonl", "{\"A_VAR\": \"1\"}\n{}\n")
+	writeBatchTestFile(t, cwd, "bad-header.csv", "a_var\n1\n")
+	writeBatchTestFile(t, cwd, "empty.jsonl", "\n")
+	writeBatchTestFile(t, cwd, "nested.jsonl", "{\"A_VAR\": {\"x\": 1}}\n")
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"--vars-file", "tasks.csv"}, wantErr: "batch requires exactly one plan path"},
+		{args: []string{"pipeline.yaml"}, wantErr: "batch requires --vars-file"},
+		{args: []string{"pipeline.yaml", "--vars-file", "tasks.csv", "--parallel", "0"}, wantErr: "invalid --parallel 0"},
+		{args: []string{"pipeline.yaml", "--vars-file", "tasks.csv", "--model", "haiku"}, wantErr: "invalid --model"},
+		{args: []string{"pipeline.yaml", "--vars-file", "bad-header.csv"}, wantErr: `header: invalid variable name "a_var"`},
+		{args: []string{"pipeline.yaml", "--vars-file", "empty.jsonl"}, wantErr: "no rows"},
+		{args: []string{"pipeline.yaml", "--vars-file", "nested.jsonl"}, wantErr: "line 1: A_VAR must be a string"},
+		{args: []string{"pipeline.yaml", "--vars-file", "missing-var.jsonl"}, wantErr: "row 2: invalid pipeline plan"},
+	}
+	for _, tt := range tests {
+		_, err := parseBatchArgs(cwd, tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("unexpected error for %q: %v", tt.args, err)
+		}
+	}
+}
+
+func TestBatchCommandRunsRowsAndSavesSummary(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+	writeBatchTestFile(t, cwd, "pipeline.yaml", testPipelinePlan("A_VAR"))
+	writeBatchTestFile(t, cwd, "tasks.csv", "A_VAR\nok\nfail\nok\n")
+
+	successLine := `{"type":"pipeline_result","version":"v2","status":"success","is_error":false,"entry_node":"implement","terminal_node":"success","terminal_status":"success","exit_code":0,"iterations":1,"node_run_count":1,"failed_node_count":0,"node_runs":[{"node_id":"implement","node_run_id":"implement-1","kind":"command","status":"success","exit_code":0,"started_at":"2026-02-16T00:00:00Z","finished_at":"2026-02-16T00:00:01Z","duration_ms":1000,"error_message":""}]}`
+	failureLine := `{"type":"pipeline_result","version":"v2","status":"error","is_error":true,"entry_node":"implement","terminal_node":"fail","terminal_status":"failed","exit_code":1,"iterations":1,"node_run_count":1,"failed_node_count":1,"node_runs":[{"node_id":"implement","node_run_id":"implement-1","kind":"command","status":"error","exit_code":2,"started_at":"2026-02-16T00:00:00Z","finished_at":"2026-02-16T00:00:01Z","duration_ms":1000,"error_message":"make failed"}]}`
+
+	var mu sync.Mutex
+	labelsByRow := map[string]map[string]string{}
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			mu.Lock()
+			labelsByRow[req.Labels[batchRowLabelKey]] = req.Labels
+			mu.Unlock()
+
+			lines := []string{`{"type":"pipeline_event","event":"node_start","node_id":"implement"}`, successLine}
+			if req.TemplateVars["A_VAR"] == "fail" {
+				lines[1] = failureLine
+			}
+			for _, line := range lines {
+				hooks.OnStdoutLine(line)
+			}
+			return runner.RunOutput{Stdout: strings.Join(lines, "\n") + "\n"}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	err := BatchCommand(
+		context.Background(),
+		cwd,
+		[]string{"pipeline.yaml", "--vars-file", "tasks.csv", "--parallel", "2", "--json"},
+	)
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 run(s) failed") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var printed stats.BatchRecord
+	if err := json.Unmarshal(out.Bytes(), &printed); err != nil {
+		t.Fatalf("decode batch output: %v", err)
+	}
+	if printed.Status != stats.RunStatusError || printed.Parallel != 2 || len(printed.Runs) != 3 {
+		t.Fatalf("unexpected batch record: %+v", printed)
+	}
+	for index, entry := range printed.Runs {
+		wantStatus := stats.RunStatusSuccess
+		wantTerminal := "success"
+		if index == 1 {
+			wantStatus = stats.RunStatusError
+			wantTerminal = "fail"
+		}
+		if entry.Row != index+1 || entry.Status != wantStatus || entry.TerminalNode != wantTerminal || entry.RunID == "" {
+			t.Fatalf("unexpected batch run %d: %+v", index, entry)
+		}
+
+		record, _, err := stats.FindRunRecord(config.RunsDir(cwd), entry.RunID)
+		if err != nil {
+			t.Fatalf("find run record: %v", err)
+		}
+		if record.BatchID != printed.BatchID || record.TemplateVars["A_VAR"] != entry.TemplateVars["A_VAR"] {
+			t.Fatalf("unexpected run record: %+v", record)
+		}
+
+		labels := labelsByRow[strconv.Itoa(index+1)]
+		if labels[batchIDLabelKey] != printed.BatchID {
+			t.Fatalf("unexpected labels for row %d: %#v", index+1, labels)
+		}
+	}
+
+	batches, err := os.ReadDir(config.BatchesDir(cwd))
+	if err != nil {
+		t.Fatalf("read batches dir: %v", err)
+	}
+	if len(batches) != 1 || !strings.HasSuffix(batches[0].Name(), "-"+printed.BatchID+".json") {
+		t.Fatalf("unexpected batch files: %v", batches)
+	}
+}
+
+func TestBatchCommandTotalsEffectiveCosts(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+	appendTestConfig(t, cwd, "\n[pricing.sonnet]\ninput_per_mtok = 100000\noutput_per_mtok = 200000\n")
+	writeBatchTestFile(t, cwd, "pipeline.yaml", testPipelinePlan("A_VAR"))
+	writeBatchTestFile(t, cwd, "tasks.csv", "A_VAR\none\ntwo\n")
+
+	resultLine := `{"type":"result","subtype":"success","is_error":false,"duration_ms":10,"duration_api_ms":12,"num_turns":1,"result":"ok","stop_reason":null,"session_id":"s1","total_cost_usd":0.5,"usage":{"input_tokens":10,"cache_creation_input_tokens":0,"cache_read_input_tokens":0,"output_tokens":5},"modelUsage":{"claude-sonnet":{"inputTokens":10,"outputTokens":5,"cacheReadInputTokens":0,"cacheCreationInputTokens":0,"webSearchRequests":0,"costUSD":0.5}},"uuid":"u1"}`
+	successLine := `{"type":"pipeline_result","version":"v2","status":"success","is_error":false,"entry_node":"implement","terminal_node":"success","terminal_status":"success","exit_code":0,"iterations":1,"node_run_count":1,"failed_node_count":0,"node_runs":[{"node_id":"implement","node_run_id":"implement-1","kind":"command","status":"success","exit_code":0,"started_at":"2026-02-16T00:00:00Z","finished_at":"2026-02-16T00:00:01Z","duration_ms":1000,"error_message":""}]}`
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			lines := []string{resultLine, successLine}
+			for _, line := range lines {
+				hooks.OnStdoutLine(line)
+			}
+			return runner.RunOutput{Stdout: strings.Join(lines, "\n") + "\n"}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	if err := BatchCommand(context.Background(), cwd, []string{"pipeline.yaml", "--vars-file", "tasks.csv", "--json"}); err != nil {
+		t.Fatalf("batch command: %v", err)
+	}
+
+	var printed stats.BatchRecord
+	if err := json.Unmarshal(out.Bytes(), &printed); err != nil {
+		t.Fatalf("decode batch output: %v", err)
+	}
+	for _, entry := range printed.Runs {
+		assertFloatNear(t, entry.TotalCostUSD, 2, "row total_cost_usd")
+	}
+	assertFloatNear(t, printed.TotalCostUSD, 4, "batch total_cost_usd")
+}
+
+func TestBatchTUIModelTracksRows(t *testing.T) {
+	t.Parallel()
+
+	batch := &stats.BatchRecord{
+		BatchID:  "batch-1",
+		Parallel: 2,
+		Runs: []stats.BatchRunRecord{
+			{Row: 1, TemplateVars: map[string]string{"ISSUE": "12"}},
+			{Row: 2, TemplateVars: map[string]string{"ISSUE": strings.Repeat("x", 60)}},
+		},
+	}
+	model := newBatchTUIModel(batch, nil)
+
+	next, _ := model.Update(batchRowStartedMsg{Index: 0})
+	model = next.(batchTUIModel)
+	for _, line := range []string{
+		`{"type":"pipeline_event","event":"node_start","node_id":"planner"}`,
+		`{"type":"result","subtype":"success","is_error":false,"total_cost_usd":0.25,"session_id":"s1"}`,
+	} {
+		model = applyBatchStreamLine(t, model, 0, line)
+	}
+
+	view := model.View()
+	assertContains(t, view, "Batch batch-1 (0/2 done, parallel 2)")
+	assertContains(t, view, "ISSUE=12")
+	assertContains(t, view, "planner")
+	assertContains(t, view, "0.250000")
+	assertContains(t, view, "running")
+	assertContains(t, view, "queued")
+	assertContains(t, view, "ISSUE="+strings.Repeat("x", 31)+"...")
+
+	next, _ = model.Update(batchRowFinishedMsg{Index: 0, Entry: stats.BatchRunRecord{
+		RunID:        "run-1",
+		Status:       stats.RunStatusSuccess,
+		TerminalNode: "success",
+		TotalCostUSD: 0.5,
+	}})
+	model = next.(batchTUIModel)
+	batch.Status = stats.RunStatusError
+	batch.Runs[0].Status = stats.RunStatusSuccess
+	batch.Runs[1].Status = stats.RunStatusExecError
+	batch.TotalCostUSD = 0.5
+	next, _ = model.Update(batchFinishedMsg{Record: batch})
+	model = next.(batchTUIModel)
+
+	view = model.View()
+	assertContains(t, view, "Batch batch-1 (1/2 done, parallel 2)")
+	assertContains(t, view, "run-1")
+	assertContains(t, view, "0.500000")
+	assertContains(t, view, "Batch error: 1 succeeded, 1 failed, cost 0.500000 USD")
+}
+
+func applyBatchStreamLine(t *testing.T, model batchTUIModel, index int, line string) batchTUIModel {
+	t.Helper()
+
+	event, kind, err := result.ParseStreamLine(line)
+	if err != nil || kind != result.StreamLineJSONEvent {
+		t.Fatalf("parse stream line: kind=%s err=%v", kind, err)
+	}
+	next, _ := model.Update(batchRowEventMsg{Index: index, Event: event})
+	return next.(batchTUIModel)
+}
+
+func writeBatchTestFile(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
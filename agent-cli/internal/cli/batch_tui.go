@@ -0,0 +1,277 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"agent-cli/internal/result"
+	"agent-cli/internal/stats"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const batchVarsColumnWidth = 40
+
+// BatchTUI renders one compact row per batch run.
+type BatchTUI struct {
+	program  *tea.Program
+	doneCh   chan error
+	finished atomic.Bool
+}
+
+type batchRowStartedMsg struct {
+	Index int
+}
+
+type batchRowEventMsg struct {
+	Index int
+	Event *result.StreamEvent
+}
+
+type batchRowFinishedMsg struct {
+	Index int
+	Entry stats.BatchRunRecord
+}
+
+type batchFinishedMsg struct {
+	Record *stats.BatchRecord
+}
+
+func NewBatchTUI(
+	ctx context.Context,
+	output io.Writer,
+	input io.Reader,
+	batch *stats.BatchRecord,
+	cancelRun context.CancelFunc,
+) *BatchTUI {
+	model := newBatchTUIModel(batch, cancelRun)
+	program := tea.NewProgram(
+		model,
+		tea.WithContext(ctx),
+		tea.WithInput(input),
+		tea.WithOutput(output),
+		tea.WithoutSignals(),
+	)
+
+	return &BatchTUI{
+		program: program,
+		doneCh:  make(chan error, 1),
+	}
+}
+
+func (p *BatchTUI) Start() {
+	go func() {
+		_, err := p.program.Run()
+		p.doneCh <- err
+	}()
+}
+
+func (p *BatchTUI) StartRow(index int) {
+	p.program.Send(batchRowStartedMsg{Index: index})
+}
+
+func (p *BatchTUI) SendRowEvent(index int, event *result.StreamEvent) {
+	if event == nil {
+		return
+	}
+	p.program.Send(batchRowEventMsg{Index: index, Event: event})
+}
+
+func (p *BatchTUI) FinishRow(index int, entry stats.BatchRunRecord) {
+	p.program.Send(batchRowFinishedMsg{Index: index, Entry: entry})
+}
+
+func (p *BatchTUI) Finish(record *stats.BatchRecord) {
+	if !p.finished.CompareAndSwap(false, true) {
+		return
+	}
+	p.program.Send(batchFinishedMsg{Record: record})
+}
+
+func (p *BatchTUI) Wait() error {
+	return <-p.doneCh
+}
+
+type batchTUIModel struct {
+	batchID  string
+	parallel int
+	rows     []batchRowState
+
+	interrupting bool
+	done         bool
+	final        *stats.BatchRecord
+	cancelRun    context.CancelFunc
+}
+
+type batchRowState struct {
+	Vars    string
+	Node    string
+	CostUSD float64
+	Status  string
+	RunID   string
+}
+
+func newBatchTUIModel(batch *stats.BatchRecord, cancelRun context.CancelFunc) batchTUIModel {
+	rows := make([]batchRowState, len(batch.Runs))
+	for index, entry := range batch.Runs {
+		rows[index] = batchRowState{
+			Vars:   formatBatchVars(entry.TemplateVars),
+			Status: "queued",
+		}
+	}
+	return batchTUIModel{
+		batchID:   batch.BatchID,
+		parallel:  batch.Parallel,
+		rows:      rows,
+		cancelRun: cancelRun,
+	}
+}
+
+func (m batchTUIModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m batchTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch typed := msg.(type) {
+	case tea.KeyMsg:
+		if typed.String() == "ctrl+c" {
+			return m, m.interruptRun()
+		}
+	case tea.InterruptMsg:
+		return m, m.interruptRun()
+	case batchRowStartedMsg:
+		if row := m.row(typed.Index); row != nil {
+			row.Status = "running"
+		}
+	case batchRowEventMsg:
+		if row := m.row(typed.Index); row != nil {
+			row.handleEvent(typed.Event)
+		}
+	case batchRowFinishedMsg:
+		if row := m.row(typed.Index); row != nil {
+			row.Status = normalizeStatus(string(typed.Entry.Status), "error")
+			row.RunID = typed.Entry.RunID
+			row.CostUSD = typed.Entry.TotalCostUSD
+			if typed.Entry.TerminalNode != "" {
+				row.Node = typed.Entry.TerminalNode
+			}
+		}
+	case batchFinishedMsg:
+		m.final = typed.Record
+		m.done = true
+		return m, tea.Quit
+	}
+
+	return m, nil
+}
+
+func (m *batchTUIModel) interruptRun() tea.Cmd {
+	if !m.interrupting && !m.done {
+		m.interrupting = true
+		if m.cancelRun != nil {
+			m.cancelRun()
+		}
+	}
+	return tea.Quit
+}
+
+func (m *batchTUIModel) row(index int) *batchRowState {
+	if index < 0 || index >= len(m.rows) {
+		return nil
+	}
+	return &m.rows[index]
+}
+
+func (row *batchRowState) handleEvent(event *result.StreamEvent) {
+	if event.Pipeline != nil {
+		switch event.Pipeline.Event {
+		case "node_start":
+			row.Node = event.Pipeline.NodeID
+		case "plan_finish":
+			if event.Pipeline.TerminalNode != "" {
+				row.Node = event.Pipeline.TerminalNode
+			}
+		}
+	}
+	if event.Result != nil {
+		row.CostUSD += result.ExtractMetrics(*event.Result).TotalCostUSD
+	}
+}
+
+func (m batchTUIModel) View() string {
+	finished := 0
+	for _, row := range m.rows {
+		if row.Status != "queued" && row.Status != "running" {
+			finished++
+		}
+	}
+
+	lines := make([]string, 0, len(m.rows)+8)
+	lines = append(lines, fmt.Sprintf(
+		"Batch %s (%d/%d done, parallel %d)",
+		m.batchID,
+		finished,
+		len(m.rows),
+		m.parallel,
+	))
+	lines = append(lines, renderTextTable(batchTableHeaders(), m.tableRows())...)
+
+	if m.done && m.final != nil {
+		failed := 0
+		for _, entry := range m.final.Runs {
+			if entry.Status != stats.RunStatusSuccess {
+				failed++
+			}
+		}
+		lines = append(lines, "")
+		lines = append(lines, fmt.Sprintf(
+			"Batch %s: %d succeeded, %d failed, cost %s USD, duration %s",
+			normalizeStatus(string(m.final.Status), "success"),
+			len(m.final.Runs)-failed,
+			failed,
+			formatCostUSD(m.final.TotalCostUSD),
+			formatDurationMS(m.final.DurationMS),
+		))
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func batchTableHeaders() []string {
+	return []string{"#", "Vars", "Node", "Cost(USD)", "Status", "Run"}
+}
+
+func (m batchTUIModel) tableRows() [][]string {
+	rows := make([][]string, 0, len(m.rows))
+	for index, row := range m.rows {
+		node := row.Node
+		if node == "" {
+			node = "-"
+		}
+		runID := row.RunID
+		if runID == "" {
+			runID = "-"
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(index + 1),
+			truncateBatchVars(row.Vars),
+			node,
+			formatCostUSD(row.CostUSD),
+			row.Status,
+			runID,
+		})
+	}
+	return rows
+}
+
+func truncateBatchVars(vars string) string {
+	runes := []rune(vars)
+	if len(runes) <= batchVarsColumnWidth {
+		return vars
+	}
+	return string(runes[:batchVarsColumnWidth-3]) + "..."
+}
@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"agent-cli/internal/config"
+	"agent-cli/internal/result"
+	"agent-cli/internal/stats"
+)
+
+const (
+	budgetLimitCostUSD = "max_cost_usd"
+	budgetLimitTokens  = "max_tokens"
+
+	// budgetWarningRatio is the share of a limit at which the run shows a warning.
+	budgetWarningRatio = 0.8
+)
+
+// budgetTracker checks accumulated spend against the run and per-node budget caps.
+type budgetTracker struct {
+	budget config.BudgetConfig
+	warned map[string]bool
+}
+
+// budgetSpend is the spend of one budget scope: the whole run or all runs of one node.
+type budgetSpend struct {
+	CostUSD float64
+	Tokens  int64
+}
+
+func newBudgetTracker(budget config.BudgetConfig) *budgetTracker {
+	return &budgetTracker{
+		budget: budget,
+		warned: map[string]bool{},
+	}
+}
+
+func (b *budgetTracker) enabled() bool {
+	if b == nil {
+		return false
+	}
+	if b.budget.MaxCostUSD > 0 || b.budget.MaxTokens > 0 {
+		return true
+	}
+	for _, node := range b.budget.Nodes {
+		if node.MaxCostUSD > 0 || node.MaxTokens > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns the first crossed limit, if any, and warnings for limits that passed
+// budgetWarningRatio since the previous check. Each warning is returned once.
+func (b *budgetTracker) check(run budgetSpend, nodes map[string]budgetSpend) (*stats.BudgetBreach, []string) {
+	warnings := make([]string, 0)
+	if breach := b.checkScope("", run, b.budget.MaxCostUSD, b.budget.MaxTokens, &warnings); breach != nil {
+		return breach, warnings
+	}
+
+	nodeIDs := make([]string, 0, len(b.budget.Nodes))
+	for nodeID := range b.budget.Nodes {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+	for _, nodeID := range nodeIDs {
+		limits := b.budget.Nodes[nodeID]
+		if breach := b.checkScope(nodeID, nodes[nodeID], limits.MaxCostUSD, limits.MaxTokens, &warnings); breach != nil {
+			return breach, warnings
+		}
+	}
+	return nil, warnings
+}
+
+func (b *budgetTracker) checkScope(
+	nodeID string,
+	spend budgetSpend,
+	maxCostUSD float64,
+	maxTokens int64,
+	warnings *[]string,
+) *stats.BudgetBreach {
+	breach := &stats.BudgetBreach{
+		NodeID:  nodeID,
+		CostUSD: spend.CostUSD,
+		Tokens:  spend.Tokens,
+	}
+	if maxCostUSD > 0 {
+		if spend.CostUSD > maxCostUSD {
+			breach.Limit = budgetLimitCostUSD
+			breach.MaxCostUSD = maxCostUSD
+			return breach
+		}
+		if spend.CostUSD >= maxCostUSD*budgetWarningRatio {
+			b.warnOnce(nodeID, budgetLimitCostUSD, warnings, fmt.Sprintf(
+				"%s cost %s of %s USD (%.0f%%)",
+				budgetScopeName(nodeID),
+				formatCostUSD(spend.CostUSD),
+				formatCostUSD(maxCostUSD),
+				spend.CostUSD/maxCostUSD*100,
+			))
+		}
+	}
+	if maxTokens > 0 {
+		if spend.Tokens > maxTokens {
+			breach.Limit = budgetLimitTokens
+			breach.MaxTokens = maxTokens
+			return breach
+		}
+		if float64(spend.Tokens) >= float64(maxTokens)*budgetWarningRatio {
+			b.warnOnce(nodeID, budgetLimitTokens, warnings, fmt.Sprintf(
+				"%s used %d of %d tokens (%.0f%%)",
+				budgetScopeName(nodeID),
+				spend.Tokens,
+				maxTokens,
+				float64(spend.Tokens)/float64(maxTokens)*100,
+			))
+		}
+	}
+	return nil
+}
+
+func (b *budgetTracker) warnOnce(nodeID string, limit string, warnings *[]string, message string) {
+	key := nodeID + "\x00" + limit
+	if b.warned[key] {
+		return
+	}
+	b.warned[key] = true
+	*warnings = append(*warnings, "budget warning: "+message)
+}
+
+func budgetScopeName(nodeID string) string {
+	if nodeID == "" {
+		return "run"
+	}
+	return "node " + nodeID
+}
+
+// budgetBreachMessage describes a breach for RunRecord.ErrorMessage.
+func budgetBreachMessage(breach *stats.BudgetBreach) string {
+	if breach.Limit == budgetLimitTokens {
+		return fmt.Sprintf(
+			"budget exceeded: %s used %d tokens, over %s %d",
+			budgetScopeName(breach.NodeID),
+			breach.Tokens,
+			budgetLimitTokens,
+			breach.MaxTokens,
+		)
+	}
+	return fmt.Sprintf(
+		"budget exceeded: %s cost %s USD, over %s %s",
+		budgetScopeName(breach.NodeID),
+		formatCostUSD(breach.CostUSD),
+		budgetLimitCostUSD,
+		formatCostUSD(breach.MaxCostUSD),
+	)
+}
+
+func runBudgetSpend(metrics result.NormalizedMetrics) budgetSpend {
+	return budgetSpend{
+		CostUSD: metrics.TotalCostUSD,
+		Tokens: metrics.InputTokens +
+			metrics.CacheCreationInputTokens +
+			metrics.CacheReadInputTokens +
+			metrics.OutputTokens,
+	}
+}
+
+// nodeBudgetSpend sums the usage attributed so far to each node across all of its node runs.
+func nodeBudgetSpend(
+	sessionTaskBindings map[string]pipelineNodeRunRef,
+	taskUsageByKey map[string]*stats.PipelineNodeRunNormalized,
+) map[string]budgetSpend {
+	spend := map[string]budgetSpend{}
+	counted := map[string]bool{}
+	for _, ref := range sessionTaskBindings {
+		key := buildPipelineNodeRunKey(ref.NodeID, ref.NodeRunID)
+		usage := taskUsageByKey[key]
+		if usage == nil || counted[key] {
+			continue
+		}
+		counted[key] = true
+
+		nodeSpend := spend[ref.NodeID]
+		nodeSpend.CostUSD += usage.CostUSD
+		nodeSpend.Tokens += usage.InputTokens +
+			usage.CacheCreationInputTokens +
+			usage.CacheReadInputTokens +
+			usage.OutputTokens
+		spend[ref.NodeID] = nodeSpend
+	}
+	return spend
+}
+
+// checkSpendCaps sums the spend of the windows capped by [budget] daily_usd, weekly_usd and
+// monthly_usd. A reached cap refuses the run unless force is set, in which case it only warns.
+func checkSpendCaps(cwd string, budget config.BudgetConfig, force bool) ([]stats.SpendCap, error) {
+	limits := stats.SpendLimits{
+		DailyUSD:   budget.DailyUSD,
+		WeeklyUSD:  budget.WeeklyUSD,
+		MonthlyUSD: budget.MonthlyUSD,
+	}
+	caps, err := stats.SpendCaps(config.RunsDir(cwd), config.ActiveRunsDir(cwd), limits, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("check spend caps: %w", err)
+	}
+	for _, spendCap := range caps {
+		if !spendCap.Reached() {
+			continue
+		}
+		if !force {
+			return nil, fmt.Errorf("%s; pass --force to run anyway", spendCap.Message())
+		}
+		fmt.Fprintf(os.Stderr, "warning: %s; running anyway because of --force\n", spendCap.Message())
+	}
+	return caps, nil
+}
+
+// spendCapsLine lists what is left of each spend cap once runCostUSD, the cost of the current run,
+// is spent, such as "Allowance left: daily 4.000000 of 10.000000 USD · monthly 120.000000 of 200.000000 USD".
+func spendCapsLine(caps []stats.SpendCap, runCostUSD float64) string {
+	parts := make([]string, 0, len(caps))
+	for _, spendCap := range caps {
+		parts = append(parts, fmt.Sprintf(
+			"%s %s of %s USD",
+			spendCap.Window,
+			formatCostUSD(max(spendCap.RemainingUSD()-runCostUSD, 0)),
+			formatCostUSD(spendCap.LimitUSD),
+		))
+	}
+	return "Allowance left: " + strings.Join(parts, " · ")
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+
+	"agent-cli/internal/config"
+	"agent-cli/internal/stats"
+)
+
+func TestBudgetTrackerWarnsOnceAndReportsBreach(t *testing.T) {
+	t.Parallel()
+
+	tracker := newBudgetTracker(config.BudgetConfig{
+		MaxCostUSD: 10,
+		Nodes: map[string]config.NodeBudgetConfig{
+			"reviewer": {MaxTokens: 1000},
+		},
+	})
+	if !tracker.enabled() {
+		t.Fatal("expected budget to be enabled")
+	}
+
+	breach, warnings := tracker.check(budgetSpend{CostUSD: 8.5}, map[string]budgetSpend{"reviewer": {Tokens: 900}})
+	if breach != nil {
+		t.Fatalf("unexpected breach: %+v", breach)
+	}
+	want := []string{
+		"budget warning: run cost 8.500000 of 10.000000 USD (85%)",
+		"budget warning: node reviewer used 900 of 1000 tokens (90%)",
+	}
+	if !slices.Equal(warnings, want) {
+		t.Fatalf("unexpected warnings: %q", warnings)
+	}
+
+	breach, warnings = tracker.check(budgetSpend{CostUSD: 9}, map[string]budgetSpend{"reviewer": {CostUSD: 1, Tokens: 1200}})
+	if len(warnings) != 0 {
+		t.Fatalf("expected warnings to be reported once, got %q", warnings)
+	}
+	wantBreach := stats.BudgetBreach{Limit: "max_tokens", NodeID: "reviewer", MaxTokens: 1000, CostUSD: 1, Tokens: 1200}
+	if breach == nil || *breach != wantBreach {
+		t.Fatalf("unexpected breach: %+v", breach)
+	}
+	if got := budgetBreachMessage(breach); got != "budget exceeded: node reviewer used 1200 tokens, over max_tokens 1000" {
+		t.Fatalf("unexpected message: %q", got)
+	}
+
+	if newBudgetTracker(config.BudgetConfig{Nodes: map[string]config.NodeBudgetConfig{"a": {}}}).enabled() {
+		t.Fatal("expected budget without limits to be disabled")
+	}
+}
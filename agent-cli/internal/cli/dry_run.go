@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"agent-cli/internal/redact"
+	"agent-cli/internal/runner"
+)
+
+// secretEnvKeys are always masked in dry-run output, even when the token is too short for the redactor.
+var secretEnvKeys = map[string]bool{
+	"GH_TOKEN":                true,
+	"CLAUDE_CODE_OAUTH_TOKEN": true,
+}
+
+// printDryRun prints the resolved container spec with secrets masked.
+func printDryRun(out io.Writer, spec runner.ContainerSpec, redactor *redact.Redactor, jsonOutput bool) error {
+	spec = redactContainerSpec(spec, redactor)
+
+	if jsonOutput {
+		encoded, err := json.MarshalIndent(spec, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode dry-run JSON: %w", err)
+		}
+		fmt.Fprintln(out, string(encoded))
+		return nil
+	}
+
+	fmt.Fprintln(out, "Dry Run (container not started)")
+	for _, line := range renderTextTable([]string{"FIELD", "VALUE"}, dryRunTableRows(spec)) {
+		fmt.Fprintln(out, line)
+	}
+	return nil
+}
+
+func redactContainerSpec(spec runner.ContainerSpec, redactor *redact.Redactor) runner.ContainerSpec {
+	env := make([]string, 0, len(spec.Env))
+	for _, entry := range spec.Env {
+		key, value, _ := strings.Cut(entry, "=")
+		if secretEnvKeys[key] && value != "" {
+			env = append(env, key+"="+redact.Mask)
+			continue
+		}
+		env = append(env, redactor.String(entry))
+	}
+	spec.Env = env
+
+	cmd := make([]string, 0, len(spec.Cmd))
+	for _, arg := range spec.Cmd {
+		cmd = append(cmd, redactor.String(arg))
+	}
+	spec.Cmd = cmd
+
+	if len(spec.TemplateVars) > 0 {
+		templateVars := make(map[string]string, len(spec.TemplateVars))
+		for key, value := range spec.TemplateVars {
+			templateVars[key] = redactor.String(value)
+		}
+		spec.TemplateVars = templateVars
+	}
+
+	return spec
+}
+
+func dryRunTableRows(spec runner.ContainerSpec) [][]string {
+	rows := [][]string{
+		{"image", spec.Image},
+		{"model", spec.Model},
+		{"docker_mode", spec.DockerMode},
+		{"dind_storage_driver", spec.DinDStorageDriver},
+		{"network_mode", spec.NetworkMode},
+		{"privileged", strconv.FormatBool(spec.Privileged)},
+		{"auto_remove", strconv.FormatBool(spec.AutoRemove)},
+	}
+	if spec.ContainerPipelinePath != "" {
+		rows = append(rows, []string{"container_pipeline_path", spec.ContainerPipelinePath})
+	}
+
+	rows = appendDryRunListRows(rows, "cmd", spec.Cmd)
+	rows = appendDryRunListRows(rows, "env", spec.Env)
+	rows = appendDryRunListRows(rows, "binds", spec.Binds)
+	rows = appendDryRunListRows(rows, "labels", sortedKeyValuePairs(spec.Labels))
+	rows = appendDryRunListRows(rows, "template_vars", sortedKeyValuePairs(spec.TemplateVars))
+	return rows
+}
+
+func appendDryRunListRows(rows [][]string, field string, values []string) [][]string {
+	for index, value := range values {
+		label := ""
+		if index == 0 {
+			label = field
+		}
+		rows = append(rows, []string{label, value})
+	}
+	return rows
+}
+
+func sortedKeyValuePairs(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+values[key])
+	}
+	return pairs
+}
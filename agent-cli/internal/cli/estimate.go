@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"agent-cli/internal/pipeline"
+	"agent-cli/internal/stats"
+)
+
+// planNodeIDs lists the nodes of plan that run, in plan file order; terminal nodes cost nothing.
+func planNodeIDs(plan *pipeline.Plan) []string {
+	nodeIDs := make([]string, 0, len(plan.NodeOrder))
+	for _, nodeID := range plan.NodeOrder {
+		if node := plan.Nodes[nodeID]; node != nil && !node.Terminal {
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+	}
+	return nodeIDs
+}
+
+// printPipelineEstimate prints the expected cost and duration of a run of planPath per node and in total.
+func printPipelineEstimate(out io.Writer, planPath string, estimate *stats.PipelineEstimate, jsonOutput bool) error {
+	if jsonOutput {
+		encoded, err := json.MarshalIndent(estimate, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode estimate JSON: %w", err)
+		}
+		fmt.Fprintln(out, string(encoded))
+		return nil
+	}
+
+	switch estimate.Basis {
+	case stats.EstimateBasisPlan:
+		fmt.Fprintf(out, "Estimate for %s (from %d past run(s) of this plan)\n", planPath, estimate.Runs)
+	case stats.EstimateBasisNodes:
+		fmt.Fprintf(
+			out,
+			"Estimate for %s (from %d past run(s) of other plans with the same node ids)\n",
+			planPath,
+			estimate.Runs,
+		)
+	default:
+		fmt.Fprintf(out, "No past runs of %s or of its nodes to estimate from.\n", planPath)
+		return nil
+	}
+
+	rows := make([][]string, 0, len(estimate.Nodes)+1)
+	for _, node := range estimate.Nodes {
+		row := []string{node.NodeID, fmt.Sprintf("%d/%d", node.Runs, estimate.Runs), "-", "-", "-", "-"}
+		if node.Runs > 0 {
+			row = append(row[:2], estimateRangeCells(node.CostUSD, node.DurationMS)...)
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, append(
+		[]string{"Total", fmt.Sprintf("%d", estimate.Runs)},
+		estimateRangeCells(estimate.CostUSD, estimate.DurationMS)...,
+	))
+
+	headers := []string{"Node", "Runs", "p50 Cost(USD)", "p90 Cost(USD)", "p50 Duration", "p90 Duration"}
+	for _, line := range renderTextTable(headers, rows) {
+		fmt.Fprintln(out, line)
+	}
+	return nil
+}
+
+func estimateRangeCells(cost stats.CostRange, duration stats.DurationRange) []string {
+	return []string{
+		formatCostUSD(cost.P50),
+		formatCostUSD(cost.P90),
+		formatDurationMS(duration.P50),
+		formatDurationMS(duration.P90),
+	}
+}
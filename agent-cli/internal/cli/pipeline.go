@@ -0,0 +1,509 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"agent-cli/internal/config"
+	"agent-cli/internal/pipeline"
+	"agent-cli/internal/stats"
+)
+
+var pipelineOutputWriter io.Writer = os.Stdout
+
+func PipelineCommand(cwd string, args []string) error {
+	if len(args) == 0 {
+		return errors.New("pipeline command requires a subcommand: validate, lint, graph, simulate, estimate")
+	}
+
+	switch args[0] {
+	case "validate":
+		return pipelineValidateCommand(cwd, args[1:])
+	case "lint":
+		return pipelineLintCommand(cwd, args[1:])
+	case "graph":
+		return pipelineGraphCommand(cwd, args[1:])
+	case "simulate":
+		return pipelineSimulateCommand(cwd, args[1:])
+	case "estimate":
+		return pipelineEstimateCommand(cwd, args[1:])
+	default:
+		return fmt.Errorf("unknown pipeline subcommand %q", args[0])
+	}
+}
+
+func pipelineValidateCommand(cwd string, args []string) error {
+	fs := flag.NewFlagSet("pipeline validate", flag.ContinueOnError)
+	planFlags := registerPlanFlags(fs)
+
+	planPath, err := parsePlanArgs(fs, "pipeline validate", args)
+	if err != nil {
+		return err
+	}
+	opts, err := planFlags.loadOptions()
+	if err != nil {
+		return err
+	}
+
+	plan, err := loadPipelinePlan(cwd, planPath, opts)
+	if err != nil {
+		return err
+	}
+
+	printPlanSummary(pipelineOutputWriter, planPath, plan)
+	return nil
+}
+
+func pipelineLintCommand(cwd string, args []string) error {
+	fs := flag.NewFlagSet("pipeline lint", flag.ContinueOnError)
+	planFlags := registerPlanFlags(fs)
+	var jsonOutput bool
+	fs.BoolVar(&jsonOutput, "json", false, "print findings as JSON")
+
+	planPath, err := parsePlanArgs(fs, "pipeline lint", args)
+	if err != nil {
+		return err
+	}
+	opts, err := planFlags.loadOptions()
+	if err != nil {
+		return err
+	}
+	// Lint works on the plan shape, so placeholders without a --var value are fine.
+	opts.KeepTemplatePlaceholders = true
+
+	plan, err := loadPipelinePlan(cwd, planPath, opts)
+	if err != nil {
+		return err
+	}
+
+	findings := pipeline.Lint(plan)
+	if jsonOutput {
+		encoded, err := json.MarshalIndent(map[string]any{"plan": planPath, "findings": findings}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal lint findings: %w", err)
+		}
+		fmt.Fprintln(pipelineOutputWriter, string(encoded))
+	} else {
+		printLintFindings(pipelineOutputWriter, planPath, findings)
+	}
+
+	errorCount := countFindings(findings, pipeline.SeverityError)
+	if errorCount > 0 {
+		return fmt.Errorf("pipeline lint found %d error(s) in %s", errorCount, planPath)
+	}
+	return nil
+}
+
+func pipelineGraphCommand(cwd string, args []string) error {
+	fs := flag.NewFlagSet("pipeline graph", flag.ContinueOnError)
+	planFlags := registerPlanFlags(fs)
+	var format string
+	var runID string
+	fs.StringVar(&format, "format", graphFormatMermaid, "output format (mermaid|dot|svg)")
+	fs.StringVar(&runID, "run", "", "overlay the path taken by a recorded run (run id, id prefix or run directory name)")
+
+	planPath, err := parsePlanArgs(fs, "pipeline graph", args)
+	if err != nil {
+		return err
+	}
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format != graphFormatMermaid && format != graphFormatDOT && format != graphFormatSVG {
+		return fmt.Errorf("invalid --format %q: expected mermaid, dot or svg", format)
+	}
+	opts, err := planFlags.loadOptions()
+	if err != nil {
+		return err
+	}
+	opts.KeepTemplatePlaceholders = true
+
+	plan, err := loadPipelinePlan(cwd, planPath, opts)
+	if err != nil {
+		return err
+	}
+
+	var overlay *pipeline.GraphOverlay
+	if strings.TrimSpace(runID) != "" {
+		record, _, err := stats.FindRunRecord(config.RunsDir(cwd), runID)
+		if err != nil {
+			return err
+		}
+		overlay, err = graphOverlayFromRun(plan, record)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch format {
+	case graphFormatDOT:
+		_, err = io.WriteString(pipelineOutputWriter, pipeline.RenderDOT(plan, overlay))
+	case graphFormatSVG:
+		var svg []byte
+		svg, err = renderSVGFn(pipeline.RenderDOT(plan, overlay))
+		if err == nil {
+			_, err = pipelineOutputWriter.Write(svg)
+		}
+	default:
+		_, err = io.WriteString(pipelineOutputWriter, pipeline.RenderMermaid(plan, overlay))
+	}
+	return err
+}
+
+func pipelineSimulateCommand(cwd string, args []string) error {
+	fs := flag.NewFlagSet("pipeline simulate", flag.ContinueOnError)
+	planFlags := registerPlanFlags(fs)
+	var scriptPath string
+	var jsonOutput bool
+	fs.StringVar(&scriptPath, "script", "", "YAML file with scripted node outcomes (required)")
+	fs.BoolVar(&jsonOutput, "json", false, "print simulation results as JSON")
+
+	planPath, err := parsePlanArgs(fs, "pipeline simulate", args)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(scriptPath) == "" {
+		return errors.New("pipeline simulate requires --script")
+	}
+	opts, err := planFlags.loadOptions()
+	if err != nil {
+		return err
+	}
+	opts.KeepTemplatePlaceholders = true
+
+	plan, err := loadPipelinePlan(cwd, planPath, opts)
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(scriptPath) {
+		scriptPath = filepath.Join(cwd, scriptPath)
+	}
+	script, err := pipeline.LoadSimulationScript(scriptPath)
+	if err != nil {
+		return err
+	}
+
+	results := make([]*pipeline.SimulationResult, 0, len(script.Scenarios))
+	failed := 0
+	for _, scenario := range script.Scenarios {
+		result := pipeline.Simulate(plan, scenario)
+		if !result.Passed() {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	if jsonOutput {
+		encoded, err := json.MarshalIndent(map[string]any{"plan": planPath, "scenarios": results}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal simulation results: %w", err)
+		}
+		fmt.Fprintln(pipelineOutputWriter, string(encoded))
+	} else {
+		printSimulationResults(pipelineOutputWriter, results)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("pipeline simulate: %d of %d scenario(s) failed", failed, len(results))
+	}
+	return nil
+}
+
+func pipelineEstimateCommand(cwd string, args []string) error {
+	fs := flag.NewFlagSet("pipeline estimate", flag.ContinueOnError)
+	planFlags := registerPlanFlags(fs)
+	var jsonOutput bool
+	fs.BoolVar(&jsonOutput, "json", false, "print the estimate as JSON")
+
+	planPath, err := parsePlanArgs(fs, "pipeline estimate", args)
+	if err != nil {
+		return err
+	}
+	opts, err := planFlags.loadOptions()
+	if err != nil {
+		return err
+	}
+	// Only the node ids matter, so placeholders without a --var value are fine.
+	opts.KeepTemplatePlaceholders = true
+
+	plan, err := loadPipelinePlan(cwd, planPath, opts)
+	if err != nil {
+		return err
+	}
+	// Runs record the absolute plan path.
+	resolved := planPath
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(cwd, resolved)
+	}
+
+	estimate, err := stats.EstimatePipeline(config.RunsDir(cwd), resolved, planNodeIDs(plan))
+	if err != nil {
+		return err
+	}
+	return printPipelineEstimate(pipelineOutputWriter, planPath, estimate, jsonOutput)
+}
+
+func printSimulationResults(out io.Writer, results []*pipeline.SimulationResult) {
+	passed := 0
+	for index, result := range results {
+		if index > 0 {
+			fmt.Fprintln(out)
+		}
+		verdict := "FAIL"
+		if result.Passed() {
+			verdict = "PASS"
+			passed++
+		}
+		fmt.Fprintf(out, "Scenario: %s [%s]\n", result.Name, verdict)
+
+		if len(result.Steps) > 0 {
+			rows := make([][]string, 0, len(result.Steps))
+			for _, step := range result.Steps {
+				rows = append(rows, simulationStepRow(step))
+			}
+			headers := []string{"ITER", "NODE", "ATTEMPT", "STATUS", "EXIT", "DECISION", "TRANSITION"}
+			for _, line := range renderTextTable(headers, rows) {
+				fmt.Fprintf(out, "  %s\n", line)
+			}
+		}
+
+		switch {
+		case result.TerminalNode != "":
+			fmt.Fprintf(
+				out,
+				"  Result: %s (%s, exit %d) after %d iteration(s)\n",
+				result.TerminalNode,
+				result.TerminalStatus,
+				result.ExitCode,
+				result.Iterations,
+			)
+		case result.Error != "":
+			fmt.Fprintf(out, "  Result: %s (exit %d) after %d iteration(s)\n", result.Error, result.ExitCode, result.Iterations)
+		}
+		if len(result.Path) > 0 {
+			fmt.Fprintf(out, "  Path: %s\n", strings.Join(result.Path, " -> "))
+		}
+		for _, failure := range result.Failures {
+			fmt.Fprintf(out, "  - %s\n", failure)
+		}
+	}
+	fmt.Fprintf(out, "\nScenarios: %d passed, %d failed\n", passed, len(results)-passed)
+}
+
+func simulationStepRow(step pipeline.SimulationStep) []string {
+	decision := "-"
+	if len(step.Decision) > 0 {
+		encoded, err := json.Marshal(step.Decision)
+		if err == nil {
+			decision = string(encoded)
+		}
+	}
+	if len(step.Errors) > 0 {
+		decision += " (" + strings.Join(step.Errors, "; ") + ")"
+	}
+
+	transition := "-"
+	if step.To != "" {
+		transition = fmt.Sprintf("%s -> %s", step.When, step.To)
+	}
+	return []string{
+		strconv.Itoa(step.Iteration),
+		step.NodeID,
+		strconv.Itoa(step.Attempt),
+		step.Status,
+		strconv.Itoa(step.ExitCode),
+		decision,
+		transition,
+	}
+}
+
+const (
+	graphFormatMermaid = "mermaid"
+	graphFormatDOT     = "dot"
+	graphFormatSVG     = "svg"
+)
+
+var renderSVGFn = renderDOTToSVG
+
+// renderDOTToSVG lays out the graph with Graphviz, which must be installed on the host.
+func renderDOTToSVG(dot string) ([]byte, error) {
+	dotPath, err := exec.LookPath("dot")
+	if err != nil {
+		return nil, fmt.Errorf("svg output requires Graphviz dot on PATH (or use --format dot): %w", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(dotPath, "-Tsvg")
+	cmd.Stdin = strings.NewReader(dot)
+	cmd.Stderr = &stderr
+	svg, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("render svg with dot: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return svg, nil
+}
+
+// graphOverlayFromRun counts node visits and node-to-node hops from a recorded pipeline run.
+func graphOverlayFromRun(plan *pipeline.Plan, record *stats.RunRecord) (*pipeline.GraphOverlay, error) {
+	if record.Pipeline == nil {
+		return nil, fmt.Errorf("run %s is not a pipeline run", record.RunID)
+	}
+
+	overlay := &pipeline.GraphOverlay{NodeVisits: map[string]int{}, EdgeVisits: map[string]int{}}
+	previous := ""
+	for _, nodeRun := range record.Pipeline.NodeRuns {
+		if _, ok := plan.Nodes[nodeRun.NodeID]; !ok {
+			return nil, fmt.Errorf("run %s visited node %s, which is not in the plan", record.RunID, nodeRun.NodeID)
+		}
+		overlay.NodeVisits[nodeRun.NodeID]++
+		if previous != "" {
+			overlay.EdgeVisits[pipeline.GraphEdgeKey(previous, nodeRun.NodeID)]++
+		}
+		previous = nodeRun.NodeID
+	}
+
+	terminal := record.Pipeline.TerminalNode
+	if _, ok := plan.Nodes[terminal]; ok && terminal != "" {
+		overlay.NodeVisits[terminal]++
+		if previous != "" {
+			overlay.EdgeVisits[pipeline.GraphEdgeKey(previous, terminal)]++
+		}
+	}
+	return overlay, nil
+}
+
+// planFlags holds the plan loading flags shared by pipeline subcommands.
+type planFlags struct {
+	model        string
+	templateVars templateVarValues
+}
+
+func registerPlanFlags(fs *flag.FlagSet) *planFlags {
+	fs.SetOutput(os.Stderr)
+
+	flags := &planFlags{}
+	fs.StringVar(&flags.model, "model", "", "fallback model for nodes without defaults.model (sonnet|opus)")
+	fs.Var(&flags.templateVars, "var", "template variable in KEY=VALUE format (repeatable)")
+	return flags
+}
+
+func (f *planFlags) loadOptions() (pipeline.LoadOptions, error) {
+	model := strings.ToLower(strings.TrimSpace(f.model))
+	if model != "" && !config.IsValidDockerModel(model) {
+		return pipeline.LoadOptions{}, fmt.Errorf(
+			"invalid --model %q: expected %s or %s",
+			model,
+			config.DockerModelSonnet,
+			config.DockerModelOpus,
+		)
+	}
+	return pipeline.LoadOptions{TemplateVars: f.templateVars.values, FallbackModel: model}, nil
+}
+
+func parsePlanArgs(fs *flag.FlagSet, command string, args []string) (string, error) {
+	positionals, err := parseInterspersedFlags(fs, args)
+	if err != nil {
+		return "", err
+	}
+	if len(positionals) != 1 {
+		return "", fmt.Errorf("%s requires exactly one plan path", command)
+	}
+	return positionals[0], nil
+}
+
+// loadPipelinePlan validates a plan the same way the container entrypoint does, using cwd as the workspace.
+func loadPipelinePlan(cwd string, planPath string, opts pipeline.LoadOptions) (*pipeline.Plan, error) {
+	resolved := planPath
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(cwd, resolved)
+	}
+
+	opts.WorkspaceDir = cwd
+	plan, err := pipeline.LoadPlanFile(resolved, opts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pipeline plan %s: %w", planPath, err)
+	}
+	return plan, nil
+}
+
+func printPlanSummary(out io.Writer, planPath string, plan *pipeline.Plan) {
+	terminalCount := 0
+	builtinCount := 0
+	for _, node := range plan.Nodes {
+		if node.Builtin {
+			builtinCount++
+			continue
+		}
+		if node.Terminal {
+			terminalCount++
+		}
+	}
+
+	fmt.Fprintf(out, "Pipeline plan is valid: %s\n", planPath)
+	fmt.Fprintf(out, "  Entry: %s\n", plan.EntryNode)
+	fmt.Fprintf(out, "  Nodes: %d (%d terminal, %d implicit built-in)\n", len(plan.NodeOrder), terminalCount, builtinCount)
+	fmt.Fprintf(out, "  Default Model: %s\n", plan.Defaults.Model)
+	fmt.Fprintf(
+		out,
+		"  Limits: max_iterations=%d, max_same_node_hits=%d\n",
+		plan.Limits.MaxIterations,
+		plan.Limits.MaxSameNodeHits,
+	)
+}
+
+func printLintFindings(out io.Writer, planPath string, findings []pipeline.Finding) {
+	if len(findings) == 0 {
+		fmt.Fprintf(out, "No lint findings: %s\n", planPath)
+		return
+	}
+
+	for _, finding := range findings {
+		location := planPath
+		if finding.Line > 0 {
+			location = fmt.Sprintf("%s:%d", planPath, finding.Line)
+		}
+		fmt.Fprintf(out, "%s: %s: %s [%s]\n", location, finding.Severity, finding.Message, finding.Rule)
+	}
+	fmt.Fprintf(
+		out,
+		"\n%d error(s), %d warning(s)\n",
+		countFindings(findings, pipeline.SeverityError),
+		countFindings(findings, pipeline.SeverityWarning),
+	)
+}
+
+func countFindings(findings []pipeline.Finding, severity pipeline.Severity) int {
+	count := 0
+	for _, finding := range findings {
+		if finding.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// parseInterspersedFlags parses flags that may appear before or after positional arguments.
+func parseInterspersedFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	positionals := make([]string, 0, 1)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positionals, nil
+		}
+		if rest[0] == "--" {
+			return append(positionals, rest[1:]...), nil
+		}
+		positionals = append(positionals, rest[0])
+		args = rest[1:]
+	}
+}
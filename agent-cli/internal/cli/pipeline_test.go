@@ -0,0 +1,375 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agent-cli/internal/config"
+	"agent-cli/internal/pipeline"
+	"agent-cli/internal/stats"
+)
+
+func TestPipelineValidateCommandPrintsSummary(t *testing.T) {
+	cwd := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cwd, "pipeline.yaml"), []byte(testPipelinePlan("ISSUE")), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+
+	var out bytes.Buffer
+	withPipelineOutput(t, &out)
+
+	if err := PipelineCommand(cwd, []string{"validate", "pipeline.yaml", "--var", "ISSUE=42"}); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	text := out.String()
+	for _, want := range []string{
+		"Pipeline plan is valid: pipeline.yaml",
+		"Entry: implement",
+		"Nodes: 1 (0 terminal, 2 implicit built-in)",
+		"Default Model: opus",
+		"Limits: max_iterations=100, max_same_node_hits=25",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in output: %q", want, text)
+		}
+	}
+}
+
+func TestPipelineValidateCommandReportsPlanErrors(t *testing.T) {
+	cwd := t.TempDir()
+	plan := strings.Replace(testPipelinePlan(), "to: success", "to: nowhere", 1)
+	if err := os.WriteFile(filepath.Join(cwd, "pipeline.yaml"), []byte(plan), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+
+	var out bytes.Buffer
+	withPipelineOutput(t, &out)
+
+	err := PipelineCommand(cwd, []string{"validate", "pipeline.yaml"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if err.Error() != "invalid pipeline plan pipeline.yaml: nodes.implement.transitions has unknown target node: nowhere" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected no output, got %q", out.String())
+	}
+}
+
+func TestPipelineValidateCommandRequiresSinglePlan(t *testing.T) {
+	t.Parallel()
+
+	for _, args := range [][]string{
+		{"validate"},
+		{"validate", "a.yaml", "b.yaml"},
+	} {
+		err := PipelineCommand(t.TempDir(), args)
+		if err == nil || !strings.Contains(err.Error(), "requires exactly one plan path") {
+			t.Fatalf("unexpected error for %v: %v", args, err)
+		}
+	}
+}
+
+func TestPipelineLintCommandReportsFindingsWithLines(t *testing.T) {
+	cwd := t.TempDir()
+	plan := testPipelinePlan("ISSUE") +
+		"  orphan:\n    run: {kind: command, cmd: x}\n    transitions: [{when: 'true', to: orphan}]\n"
+	if err := os.WriteFile(filepath.Join(cwd, "pipeline.yaml"), []byte(plan), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+
+	var out bytes.Buffer
+	withPipelineOutput(t, &out)
+
+	if err := PipelineCommand(cwd, []string{"lint", "pipeline.yaml"}); err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+
+	want := "pipeline.yaml:11: warning: node orphan is not reachable from entry node implement [unreachable-node]\n" +
+		"\n0 error(s), 1 warning(s)\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+}
+
+func TestPipelineLintCommandFailsOnErrors(t *testing.T) {
+	cwd := t.TempDir()
+	plan := strings.Replace(testPipelinePlan(), "to: success", "to: implement", 1)
+	if err := os.WriteFile(filepath.Join(cwd, "pipeline.yaml"), []byte(plan), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+
+	var out bytes.Buffer
+	withPipelineOutput(t, &out)
+
+	err := PipelineCommand(cwd, []string{"lint", "--json", "pipeline.yaml"})
+	if err == nil || err.Error() != "pipeline lint found 1 error(s) in pipeline.yaml" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var payload struct {
+		Plan     string             `json:"plan"`
+		Findings []pipeline.Finding `json:"findings"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &payload); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if payload.Plan != "pipeline.yaml" || len(payload.Findings) != 1 {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+	finding := payload.Findings[0]
+	if finding.Rule != pipeline.RuleNoTerminalPath || finding.NodeID != "implement" || finding.Line != 4 {
+		t.Fatalf("unexpected finding: %+v", finding)
+	}
+}
+
+func TestPipelineGraphCommandMermaid(t *testing.T) {
+	cwd := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cwd, "pipeline.yaml"), []byte(testPipelinePlan("ISSUE")), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+
+	var out bytes.Buffer
+	withPipelineOutput(t, &out)
+
+	if err := PipelineCommand(cwd, []string{"graph", "pipeline.yaml"}); err != nil {
+		t.Fatalf("graph: %v", err)
+	}
+
+	text := out.String()
+	for _, want := range []string{
+		"flowchart TD\n",
+		`  n0[["implement<br/>command"]]`,
+		"  entry --> n0\n",
+		`  n0 -->|"run.exit_code == 0"| n1`,
+		`  n0 -.->|"run.status == #quot;error#quot; (implicit)"| n2`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in output:\n%s", want, text)
+		}
+	}
+}
+
+func TestPipelineGraphCommandRunOverlay(t *testing.T) {
+	cwd := t.TempDir()
+	plan := testPipelinePlan() + "      - when: run.exit_code != 0\n        to: implement\n"
+	if err := os.WriteFile(filepath.Join(cwd, "pipeline.yaml"), []byte(plan), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+	_, err := stats.SaveRunRecord(config.RunsDir(cwd), &stats.RunRecord{
+		RunID:  "feedbeef00",
+		Status: stats.RunStatusSuccess,
+		Pipeline: &stats.PipelineRunRecord{
+			EntryNode:    "implement",
+			TerminalNode: "success",
+			NodeRuns: []stats.PipelineNodeRunRecord{
+				{NodeID: "implement", ExitCode: 1},
+				{NodeID: "implement", ExitCode: 0},
+			},
+		},
+	}, stats.RetentionPolicy{})
+	if err != nil {
+		t.Fatalf("save record: %v", err)
+	}
+
+	var out bytes.Buffer
+	withPipelineOutput(t, &out)
+
+	if err := PipelineCommand(cwd, []string{"graph", "pipeline.yaml", "--format", "dot", "--run", "feedbeef"}); err != nil {
+		t.Fatalf("graph: %v", err)
+	}
+
+	text := out.String()
+	for _, want := range []string{
+		`"implement" [label="implement\ncommand\nvisits: 2"`,
+		`"success" [label="success\nterminal: success (exit 0)\nvisits: 1"`,
+		`"implement" -> "success" [label="run.exit_code == 0 ×1", color="#28a745", penwidth=2.5];`,
+		`"implement" -> "implement" [label="run.exit_code != 0 ×1", color="#28a745", penwidth=2.5];`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in output:\n%s", want, text)
+		}
+	}
+}
+
+func TestPipelineGraphCommandSVGUsesRenderer(t *testing.T) {
+	cwd := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cwd, "pipeline.yaml"), []byte(testPipelinePlan()), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+
+	var out bytes.Buffer
+	withPipelineOutput(t, &out)
+	prevRender := renderSVGFn
+	var gotDOT string
+	renderSVGFn = func(dot string) ([]byte, error) {
+		gotDOT = dot
+		return []byte("<svg/>\n"), nil
+	}
+	t.Cleanup(func() {
+		renderSVGFn = prevRender
+	})
+
+	if err := PipelineCommand(cwd, []string{"graph", "--format", "SVG", "pipeline.yaml"}); err != nil {
+		t.Fatalf("graph: %v", err)
+	}
+	if out.String() != "<svg/>\n" {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+	if !strings.HasPrefix(gotDOT, "digraph pipeline {") {
+		t.Fatalf("unexpected dot input: %q", gotDOT)
+	}
+}
+
+func TestPipelineGraphCommandRejectsUnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	err := PipelineCommand(t.TempDir(), []string{"graph", "--format", "png", "pipeline.yaml"})
+	if err == nil || err.Error() != `invalid --format "png": expected mermaid, dot or svg` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPipelineSimulateCommandPrintsPathAndFailsOnUnmetExpectations(t *testing.T) {
+	cwd := t.TempDir()
+	plan := testPipelinePlan("ISSUE") + "      - when: run.exit_code == 2\n        to: implement\n"
+	if err := os.WriteFile(filepath.Join(cwd, "pipeline.yaml"), []byte(plan), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+	script := `
+scenarios:
+  - name: retry then pass
+    nodes:
+      implement: [{exit_code: 2}, {exit_code: 0}]
+    expect: {terminal: success}
+  - name: expected to pass
+    nodes:
+      implement: {exit_code: 1}
+    expect: {terminal: success}
+`
+	if err := os.WriteFile(filepath.Join(cwd, "sim.yaml"), []byte(script), 0o644); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+
+	var out bytes.Buffer
+	withPipelineOutput(t, &out)
+
+	err := PipelineCommand(cwd, []string{"simulate", "pipeline.yaml", "--script", "sim.yaml"})
+	if err == nil || err.Error() != "pipeline simulate: 1 of 2 scenario(s) failed" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	text := out.String()
+	for _, want := range []string{
+		"Scenario: retry then pass [PASS]",
+		"run.exit_code == 2 -> implement",
+		"Result: success (success, exit 0) after 2 iteration(s)",
+		"Path: implement -> implement -> success",
+		"Scenario: expected to pass [FAIL]",
+		"Result: fail (failed, exit 1) after 1 iteration(s)",
+		"  - terminal: expected success, got fail",
+		"Scenarios: 1 passed, 1 failed",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in output:\n%s", want, text)
+		}
+	}
+}
+
+func TestPipelineSimulateCommandRequiresScript(t *testing.T) {
+	t.Parallel()
+
+	err := PipelineCommand(t.TempDir(), []string{"simulate", "pipeline.yaml"})
+	if err == nil || err.Error() != "pipeline simulate requires --script" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPipelineEstimateCommand(t *testing.T) {
+	cwd := t.TempDir()
+	planPath := filepath.Join(cwd, "pipeline.yaml")
+	if err := os.WriteFile(planPath, []byte(testResumePipelinePlan), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+
+	var out bytes.Buffer
+	withPipelineOutput(t, &out)
+
+	if err := PipelineCommand(cwd, []string{"estimate", "pipeline.yaml"}); err != nil {
+		t.Fatalf("estimate: %v", err)
+	}
+	assertContains(t, out.String(), "No past runs of pipeline.yaml or of its nodes to estimate from.")
+
+	for _, costs := range [][2]float64{{1, 0.5}, {3, 0.25}} {
+		record := &stats.RunRecord{
+			Status:       stats.RunStatusSuccess,
+			PipelinePath: planPath,
+			Pipeline: &stats.PipelineRunRecord{NodeRuns: []stats.PipelineNodeRunRecord{
+				{NodeID: "build", DurationMS: 60000, Normalized: &stats.PipelineNodeRunNormalized{CostUSD: costs[0]}},
+				{NodeID: "review", DurationMS: 30000, Normalized: &stats.PipelineNodeRunNormalized{CostUSD: costs[1]}},
+			}},
+		}
+		if _, err := stats.SaveRunRecord(config.RunsDir(cwd), record, stats.RetentionPolicy{}); err != nil {
+			t.Fatalf("save run record: %v", err)
+		}
+	}
+
+	out.Reset()
+	if err := PipelineCommand(cwd, []string{"estimate", "pipeline.yaml"}); err != nil {
+		t.Fatalf("estimate: %v", err)
+	}
+	text := out.String()
+	for _, want := range []string{
+		"Estimate for pipeline.yaml (from 2 past run(s) of this plan)",
+		"p50 Cost(USD)",
+		"build",
+		"2/2",
+		"1.000000",
+		"3.000000",
+		"1m",
+		"3.250000",
+		"1m30s",
+	} {
+		assertContains(t, text, want)
+	}
+
+	out.Reset()
+	if err := PipelineCommand(cwd, []string{"estimate", "--json", "pipeline.yaml"}); err != nil {
+		t.Fatalf("estimate: %v", err)
+	}
+	var estimate stats.PipelineEstimate
+	if err := json.Unmarshal(out.Bytes(), &estimate); err != nil {
+		t.Fatalf("decode estimate: %v", err)
+	}
+	if estimate.Basis != stats.EstimateBasisPlan || estimate.Runs != 2 || estimate.Pipeline != planPath {
+		t.Fatalf("unexpected estimate: %+v", estimate)
+	}
+	if len(estimate.Nodes) != 2 || estimate.Nodes[0].NodeID != "build" || estimate.Nodes[1].CostUSD.P90 != 0.5 {
+		t.Fatalf("unexpected node estimates: %+v", estimate.Nodes)
+	}
+}
+
+func TestPipelineCommandUnknownSubcommand(t *testing.T) {
+	t.Parallel()
+
+	err := PipelineCommand(t.TempDir(), []string{"bogus"})
+	if err == nil || err.Error() != `unknown pipeline subcommand "bogus"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func withPipelineOutput(t *testing.T, out *bytes.Buffer) {
+	t.Helper()
+
+	prev := pipelineOutputWriter
+	pipelineOutputWriter = out
+	t.Cleanup(func() {
+		pipelineOutputWriter = prev
+	})
+}
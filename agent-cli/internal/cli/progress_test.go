@@ -3,6 +3,7 @@ package cli
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"agent-cli/internal/result"
 	"agent-cli/internal/stats"
@@ -484,6 +485,84 @@ func TestProgressTUIModelShowsNonJSONLogCounterOnly(t *testing.T) {
 	assertNotContains(t, view, "[stderr] gh: auth ok")
 }
 
+func TestProgressTUIModelShowsBudgetWarnings(t *testing.T) {
+	t.Parallel()
+
+	model := newProgressTUIModel(true, nil)
+	nextModel, _ := model.Update(warningMsg{Text: "budget warning: run cost 8.500000 of 10.000000 USD (85%)"})
+	updated, ok := nextModel.(progressTUIModel)
+	if !ok {
+		t.Fatalf("unexpected model type: %T", nextModel)
+	}
+
+	assertContains(t, updated.View(), "⚠ budget warning: run cost 8.500000 of 10.000000 USD (85%)")
+}
+
+func TestProgressTUIModelShowsSpendCaps(t *testing.T) {
+	t.Parallel()
+
+	model := newProgressTUIModel(false, nil)
+	nextModel, _ := model.Update(spendCapsMsg{Caps: []stats.SpendCap{
+		{Window: stats.SpendWindowDaily, LimitUSD: 10, SpentUSD: 4},
+		{Window: stats.SpendWindowMonthly, LimitUSD: 200, SpentUSD: 80},
+	}})
+	updated, ok := nextModel.(progressTUIModel)
+	if !ok {
+		t.Fatalf("unexpected model type: %T", nextModel)
+	}
+	assertContains(t, updated.View(), "Allowance left: daily 6.000000 of 10.000000 USD · monthly 120.000000 of 200.000000 USD")
+
+	updated = applyStreamLine(t, updated, `{"type":"result","subtype":"success","is_error":false,"session_id":"s1","total_cost_usd":1.5,"usage":{"input_tokens":10,"cache_creation_input_tokens":0,"cache_read_input_tokens":0,"output_tokens":5}}`)
+	assertContains(t, updated.View(), "Allowance left: daily 4.500000 of 10.000000 USD · monthly 118.500000 of 200.000000 USD")
+}
+
+func TestProgressTUIModelShowsEstimate(t *testing.T) {
+	t.Parallel()
+
+	model := newProgressTUIModel(true, nil)
+	startedAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	clock := startedAt.Add(20 * time.Second)
+	model.now = func() time.Time { return clock }
+	if model.Init() == nil {
+		t.Fatal("expected Init to start the progress tick")
+	}
+	nextModel, _ := model.Update(estimateMsg{Estimate: &stats.PipelineEstimate{
+		Runs: 4,
+		Nodes: []stats.NodeEstimate{
+			{NodeID: "implement", Runs: 4, VisitRate: 1, CostUSD: stats.CostRange{P50: 2}, DurationMS: stats.DurationRange{P50: 60000}},
+			{NodeID: "review", Runs: 2, VisitRate: 0.5, CostUSD: stats.CostRange{P50: 1}, DurationMS: stats.DurationRange{P50: 40000}},
+			{NodeID: "deploy"},
+		},
+	}})
+	updated, ok := nextModel.(progressTUIModel)
+	if !ok {
+		t.Fatalf("unexpected model type: %T", nextModel)
+	}
+	assertContains(t, updated.View(), "Estimate: ETA ~1m20s · projected cost 2.500000 USD (from 4 past run(s))")
+
+	updated = applyStreamLine(t, updated, `{"type":"pipeline_event","event":"node_start","node_id":"implement","node_run_id":"implement-1","started_at":"2026-10-18T12:00:00Z"}`)
+	assertContains(t, updated.View(), "Estimate: ETA ~1m0s · projected cost 2.500000 USD")
+
+	// Ticks re-render the ETA while the node runs without output.
+	clock = clock.Add(15 * time.Second)
+	nextModel, cmd := updated.Update(progressTickMsg(clock))
+	if cmd == nil {
+		t.Fatal("expected the tick to be re-armed")
+	}
+	updated = nextModel.(progressTUIModel)
+	assertContains(t, updated.View(), "Estimate: ETA ~45s · projected cost 2.500000 USD")
+
+	updated = applyStreamLine(t, updated, `{"type":"pipeline_event","event":"node_session_bind","node_id":"implement","node_run_id":"implement-1","session_id":"s1"}`)
+	updated = applyStreamLine(t, updated, `{"type":"result","subtype":"success","is_error":false,"session_id":"s1","total_cost_usd":3,"usage":{"input_tokens":10,"cache_creation_input_tokens":0,"cache_read_input_tokens":0,"output_tokens":5}}`)
+	updated = applyStreamLine(t, updated, `{"type":"pipeline_event","event":"node_finish","node_id":"implement","node_run_id":"implement-1","status":"success","duration_ms":65000}`)
+	assertContains(t, updated.View(), "Estimate: ETA ~20s · projected cost 3.500000 USD")
+
+	nextModel, _ = updated.Update(runFinishedMsg{})
+	if _, cmd := nextModel.Update(progressTickMsg(clock)); cmd != nil {
+		t.Fatal("expected the tick to stop once the run finished")
+	}
+}
+
 func TestFormatCompactTokens(t *testing.T) {
 	t.Parallel()
 
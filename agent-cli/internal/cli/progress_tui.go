@@ -15,8 +15,9 @@ import (
 )
 
 const (
-	ansiGreen = "\x1b[32m"
-	ansiReset = "\x1b[0m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
 )
 
 type ProgressTUI struct {
@@ -34,10 +35,29 @@ type rawLogLineMsg struct {
 	Line   string
 }
 
+type warningMsg struct {
+	Text string
+}
+
+type spendCapsMsg struct {
+	Caps []stats.SpendCap
+}
+
+type estimateMsg struct {
+	Estimate *stats.PipelineEstimate
+}
+
 type runFinishedMsg struct {
 	Record *stats.RunRecord
 }
 
+// progressTickMsg re-renders the view between stream events, so the ETA keeps counting down while a
+// node runs without output.
+type progressTickMsg time.Time
+
+// progressTickInterval is how often progressTickMsg arrives.
+const progressTickInterval = time.Second
+
 func NewProgressTUI(
 	ctx context.Context,
 	output io.Writer,
@@ -84,6 +104,20 @@ func (p *ProgressTUI) SendRawLine(source string, line string) {
 	})
 }
 
+func (p *ProgressTUI) SendWarning(text string) {
+	p.program.Send(warningMsg{Text: text})
+}
+
+// SendSpendCaps shows what is left of the spend caps below the header.
+func (p *ProgressTUI) SendSpendCaps(caps []stats.SpendCap) {
+	p.program.Send(spendCapsMsg{Caps: caps})
+}
+
+// SendEstimate shows a live ETA and projected cost below the header, based on estimate.
+func (p *ProgressTUI) SendEstimate(estimate *stats.PipelineEstimate) {
+	p.program.Send(estimateMsg{Estimate: estimate})
+}
+
 func (p *ProgressTUI) Finish(record *stats.RunRecord) {
 	if !p.finished.CompareAndSwap(false, true) {
 		return
@@ -112,9 +146,18 @@ type progressTUIModel struct {
 	toolUseIDByToolKey     map[string]string
 	pendingOutcomeBySessID map[string]taskOutcome
 	nonJSONLogCount        int
+	warnings               []string
+
+	// spendCaps are the [budget] spend caps as of the run start; runCostUSD is what the run has
+	// spent since, which the header subtracts from them.
+	spendCaps  []stats.SpendCap
+	runCostUSD float64
+	// estimate, if set, projects the remaining duration and final cost of a pipeline run.
+	estimate *stats.PipelineEstimate
 
 	finalRecord *stats.RunRecord
 	cancelRun   context.CancelFunc
+	now         func() time.Time
 }
 
 type pipelineStageState struct {
@@ -140,8 +183,9 @@ type pipelineTaskState struct {
 	CacheReadTokens int64
 	CostUSD         float64
 
-	Started bool
-	Done    bool
+	Started   bool
+	StartedAt time.Time
+	Done      bool
 
 	ActiveSteps     map[string]activeStep
 	StepOrder       []string
@@ -171,6 +215,7 @@ func newProgressTUIModel(pipelineHint bool, cancelRun context.CancelFunc) progre
 		toolUseIDByToolKey:     map[string]string{},
 		pendingOutcomeBySessID: map[string]taskOutcome{},
 		cancelRun:              cancelRun,
+		now:                    time.Now,
 	}
 
 	if !pipelineHint {
@@ -181,7 +226,13 @@ func newProgressTUIModel(pipelineHint bool, cancelRun context.CancelFunc) progre
 }
 
 func (m progressTUIModel) Init() tea.Cmd {
-	return nil
+	return progressTick()
+}
+
+func progressTick() tea.Cmd {
+	return tea.Tick(progressTickInterval, func(t time.Time) tea.Msg {
+		return progressTickMsg(t)
+	})
 }
 
 func (m progressTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -197,6 +248,17 @@ func (m progressTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.handleStreamEvent(typed.Event)
 	case rawLogLineMsg:
 		m.countNonJSONLogLine(typed.Source, typed.Line)
+	case warningMsg:
+		m.warnings = append(m.warnings, typed.Text)
+	case spendCapsMsg:
+		m.spendCaps = typed.Caps
+	case estimateMsg:
+		m.estimate = typed.Estimate
+	case progressTickMsg:
+		if m.done {
+			return m, nil
+		}
+		return m, progressTick()
 	case runFinishedMsg:
 		m.finalRecord = typed.Record
 		if typed.Record != nil {
@@ -251,6 +313,15 @@ func (m progressTUIModel) View() string {
 	} else {
 		lines = append(lines, m.renderRunHeader())
 	}
+	if len(m.spendCaps) > 0 {
+		lines = append(lines, spendCapsLine(m.spendCaps, m.runCostUSD))
+	}
+	if m.estimate != nil && m.estimate.Runs > 0 && !m.done {
+		lines = append(lines, m.renderEstimateLine())
+	}
+	for _, warning := range m.warnings {
+		lines = append(lines, colorWarning("⚠ "+warning))
+	}
 
 	lines = append(lines, m.renderTree()...)
 
@@ -324,6 +395,10 @@ func (m *progressTUIModel) handlePipelineEvent(event *result.PipelineEvent) {
 		m.ensureStage(event.NodeID)
 		task := m.ensureTask(event.NodeID, event.NodeRunID)
 		task.Started = true
+		task.StartedAt = m.now()
+		if startedAt, err := time.Parse(time.RFC3339Nano, event.StartedAt); err == nil {
+			task.StartedAt = startedAt
+		}
 		task.Status = "running"
 		if strings.TrimSpace(event.Model) != "" {
 			task.Model = strings.TrimSpace(event.Model)
@@ -498,6 +573,7 @@ func (m *progressTUIModel) handleResultEvent(event *result.AgentResult) {
 	deltaCost := event.TotalCostUSD
 	resultText := strings.TrimSpace(event.Result)
 	sessionID := strings.TrimSpace(event.SessionID)
+	m.runCostUSD += deltaCost
 
 	if task := m.resolveTaskForSession(sessionID, false); task != nil {
 		applyTaskOutcome(task, deltaTokens, deltaCacheRead, deltaCost, resultText)
@@ -651,6 +727,47 @@ func (m *progressTUIModel) renderRunHeader() string {
 	return "Running agent..."
 }
 
+// renderEstimateLine projects the rest of the run from the estimate: nodes not started yet add their
+// p50 weighted by how often past runs visited them, and a running node adds what is left of its p50.
+// Node costs only arrive with their result events, so a running node counts its p50 cost until then.
+func (m *progressTUIModel) renderEstimateLine() string {
+	var remainingMS int64
+	projectedCostUSD := m.runCostUSD
+	for _, node := range m.estimate.Nodes {
+		if node.Runs == 0 {
+			continue
+		}
+		stage := m.stages[node.NodeID]
+		if stage == nil || len(stage.TaskOrder) == 0 {
+			remainingMS += int64(float64(node.DurationMS.P50) * node.VisitRate)
+			projectedCostUSD += node.CostUSD.P50 * node.VisitRate
+			continue
+		}
+
+		var stageCostUSD float64
+		for _, task := range stage.Tasks {
+			stageCostUSD += task.CostUSD
+		}
+		latest := stage.Tasks[stage.TaskOrder[len(stage.TaskOrder)-1]]
+		if latest == nil || latest.Done {
+			continue
+		}
+		var elapsedMS int64
+		if !latest.StartedAt.IsZero() {
+			elapsedMS = m.now().Sub(latest.StartedAt).Milliseconds()
+		}
+		remainingMS += max(node.DurationMS.P50-elapsedMS, 0)
+		projectedCostUSD += max(node.CostUSD.P50-stageCostUSD, 0)
+	}
+
+	return fmt.Sprintf(
+		"Estimate: ETA ~%s · projected cost %s USD (from %d past run(s))",
+		formatDurationMS(remainingMS),
+		formatCostUSD(projectedCostUSD),
+		m.estimate.Runs,
+	)
+}
+
 func (m *progressTUIModel) renderTree() []string {
 	if len(m.stageOrder) == 0 {
 		return []string{"└─ Waiting for events..."}
@@ -1075,6 +1192,10 @@ func padTableRow(cells []string, widths []int) string {
 	return strings.Join(padded, " | ")
 }
 
+func colorWarning(value string) string {
+	return ansiYellow + value + ansiReset
+}
+
 func colorSuccess(value string) string {
 	trimmed := strings.TrimSpace(value)
 	if trimmed == "" {
@@ -15,6 +15,8 @@ import (
 	"time"
 
 	"agent-cli/internal/config"
+	"agent-cli/internal/pipeline"
+	"agent-cli/internal/redact"
 	"agent-cli/internal/result"
 	"agent-cli/internal/runner"
 	"agent-cli/internal/stats"
@@ -26,7 +28,29 @@ type runOptions struct {
 	TemplateVars map[string]string
 	JSONOutput   bool
 	Model        string
+	Profile      string
 	Debug        bool
+	DryRun       bool
+	Resume       *pipelineResume
+	// Estimate prints the expected cost and duration of the pipeline run instead of starting it.
+	Estimate bool
+	// NodeIDs are the plan's non-terminal nodes in plan order (pipeline mode only).
+	NodeIDs []string
+	// MaxCostUSD and MaxTokens override the [budget] run caps when positive.
+	MaxCostUSD float64
+	MaxTokens  int64
+	// Labels are recorded on the run and set on its container.
+	Labels map[string]string
+	// Force starts the run even when a [budget] spend cap is reached.
+	Force bool
+}
+
+// pipelineResume describes where a resumed pipeline run picks up from its parent run.
+type pipelineResume struct {
+	ParentRunID string
+	// StateDir is the parent's exported state directory, or empty when it exported none.
+	StateDir  string
+	StartNode string
 }
 
 var templateVarNamePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
@@ -76,6 +100,56 @@ func (v *templateVarValues) Set(raw string) error {
 	return nil
 }
 
+// reservedLabelPrefix marks the container labels agent-cli sets itself.
+const reservedLabelPrefix = "agent-cli."
+
+// pipelineStateDirPattern names the state directory of each pipeline run, see os.MkdirTemp.
+const pipelineStateDirPattern = "run-*"
+
+// labelValues collects repeatable --label KEY=VALUE flags.
+type labelValues struct {
+	values map[string]string
+}
+
+func (v *labelValues) String() string {
+	if len(v.values) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+v.values[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (v *labelValues) Set(raw string) error {
+	key, value, ok := strings.Cut(strings.TrimSpace(raw), "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("invalid --label %q: expected KEY=VALUE", raw)
+	}
+	if strings.HasPrefix(key, reservedLabelPrefix) {
+		return fmt.Errorf("invalid --label %q: the %s prefix is reserved", raw, reservedLabelPrefix)
+	}
+
+	if v.values == nil {
+		v.values = map[string]string{}
+	}
+	if _, exists := v.values[key]; exists {
+		return fmt.Errorf("duplicate --label key %q", key)
+	}
+
+	v.values[key] = value
+	return nil
+}
+
 var (
 	runDockerStreamingFn           = runner.RunDockerStreaming
 	runOutputWriter      io.Writer = os.Stdout
@@ -86,48 +160,85 @@ func RunCommand(ctx context.Context, cwd string, args []string) error {
 	if err != nil {
 		return err
 	}
+	if opts.Estimate {
+		// An estimate only reads past runs, so it needs no config.
+		estimate, err := stats.EstimatePipeline(config.RunsDir(cwd), opts.Pipeline, opts.NodeIDs)
+		if err != nil {
+			return err
+		}
+		return printPipelineEstimate(runOutputWriter, opts.Pipeline, estimate, opts.JSONOutput)
+	}
 
-	cfg, err := config.Load(cwd)
+	// Tokens are resolved only once the run is about to start, so a dry run or a refused run runs
+	// no token command.
+	cfg, err := config.ReadProfile(cwd, opts.Profile)
 	if err != nil {
 		return err
 	}
 
-	model := cfg.Docker.Model
-	if strings.TrimSpace(opts.Model) != "" {
-		model = opts.Model
+	if opts.DryRun {
+		// Token env values are masked regardless, so the dry run needs no resolved tokens.
+		redactor, err := redact.New([]string{cfg.Auth.GitHubToken, cfg.Auth.ClaudeToken}, cfg.Redact.Patterns)
+		if err != nil {
+			return err
+		}
+		spec, err := runner.ResolveContainerSpec(newRunRequest(cwd, cfg, opts))
+		if err != nil {
+			return err
+		}
+		return printDryRun(runOutputWriter, spec, redactor, opts.JSONOutput)
 	}
 
-	isPipelineRun := strings.TrimSpace(opts.Pipeline) != ""
-	runCtx, cancelRun := context.WithCancel(ctx)
-	defer cancelRun()
-	record := &stats.RunRecord{
-		Timestamp: time.Now().UTC(),
-		Status:    stats.RunStatusExecError,
-		CWD:       cwd,
+	spendCaps, err := checkSpendCaps(cwd, cfg.Budget, opts.Force)
+	if err != nil {
+		return err
 	}
 
-	stdoutLines := make([]string, 0, 32)
-	stderrLines := make([]string, 0, 16)
-	streamMetrics := result.NormalizedMetrics{
-		ByModel: map[string]result.ModelMetric{},
+	if err := cfg.ResolveSecrets(cwd); err != nil {
+		return err
 	}
-	sessionTaskBindings := map[string]pipelineNodeRunRef{}
-	taskUsageByKey := map[string]*stats.PipelineNodeRunNormalized{}
-	taskUsagePendingBySession := map[string]*stats.PipelineNodeRunNormalized{}
-	taskUsageSeen := map[string]bool{}
+	redactor, err := redact.New([]string{cfg.Auth.GitHubToken, cfg.Auth.ClaudeToken}, cfg.Redact.Patterns)
+	if err != nil {
+		return err
+	}
+	runRequest := newRunRequest(cwd, cfg, opts)
+
+	if opts.Resume != nil && opts.Resume.StateDir == "" {
+		fmt.Fprintf(
+			os.Stderr,
+			"warning: run %s exported no resume state; resuming on the current workspace\n",
+			opts.Resume.ParentRunID,
+		)
+	}
+
+	isPipelineRun := strings.TrimSpace(opts.Pipeline) != ""
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
 
 	var progressUI *ProgressTUI
 	if !opts.JSONOutput {
 		progressUI = NewProgressTUI(runCtx, runOutputWriter, os.Stdin, isPipelineRun, cancelRun)
 		progressUI.Start()
+		if len(spendCaps) > 0 {
+			progressUI.SendSpendCaps(spendCaps)
+		}
+		// Resumed runs skip part of the path, which the estimate cannot tell. The estimate only adds
+		// an ETA to the header, so failing to read past runs does not fail the run.
+		if isPipelineRun && opts.Resume == nil {
+			estimate, err := stats.EstimatePipeline(config.RunsDir(cwd), opts.Pipeline, opts.NodeIDs)
+			if err == nil && estimate.Runs > 0 {
+				progressUI.SendEstimate(estimate)
+			}
+		}
 	}
+	var finalRecord *stats.RunRecord
 	progressClosed := false
 	closeProgress := func() error {
 		if progressUI == nil || progressClosed {
 			return nil
 		}
 		progressClosed = true
-		progressUI.Finish(record)
+		progressUI.Finish(finalRecord)
 		return progressUI.Wait()
 	}
 	defer func() {
@@ -136,7 +247,58 @@ func RunCommand(ctx context.Context, cwd string, args []string) error {
 		}
 	}()
 
-	runOutput, runErr := runDockerStreamingFn(runCtx, runner.RunRequest{
+	execution := runExecution{
+		CWD:          cwd,
+		Profile:      cfg.Profile,
+		Request:      runRequest,
+		Redactor:     redactor,
+		TemplateVars: opts.TemplateVars,
+		Resume:       opts.Resume,
+		Budget:       resolveBudget(cfg.Budget, opts.MaxCostUSD, opts.MaxTokens),
+		Pricing:      statsPricing(cfg.Pricing),
+		Retention:    retentionPolicy(cfg.Retention),
+	}
+	if progressUI != nil {
+		execution.OnEvent = progressUI.SendEvent
+		execution.OnRawLine = progressUI.SendRawLine
+		execution.OnBudgetWarning = progressUI.SendWarning
+	} else {
+		execution.OnBudgetWarning = func(message string) {
+			fmt.Fprintln(os.Stderr, "warning: "+message)
+		}
+	}
+	outcome, err := executeRun(runCtx, execution)
+	if err != nil {
+		return err
+	}
+	finalRecord = outcome.Record
+
+	if opts.JSONOutput && outcome.ParseErr == nil {
+		if isPipelineRun {
+			if strings.TrimSpace(outcome.PipelineRaw) != "" {
+				fmt.Fprintln(runOutputWriter, outcome.PipelineRaw)
+			}
+		} else {
+			fmt.Fprintln(runOutputWriter, string(outcome.Parsed.Raw))
+		}
+	}
+	if !opts.JSONOutput {
+		if err := closeProgress(); err != nil {
+			return fmt.Errorf("render progress ui: %w", err)
+		}
+	}
+
+	return outcome.Err()
+}
+
+// newRunRequest builds the runner request for opts with the resolved profile config.
+func newRunRequest(cwd string, cfg *config.Config, opts *runOptions) runner.RunRequest {
+	model := cfg.Docker.Model
+	if strings.TrimSpace(opts.Model) != "" {
+		model = opts.Model
+	}
+
+	runRequest := runner.RunRequest{
 		Image:                      cfg.Docker.Image,
 		CWD:                        cwd,
 		SourceWorkspaceDir:         cfg.Workspace.SourceWorkspaceDir,
@@ -147,32 +309,156 @@ func RunCommand(ctx context.Context, cwd string, args []string) error {
 		Prompt:                     opts.Prompt,
 		Pipeline:                   opts.Pipeline,
 		TemplateVars:               cloneTemplateVars(opts.TemplateVars),
+		Labels:                     cloneTemplateVars(opts.Labels),
 		Model:                      model,
 		Debug:                      opts.Debug,
 		DockerMode:                 cfg.Docker.Mode,
 		DinDStorageDriver:          cfg.Docker.DinDStorageDriver,
 		RunIdleTimeoutSec:          cfg.Docker.RunIdleTimeoutSec,
 		PipelineTaskIdleTimeoutSec: cfg.Docker.PipelineTaskIdleTimeoutSec,
-	}, runner.StreamHooks{
+	}
+	if strings.TrimSpace(opts.Pipeline) != "" {
+		// executeRun replaces the pattern with the directory it creates for the run.
+		runRequest.StateDir = filepath.Join(config.PipelineStateDir(cwd), pipelineStateDirPattern)
+	}
+	if opts.Resume != nil {
+		runRequest.StartNode = opts.Resume.StartNode
+		runRequest.ResumeDir = opts.Resume.StateDir
+	}
+	return runRequest
+}
+
+// runExecution is a single container run together with what is needed to record it.
+type runExecution struct {
+	CWD          string
+	Profile      string
+	Request      runner.RunRequest
+	Redactor     *redact.Redactor
+	TemplateVars map[string]string
+	Resume       *pipelineResume
+	BatchID      string
+	Budget       config.BudgetConfig
+	// Pricing sets the effective costs of the saved record; it may be nil.
+	Pricing stats.Pricing
+	// Retention prunes older runs once the record is saved.
+	Retention stats.RetentionPolicy
+	// OnEvent and OnRawLine receive redacted stream output as it arrives; both may be nil.
+	OnEvent   func(event *result.StreamEvent)
+	OnRawLine func(source string, line string)
+	// OnBudgetWarning receives each budget warning once; it may be nil.
+	OnBudgetWarning func(message string)
+}
+
+// runOutcome is the persisted result of a runExecution.
+type runOutcome struct {
+	Record      *stats.RunRecord
+	RunDir      string
+	Parsed      *result.ParsedResult
+	PipelineRaw string
+	ParseErr    error
+	RunErr      error
+	ExitCode    int
+}
+
+// executeRun runs the container, extracts the final result and persists the run record and artifacts.
+// The returned error is set only when the run could not be persisted; run failures are reported by
+// runOutcome.Err.
+func executeRun(ctx context.Context, execution runExecution) (*runOutcome, error) {
+	runRequest := execution.Request
+	redactor := execution.Redactor
+	isPipelineRun := strings.TrimSpace(runRequest.Pipeline) != ""
+	if isPipelineRun {
+		// The directory stays private to the host user: the entrypoint installs the exported files
+		// as the directory owner, and they are replayed on --resume.
+		parentDir := config.PipelineStateDir(execution.CWD)
+		if err := os.MkdirAll(parentDir, 0o700); err != nil {
+			return nil, fmt.Errorf("create pipeline state directory: %w", err)
+		}
+		stateDir, err := os.MkdirTemp(parentDir, pipelineStateDirPattern)
+		if err != nil {
+			return nil, fmt.Errorf("create pipeline state directory: %w", err)
+		}
+		defer os.RemoveAll(stateDir)
+		runRequest.StateDir = stateDir
+	}
+
+	record := &stats.RunRecord{
+		Timestamp:    time.Now().UTC(),
+		Status:       stats.RunStatusExecError,
+		CWD:          execution.CWD,
+		Profile:      execution.Profile,
+		Model:        runRequest.Model,
+		PipelinePath: runRequest.Pipeline,
+		TemplateVars: cloneTemplateVars(execution.TemplateVars),
+		Labels:       cloneTemplateVars(runRequest.Labels),
+		BatchID:      execution.BatchID,
+	}
+	if execution.Resume != nil {
+		record.ParentRunID = execution.Resume.ParentRunID
+	}
+	runID, err := stats.NewRunID()
+	if err != nil {
+		return nil, err
+	}
+	record.RunID = runID
+
+	// The marker lets spend caps count the run before its record is saved. It only feeds those
+	// checks, so failing to write it does not fail the run.
+	activeDir := config.ActiveRunsDir(execution.CWD)
+	activeRun := stats.ActiveRun{RunID: runID, PID: os.Getpid(), StartedAt: record.Timestamp}
+	_ = stats.WriteActiveRun(activeDir, activeRun)
+	defer stats.RemoveActiveRun(activeDir, runID)
+
+	stdoutLines := make([]string, 0, 32)
+	stderrLines := make([]string, 0, 16)
+	streamMetrics := result.NormalizedMetrics{
+		ByModel: map[string]result.ModelMetric{},
+	}
+	sessionTaskBindings := map[string]pipelineNodeRunRef{}
+	taskUsageByKey := map[string]*stats.PipelineNodeRunNormalized{}
+	taskUsagePendingBySession := map[string]*stats.PipelineNodeRunNormalized{}
+	taskUsageSeen := map[string]bool{}
+	transcripts := newNodeTranscripts()
+
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+	budget := newBudgetTracker(execution.Budget)
+	var budgetBreach *stats.BudgetBreach
+	checkBudget := func() {
+		if budgetBreach != nil || !budget.enabled() {
+			return
+		}
+		var warnings []string
+		budgetBreach, warnings = budget.check(
+			runBudgetSpend(streamMetrics),
+			nodeBudgetSpend(sessionTaskBindings, taskUsageByKey),
+		)
+		if execution.OnBudgetWarning != nil {
+			for _, warning := range warnings {
+				execution.OnBudgetWarning(warning)
+			}
+		}
+		if budgetBreach != nil {
+			cancelRun()
+		}
+	}
+
+	runOutput, runErr := runDockerStreamingFn(runCtx, runRequest, runner.StreamHooks{
 		OnStdoutLine: func(line string) {
+			line = redactor.String(line)
 			stdoutLines = append(stdoutLines, line)
 			event, kind, parseErr := result.ParseStreamLine(line)
-			if parseErr != nil {
-				if progressUI != nil {
-					progressUI.SendRawLine("stdout", line)
-				}
-				return
-			}
-
-			if kind != result.StreamLineJSONEvent || event == nil {
-				if progressUI != nil {
-					progressUI.SendRawLine("stdout", line)
+			if parseErr != nil || kind != result.StreamLineJSONEvent || event == nil {
+				if execution.OnRawLine != nil {
+					execution.OnRawLine("stdout", line)
 				}
 				return
 			}
 
 			if event.Result != nil {
 				mergeNormalizedMetrics(&streamMetrics, result.ExtractMetrics(*event.Result))
+				activeRun.CostUSD = streamMetrics.TotalCostUSD
+				_ = stats.WriteActiveRun(activeDir, activeRun)
 				if isPipelineRun {
 					mergePipelineNodeRunUsageForResult(
 						*event.Result,
@@ -185,6 +471,7 @@ func RunCommand(ctx context.Context, cwd string, args []string) error {
 			}
 
 			if isPipelineRun {
+				transcripts.add(line, event, sessionTaskBindings)
 				bindPipelineNodeRunSession(
 					event,
 					sessionTaskBindings,
@@ -194,42 +481,65 @@ func RunCommand(ctx context.Context, cwd string, args []string) error {
 				)
 			}
 
-			if progressUI != nil {
-				progressUI.SendEvent(event)
+			if execution.OnEvent != nil {
+				execution.OnEvent(event)
 			}
+			checkBudget()
 		},
 		OnStderrLine: func(line string) {
+			line = redactor.String(line)
 			stderrLines = append(stderrLines, line)
-			if progressUI != nil {
-				progressUI.SendRawLine("stderr", line)
+			if execution.OnRawLine != nil {
+				execution.OnRawLine("stderr", line)
 			}
 		},
 	})
 	record.Normalized = streamMetrics
 	record.DockerExitCode = runOutput.ExitCode
 
-	var (
-		parsed      *result.ParsedResult
-		parseErr    error
-		pipelineRaw string
-	)
+	var transcriptFiles map[string]string
+	var decisionRedactions int
+	outcome := &runOutcome{
+		Record:   record,
+		RunErr:   runErr,
+		ExitCode: runOutput.ExitCode,
+	}
 	if isPipelineRun {
 		var pipelineRecord *stats.PipelineRunRecord
-		pipelineRecord, pipelineRaw, parseErr = extractPipelineResultFromStream(stdoutLines, stderrLines)
-		if parseErr == nil {
+		pipelineRecord, outcome.PipelineRaw, outcome.ParseErr = extractPipelineResultFromStream(stdoutLines, stderrLines)
+		if outcome.ParseErr == nil {
 			applyPipelineNodeRunUsage(pipelineRecord, taskUsageByKey, taskUsageSeen)
+			if execution.Resume != nil {
+				pipelineRecord.StartNode = execution.Resume.StartNode
+			}
+			if state, err := stats.LoadPipelineResumeState(runRequest.StateDir); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			} else if state != nil {
+				pipelineRecord.Workspace = state.Workspace
+				pipelineRecord.LastDecision = state.LastDecision
+				decisionRedactions = redactLastDecision(pipelineRecord.LastDecision, redactor)
+			}
+			transcriptFiles = transcripts.files(pipelineRecord)
 			record.Pipeline = pipelineRecord
 		}
 	} else {
-		parsed, parseErr = result.ExtractFinalResultFromStream(stdoutLines)
-		if parseErr == nil {
-			agent := parsed.Agent
+		outcome.Parsed, outcome.ParseErr = result.ExtractFinalResultFromStream(stdoutLines)
+		if outcome.ParseErr == nil {
+			agent := outcome.Parsed.Agent
 			record.AgentResult = &agent
-			record.Normalized = parsed.Metrics
+			record.Normalized = outcome.Parsed.Metrics
 		}
 	}
 
+	parsed := outcome.Parsed
+	parseErr := outcome.ParseErr
 	switch {
+	case budgetBreach != nil:
+		// Cancelling the run interrupts the container, so the breach takes precedence over runErr.
+		record.Status = stats.RunStatusError
+		record.ErrorType = "budget_exceeded"
+		record.ErrorMessage = budgetBreachMessage(budgetBreach)
+		record.BudgetExceeded = budgetBreach
 	case runErr != nil && errors.Is(runErr, runner.ErrIdleTimeout):
 		record.Status = stats.RunStatusError
 		record.ErrorType = "timeout"
@@ -270,54 +580,104 @@ func RunCommand(ctx context.Context, cwd string, args []string) error {
 		record.Status = stats.RunStatusSuccess
 	}
 
-	runsDir := config.RunsDir(cwd)
-	savedPath, saveErr := stats.SaveRunRecord(runsDir, record)
-	if saveErr != nil {
-		return fmt.Errorf("save run statistics: %w", saveErr)
+	stdoutArtifact, stdoutRedactions := redactor.Redact(runOutput.Stdout)
+	stderrArtifact, stderrRedactions := redactor.Redact(runOutput.Stderr)
+	errorMessage, errorRedactions := redactor.Redact(record.ErrorMessage)
+	record.ErrorMessage = errorMessage
+	record.RedactionCount = stdoutRedactions + stderrRedactions + errorRedactions + decisionRedactions
+	stats.ApplyPricing(record, execution.Pricing)
+
+	runsDir := config.RunsDir(execution.CWD)
+	savedPath, saveErr := stats.SaveRunRecord(runsDir, record, execution.Retention)
+	var retentionErr *stats.RetentionError
+	if errors.As(saveErr, &retentionErr) {
+		// The run itself is recorded, so a pruning failure only warns.
+		fmt.Fprintf(os.Stderr, "warning: %v\n", retentionErr)
+	} else if saveErr != nil {
+		return nil, fmt.Errorf("save run statistics: %w", saveErr)
 	}
-	if err := stats.SaveRunArtifacts(filepath.Dir(savedPath), runOutput.Stdout, runOutput.Stderr); err != nil {
-		return fmt.Errorf("save run artifacts: %w", err)
+	outcome.RunDir = filepath.Dir(savedPath)
+	if err := stats.SaveRunArtifacts(outcome.RunDir, stdoutArtifact, stderrArtifact); err != nil {
+		return nil, fmt.Errorf("save run artifacts: %w", err)
 	}
-
-	if opts.JSONOutput && parseErr == nil {
-		if isPipelineRun {
-			if strings.TrimSpace(pipelineRaw) != "" {
-				fmt.Fprintln(runOutputWriter, pipelineRaw)
-			}
-		} else {
-			fmt.Fprintln(runOutputWriter, string(parsed.Raw))
+	if isPipelineRun {
+		if err := stats.SaveNodeTranscripts(outcome.RunDir, transcriptFiles); err != nil {
+			return nil, fmt.Errorf("save node transcripts: %w", err)
+		}
+		if err := stats.SaveRunState(outcome.RunDir, runRequest.StateDir); err != nil {
+			return nil, fmt.Errorf("save run state: %w", err)
 		}
 	}
-	if !opts.JSONOutput {
-		if err := closeProgress(); err != nil {
-			return fmt.Errorf("render progress ui: %w", err)
+
+	return outcome, nil
+}
+
+// redactLastDecision masks secrets in the string values of decision, which is read from the exported
+// resume state rather than the redacted stream, and returns the number of masks applied.
+func redactLastDecision(decision *stats.PipelineLastDecision, redactor *redact.Redactor) int {
+	if decision == nil {
+		return 0
+	}
+	// Maps and slices are masked in place.
+	_, count := redactDecisionValue(decision.Decision, redactor)
+	return count
+}
+
+func redactDecisionValue(value any, redactor *redact.Redactor) (any, int) {
+	switch typed := value.(type) {
+	case string:
+		return redactor.Redact(typed)
+	case map[string]any:
+		count := 0
+		for key, item := range typed {
+			redacted, n := redactDecisionValue(item, redactor)
+			typed[key] = redacted
+			count += n
 		}
+		return typed, count
+	case []any:
+		count := 0
+		for index, item := range typed {
+			redacted, n := redactDecisionValue(item, redactor)
+			typed[index] = redacted
+			count += n
+		}
+		return typed, count
+	default:
+		return value, 0
 	}
+}
 
+// Err returns the error the run command reports for the outcome, or nil when the run succeeded.
+func (o *runOutcome) Err() error {
+	if o.Record.BudgetExceeded != nil {
+		return errors.New(o.Record.ErrorMessage)
+	}
+	runErr := o.RunErr
 	if runErr != nil && errors.Is(runErr, runner.ErrIdleTimeout) {
 		return runErr
 	}
 	if runErr != nil && errors.Is(runErr, runner.ErrInterrupted) {
 		return runErr
 	}
-	if runErr != nil && runOutput.ExitCode == -1 {
+	if runErr != nil && o.ExitCode == -1 {
 		return runErr
 	}
-	if parseErr != nil {
-		return parseErr
+	if o.ParseErr != nil {
+		return o.ParseErr
 	}
-	if !isPipelineRun && parsed != nil && parsed.Agent.IsError {
+	if o.Parsed != nil && o.Parsed.Agent.IsError {
 		return errors.New("agent returned is_error=true")
 	}
-	if isPipelineRun && record.Pipeline != nil && record.Pipeline.IsError {
-		message := strings.TrimSpace(record.ErrorMessage)
+	if o.Record.Pipeline != nil && o.Record.Pipeline.IsError {
+		message := strings.TrimSpace(o.Record.ErrorMessage)
 		if message == "" {
-			message = pipelineFailureMessage(record.Pipeline)
+			message = pipelineFailureMessage(o.Record.Pipeline)
 		}
 		return errors.New(message)
 	}
 	if runErr != nil {
-		return fmt.Errorf("docker exited with code %d", runOutput.ExitCode)
+		return fmt.Errorf("docker exited with code %d", o.ExitCode)
 	}
 
 	return nil
@@ -331,20 +691,50 @@ func parseRunArgs(cwd string, args []string) (*runOptions, error) {
 	var pipelinePath string
 	var jsonOutput bool
 	var modelOverride string
+	var profile string
 	var debug bool
+	var dryRun bool
+	var estimate bool
+	var resumeRunID string
+	var resumeFrom string
+	var maxCostUSD float64
+	var maxTokens int64
+	var force bool
 	var templateVars templateVarValues
+	var labels labelValues
 	fs.StringVar(&filePath, "file", "", "path to file with prompt")
 	fs.StringVar(&pipelinePath, "pipeline", "", "path to YAML pipeline plan file")
 	fs.BoolVar(&jsonOutput, "json", false, "print raw JSON agent result")
 	fs.StringVar(&modelOverride, "model", "", "model override (sonnet|opus)")
+	fs.StringVar(&profile, "profile", "", "config profile from [profiles.<name>] (default: default_profile)")
 	fs.BoolVar(&debug, "debug", false, "enable debug logs in container entrypoint")
+	fs.BoolVar(&dryRun, "dry-run", false, "print the resolved container spec without starting it")
+	fs.BoolVar(&estimate, "estimate", false, "print the expected cost and duration from past runs without starting it (pipeline mode only)")
 	fs.Var(&templateVars, "var", "template variable in KEY=VALUE format (repeatable, pipeline mode only)")
+	fs.Var(&labels, "label", "label in KEY=VALUE format recorded on the run and its container (repeatable)")
+	fs.StringVar(&resumeRunID, "resume", "", "resume a previous pipeline run by run id (pipeline mode only)")
+	fs.StringVar(&resumeFrom, "from", "", "node to resume at (default: the node the resumed run stopped at)")
+	fs.Float64Var(&maxCostUSD, "max-cost-usd", 0, "cancel the run once it costs more than this (default: budget.max_cost_usd)")
+	fs.Int64Var(&maxTokens, "max-tokens", 0, "cancel the run once it uses more tokens than this (default: budget.max_tokens)")
+	fs.BoolVar(&force, "force", false, "start the run even when a daily, weekly or monthly spend cap is reached")
 
 	if err := fs.Parse(args); err != nil {
 		return nil, err
 	}
+	resumeRunID = strings.TrimSpace(resumeRunID)
+	resumeFrom = strings.TrimSpace(resumeFrom)
+	if resumeFrom != "" && resumeRunID == "" {
+		return nil, errors.New("--from requires --resume")
+	}
+	if err := validateBudgetFlags(maxCostUSD, maxTokens); err != nil {
+		return nil, err
+	}
+	if estimate && dryRun {
+		return nil, errors.New("use either --estimate or --dry-run, not both")
+	}
 
 	modelOverride = strings.ToLower(strings.TrimSpace(modelOverride))
+	profile = strings.TrimSpace(profile)
 	if modelOverride != "" && !config.IsValidDockerModel(modelOverride) {
 		return nil, fmt.Errorf(
 			"invalid --model %q: expected %s or %s",
@@ -378,18 +768,64 @@ func parseRunArgs(cwd string, args []string) (*runOptions, error) {
 			return nil, errors.New("pipeline file is empty")
 		}
 
+		planVars := templateVars.values
+		var parent *stats.RunRecord
+		var parentRunDir string
+		if resumeRunID != "" {
+			parent, parentRunDir, err = stats.FindRunRecord(config.RunsDir(cwd), resumeRunID)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --resume: %w", err)
+			}
+			if parent.PipelinePath == "" && parent.Pipeline == nil {
+				return nil, fmt.Errorf("invalid --resume: run %s is not a pipeline run", parent.RunID)
+			}
+			planVars = mergeTemplateVars(parent.TemplateVars, templateVars.values)
+		}
+
+		plan, err := pipeline.ParsePlan(planBytes, pipeline.LoadOptions{
+			WorkspaceDir:  cwd,
+			TemplateVars:  planVars,
+			FallbackModel: modelOverride,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid pipeline plan %s: %w", pipelinePath, err)
+		}
+
+		var resume *pipelineResume
+		if parent != nil {
+			resume, err = resolvePipelineResume(plan, parent, parentRunDir, resumeFrom)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		return &runOptions{
 			Pipeline:     pathForRecord,
-			TemplateVars: cloneTemplateVars(templateVars.values),
+			TemplateVars: cloneTemplateVars(planVars),
 			JSONOutput:   jsonOutput,
 			Model:        modelOverride,
+			Profile:      profile,
 			Debug:        debug,
+			DryRun:       dryRun,
+			Estimate:     estimate,
+			NodeIDs:      planNodeIDs(plan),
+			Resume:       resume,
+			MaxCostUSD:   maxCostUSD,
+			MaxTokens:    maxTokens,
+			Labels:       labels.values,
+			Force:        force,
 		}, nil
 	}
 
 	if len(templateVars.values) > 0 {
 		return nil, errors.New("--var is only supported with --pipeline")
 	}
+	if resumeRunID != "" {
+		return nil, errors.New("--resume is only supported with --pipeline")
+	}
+	if estimate {
+		return nil, errors.New("--estimate is only supported with --pipeline")
+	}
 
 	if strings.TrimSpace(filePath) != "" && len(rest) > 0 {
 		return nil, errors.New("use either positional prompt text or --file, not both")
@@ -410,7 +846,13 @@ func parseRunArgs(cwd string, args []string) (*runOptions, error) {
 			Prompt:     prompt,
 			JSONOutput: jsonOutput,
 			Model:      modelOverride,
+			Profile:    profile,
 			Debug:      debug,
+			DryRun:     dryRun,
+			MaxCostUSD: maxCostUSD,
+			MaxTokens:  maxTokens,
+			Labels:     labels.values,
+			Force:      force,
 		}, nil
 	}
 
@@ -423,10 +865,103 @@ func parseRunArgs(cwd string, args []string) (*runOptions, error) {
 		Prompt:     prompt,
 		JSONOutput: jsonOutput,
 		Model:      modelOverride,
+		Profile:    profile,
 		Debug:      debug,
+		DryRun:     dryRun,
+		MaxCostUSD: maxCostUSD,
+		MaxTokens:  maxTokens,
+		Labels:     labels.values,
+		Force:      force,
 	}, nil
 }
 
+func validateBudgetFlags(maxCostUSD float64, maxTokens int64) error {
+	if maxCostUSD < 0 {
+		return fmt.Errorf("invalid --max-cost-usd %v: must not be negative", maxCostUSD)
+	}
+	if maxTokens < 0 {
+		return fmt.Errorf("invalid --max-tokens %d: must not be negative", maxTokens)
+	}
+	return nil
+}
+
+// resolveBudget applies the --max-cost-usd and --max-tokens overrides to the configured budget.
+func resolveBudget(budget config.BudgetConfig, maxCostUSD float64, maxTokens int64) config.BudgetConfig {
+	if maxCostUSD > 0 {
+		budget.MaxCostUSD = maxCostUSD
+	}
+	if maxTokens > 0 {
+		budget.MaxTokens = maxTokens
+	}
+	return budget
+}
+
+// resolvePipelineResume picks the node a resumed run starts at: from when set, otherwise the
+// node the parent run failed at (or last ran, when no node run failed).
+func resolvePipelineResume(
+	plan *pipeline.Plan,
+	parent *stats.RunRecord,
+	parentRunDir string,
+	from string,
+) (*pipelineResume, error) {
+	startNode := from
+	if startNode == "" {
+		if parent.Status == stats.RunStatusSuccess {
+			return nil, fmt.Errorf("run %s succeeded: pass --from to rerun part of it", parent.RunID)
+		}
+		if parent.Pipeline == nil {
+			return nil, fmt.Errorf("run %s has no pipeline result: pass --from to pick the node to resume at", parent.RunID)
+		}
+		startNode = pipelineResumeNode(parent.Pipeline)
+	}
+	if startNode == "" {
+		startNode = plan.EntryNode
+	}
+
+	node := plan.Nodes[startNode]
+	if node == nil {
+		return nil, fmt.Errorf("cannot resume at %s: node not found in plan", startNode)
+	}
+	if node.Terminal {
+		return nil, fmt.Errorf("cannot resume at %s: node is terminal", startNode)
+	}
+
+	resume := &pipelineResume{
+		ParentRunID: parent.RunID,
+		StartNode:   startNode,
+	}
+	if state, err := stats.LoadPipelineResumeState(stats.RunStateDir(parentRunDir)); err != nil {
+		return nil, fmt.Errorf("invalid --resume: %w", err)
+	} else if state != nil {
+		resume.StateDir = stats.RunStateDir(parentRunDir)
+	}
+	return resume, nil
+}
+
+func pipelineResumeNode(record *stats.PipelineRunRecord) string {
+	for i := len(record.NodeRuns) - 1; i >= 0; i-- {
+		if strings.EqualFold(strings.TrimSpace(record.NodeRuns[i].Status), "error") {
+			return record.NodeRuns[i].NodeID
+		}
+	}
+	if len(record.NodeRuns) > 0 {
+		return record.NodeRuns[len(record.NodeRuns)-1].NodeID
+	}
+	return ""
+}
+
+// mergeTemplateVars returns recorded overlaid with overrides.
+func mergeTemplateVars(recorded map[string]string, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(recorded)+len(overrides))
+	for key, value := range recorded {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
 func cloneTemplateVars(input map[string]string) map[string]string {
 	if len(input) == 0 {
 		return nil
@@ -452,6 +987,28 @@ type pipelineResultEvent struct {
 	NodeRunCount    int                           `json:"node_run_count"`
 	FailedNodeCount int                           `json:"failed_node_count"`
 	NodeRuns        []stats.PipelineNodeRunRecord `json:"node_runs"`
+	Transitions     []pipelineTransitionEvent     `json:"transitions"`
+}
+
+// pipelineTransitionEvent matches both pipeline_result.transitions entries and transition_taken events.
+type pipelineTransitionEvent struct {
+	Type      string `json:"type"`
+	Event     string `json:"event"`
+	FromNode  string `json:"from_node"`
+	ToNode    string `json:"to_node"`
+	When      string `json:"when"`
+	Iteration int    `json:"iteration"`
+	NodeRunID string `json:"node_run_id"`
+}
+
+func (e pipelineTransitionEvent) record() stats.PipelineTransition {
+	return stats.PipelineTransition{
+		From:      e.FromNode,
+		To:        e.ToNode,
+		When:      e.When,
+		Iteration: e.Iteration,
+		NodeRunID: e.NodeRunID,
+	}
 }
 
 func extractPipelineResultFromStream(
@@ -472,6 +1029,15 @@ func extractPipelineResultFromStream(
 			continue
 		}
 
+		transitions := make([]stats.PipelineTransition, 0, len(event.Transitions))
+		for _, transition := range event.Transitions {
+			transitions = append(transitions, transition.record())
+		}
+		if event.Transitions == nil {
+			// Older entrypoint images do not include transitions in the result event.
+			transitions = extractPipelineTransitionsFromStream(stdoutLines[:i])
+		}
+
 		return &stats.PipelineRunRecord{
 			Version:         event.Version,
 			Status:          event.Status,
@@ -484,6 +1050,7 @@ func extractPipelineResultFromStream(
 			NodeRunCount:    event.NodeRunCount,
 			FailedNodeCount: event.FailedNodeCount,
 			NodeRuns:        event.NodeRuns,
+			Transitions:     transitions,
 		}, line, nil
 	}
 
@@ -494,6 +1061,25 @@ func extractPipelineResultFromStream(
 	return nil, "", errors.New("pipeline result event not found in stream output")
 }
 
+func extractPipelineTransitionsFromStream(stdoutLines []string) []stats.PipelineTransition {
+	transitions := make([]stats.PipelineTransition, 0)
+	for _, line := range stdoutLines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		var event pipelineTransitionEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			continue
+		}
+		if event.Type == "pipeline_event" && event.Event == "transition_taken" {
+			transitions = append(transitions, event.record())
+		}
+	}
+	return transitions
+}
+
 func extractPipelineFailureMessage(lineSets ...[]string) string {
 	fallback := ""
 	for _, lines := range lineSets {
@@ -11,8 +11,10 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"agent-cli/internal/config"
+	"agent-cli/internal/result"
 	"agent-cli/internal/runner"
 	"agent-cli/internal/stats"
 )
@@ -201,6 +203,75 @@ func TestRunCommandParseError(t *testing.T) {
 	}
 }
 
+func TestRunCommandAppliesRetention(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+	appendTestConfig(t, cwd, "\n[retention]\nmax_age_days = 30\nkeep_failed = true\n")
+	for _, record := range []*stats.RunRecord{
+		{RunID: "oldok", Timestamp: time.Now().UTC().AddDate(0, 0, -31), Status: stats.RunStatusSuccess},
+		{RunID: "oldfailed", Timestamp: time.Now().UTC().AddDate(0, 0, -31), Status: stats.RunStatusError},
+	} {
+		if _, err := stats.SaveRunRecord(config.RunsDir(cwd), record, stats.RetentionPolicy{}); err != nil {
+			t.Fatalf("save record: %v", err)
+		}
+	}
+
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			hooks.OnStdoutLine("plain text line")
+			return runner.RunOutput{Stdout: "plain text line\n"}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	if err := RunCommand(context.Background(), cwd, []string{"build"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	runs, _, err := stats.ListRunRecords(config.RunsDir(cwd))
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	if len(runs) != 2 || runs[0].Record.RunID != "oldfailed" || runs[1].Record.Status != stats.RunStatusParseError {
+		t.Fatalf("unexpected runs after retention: %+v", runs)
+	}
+}
+
+func TestRunCommandAppliesPricing(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+	appendTestConfig(t, cwd, "\n[pricing.sonnet]\ninput_per_mtok = 100000\noutput_per_mtok = 200000\n")
+
+	line := `{"type":"result","subtype":"success","is_error":false,"duration_ms":10,"duration_api_ms":12,"num_turns":1,"result":"ok","stop_reason":null,"session_id":"s1","total_cost_usd":0.5,"usage":{"input_tokens":10,"cache_creation_input_tokens":0,"cache_read_input_tokens":0,"output_tokens":5},"modelUsage":{"claude-sonnet":{"inputTokens":10,"outputTokens":5,"cacheReadInputTokens":0,"cacheCreationInputTokens":0,"webSearchRequests":0,"costUSD":0.5}},"uuid":"u1"}`
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			hooks.OnStdoutLine(line)
+			return runner.RunOutput{Stdout: line + "\n"}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	if err := RunCommand(context.Background(), cwd, []string{"build"}); err != nil {
+		t.Fatalf("run command: %v", err)
+	}
+
+	record := loadSingleRunRecord(t, cwd).Record
+	if record.EffectiveCostUSD == nil {
+		t.Fatal("expected effective cost")
+	}
+	assertFloatNear(t, *record.EffectiveCostUSD, 2, "effective_cost_usd")
+	assertFloatNear(t, record.EffectiveCostByModel["claude-sonnet"], 2, "effective_cost_by_model")
+	assertFloatNear(t, record.Normalized.TotalCostUSD, 0.5, "total_cost_usd")
+}
+
 func TestRunCommandDockerExitError(t *testing.T) {
 	cwd := t.TempDir()
 	writeTestConfig(t, cwd)
@@ -303,6 +374,160 @@ func TestRunCommandInterrupted(t *testing.T) {
 	}
 }
 
+func TestRunCommandRefusesRunAtSpendCap(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+	appendTestConfig(t, cwd, "\n[budget]\ndaily_usd = 5\nmonthly_usd = 1\n")
+
+	previous := &stats.RunRecord{
+		Timestamp:  time.Now().UTC(),
+		Status:     stats.RunStatusSuccess,
+		Normalized: result.NormalizedMetrics{TotalCostUSD: 1.2},
+	}
+	if _, err := stats.SaveRunRecord(config.RunsDir(cwd), previous, stats.RetentionPolicy{}); err != nil {
+		t.Fatalf("save run record: %v", err)
+	}
+
+	line := `{"type":"result","subtype":"success","is_error":false,"duration_ms":10,"duration_api_ms":12,"num_turns":1,"result":"ok","stop_reason":null,"session_id":"s1","total_cost_usd":0.5,"usage":{"input_tokens":10,"cache_creation_input_tokens":0,"cache_read_input_tokens":0,"output_tokens":5},"uuid":"u1"}`
+	started := 0
+	var activeDuringRun []stats.ActiveRun
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			started++
+			hooks.OnStdoutLine(line)
+			var err error
+			activeDuringRun, err = stats.ListActiveRuns(config.ActiveRunsDir(cwd))
+			if err != nil {
+				t.Fatalf("list active runs: %v", err)
+			}
+			return runner.RunOutput{Stdout: line + "\n"}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	err := RunCommand(context.Background(), cwd, []string{"--json", "build"})
+	if err == nil {
+		t.Fatal("expected spend cap error")
+	}
+	assertContains(t, err.Error(), "monthly spend cap reached: 1.200000 of 1.000000 USD spent since ")
+	assertContains(t, err.Error(), "pass --force to run anyway")
+	if started != 0 {
+		t.Fatalf("unexpected runs started: %d", started)
+	}
+
+	if err := RunCommand(context.Background(), cwd, []string{"--json", "--force", "build"}); err != nil {
+		t.Fatalf("run command: %v", err)
+	}
+	if started != 1 {
+		t.Fatalf("unexpected runs started: %d", started)
+	}
+	if len(activeDuringRun) != 1 || activeDuringRun[0].CostUSD != 0.5 {
+		t.Fatalf("unexpected active runs during the run: %+v", activeDuringRun)
+	}
+	active, err := stats.ListActiveRuns(config.ActiveRunsDir(cwd))
+	if err != nil || len(active) != 0 {
+		t.Fatalf("unexpected active runs after the run: %+v, %v", active, err)
+	}
+	// The marker and the saved record share the run id.
+	if _, _, err := stats.FindRunRecord(config.RunsDir(cwd), activeDuringRun[0].RunID); err != nil {
+		t.Fatalf("find run record: %v", err)
+	}
+}
+
+func TestRunCommandEstimateDoesNotStartRun(t *testing.T) {
+	cwd := t.TempDir()
+	planPath := filepath.Join(cwd, "pipeline.yaml")
+	if err := os.WriteFile(planPath, []byte(testPipelinePlan()), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+	record := &stats.RunRecord{
+		Status:       stats.RunStatusSuccess,
+		PipelinePath: planPath,
+		Pipeline: &stats.PipelineRunRecord{NodeRuns: []stats.PipelineNodeRunRecord{
+			{NodeID: "implement", DurationMS: 1000},
+		}},
+	}
+	if _, err := stats.SaveRunRecord(config.RunsDir(cwd), record, stats.RetentionPolicy{}); err != nil {
+		t.Fatalf("save run record: %v", err)
+	}
+
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			t.Fatal("estimate must not start a container")
+			return runner.RunOutput{}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	// No config is written: an estimate only reads past runs.
+	if err := RunCommand(context.Background(), cwd, []string{"--estimate", "--json", "--pipeline", "pipeline.yaml"}); err != nil {
+		t.Fatalf("run command: %v", err)
+	}
+	var estimate stats.PipelineEstimate
+	if err := json.Unmarshal(out.Bytes(), &estimate); err != nil {
+		t.Fatalf("decode estimate: %v", err)
+	}
+	if estimate.Runs != 1 || len(estimate.Nodes) != 1 || estimate.Nodes[0].DurationMS.P50 != 1000 {
+		t.Fatalf("unexpected estimate: %+v", estimate)
+	}
+}
+
+func TestRunCommandBudgetExceededCancelsRun(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+	appendTestConfig(t, cwd, "\n[budget]\nmax_cost_usd = 1\n")
+
+	planPath := filepath.Join(cwd, "pipeline.yaml")
+	if err := os.WriteFile(planPath, []byte(testPipelinePlan()), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+
+	resultLine := `{"type":"result","subtype":"success","is_error":false,"session_id":"s%d","total_cost_usd":0.6,"usage":{"input_tokens":10,"cache_creation_input_tokens":0,"cache_read_input_tokens":0,"output_tokens":5}}`
+	emitted := 0
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			for i := 1; i <= 5; i++ {
+				if ctx.Err() != nil {
+					return runner.RunOutput{ExitCode: 130}, fmt.Errorf("%w: run interrupted by signal", runner.ErrInterrupted)
+				}
+				emitted++
+				hooks.OnStdoutLine(fmt.Sprintf(resultLine, i))
+			}
+			return runner.RunOutput{}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	err := RunCommand(context.Background(), cwd, []string{"--json", "--pipeline", planPath, "--max-tokens", "1000"})
+	if err == nil || err.Error() != "budget exceeded: run cost 1.200000 USD, over max_cost_usd 1.000000" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if emitted != 2 {
+		t.Fatalf("expected the run to be cancelled after the second result, got %d", emitted)
+	}
+
+	record := loadSingleRunRecord(t, cwd).Record
+	if record.Status != stats.RunStatusError || record.ErrorType != "budget_exceeded" {
+		t.Fatalf("unexpected status: %s/%s", record.Status, record.ErrorType)
+	}
+	want := stats.BudgetBreach{Limit: "max_cost_usd", MaxCostUSD: 1, CostUSD: 1.2, Tokens: 30}
+	if record.BudgetExceeded == nil || *record.BudgetExceeded != want {
+		t.Fatalf("unexpected budget breach: %+v", record.BudgetExceeded)
+	}
+}
+
 func TestRunCommandIdleTimeout(t *testing.T) {
 	cwd := t.TempDir()
 	writeTestConfig(t, cwd)
@@ -434,7 +659,7 @@ func TestRunCommandPipelineJSONOutput(t *testing.T) {
 	writeTestConfig(t, cwd)
 
 	planPath := filepath.Join(cwd, "pipeline.yaml")
-	planContent := "version: v1\nstages:\n  - id: dev\n    mode: sequential\n    tasks:\n      - id: implement\n        prompt: hello\n"
+	planContent := testPipelinePlan()
 	if err := os.WriteFile(planPath, []byte(planContent), 0o644); err != nil {
 		t.Fatalf("write plan file: %v", err)
 	}
@@ -503,7 +728,7 @@ func TestRunCommandPipelinePassesTemplateVarsToRunner(t *testing.T) {
 	writeTestConfig(t, cwd)
 
 	planPath := filepath.Join(cwd, "pipeline.yaml")
-	planContent := "version: v1\nstages:\n  - id: dev\n    mode: sequential\n    tasks:\n      - id: implement\n        prompt: hello\n"
+	planContent := testPipelinePlan("A_VAR", "B_VAR")
 	if err := os.WriteFile(planPath, []byte(planContent), 0o644); err != nil {
 		t.Fatalf("write plan file: %v", err)
 	}
@@ -539,11 +764,19 @@ func TestRunCommandPipelinePassesTemplateVarsToRunner(t *testing.T) {
 	if err := RunCommand(
 		context.Background(),
 		cwd,
-		[]string{"--pipeline", planPath, "--var", "B_VAR=2", "--var", "A_VAR=1"},
+		[]string{"--pipeline", planPath, "--var", "B_VAR=2", "--var", "A_VAR=1", "--label", "team=core"},
 	); err != nil {
 		t.Fatalf("run command: %v", err)
 	}
 
+	if capturedReq.Labels["team"] != "core" {
+		t.Fatalf("unexpected labels: %#v", capturedReq.Labels)
+	}
+	record := loadSingleRunRecord(t, cwd).Record
+	if record.Model != capturedReq.Model || record.PipelinePath != planPath || record.Labels["team"] != "core" {
+		t.Fatalf("unexpected run record: model=%q pipeline_path=%q labels=%#v", record.Model, record.PipelinePath, record.Labels)
+	}
+
 	if capturedReq.TemplateVars["A_VAR"] != "1" {
 		t.Fatalf("expected A_VAR=1, got %#v", capturedReq.TemplateVars)
 	}
@@ -555,12 +788,154 @@ func TestRunCommandPipelinePassesTemplateVarsToRunner(t *testing.T) {
 	}
 }
 
+func TestRunCommandPipelineResumeLinksParentAndSavesState(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+
+	planPath := filepath.Join(cwd, "pipeline.yaml")
+	if err := os.WriteFile(planPath, []byte(testResumePipelinePlan), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+	parent, parentRunDir := saveResumableRun(t, cwd, stats.RunStatusError, "build", "review")
+	if err := os.MkdirAll(stats.RunStateDir(parentRunDir), 0o755); err != nil {
+		t.Fatalf("mkdir state dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(stats.RunStateDir(parentRunDir), "resume.json"), []byte(`{}`), 0o644); err != nil {
+		t.Fatalf("write resume state: %v", err)
+	}
+
+	pipelineResultLine := `{"type":"pipeline_result","version":"v2","status":"success","is_error":false,"entry_node":"build","terminal_node":"success","terminal_status":"success","exit_code":0,"iterations":1,"node_run_count":1,"failed_node_count":0,"node_runs":[{"node_id":"review","node_run_id":"review-1","kind":"command","status":"success","model":"","prompt_source":"","prompt_file":"","cmd":"review","cwd":".","exit_code":0,"signal":"","timed_out":false,"started_at":"2026-02-16T00:00:00Z","finished_at":"2026-02-16T00:00:01Z","duration_ms":1000,"error_message":""}]}`
+	resumeState := `{"workspace":{"base_commit":"abc123","head_commit":"def456","branch":"feature","pushed":false,` +
+		`"patch_file":"workspace.patch"},"last_decision":{"node_id":"plan","node_run_id":"plan-1","decision":{"status":"done","notes":["pushed with ghp_abcdefghijklmnopqrstuvwx"]}}}`
+
+	var capturedReq runner.RunRequest
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			capturedReq = req
+			if err := os.WriteFile(filepath.Join(req.StateDir, "resume.json"), []byte(resumeState), 0o644); err != nil {
+				t.Fatalf("write exported state: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(req.StateDir, "workspace.patch"), []byte("diff --git a/x b/x\n"), 0o644); err != nil {
+				t.Fatalf("write exported patch: %v", err)
+			}
+			hooks.OnStdoutLine(pipelineResultLine)
+			return runner.RunOutput{Stdout: pipelineResultLine + "\n", ExitCode: 0}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	if err := RunCommand(context.Background(), cwd, []string{"--json", "--pipeline", planPath, "--resume", parent.RunID}); err != nil {
+		t.Fatalf("run command: %v", err)
+	}
+
+	if capturedReq.StartNode != "review" || capturedReq.ResumeDir != stats.RunStateDir(parentRunDir) {
+		t.Fatalf("unexpected resume request: start=%q resume_dir=%q", capturedReq.StartNode, capturedReq.ResumeDir)
+	}
+	assertTemplateVars(t, capturedReq.TemplateVars, map[string]string{"A_VAR": "recorded-a", "B_VAR": "recorded-b"})
+	if filepath.Dir(capturedReq.StateDir) != config.PipelineStateDir(cwd) {
+		t.Fatalf("unexpected state dir: %q", capturedReq.StateDir)
+	}
+	if info, err := os.Stat(config.PipelineStateDir(cwd)); err != nil || info.Mode().Perm() != 0o700 {
+		t.Fatalf("unexpected state parent dir: %v err=%v", info, err)
+	}
+	if _, err := os.Stat(capturedReq.StateDir); !os.IsNotExist(err) {
+		t.Fatalf("expected temporary state dir to be removed, got err=%v", err)
+	}
+
+	agg, err := stats.AggregateStats(config.RunsDir(cwd), nil)
+	if err != nil {
+		t.Fatalf("aggregate stats: %v", err)
+	}
+	if len(agg.ResumeChains) != 1 || agg.ResumeChains[0].RootRunID != parent.RunID || len(agg.ResumeChains[0].RunIDs) != 2 {
+		t.Fatalf("unexpected resume chains: %+v", agg.ResumeChains)
+	}
+	child, childRunDir, err := stats.FindRunRecord(config.RunsDir(cwd), agg.ResumeChains[0].RunIDs[1])
+	if err != nil {
+		t.Fatalf("find resumed run: %v", err)
+	}
+	if child.ParentRunID != parent.RunID || child.Pipeline.StartNode != "review" {
+		t.Fatalf("unexpected resumed record: parent=%q start=%q", child.ParentRunID, child.Pipeline.StartNode)
+	}
+	if child.Pipeline.Workspace == nil || child.Pipeline.Workspace.BaseCommit != "abc123" || child.Pipeline.Workspace.Branch != "feature" {
+		t.Fatalf("unexpected workspace snapshot: %+v", child.Pipeline.Workspace)
+	}
+	if child.Pipeline.LastDecision == nil || child.Pipeline.LastDecision.Decision["status"] != "done" {
+		t.Fatalf("unexpected last decision: %+v", child.Pipeline.LastDecision)
+	}
+	if notes := child.Pipeline.LastDecision.Decision["notes"].([]any); notes[0] != "pushed with [REDACTED]" {
+		t.Fatalf("expected redacted decision notes, got %#v", notes)
+	}
+	if child.RedactionCount != 1 {
+		t.Fatalf("expected the decision redaction to be counted, got %d", child.RedactionCount)
+	}
+	if child.TemplateVars["A_VAR"] != "recorded-a" {
+		t.Fatalf("unexpected recorded template vars: %#v", child.TemplateVars)
+	}
+	patch, err := os.ReadFile(filepath.Join(stats.RunStateDir(childRunDir), "workspace.patch"))
+	if err != nil || string(patch) != "diff --git a/x b/x\n" {
+		t.Fatalf("unexpected saved patch: %q err=%v", patch, err)
+	}
+	// The state is kept unredacted for resuming, so only the owner may read it.
+	info, err := os.Stat(filepath.Join(stats.RunStateDir(childRunDir), "resume.json"))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("unexpected resume state mode: %v err=%v", info, err)
+	}
+}
+
+func TestRunCommandPipelineResumesRunWithoutResult(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+
+	planPath := filepath.Join(cwd, "pipeline.yaml")
+	if err := os.WriteFile(planPath, []byte(testResumePipelinePlan), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+
+	var capturedReq runner.RunRequest
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			capturedReq = req
+			return runner.RunOutput{ExitCode: 137}, errors.New("container exited with code 137")
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	args := []string{"--json", "--pipeline", planPath, "--var", "A_VAR=a", "--var", "B_VAR=b"}
+	if err := RunCommand(context.Background(), cwd, args); err == nil {
+		t.Fatal("expected error")
+	}
+	parent := loadSingleRunRecord(t, cwd).Record
+	if parent.Status != stats.RunStatusParseError || parent.Pipeline != nil {
+		t.Fatalf("unexpected parent record: status=%s pipeline=%+v", parent.Status, parent.Pipeline)
+	}
+	assertTemplateVars(t, parent.TemplateVars, map[string]string{"A_VAR": "a", "B_VAR": "b"})
+
+	err := RunCommand(context.Background(), cwd, []string{"--json", "--pipeline", planPath, "--resume", parent.RunID})
+	if err == nil || !strings.Contains(err.Error(), "has no pipeline result: pass --from") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	capturedReq = runner.RunRequest{}
+	_ = RunCommand(context.Background(), cwd, []string{"--json", "--pipeline", planPath, "--resume", parent.RunID, "--from", "review"})
+	if capturedReq.StartNode != "review" {
+		t.Fatalf("unexpected start node: %q", capturedReq.StartNode)
+	}
+	assertTemplateVars(t, capturedReq.TemplateVars, map[string]string{"A_VAR": "a", "B_VAR": "b"})
+}
+
 func TestRunCommandPipelineFailureReturnsTaskErrorDetails(t *testing.T) {
 	cwd := t.TempDir()
 	writeTestConfig(t, cwd)
 
 	planPath := filepath.Join(cwd, "pipeline.yaml")
-	planContent := "version: v1\nstages:\n  - id: main\n    mode: sequential\n    tasks:\n      - id: print_version\n        prompt: hi\n"
+	planContent := testPipelinePlan()
 	if err := os.WriteFile(planPath, []byte(planContent), 0o644); err != nil {
 		t.Fatalf("write plan file: %v", err)
 	}
@@ -614,7 +989,7 @@ func TestRunCommandPipelineSummaryShowsTaskStatsTable(t *testing.T) {
 	writeTestConfig(t, cwd)
 
 	planPath := filepath.Join(cwd, "pipeline.yaml")
-	planContent := "version: v1\nstages:\n  - id: dev\n    mode: sequential\n    tasks:\n      - id: task_a\n        prompt: hello a\n      - id: task_b\n        prompt: hello b\n"
+	planContent := testPipelinePlan()
 	if err := os.WriteFile(planPath, []byte(planContent), 0o644); err != nil {
 		t.Fatalf("write plan file: %v", err)
 	}
@@ -686,7 +1061,7 @@ func TestRunCommandPipelineTaskNormalizedIncludesCostWebSearchAndOutOfOrder(t *t
 	writeTestConfig(t, cwd)
 
 	planPath := filepath.Join(cwd, "pipeline.yaml")
-	planContent := "version: v1\nstages:\n  - id: dev\n    mode: sequential\n    tasks:\n      - id: task_a\n        prompt: a\n      - id: task_b\n        prompt: b\n"
+	planContent := testPipelinePlan()
 	if err := os.WriteFile(planPath, []byte(planContent), 0o644); err != nil {
 		t.Fatalf("write plan file: %v", err)
 	}
@@ -786,12 +1161,84 @@ func TestRunCommandPipelineTaskNormalizedIncludesCostWebSearchAndOutOfOrder(t *t
 	assertFloatNear(t, modelB.CostUSD, 0.05, "task_b by_model cost_usd")
 }
 
+func TestRunCommandPipelineWritesNodeTranscripts(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+
+	planPath := filepath.Join(cwd, "pipeline.yaml")
+	if err := os.WriteFile(planPath, []byte(testPipelinePlan()), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+
+	nodeStartA := `{"type":"pipeline_event","event":"node_start","node_id":"dev","node_run_id":"dev-1"}`
+	systemA := `{"type":"system","subtype":"init","session_id":"s1","model":"claude-opus"}`
+	bindA := `{"type":"pipeline_event","event":"node_session_bind","node_id":"dev","node_run_id":"dev-1","session_id":"s1"}`
+	resultA := `{"type":"result","subtype":"success","is_error":false,"result":"ok","session_id":"s1","total_cost_usd":0.25}`
+	finishA := `{"type":"pipeline_event","event":"node_finish","node_id":"dev","node_run_id":"dev-1","status":"success"}`
+	nodeStartB := `{"type":"pipeline_event","event":"node_start","node_id":"review","node_run_id":"review-2"}`
+	bindB := `{"type":"pipeline_event","event":"node_session_bind","node_id":"review","node_run_id":"review-2","session_id":"s2"}`
+	resultB := `{"type":"result","subtype":"error_during_execution","is_error":true,"result":"failed","session_id":"s2","total_cost_usd":0.05}`
+	pipelineResultLine := `{"type":"pipeline_result","version":"v2","status":"error","is_error":true,"entry_node":"dev","terminal_node":"fail","terminal_status":"failed","exit_code":1,"iterations":2,"node_run_count":2,"failed_node_count":1,"node_runs":[{"node_id":"dev","node_run_id":"dev-1","kind":"agent","status":"success"},{"node_id":"review","node_run_id":"review-2","kind":"agent","status":"error","error_message":"review failed"},{"node_id":"cleanup","node_run_id":"cleanup-3","kind":"command","status":"skipped"}]}`
+	lines := []string{nodeStartA, systemA, bindA, resultA, finishA, nodeStartB, bindB, resultB, pipelineResultLine}
+
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			for _, line := range lines {
+				hooks.OnStdoutLine(line)
+			}
+			return runner.RunOutput{Stdout: strings.Join(lines, "\n") + "\n"}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	if err := RunCommand(context.Background(), cwd, []string{"--pipeline", planPath}); err == nil {
+		t.Fatal("expected pipeline error")
+	}
+
+	saved := loadSingleRunRecord(t, cwd)
+	pipeline := saved.Record.Pipeline
+	if pipeline == nil {
+		t.Fatal("expected pipeline record")
+	}
+
+	tests := []struct {
+		nodeID    string
+		nodeRunID string
+		wantFile  string
+		wantLines []string
+	}{
+		{nodeID: "dev", nodeRunID: "dev-1", wantFile: "nodes/dev-dev-1.ndjson", wantLines: []string{nodeStartA, systemA, bindA, resultA, finishA}},
+		{nodeID: "review", nodeRunID: "review-2", wantFile: "nodes/review-review-2.ndjson", wantLines: []string{nodeStartB, bindB, resultB}},
+	}
+	for _, tt := range tests {
+		nodeRun := mustFindPipelineNodeRun(t, pipeline, tt.nodeID, tt.nodeRunID)
+		if nodeRun.TranscriptFile != tt.wantFile {
+			t.Fatalf("unexpected transcript file for %s: %q", tt.nodeRunID, nodeRun.TranscriptFile)
+		}
+		content, err := os.ReadFile(filepath.Join(saved.RunDir, nodeRun.TranscriptFile))
+		if err != nil {
+			t.Fatalf("read transcript: %v", err)
+		}
+		if got, want := string(content), strings.Join(tt.wantLines, "\n")+"\n"; got != want {
+			t.Fatalf("unexpected transcript for %s:\n%s", tt.nodeRunID, got)
+		}
+	}
+
+	if cleanup := mustFindPipelineNodeRun(t, pipeline, "cleanup", "cleanup-3"); cleanup.TranscriptFile != "" {
+		t.Fatalf("unexpected transcript file for a node run without events: %q", cleanup.TranscriptFile)
+	}
+}
+
 func TestRunCommandPipelineTaskNormalizedOmittedWithoutTaskResult(t *testing.T) {
 	cwd := t.TempDir()
 	writeTestConfig(t, cwd)
 
 	planPath := filepath.Join(cwd, "pipeline.yaml")
-	planContent := "version: v1\nstages:\n  - id: dev\n    mode: sequential\n    tasks:\n      - id: task_a\n        prompt: a\n      - id: task_b\n        prompt: b\n"
+	planContent := testPipelinePlan()
 	if err := os.WriteFile(planPath, []byte(planContent), 0o644); err != nil {
 		t.Fatalf("write plan file: %v", err)
 	}
@@ -849,7 +1296,7 @@ func TestRunCommandPipelineParseErrorUsesEntrypointMessage(t *testing.T) {
 	writeTestConfig(t, cwd)
 
 	planPath := filepath.Join(cwd, "pipeline.yaml")
-	planContent := "version: v1\nstages:\n  - id: main\n    mode: parallel\n    tasks:\n      - id: build\n        prompt: build\n"
+	planContent := testPipelinePlan()
 	if err := os.WriteFile(planPath, []byte(planContent), 0o644); err != nil {
 		t.Fatalf("write plan file: %v", err)
 	}
@@ -931,6 +1378,292 @@ func TestRunCommandModelOverrideTakesPriority(t *testing.T) {
 	}
 }
 
+func TestRunCommandAppliesProfileAndRecordsName(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfigWithModel(t, cwd, "sonnet")
+	appendTestConfig(t, cwd, `
+[profiles.heavy.docker]
+model = "opus"
+mode = "dind"
+run_idle_timeout_sec = 14400
+`)
+
+	resultLine := `{"type":"result","subtype":"success","is_error":false,"duration_ms":1,"duration_api_ms":2,"num_turns":1,"result":"ok","stop_reason":null,"session_id":"s1","total_cost_usd":0.1,"usage":{"input_tokens":1,"cache_creation_input_tokens":0,"cache_read_input_tokens":0,"output_tokens":1,"server_tool_use":{"web_search_requests":0,"web_fetch_requests":0},"service_tier":"standard"},"modelUsage":{},"uuid":"u1"}`
+
+	var capturedReq runner.RunRequest
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			capturedReq = req
+			if hooks.OnStdoutLine != nil {
+				hooks.OnStdoutLine(resultLine)
+			}
+			return runner.RunOutput{
+				Stdout:   resultLine + "\n",
+				ExitCode: 0,
+			}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	if err := RunCommand(context.Background(), cwd, []string{"--profile", "heavy", "build"}); err != nil {
+		t.Fatalf("run command: %v", err)
+	}
+	if capturedReq.Model != "opus" {
+		t.Fatalf("expected profile model, got %q", capturedReq.Model)
+	}
+	if capturedReq.DockerMode != config.DockerModeDinD {
+		t.Fatalf("expected profile docker mode, got %q", capturedReq.DockerMode)
+	}
+	if capturedReq.RunIdleTimeoutSec != 14400 {
+		t.Fatalf("expected profile run idle timeout, got %d", capturedReq.RunIdleTimeoutSec)
+	}
+
+	saved := loadSingleRunRecord(t, cwd)
+	if saved.Record.Profile != "heavy" {
+		t.Fatalf("expected profile in run record, got %q", saved.Record.Profile)
+	}
+}
+
+func TestRunCommandUnknownProfileFailsBeforeRun(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+
+	called := false
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			called = true
+			return runner.RunOutput{}, nil
+		},
+	)
+	defer restore()
+
+	err := RunCommand(context.Background(), cwd, []string{"--profile", "missing", "build"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), `profile "missing" is not defined`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if called {
+		t.Fatal("runner must not be called for unknown profile")
+	}
+}
+
+func TestRunCommandRedactsSecretsInArtifacts(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+	appendTestConfig(t, cwd, `
+[redact]
+patterns = ["internal-[0-9]{4}"]
+`)
+
+	githubPAT := "ghp_" + strings.Repeat("a", 36)
+	lines := []string{
+		`{"type":"system","subtype":"init","session_id":"s1","model":"claude-sonnet"}`,
+		`{"type":"user","session_id":"s1","message":{"content":[{"tool_use_id":"t1","type":"tool_result","content":"GH_TOKEN=gh-token CLAUDE_CODE_OAUTH_TOKEN=claude-token","is_error":false}]}}`,
+		`{"type":"result","subtype":"success","is_error":false,"duration_ms":10,"num_turns":1,"result":"ok","session_id":"s1","total_cost_usd":0.1,"usage":{"input_tokens":1,"output_tokens":1}}`,
+	}
+	stderrLines := []string{"leaked " + githubPAT + " and internal-1234"}
+
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			for _, line := range lines {
+				hooks.OnStdoutLine(line)
+			}
+			for _, line := range stderrLines {
+				hooks.OnStderrLine(line)
+			}
+			return runner.RunOutput{
+				Stdout:   strings.Join(lines, "\n") + "\n",
+				Stderr:   strings.Join(stderrLines, "\n") + "\n",
+				ExitCode: 0,
+			}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	if err := RunCommand(context.Background(), cwd, []string{"print", "env"}); err != nil {
+		t.Fatalf("run command: %v", err)
+	}
+	assertNotContains(t, out.String(), githubPAT)
+
+	saved := loadSingleRunRecord(t, cwd)
+	if saved.Record.RedactionCount != 4 {
+		t.Fatalf("unexpected redaction count: %d", saved.Record.RedactionCount)
+	}
+
+	for _, name := range []string{"output.ndjson", "output.log"} {
+		content, err := os.ReadFile(filepath.Join(saved.RunDir, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		for _, secret := range []string{"gh-token", "claude-token", githubPAT, "internal-1234"} {
+			assertNotContains(t, string(content), secret)
+		}
+	}
+
+	ndjson, err := os.ReadFile(filepath.Join(saved.RunDir, "output.ndjson"))
+	if err != nil {
+		t.Fatalf("read output.ndjson: %v", err)
+	}
+	assertContains(t, string(ndjson), "GH_TOKEN=[REDACTED] CLAUDE_CODE_OAUTH_TOKEN=[REDACTED]")
+}
+
+func TestRunCommandDryRunPrintsSpecWithoutRunning(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfigWithDockerRuntime(t, cwd, "", "dind", "vfs")
+	planPath := filepath.Join(cwd, "plans", "flow.yml")
+	if err := os.MkdirAll(filepath.Dir(planPath), 0o755); err != nil {
+		t.Fatalf("mkdir plans: %v", err)
+	}
+	if err := os.WriteFile(planPath, []byte(testPipelinePlan("ISSUE")), 0o644); err != nil {
+		t.Fatalf("write plan: %v", err)
+	}
+
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			t.Fatal("runner must not be called in dry-run mode")
+			return runner.RunOutput{}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	err := RunCommand(
+		context.Background(),
+		cwd,
+		[]string{"--dry-run", "--pipeline", "plans/flow.yml", "--var", "ISSUE=42"},
+	)
+	if err != nil {
+		t.Fatalf("run command: %v", err)
+	}
+
+	output := out.String()
+	assertContains(t, output, "Dry Run (container not started)")
+	assertContains(t, output, "FIELD")
+	assertContains(t, output, "container_pipeline_path")
+	assertContains(t, output, "/workspace/plans/flow.yml")
+	assertContains(t, output, "network_mode")
+	assertContains(t, output, "bridge")
+	assertContains(t, output, "ISSUE=42")
+	assertContains(t, output, "GH_TOKEN=[REDACTED]")
+	assertContains(t, output, "CLAUDE_CODE_OAUTH_TOKEN=[REDACTED]")
+	assertNotContains(t, output, "gh-token")
+	assertNotContains(t, output, "claude-token")
+
+	if _, err := os.Stat(config.RunsDir(cwd)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("dry run must not persist runs: %v", err)
+	}
+}
+
+func TestRunCommandDryRunJSON(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			t.Fatal("runner must not be called in dry-run mode")
+			return runner.RunOutput{}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	if err := RunCommand(context.Background(), cwd, []string{"--dry-run", "--json", "build"}); err != nil {
+		t.Fatalf("run command: %v", err)
+	}
+
+	var spec runner.ContainerSpec
+	if err := json.Unmarshal(out.Bytes(), &spec); err != nil {
+		t.Fatalf("decode dry-run JSON: %v\n%s", err, out.String())
+	}
+	if spec.Image != "claude:go" || spec.NetworkMode != "host" || spec.Privileged {
+		t.Fatalf("unexpected spec: %+v", spec)
+	}
+	if strings.Join(spec.Cmd, " ") != "--model opus -vv -v build" {
+		t.Fatalf("unexpected cmd: %q", spec.Cmd)
+	}
+	assertContains(t, strings.Join(spec.Env, "\n"), "GH_TOKEN=[REDACTED]")
+	assertNotContains(t, out.String(), "claude-token")
+}
+
+func TestRunCommandDryRunPipelineShowsStateMount(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+
+	planPath := filepath.Join(cwd, "pipeline.yaml")
+	if err := os.WriteFile(planPath, []byte(testResumePipelinePlan), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			t.Fatal("runner must not be called in dry-run mode")
+			return runner.RunOutput{}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	if err := RunCommand(context.Background(), cwd, []string{"--dry-run", "--json", "--pipeline", planPath, "--var", "A_VAR=1", "--var", "B_VAR=2"}); err != nil {
+		t.Fatalf("run command: %v", err)
+	}
+
+	var spec runner.ContainerSpec
+	if err := json.Unmarshal(out.Bytes(), &spec); err != nil {
+		t.Fatalf("decode dry-run JSON: %v\n%s", err, out.String())
+	}
+	assertContains(t, strings.Join(spec.Env, "\n"), "PIPELINE_STATE_DIR=/run/agent-cli/state")
+	assertContains(t, strings.Join(spec.Binds, "\n"), filepath.Join(config.PipelineStateDir(cwd), "run-*")+":/run/agent-cli/state")
+	if _, err := os.Stat(config.PipelineStateDir(cwd)); !os.IsNotExist(err) {
+		t.Fatalf("dry run must not create the state directory, got err=%v", err)
+	}
+}
+
+func TestRunCommandResolvesTokensOnlyBeforeStarting(t *testing.T) {
+	cwd := t.TempDir()
+	writeTestConfig(t, cwd)
+	appendTestConfig(t, cwd, "\n[profiles.broken.auth]\ngithub_token_cmd = \"echo token lookup failed >&2; exit 1\"\n")
+
+	restore := withRunCommandDeps(
+		t,
+		func(ctx context.Context, req runner.RunRequest, hooks runner.StreamHooks) (runner.RunOutput, error) {
+			t.Fatal("runner must not be called without resolved tokens")
+			return runner.RunOutput{}, nil
+		},
+	)
+	defer restore()
+
+	var out bytes.Buffer
+	runOutputWriter = &out
+
+	if err := RunCommand(context.Background(), cwd, []string{"--dry-run", "--profile", "broken", "build"}); err != nil {
+		t.Fatalf("dry run must not run token commands: %v", err)
+	}
+	err := RunCommand(context.Background(), cwd, []string{"--profile", "broken", "build"})
+	if err == nil || !strings.Contains(err.Error(), "run auth.github_token_cmd") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestRunCommandUsesConfigModelWithoutOverride(t *testing.T) {
 	cwd := t.TempDir()
 	writeTestConfigWithModel(t, cwd, "sonnet")
@@ -1149,6 +1882,20 @@ user_email = "user@example.com"
 	}
 }
 
+func appendTestConfig(t *testing.T, cwd string, content string) {
+	t.Helper()
+
+	file, err := os.OpenFile(config.ConfigPath(cwd), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open config: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatalf("append config: %v", err)
+	}
+}
+
 type savedRunRecord struct {
 	Record       *stats.RunRecord
 	RunDir       string
@@ -6,6 +6,10 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"agent-cli/internal/config"
+	"agent-cli/internal/stats"
 )
 
 func TestParseRunArgsInline(t *testing.T) {
@@ -110,6 +114,33 @@ func TestParseRunArgsDebugFlag(t *testing.T) {
 	}
 }
 
+func TestParseRunArgsProfile(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseRunArgs(t.TempDir(), []string{"--profile", " heavy ", "build"})
+	if err != nil {
+		t.Fatalf("parse args: %v", err)
+	}
+	if opts.Profile != "heavy" {
+		t.Fatalf("unexpected profile: %q", opts.Profile)
+	}
+	if opts.Prompt != "build" {
+		t.Fatalf("unexpected prompt: %q", opts.Prompt)
+	}
+}
+
+func TestParseRunArgsDryRun(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseRunArgs(t.TempDir(), []string{"--dry-run", "build"})
+	if err != nil {
+		t.Fatalf("parse args: %v", err)
+	}
+	if !opts.DryRun {
+		t.Fatal("expected dry run")
+	}
+}
+
 func TestParseRunArgsInvalidModelOverride(t *testing.T) {
 	t.Parallel()
 
@@ -119,6 +150,77 @@ func TestParseRunArgsInvalidModelOverride(t *testing.T) {
 	}
 }
 
+func TestParseRunArgsBudgetFlags(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseRunArgs(t.TempDir(), []string{"--max-cost-usd", "2.5", "--max-tokens", "100000", "--force", "build"})
+	if err != nil {
+		t.Fatalf("parse args: %v", err)
+	}
+	if opts.MaxCostUSD != 2.5 || opts.MaxTokens != 100000 || !opts.Force {
+		t.Fatalf("unexpected budget flags: %+v", opts)
+	}
+
+	for _, args := range [][]string{{"--max-cost-usd", "-1", "build"}, {"--max-tokens", "-1", "build"}} {
+		if _, err := parseRunArgs(t.TempDir(), args); err == nil || !strings.Contains(err.Error(), "must not be negative") {
+			t.Fatalf("unexpected error for %q: %v", args, err)
+		}
+	}
+}
+
+func TestParseRunArgsEstimate(t *testing.T) {
+	t.Parallel()
+
+	cwd := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cwd, "pipeline.yaml"), []byte(testResumePipelinePlan), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+	opts, err := parseRunArgs(cwd, []string{"--estimate", "--pipeline", "pipeline.yaml", "--var", "A_VAR=a", "--var", "B_VAR=b"})
+	if err != nil {
+		t.Fatalf("parse args: %v", err)
+	}
+	if !opts.Estimate || !reflect.DeepEqual(opts.NodeIDs, []string{"build", "review"}) {
+		t.Fatalf("unexpected estimate options: %+v", opts)
+	}
+
+	tests := map[string][]string{
+		"--estimate is only supported with --pipeline": {"--estimate", "build"},
+		"use either --estimate or --dry-run, not both": {"--estimate", "--dry-run", "--pipeline", "pipeline.yaml"},
+	}
+	for wantErr, args := range tests {
+		if _, err := parseRunArgs(cwd, args); err == nil || err.Error() != wantErr {
+			t.Fatalf("unexpected error for %q: %v", args, err)
+		}
+	}
+}
+
+func TestParseRunArgsLabels(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseRunArgs(t.TempDir(), []string{"--label", "team=core", "--label", "ticket=", "build"})
+	if err != nil {
+		t.Fatalf("parse args: %v", err)
+	}
+	if !reflect.DeepEqual(opts.Labels, map[string]string{"team": "core", "ticket": ""}) {
+		t.Fatalf("unexpected labels: %#v", opts.Labels)
+	}
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"--label", "team", "build"}, wantErr: `invalid --label "team": expected KEY=VALUE`},
+		{args: []string{"--label", "=core", "build"}, wantErr: `invalid --label "=core": expected KEY=VALUE`},
+		{args: []string{"--label", "agent-cli.batch_id=1", "build"}, wantErr: "the agent-cli. prefix is reserved"},
+		{args: []string{"--label", "team=a", "--label", "team=b", "build"}, wantErr: `duplicate --label key "team"`},
+	}
+	for _, tt := range tests {
+		if _, err := parseRunArgs(t.TempDir(), tt.args); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("unexpected error for %q: %v", tt.args, err)
+		}
+	}
+}
+
 func TestParseRunArgsPipeline(t *testing.T) {
 	t.Parallel()
 
@@ -126,9 +228,7 @@ func TestParseRunArgsPipeline(t *testing.T) {
 	planFile := filepath.Join(cwd, "pipeline.yaml")
 	if err := os.WriteFile(
 		planFile,
-		[]byte(
-			"version: v1\nstages:\n  - id: s\n    mode: sequential\n    tasks:\n      - id: t\n        prompt: hi\n",
-		),
+		[]byte(testPipelinePlan()),
 		0o644,
 	); err != nil {
 		t.Fatalf("write plan file: %v", err)
@@ -150,9 +250,7 @@ func TestParseRunArgsPipelineWithTemplateVars(t *testing.T) {
 	planFile := filepath.Join(cwd, "pipeline.yaml")
 	if err := os.WriteFile(
 		planFile,
-		[]byte(
-			"version: v1\nstages:\n  - id: s\n    mode: sequential\n    tasks:\n      - id: t\n        prompt: hi\n",
-		),
+		[]byte(testPipelinePlan("A_VAR", "B_VAR")),
 		0o644,
 	); err != nil {
 		t.Fatalf("write plan file: %v", err)
@@ -169,6 +267,89 @@ func TestParseRunArgsPipelineWithTemplateVars(t *testing.T) {
 	})
 }
 
+func TestParseRunArgsPipelineRejectsInvalidPlan(t *testing.T) {
+	t.Parallel()
+
+	cwd := t.TempDir()
+	planFile := filepath.Join(cwd, "pipeline.yaml")
+	if err := os.WriteFile(planFile, []byte(testPipelinePlan("A_VAR")), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+
+	_, err := parseRunArgs(cwd, []string{"--pipeline", planFile, "--var", "A_VAR=1", "--var", "B_VAR=2"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "invalid pipeline plan") || !strings.Contains(err.Error(), "unused template vars: B_VAR") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParseRunArgsPipelineResume(t *testing.T) {
+	t.Parallel()
+
+	cwd := t.TempDir()
+	planFile := filepath.Join(cwd, "pipeline.yaml")
+	if err := os.WriteFile(planFile, []byte(testResumePipelinePlan), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+	parent, runDir := saveResumableRun(t, cwd, stats.RunStatusError, "build", "review")
+	if err := os.MkdirAll(stats.RunStateDir(runDir), 0o755); err != nil {
+		t.Fatalf("mkdir state dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(stats.RunStateDir(runDir), "resume.json"), []byte(`{"workspace":null}`), 0o644); err != nil {
+		t.Fatalf("write resume state: %v", err)
+	}
+
+	opts, err := parseRunArgs(cwd, []string{"--pipeline", planFile, "--resume", parent.RunID[:6], "--var", "B_VAR=override"})
+	if err != nil {
+		t.Fatalf("parse args: %v", err)
+	}
+	want := &pipelineResume{ParentRunID: parent.RunID, StateDir: stats.RunStateDir(runDir), StartNode: "review"}
+	if !reflect.DeepEqual(opts.Resume, want) {
+		t.Fatalf("unexpected resume: %+v", opts.Resume)
+	}
+	assertTemplateVars(t, opts.TemplateVars, map[string]string{
+		"A_VAR": "recorded-a",
+		"B_VAR": "override",
+	})
+
+	opts, err = parseRunArgs(cwd, []string{"--pipeline", planFile, "--resume", parent.RunID, "--from", "build"})
+	if err != nil {
+		t.Fatalf("parse args: %v", err)
+	}
+	if opts.Resume.StartNode != "build" {
+		t.Fatalf("unexpected start node: %q", opts.Resume.StartNode)
+	}
+}
+
+func TestParseRunArgsPipelineResumeErrors(t *testing.T) {
+	t.Parallel()
+
+	cwd := t.TempDir()
+	planFile := filepath.Join(cwd, "pipeline.yaml")
+	if err := os.WriteFile(planFile, []byte(testResumePipelinePlan), 0o644); err != nil {
+		t.Fatalf("write plan file: %v", err)
+	}
+	failed, _ := saveResumableRun(t, cwd, stats.RunStatusError, "build")
+	succeeded, _ := saveResumableRun(t, cwd, stats.RunStatusSuccess, "build", "review")
+
+	tests := map[string][]string{
+		"--from requires --resume":                   {"--pipeline", planFile, "--from", "build"},
+		"--resume is only supported with --pipeline": {"--resume", failed.RunID, "prompt"},
+		"invalid --resume: run not found: missing":   {"--pipeline", planFile, "--resume", "missing"},
+		"cannot resume at nope: node not found":      {"--pipeline", planFile, "--resume", failed.RunID, "--from", "nope"},
+		"cannot resume at success: node is terminal": {"--pipeline", planFile, "--resume", failed.RunID, "--from", "success"},
+		"succeeded: pass --from":                     {"--pipeline", planFile, "--resume", succeeded.RunID},
+	}
+	for wantErr, args := range tests {
+		_, err := parseRunArgs(cwd, args)
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("unexpected error for %q: %v", args, err)
+		}
+	}
+}
+
 func TestParseRunArgsTemplateVarRequiresPipeline(t *testing.T) {
 	t.Parallel()
 
@@ -188,9 +369,7 @@ func TestParseRunArgsTemplateVarRejectsInvalidName(t *testing.T) {
 	planFile := filepath.Join(cwd, "pipeline.yaml")
 	if err := os.WriteFile(
 		planFile,
-		[]byte(
-			"version: v1\nstages:\n  - id: s\n    mode: sequential\n    tasks:\n      - id: t\n        prompt: hi\n",
-		),
+		[]byte(testPipelinePlan()),
 		0o644,
 	); err != nil {
 		t.Fatalf("write plan file: %v", err)
@@ -212,9 +391,7 @@ func TestParseRunArgsTemplateVarRejectsDuplicateKey(t *testing.T) {
 	planFile := filepath.Join(cwd, "pipeline.yaml")
 	if err := os.WriteFile(
 		planFile,
-		[]byte(
-			"version: v1\nstages:\n  - id: s\n    mode: sequential\n    tasks:\n      - id: t\n        prompt: hi\n",
-		),
+		[]byte(testPipelinePlan()),
 		0o644,
 	); err != nil {
 		t.Fatalf("write plan file: %v", err)
@@ -236,9 +413,7 @@ func TestParseRunArgsTemplateVarRejectsInvalidFormat(t *testing.T) {
 	planFile := filepath.Join(cwd, "pipeline.yaml")
 	if err := os.WriteFile(
 		planFile,
-		[]byte(
-			"version: v1\nstages:\n  - id: s\n    mode: sequential\n    tasks:\n      - id: t\n        prompt: hi\n",
-		),
+		[]byte(testPipelinePlan()),
 		0o644,
 	); err != nil {
 		t.Fatalf("write plan file: %v", err)
@@ -258,7 +433,7 @@ func TestParseRunArgsPipelineMutualExclusionWithPrompt(t *testing.T) {
 
 	cwd := t.TempDir()
 	planFile := filepath.Join(cwd, "pipeline.yaml")
-	if err := os.WriteFile(planFile, []byte("version: v1\nstages: []\n"), 0o644); err != nil {
+	if err := os.WriteFile(planFile, []byte(testPipelinePlan()), 0o644); err != nil {
 		t.Fatalf("write plan file: %v", err)
 	}
 
@@ -274,7 +449,7 @@ func TestParseRunArgsPipelineMutualExclusionWithPromptFile(t *testing.T) {
 	cwd := t.TempDir()
 	planFile := filepath.Join(cwd, "pipeline.yaml")
 	promptFile := filepath.Join(cwd, "prompt.txt")
-	if err := os.WriteFile(planFile, []byte("version: v1\nstages: []\n"), 0o644); err != nil {
+	if err := os.WriteFile(planFile, []byte(testPipelinePlan()), 0o644); err != nil {
 		t.Fatalf("write plan file: %v", err)
 	}
 	if err := os.WriteFile(promptFile, []byte("build"), 0o644); err != nil {
@@ -292,7 +467,7 @@ func TestParseRunArgsRejectsLegacyPlanFileFlag(t *testing.T) {
 
 	cwd := t.TempDir()
 	planFile := filepath.Join(cwd, "pipeline.yaml")
-	if err := os.WriteFile(planFile, []byte("version: v1\nstages: []\n"), 0o644); err != nil {
+	if err := os.WriteFile(planFile, []byte(testPipelinePlan()), 0o644); err != nil {
 		t.Fatalf("write pipeline file: %v", err)
 	}
 
@@ -302,6 +477,16 @@ func TestParseRunArgsRejectsLegacyPlanFileFlag(t *testing.T) {
 	}
 }
 
+// testPipelinePlan returns a minimal valid v2 plan whose command references the given template vars.
+func testPipelinePlan(templateVars ...string) string {
+	cmd := "echo hello"
+	for _, name := range templateVars {
+		cmd += " {{" + name + "}}"
+	}
+	return "version: v2\nentry: implement\nnodes:\n  implement:\n    run:\n      kind: command\n      cmd: \"" + cmd +
+		"\"\n    transitions:\n      - when: run.exit_code == 0\n        to: success\n"
+}
+
 func assertTemplateVars(t *testing.T, got map[string]string, want map[string]string) {
 	t.Helper()
 
@@ -309,3 +494,90 @@ func assertTemplateVars(t *testing.T, got map[string]string, want map[string]str
 		t.Fatalf("unexpected template vars: got=%v want=%v", got, want)
 	}
 }
+
+const testResumePipelinePlan = `version: v2
+entry: build
+nodes:
+  build:
+    run: {kind: command, cmd: "make {{A_VAR}}"}
+    transitions:
+      - when: run.exit_code == 0
+        to: review
+  review:
+    run: {kind: command, cmd: "review {{B_VAR}}"}
+    transitions:
+      - when: run.exit_code == 0
+        to: success
+`
+
+// saveResumableRun saves a pipeline run record whose node runs ran nodeIDs in order, the last one failing
+// unless status is success.
+func saveResumableRun(t *testing.T, cwd string, status stats.RunStatus, nodeIDs ...string) (*stats.RunRecord, string) {
+	t.Helper()
+
+	nodeRuns := make([]stats.PipelineNodeRunRecord, 0, len(nodeIDs))
+	for index, nodeID := range nodeIDs {
+		nodeStatus := "success"
+		if status != stats.RunStatusSuccess && index == len(nodeIDs)-1 {
+			nodeStatus = "error"
+		}
+		nodeRuns = append(nodeRuns, stats.PipelineNodeRunRecord{NodeID: nodeID, Status: nodeStatus})
+	}
+	record := &stats.RunRecord{
+		Timestamp:    time.Date(2026, 2, 16, 0, 0, len(nodeIDs), 0, time.UTC),
+		Status:       status,
+		PipelinePath: "pipeline.yaml",
+		TemplateVars: map[string]string{"A_VAR": "recorded-a", "B_VAR": "recorded-b"},
+		Pipeline: &stats.PipelineRunRecord{
+			Version:   "v2",
+			EntryNode: "build",
+			NodeRuns:  nodeRuns,
+		},
+	}
+	path, err := stats.SaveRunRecord(config.RunsDir(cwd), record, stats.RetentionPolicy{})
+	if err != nil {
+		t.Fatalf("save run record: %v", err)
+	}
+	return record, filepath.Dir(path)
+}
+
+func TestExtractPipelineResultFromStreamRecordsTransitionsAndDecisions(t *testing.T) {
+	t.Parallel()
+
+	resultLine := `{"type":"pipeline_result","version":"v2","status":"success","is_error":false,"entry_node":"review","terminal_node":"success","terminal_status":"success","exit_code":0,"iterations":2,"node_run_count":2,"failed_node_count":0,"node_runs":[{"node_id":"review","node_run_id":"review-1","kind":"agent","status":"success","decision":{"approved":false}},{"node_id":"review","node_run_id":"review-2","kind":"agent","status":"success","decision":{"approved":true}}],"transitions":[{"from_node":"review","to_node":"review","when":"decision.approved == false","iteration":1,"node_run_id":"review-1"},{"from_node":"review","to_node":"success","when":"decision.approved == true","iteration":2,"node_run_id":"review-2"}]}`
+
+	pipeline, _, err := extractPipelineResultFromStream([]string{resultLine}, nil)
+	if err != nil {
+		t.Fatalf("extract pipeline result: %v", err)
+	}
+	want := []stats.PipelineTransition{
+		{From: "review", To: "review", When: "decision.approved == false", Iteration: 1, NodeRunID: "review-1"},
+		{From: "review", To: "success", When: "decision.approved == true", Iteration: 2, NodeRunID: "review-2"},
+	}
+	if !reflect.DeepEqual(pipeline.Transitions, want) {
+		t.Fatalf("unexpected transitions: %+v", pipeline.Transitions)
+	}
+	if got := pipeline.NodeRuns[1].Decision["approved"]; got != true {
+		t.Fatalf("unexpected decision: %#v", pipeline.NodeRuns[1].Decision)
+	}
+}
+
+func TestExtractPipelineResultFromStreamFallsBackToTransitionEvents(t *testing.T) {
+	t.Parallel()
+
+	lines := []string{
+		`{"type":"pipeline_event","event":"transition_taken","node_id":"implement","node_run_id":"implement-1","from_node":"implement","to_node":"success","when":"run.exit_code == 0","iteration":1}`,
+		`{"type":"pipeline_result","version":"v2","status":"success","entry_node":"implement","terminal_node":"success","node_runs":[]}`,
+	}
+
+	pipeline, _, err := extractPipelineResultFromStream(lines, nil)
+	if err != nil {
+		t.Fatalf("extract pipeline result: %v", err)
+	}
+	want := []stats.PipelineTransition{
+		{From: "implement", To: "success", When: "run.exit_code == 0", Iteration: 1, NodeRunID: "implement-1"},
+	}
+	if !reflect.DeepEqual(pipeline.Transitions, want) {
+		t.Fatalf("unexpected transitions: %+v", pipeline.Transitions)
+	}
+}
@@ -0,0 +1,416 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"agent-cli/internal/config"
+	"agent-cli/internal/stats"
+)
+
+const defaultRunsListLimit = 20
+
+var (
+	runsOutputWriter io.Writer = os.Stdout
+	runsNow                    = time.Now
+)
+
+// runListEntry is the summary of one run printed by runs list.
+type runListEntry struct {
+	RunID        string          `json:"run_id"`
+	Dir          string          `json:"dir"`
+	Timestamp    time.Time       `json:"timestamp"`
+	Mode         string          `json:"mode"`
+	Status       stats.RunStatus `json:"status"`
+	DurationMS   int64           `json:"duration_ms"`
+	TotalCostUSD float64         `json:"total_cost_usd"`
+	TerminalNode string          `json:"terminal_node,omitempty"`
+	ErrorType    string          `json:"error_type,omitempty"`
+}
+
+func RunsCommand(cwd string, args []string) error {
+	if len(args) == 0 {
+		return errors.New("runs command requires a subcommand: list, show, rm, prune, reindex")
+	}
+
+	switch args[0] {
+	case "list":
+		return runsListCommand(cwd, args[1:])
+	case "show":
+		return runsShowCommand(cwd, args[1:])
+	case "rm":
+		return runsRemoveCommand(cwd, args[1:])
+	case "prune":
+		return runsPruneCommand(cwd, args[1:])
+	case "reindex":
+		return runsReindexCommand(cwd, args[1:])
+	default:
+		return fmt.Errorf("unknown runs subcommand %q", args[0])
+	}
+}
+
+func runsListCommand(cwd string, args []string) error {
+	fs := flag.NewFlagSet("runs list", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	var jsonOutput bool
+	var status string
+	var errorType string
+	var since string
+	var limit int
+	fs.BoolVar(&jsonOutput, "json", false, "print the runs as JSON")
+	fs.StringVar(&status, "status", "", "only runs with this status (success|error|parse_error|exec_error)")
+	fs.StringVar(&errorType, "error-type", "", "only runs with this error type")
+	fs.StringVar(&since, "since", "", "only runs started within this age (7d, 12h) or since this date (2006-01-02 or RFC 3339)")
+	fs.IntVar(&limit, "limit", defaultRunsListLimit, "show at most this many of the most recent runs (0 = all)")
+
+	positionals, err := parseInterspersedFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positionals) > 0 {
+		return errors.New("runs list does not accept positional arguments")
+	}
+	if limit < 0 {
+		return fmt.Errorf("invalid --limit %d: expected 0 or more", limit)
+	}
+	filter := &stats.RunFilter{
+		Status:    stats.RunStatus(strings.ToLower(strings.TrimSpace(status))),
+		ErrorType: strings.TrimSpace(errorType),
+	}
+	if strings.TrimSpace(since) != "" {
+		sinceTime, err := parseTimeBound(since, runsNow())
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		filter.Since = &sinceTime
+	}
+
+	runs, _, err := stats.ListRunSummaries(config.RunsDir(cwd))
+	if err != nil {
+		return err
+	}
+
+	entries := make([]runListEntry, 0, len(runs))
+	for _, run := range runs {
+		if !filter.Matches(run.Record) {
+			continue
+		}
+		entries = append(entries, newRunListEntry(run))
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	if jsonOutput {
+		encoded, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode runs JSON: %w", err)
+		}
+		fmt.Fprintln(runsOutputWriter, string(encoded))
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(runsOutputWriter, "No runs found.")
+		return nil
+	}
+	rows := make([][]string, 0, len(entries))
+	for _, entry := range entries {
+		rows = append(rows, []string{
+			entry.RunID,
+			entry.Timestamp.UTC().Format("2006-01-02 15:04:05"),
+			entry.Mode,
+			string(entry.Status),
+			formatDurationMS(entry.DurationMS),
+			formatCostUSD(entry.TotalCostUSD),
+			orDash(entry.TerminalNode),
+			orDash(entry.ErrorType),
+		})
+	}
+	headers := []string{"Run", "Started(UTC)", "Mode", "Status", "Duration", "Cost(USD)", "Terminal", "Error Type"}
+	for _, line := range renderTextTable(headers, rows) {
+		fmt.Fprintln(runsOutputWriter, line)
+	}
+	return nil
+}
+
+func newRunListEntry(run stats.RunEntry) runListEntry {
+	record := run.Record
+	entry := runListEntry{
+		RunID:        record.RunID,
+		Dir:          run.Dir,
+		Timestamp:    record.Timestamp,
+		Mode:         stats.RunMode(record),
+		Status:       record.Status,
+		DurationMS:   record.Normalized.DurationMS,
+		TotalCostUSD: record.Normalized.TotalCostUSD,
+		ErrorType:    record.ErrorType,
+	}
+	if record.Pipeline != nil {
+		entry.TerminalNode = record.Pipeline.TerminalNode
+	}
+	return entry
+}
+
+func runsShowCommand(cwd string, args []string) error {
+	fs := flag.NewFlagSet("runs show", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	var jsonOutput bool
+	var timeline bool
+	fs.BoolVar(&jsonOutput, "json", false, "print the run record as JSON")
+	fs.BoolVar(&timeline, "timeline", false, "print the node runs, decisions and transitions of a pipeline run in order")
+
+	positionals, err := parseInterspersedFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positionals) != 1 {
+		return errors.New("runs show requires exactly one run id")
+	}
+
+	record, runDir, err := stats.FindRunRecord(config.RunsDir(cwd), positionals[0])
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		encoded, err := json.MarshalIndent(record, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode run JSON: %w", err)
+		}
+		fmt.Fprintln(runsOutputWriter, string(encoded))
+		return nil
+	}
+
+	printRunSummary(runsOutputWriter, record, runDir)
+	if timeline {
+		if record.Pipeline == nil {
+			return fmt.Errorf("run %s is not a pipeline run: --timeline needs node runs", record.RunID)
+		}
+		fmt.Fprintln(runsOutputWriter)
+		printRunTimeline(runsOutputWriter, record.Pipeline)
+	}
+	return nil
+}
+
+func printRunSummary(w io.Writer, record *stats.RunRecord, runDir string) {
+	fmt.Fprintf(w, "Run %s\n", record.RunID)
+	fmt.Fprintf(w, "  Directory: %s\n", runDir)
+	fmt.Fprintf(w, "  Timestamp: %s\n", record.Timestamp.UTC().Format("2006-01-02T15:04:05Z"))
+	fmt.Fprintf(w, "  Status: %s\n", normalizeStatus(string(record.Status), "unknown"))
+	if record.Profile != "" {
+		fmt.Fprintf(w, "  Profile: %s\n", record.Profile)
+	}
+	if record.ParentRunID != "" {
+		fmt.Fprintf(w, "  Resumed From: %s\n", record.ParentRunID)
+	}
+	if record.BatchID != "" {
+		fmt.Fprintf(w, "  Batch: %s\n", record.BatchID)
+	}
+	if record.Pipeline != nil {
+		fmt.Fprintf(w, "  Pipeline: %s -> %s (%d node run(s))\n",
+			record.Pipeline.EntryNode,
+			normalizeStatus(record.Pipeline.TerminalNode, "-"),
+			len(record.Pipeline.NodeRuns),
+		)
+	}
+	fmt.Fprintf(w, "  Duration: %s\n", formatDurationMS(record.Normalized.DurationMS))
+	fmt.Fprintf(w, "  Cost(USD): %s\n", formatCostUSD(record.Normalized.TotalCostUSD))
+	if record.EffectiveCostUSD != nil {
+		fmt.Fprintf(w, "  Effective Cost(USD): %s\n", formatCostUSD(*record.EffectiveCostUSD))
+	}
+	fmt.Fprintf(w, "  Tokens: input %d, cache create %d, cache read %d, output %d\n",
+		record.Normalized.InputTokens,
+		record.Normalized.CacheCreationInputTokens,
+		record.Normalized.CacheReadInputTokens,
+		record.Normalized.OutputTokens,
+	)
+	if record.ErrorType != "" {
+		fmt.Fprintf(w, "  Error Type: %s\n", record.ErrorType)
+	}
+	if record.ErrorMessage != "" {
+		fmt.Fprintf(w, "  Error: %s\n", record.ErrorMessage)
+	}
+}
+
+func runsRemoveCommand(cwd string, args []string) error {
+	if len(args) == 0 {
+		return errors.New("runs rm requires at least one run id")
+	}
+
+	runsDir := config.RunsDir(cwd)
+	for _, runID := range args {
+		runDir, err := stats.RemoveRun(runsDir, runID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(runsOutputWriter, "Removed %s\n", runDir)
+	}
+	return nil
+}
+
+// runsReindexCommand rebuilds the run summary index from the run directories.
+func runsReindexCommand(cwd string, args []string) error {
+	if len(args) > 0 {
+		return errors.New("runs reindex does not accept arguments")
+	}
+
+	runsDir := config.RunsDir(cwd)
+	indexed, skipped, err := stats.RebuildRunIndex(runsDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range skipped {
+		fmt.Fprintf(runsOutputWriter, "Skipped %s\n", name)
+	}
+	fmt.Fprintf(runsOutputWriter, "Indexed %d runs in %s\n", indexed, filepath.Join(runsDir, stats.RunIndexFileName))
+	return nil
+}
+
+func runsPruneCommand(cwd string, args []string) error {
+	fs := flag.NewFlagSet("runs prune", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	var olderThan string
+	var policy stats.RetentionPolicy
+	var jsonOutput bool
+	fs.StringVar(&olderThan, "older-than", "", "prune runs older than this age (30d, 12h)")
+	fs.BoolVar(&policy.KeepFailed, "keep-failed", false, "keep runs that did not succeed")
+	fs.BoolVar(&policy.Compress, "compress", false, "gzip the artifacts of old runs instead of removing the runs")
+	fs.BoolVar(&policy.DryRun, "dry-run", false, "list the runs that would be pruned without changing them")
+	fs.BoolVar(&jsonOutput, "json", false, "print the pruned run directories as JSON")
+
+	positionals, err := parseInterspersedFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positionals) > 0 {
+		return errors.New("runs prune does not accept positional arguments")
+	}
+	if strings.TrimSpace(olderThan) == "" {
+		return errors.New("runs prune requires --older-than")
+	}
+	policy.OlderThan, err = stats.ParseAge(olderThan)
+	if err != nil {
+		return fmt.Errorf("invalid --older-than: %w", err)
+	}
+	if policy.OlderThan == 0 {
+		return errors.New("invalid --older-than: must be greater than zero")
+	}
+
+	pruned, err := stats.PruneRuns(config.RunsDir(cwd), policy, runsNow())
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		encoded, err := json.MarshalIndent(pruned, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode prune JSON: %w", err)
+		}
+		fmt.Fprintln(runsOutputWriter, string(encoded))
+		return nil
+	}
+
+	removeVerb, compressVerb := "Removed", "Compressed"
+	if policy.DryRun {
+		removeVerb, compressVerb = "Would remove", "Would compress"
+	}
+	if policy.Compress {
+		fmt.Fprintf(runsOutputWriter, "%s %d run(s)\n", compressVerb, len(pruned.Compressed))
+		for _, name := range pruned.Compressed {
+			fmt.Fprintf(runsOutputWriter, "  %s\n", name)
+		}
+		return nil
+	}
+	fmt.Fprintf(runsOutputWriter, "%s %d run(s)\n", removeVerb, len(pruned.Removed))
+	for _, name := range pruned.Removed {
+		fmt.Fprintf(runsOutputWriter, "  %s\n", name)
+	}
+	return nil
+}
+
+// retentionPolicy is the policy SaveRunRecord prunes by after each run, from the [retention] config.
+func retentionPolicy(retention config.RetentionConfig) stats.RetentionPolicy {
+	if retention.MaxAgeDays <= 0 {
+		return stats.RetentionPolicy{}
+	}
+	return stats.RetentionPolicy{
+		OlderThan:  time.Duration(retention.MaxAgeDays) * 24 * time.Hour,
+		KeepFailed: retention.KeepFailed,
+		Compress:   retention.Compress,
+	}
+}
+
+// parseTimeBound parses an age relative to now (7d, 12h) or an absolute date (2006-01-02 or RFC 3339).
+func parseTimeBound(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	if parsed, err := time.Parse(time.DateOnly, value); err == nil {
+		return parsed, nil
+	}
+	age, err := stats.ParseAge(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a date (2006-01-02, RFC 3339) nor an age like 7d or 12h", value)
+	}
+	return now.Add(-age), nil
+}
+
+func orDash(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "-"
+	}
+	return value
+}
+
+// printRunTimeline prints each node run in execution order with its decision and the transition it took.
+func printRunTimeline(w io.Writer, pipeline *stats.PipelineRunRecord) {
+	transitionsByRun := make(map[string]stats.PipelineTransition, len(pipeline.Transitions))
+	for _, transition := range pipeline.Transitions {
+		transitionsByRun[transition.From+"\x00"+transition.NodeRunID] = transition
+	}
+
+	fmt.Fprintln(w, "Timeline")
+	for index, nodeRun := range pipeline.NodeRuns {
+		fmt.Fprintf(w, "  %d. %s  %s  %s  %s\n",
+			index+1,
+			formatStepName(nodeRun.NodeID, nodeRun.NodeRunID),
+			normalizeStatus(nodeRun.Kind, "-"),
+			normalizeStatus(nodeRun.Status, "unknown"),
+			formatDurationMS(nodeRun.DurationMS),
+		)
+		if nodeRun.Decision != nil {
+			encoded, err := json.Marshal(nodeRun.Decision)
+			if err == nil {
+				fmt.Fprintf(w, "     decision: %s\n", encoded)
+			}
+		}
+		if nodeRun.ErrorMessage != "" {
+			fmt.Fprintf(w, "     error: %s\n", nodeRun.ErrorMessage)
+		}
+		if nodeRun.TranscriptFile != "" {
+			fmt.Fprintf(w, "     transcript: %s\n", nodeRun.TranscriptFile)
+		}
+		if transition, ok := transitionsByRun[nodeRun.NodeID+"\x00"+nodeRun.NodeRunID]; ok {
+			fmt.Fprintf(w, "     -> %s (when: %s)\n", transition.To, transition.When)
+		}
+	}
+
+	terminal := strings.TrimSpace(pipeline.TerminalNode)
+	if terminal == "" {
+		fmt.Fprintf(w, "  end: no terminal node (status %s, exit code %d)\n",
+			normalizeStatus(pipeline.Status, "unknown"),
+			pipeline.ExitCode,
+		)
+		return
+	}
+	fmt.Fprintf(w, "  end: %s (%s)\n", terminal, normalizeStatus(pipeline.TerminalStatus, pipeline.Status))
+}
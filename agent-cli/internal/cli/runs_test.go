@@ -0,0 +1,325 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-cli/internal/config"
+	"agent-cli/internal/result"
+	"agent-cli/internal/stats"
+)
+
+func TestRunsShowTimelinePrintsDecisionsAndTransitions(t *testing.T) {
+	cwd := t.TempDir()
+	saveTimelineTestRun(t, cwd)
+
+	var out bytes.Buffer
+	withRunsOutput(t, &out)
+
+	if err := RunsCommand(cwd, []string{"show", "cafe01", "--timeline"}); err != nil {
+		t.Fatalf("runs show: %v", err)
+	}
+
+	text := out.String()
+	for _, want := range []string{
+		"Run cafe0123",
+		"Pipeline: review -> success (2 node run(s))",
+		"Timeline",
+		"1. review/review-1  agent  success  2s",
+		`decision: {"approved":false,"notes":"add tests"}`,
+		"transcript: nodes/review-review-1.ndjson",
+		"-> review (when: decision.approved == false)",
+		"2. review/review-2  agent  success  1s",
+		"-> success (when: decision.approved == true)",
+		"end: success (success)",
+	} {
+		assertContains(t, text, want)
+	}
+}
+
+func TestRunsShowJSONIncludesTransitions(t *testing.T) {
+	cwd := t.TempDir()
+	saveTimelineTestRun(t, cwd)
+
+	var out bytes.Buffer
+	withRunsOutput(t, &out)
+
+	if err := RunsCommand(cwd, []string{"show", "--json", "cafe0123"}); err != nil {
+		t.Fatalf("runs show: %v", err)
+	}
+
+	var record stats.RunRecord
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("decode run JSON: %v", err)
+	}
+	if record.Pipeline == nil || len(record.Pipeline.Transitions) != 2 || record.Pipeline.NodeRuns[0].Decision["notes"] != "add tests" {
+		t.Fatalf("unexpected record: %+v", record.Pipeline)
+	}
+}
+
+func TestRunsShowTimelineRequiresPipelineRun(t *testing.T) {
+	cwd := t.TempDir()
+	if _, err := stats.SaveRunRecord(config.RunsDir(cwd), &stats.RunRecord{
+		RunID:  "beef01",
+		Status: stats.RunStatusSuccess,
+	}, stats.RetentionPolicy{}); err != nil {
+		t.Fatalf("save record: %v", err)
+	}
+
+	var out bytes.Buffer
+	withRunsOutput(t, &out)
+
+	err := RunsCommand(cwd, []string{"show", "beef01", "--timeline"})
+	if err == nil || !strings.Contains(err.Error(), "run beef01 is not a pipeline run") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRunsCommandErrors(t *testing.T) {
+	t.Parallel()
+
+	cwd := t.TempDir()
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: nil, wantErr: "runs command requires a subcommand"},
+		{args: []string{"bogus"}, wantErr: `unknown runs subcommand "bogus"`},
+		{args: []string{"show"}, wantErr: "runs show requires exactly one run id"},
+		{args: []string{"show", "missing"}, wantErr: "run not found: missing"},
+		{args: []string{"list", "--since", "soon"}, wantErr: "invalid --since"},
+		{args: []string{"list", "--limit", "-1"}, wantErr: "invalid --limit -1"},
+		{args: []string{"rm"}, wantErr: "runs rm requires at least one run id"},
+		{args: []string{"rm", "missing"}, wantErr: "run not found: missing"},
+		{args: []string{"prune"}, wantErr: "runs prune requires --older-than"},
+		{args: []string{"prune", "--older-than", "0d"}, wantErr: "must be greater than zero"},
+		{args: []string{"reindex", "now"}, wantErr: "runs reindex does not accept arguments"},
+	}
+	for _, tt := range tests {
+		err := RunsCommand(cwd, tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("unexpected error for %q: %v", tt.args, err)
+		}
+	}
+}
+
+func TestRunsListFiltersAndLimits(t *testing.T) {
+	cwd := t.TempDir()
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	withRunsNow(t, now)
+	for _, record := range []*stats.RunRecord{
+		{RunID: "run-old", Timestamp: now.AddDate(0, 0, -20), Status: stats.RunStatusSuccess},
+		{
+			RunID:      "run-failed",
+			Timestamp:  now.AddDate(0, 0, -3),
+			Status:     stats.RunStatusError,
+			ErrorType:  "pipeline_error",
+			Normalized: result.NormalizedMetrics{DurationMS: 90000, TotalCostUSD: 1.5},
+			Pipeline:   &stats.PipelineRunRecord{TerminalNode: "fail"},
+		},
+		{RunID: "run-new", Timestamp: now.AddDate(0, 0, -1), Status: stats.RunStatusSuccess},
+	} {
+		if _, err := stats.SaveRunRecord(config.RunsDir(cwd), record, stats.RetentionPolicy{}); err != nil {
+			t.Fatalf("save record: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	withRunsOutput(t, &out)
+
+	if err := RunsCommand(cwd, []string{"list", "--since", "7d"}); err != nil {
+		t.Fatalf("runs list: %v", err)
+	}
+	text := out.String()
+	assertContains(t, text, "Started(UTC)")
+	assertContains(t, text, "Error Type")
+	assertContains(t, text, "run-failed | 2026-03-28 12:00:00 | pipeline | error   | 1m30s    | 1.500000  | fail     | pipeline_error")
+	assertContains(t, text, "run-new")
+	if strings.Contains(text, "run-old") {
+		t.Fatalf("expected --since to filter old runs:\n%s", text)
+	}
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{args: []string{"list", "--json"}, want: []string{"run-old", "run-failed", "run-new"}},
+		{args: []string{"list", "--json", "--limit", "2"}, want: []string{"run-failed", "run-new"}},
+		{args: []string{"list", "--json", "--status", "success", "--since", "2026-03-01"}, want: []string{"run-old", "run-new"}},
+		{args: []string{"list", "--json", "--error-type", "pipeline_error"}, want: []string{"run-failed"}},
+	}
+	for _, tt := range tests {
+		out.Reset()
+		if err := RunsCommand(cwd, tt.args); err != nil {
+			t.Fatalf("runs %q: %v", tt.args, err)
+		}
+		var entries []runListEntry
+		if err := json.Unmarshal(out.Bytes(), &entries); err != nil {
+			t.Fatalf("decode runs JSON: %v", err)
+		}
+		got := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			got = append(got, entry.RunID)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Fatalf("unexpected runs for %q: %v", tt.args, got)
+		}
+	}
+}
+
+func TestRunsRemoveAndPrune(t *testing.T) {
+	cwd := t.TempDir()
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	withRunsNow(t, now)
+	for _, record := range []*stats.RunRecord{
+		{RunID: "aaa111", Timestamp: now.AddDate(0, 0, -40), Status: stats.RunStatusSuccess},
+		{RunID: "bbb222", Timestamp: now.AddDate(0, 0, -35), Status: stats.RunStatusError},
+		{RunID: "ccc333", Timestamp: now.AddDate(0, 0, -1), Status: stats.RunStatusSuccess},
+	} {
+		if _, err := stats.SaveRunRecord(config.RunsDir(cwd), record, stats.RetentionPolicy{}); err != nil {
+			t.Fatalf("save record: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	withRunsOutput(t, &out)
+
+	if err := RunsCommand(cwd, []string{"prune", "--older-than", "30d", "--keep-failed", "--dry-run"}); err != nil {
+		t.Fatalf("runs prune: %v", err)
+	}
+	assertContains(t, out.String(), "Would remove 1 run(s)")
+	assertContains(t, out.String(), "-aaa111")
+
+	out.Reset()
+	if err := RunsCommand(cwd, []string{"prune", "--older-than", "30d", "--keep-failed"}); err != nil {
+		t.Fatalf("runs prune: %v", err)
+	}
+	assertContains(t, out.String(), "Removed 1 run(s)")
+
+	out.Reset()
+	if err := RunsCommand(cwd, []string{"rm", "bbb"}); err != nil {
+		t.Fatalf("runs rm: %v", err)
+	}
+	assertContains(t, out.String(), "Removed ")
+
+	runs, _, err := stats.ListRunRecords(config.RunsDir(cwd))
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Record.RunID != "ccc333" {
+		t.Fatalf("unexpected remaining runs: %+v", runs)
+	}
+}
+
+func TestRunsReindex(t *testing.T) {
+	cwd := t.TempDir()
+	runsDir := config.RunsDir(cwd)
+	if _, err := stats.SaveRunRecord(runsDir, &stats.RunRecord{RunID: "aaa111", Status: stats.RunStatusSuccess}, stats.RetentionPolicy{}); err != nil {
+		t.Fatalf("save record: %v", err)
+	}
+	indexPath := filepath.Join(runsDir, stats.RunIndexFileName)
+	if err := os.Remove(indexPath); err != nil {
+		t.Fatalf("remove index: %v", err)
+	}
+
+	var out bytes.Buffer
+	withRunsOutput(t, &out)
+
+	if err := RunsCommand(cwd, []string{"reindex"}); err != nil {
+		t.Fatalf("runs reindex: %v", err)
+	}
+	assertContains(t, out.String(), "Indexed 1 runs in "+indexPath)
+	content, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("read index: %v", err)
+	}
+	assertContains(t, string(content), `"run_id":"aaa111"`)
+}
+
+func TestParseTimeBound(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"7d":                   now.AddDate(0, 0, -7),
+		"12h":                  now.Add(-12 * time.Hour),
+		"2026-03-01":           time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		"2026-03-01T08:30:00Z": time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC),
+	}
+	for value, want := range tests {
+		got, err := parseTimeBound(value, now)
+		if err != nil || !got.Equal(want) {
+			t.Fatalf("parseTimeBound(%q) = %v, %v", value, got, err)
+		}
+	}
+	if _, err := parseTimeBound("last week", now); err == nil {
+		t.Fatal("expected error for an invalid bound")
+	}
+}
+
+func saveTimelineTestRun(t *testing.T, cwd string) {
+	t.Helper()
+
+	_, err := stats.SaveRunRecord(config.RunsDir(cwd), &stats.RunRecord{
+		RunID:     "cafe0123",
+		Timestamp: time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC),
+		Status:    stats.RunStatusSuccess,
+		Pipeline: &stats.PipelineRunRecord{
+			Status:         "success",
+			EntryNode:      "review",
+			TerminalNode:   "success",
+			TerminalStatus: "success",
+			NodeRuns: []stats.PipelineNodeRunRecord{
+				{
+					NodeID:         "review",
+					NodeRunID:      "review-1",
+					Kind:           "agent",
+					Status:         "success",
+					DurationMS:     2000,
+					Decision:       map[string]any{"approved": false, "notes": "add tests"},
+					TranscriptFile: "nodes/review-review-1.ndjson",
+				},
+				{
+					NodeID:     "review",
+					NodeRunID:  "review-2",
+					Kind:       "agent",
+					Status:     "success",
+					DurationMS: 1000,
+					Decision:   map[string]any{"approved": true},
+				},
+			},
+			Transitions: []stats.PipelineTransition{
+				{From: "review", To: "review", When: "decision.approved == false", Iteration: 1, NodeRunID: "review-1"},
+				{From: "review", To: "success", When: "decision.approved == true", Iteration: 2, NodeRunID: "review-2"},
+			},
+		},
+	}, stats.RetentionPolicy{})
+	if err != nil {
+		t.Fatalf("save record: %v", err)
+	}
+}
+
+func withRunsNow(t *testing.T, now time.Time) {
+	t.Helper()
+
+	prev := runsNow
+	runsNow = func() time.Time { return now }
+	t.Cleanup(func() {
+		runsNow = prev
+	})
+}
+
+func withRunsOutput(t *testing.T, out *bytes.Buffer) {
+	t.Helper()
+
+	prev := runsOutputWriter
+	runsOutputWriter = out
+	t.Cleanup(func() {
+		runsOutputWriter = prev
+	})
+}
@@ -5,73 +5,559 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 
 	"agent-cli/internal/config"
 	"agent-cli/internal/stats"
 )
 
+var statsOutputWriter io.Writer = os.Stdout
+
+const (
+	statsHistogramBuckets = 10
+	statsHistogramWidth   = 40
+)
+
+// statsFilterFlags are the run filters shared by the stats subcommands.
+type statsFilterFlags struct {
+	since     string
+	until     string
+	status    string
+	errorType string
+	mode      string
+	pipeline  string
+	model     string
+	labels    labelValues
+}
+
+func registerStatsFilterFlags(fs *flag.FlagSet) *statsFilterFlags {
+	flags := &statsFilterFlags{}
+	fs.StringVar(&flags.since, "since", "", "only runs started at or after this age (7d, 12h) or date (2006-01-02 or RFC 3339)")
+	fs.StringVar(&flags.until, "until", "", "only runs started before this age or date")
+	fs.StringVar(&flags.status, "status", "", "only runs with this status (success|error|parse_error|exec_error)")
+	fs.StringVar(&flags.errorType, "error-type", "", "only runs with this error type")
+	fs.StringVar(&flags.mode, "mode", "", "only prompt or pipeline runs (prompt|pipeline)")
+	fs.StringVar(&flags.pipeline, "pipeline", "", "only runs of this pipeline plan file")
+	fs.StringVar(&flags.model, "model", "", "only runs that used this model (sonnet, opus or part of a model name)")
+	fs.Var(&flags.labels, "label", "only runs with this label in KEY=VALUE format (repeatable)")
+	return flags
+}
+
+// filter validates the flags and returns the filter they describe; relative bounds are resolved against now.
+func (f *statsFilterFlags) filter(cwd string) (*stats.RunFilter, error) {
+	filter := &stats.RunFilter{
+		ErrorType: strings.TrimSpace(f.errorType),
+		Model:     strings.ToLower(strings.TrimSpace(f.model)),
+		Labels:    f.labels.values,
+	}
+
+	now := runsNow()
+	if strings.TrimSpace(f.since) != "" {
+		since, err := parseTimeBound(f.since, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --since: %w", err)
+		}
+		filter.Since = &since
+	}
+	if strings.TrimSpace(f.until) != "" {
+		until, err := parseTimeBound(f.until, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --until: %w", err)
+		}
+		filter.Until = &until
+	}
+	if filter.Since != nil && filter.Until != nil && !filter.Until.After(*filter.Since) {
+		return nil, errors.New("--until must be after --since")
+	}
+
+	status := stats.RunStatus(strings.ToLower(strings.TrimSpace(f.status)))
+	switch status {
+	case "", stats.RunStatusSuccess, stats.RunStatusError, stats.RunStatusParseError, stats.RunStatusExecError:
+		filter.Status = status
+	default:
+		return nil, fmt.Errorf("invalid --status %q: expected success, error, parse_error or exec_error", f.status)
+	}
+
+	mode := strings.ToLower(strings.TrimSpace(f.mode))
+	switch mode {
+	case "", stats.RunModePrompt, stats.RunModePipeline:
+		filter.Mode = mode
+	default:
+		return nil, fmt.Errorf("invalid --mode %q: expected prompt or pipeline", f.mode)
+	}
+
+	if pipelinePath := strings.TrimSpace(f.pipeline); pipelinePath != "" {
+		if !filepath.IsAbs(pipelinePath) {
+			pipelinePath = filepath.Join(cwd, pipelinePath)
+		}
+		filter.Pipeline = filepath.Clean(pipelinePath)
+	}
+
+	if filter.IsZero() {
+		return nil, nil
+	}
+	return filter, nil
+}
+
 func StatsCommand(cwd string, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "nodes":
+			return statsNodesCommand(cwd, args[1:])
+		case "export":
+			return statsExportCommand(cwd, args[1:])
+		}
+	}
+
 	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 
 	var jsonOutput bool
+	var allProjects bool
+	var recomputeCost bool
+	var groupByValue string
+	var histogramValue string
 	fs.BoolVar(&jsonOutput, "json", false, "print statistics as JSON")
+	fs.BoolVar(&allProjects, "all-projects", false, "cover the runs of every project in the user-level run registry")
+	fs.BoolVar(&recomputeCost, "recompute-cost", false, recomputeCostUsage)
+	fs.StringVar(&groupByValue, "group-by", "", "one row per day|week|month|status|error_type|pipeline|terminal_node|profile|label:<key>")
+	fs.StringVar(&histogramValue, "histogram", "", "also show a histogram of duration|api_duration|cost|turns|tokens")
+	filterFlags := registerStatsFilterFlags(fs)
 
-	if err := fs.Parse(args); err != nil {
+	positionals, err := parseInterspersedFlags(fs, args)
+	if err != nil {
 		return err
 	}
-	if fs.NArg() > 0 {
+	if len(positionals) > 0 {
 		return errors.New("stats command does not accept positional arguments")
 	}
-
-	agg, err := stats.AggregateStats(config.RunsDir(cwd))
+	filter, err := filterFlags.filter(cwd)
 	if err != nil {
 		return err
 	}
 
+	var keep stats.RunPredicate
+	if filter != nil {
+		keep = filter.Matches
+	}
+
+	var metric stats.Metric
+	if strings.TrimSpace(histogramValue) != "" {
+		metric, err = stats.ParseMetric(histogramValue)
+		if err != nil {
+			return fmt.Errorf("invalid --histogram: %w", err)
+		}
+	}
+	var groupBy stats.GroupBy
+	if strings.TrimSpace(groupByValue) != "" {
+		groupBy, err = stats.ParseGroupBy(groupByValue)
+		if err != nil {
+			return fmt.Errorf("invalid --group-by: %w", err)
+		}
+	}
+	if recomputeCost {
+		if allProjects {
+			return errors.New("--recompute-cost rewrites the runs of this project and cannot be combined with --all-projects")
+		}
+		if err := recomputeRunCosts(cwd); err != nil {
+			return err
+		}
+	}
+
+	var runs []stats.RunEntry
+	var skipped []string
+	var projects []stats.ProjectRuns
+	if allProjects {
+		registryDir, err := stats.RegistryDir()
+		if err != nil {
+			return err
+		}
+		projects, err = stats.ListRegisteredProjects(registryDir)
+		if err != nil {
+			return err
+		}
+		runs = stats.AllProjectRuns(projects)
+	} else {
+		runs, skipped, err = stats.ListRunSummaries(config.RunsDir(cwd))
+		if err != nil {
+			return err
+		}
+	}
+
+	if groupBy != "" {
+		grouped := &stats.GroupedAggregate{
+			GroupBy:      groupBy,
+			Groups:       stats.GroupRuns(runs, keep, groupBy),
+			SkippedFiles: append([]string{}, skipped...),
+			Filters:      filter,
+		}
+		if metric != "" {
+			for index := range grouped.Groups {
+				group := &grouped.Groups[index]
+				inGroup := func(record *stats.RunRecord) bool {
+					return (keep == nil || keep(record)) && groupBy.Key(record) == group.Group
+				}
+				group.Histogram = stats.HistogramRuns(runs, inGroup, metric, statsHistogramBuckets)
+			}
+		}
+		return printStatsGroups(grouped, jsonOutput)
+	}
+
+	var agg *stats.Aggregate
+	if allProjects {
+		agg = stats.AggregateProjects(projects, keep)
+	} else {
+		agg = stats.AggregateRuns(runs, skipped, keep)
+	}
+	agg.Filters = filter
+	if metric != "" {
+		agg.Histogram = stats.HistogramRuns(runs, keep, metric, statsHistogramBuckets)
+	}
+
 	if jsonOutput {
 		encoded, err := json.MarshalIndent(agg, "", "  ")
 		if err != nil {
 			return fmt.Errorf("encode stats JSON: %w", err)
 		}
-		fmt.Println(string(encoded))
+		fmt.Fprintln(statsOutputWriter, string(encoded))
 		return nil
 	}
 
-	printStatsTable(agg)
+	printStatsTable(statsOutputWriter, agg)
 	return nil
 }
 
-func printStatsTable(agg *stats.Aggregate) {
-	fmt.Println("Run Summary")
-	fmt.Printf("  Total: %d\n", agg.TotalRuns)
-	fmt.Printf("  Success: %d\n", agg.SuccessRuns)
-	fmt.Printf("  Errors: %d\n", agg.ErrorRuns)
-	fmt.Printf("  Parse Errors: %d\n", agg.ParseErrorRuns)
+// statsExportCommand writes one flat row per run, node run or run model to stdout.
+func statsExportCommand(cwd string, args []string) error {
+	fs := flag.NewFlagSet("stats export", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	var formatValue string
+	var levelValue string
+	var recomputeCost bool
+	fs.StringVar(&formatValue, "format", string(stats.ExportFormatCSV), "output format (csv|jsonl)")
+	fs.StringVar(&levelValue, "level", string(stats.ExportLevelRun), "one row per run, pipeline node run or run model (run|node|model)")
+	fs.BoolVar(&recomputeCost, "recompute-cost", false, recomputeCostUsage)
+	filterFlags := registerStatsFilterFlags(fs)
+
+	positionals, err := parseInterspersedFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positionals) > 0 {
+		return errors.New("stats export command does not accept positional arguments")
+	}
+	format, err := stats.ParseExportFormat(formatValue)
+	if err != nil {
+		return fmt.Errorf("invalid --format: %w", err)
+	}
+	level, err := stats.ParseExportLevel(levelValue)
+	if err != nil {
+		return fmt.Errorf("invalid --level: %w", err)
+	}
+	filter, err := filterFlags.filter(cwd)
+	if err != nil {
+		return err
+	}
+
+	var keep stats.RunPredicate
+	if filter != nil {
+		keep = filter.Matches
+	}
+	runs, err := listPricedRunRecords(cwd, recomputeCost)
+	if err != nil {
+		return err
+	}
+	if err := stats.ExportRunEntries(statsOutputWriter, runs, keep, format, level); err != nil {
+		return fmt.Errorf("export runs: %w", err)
+	}
+	return nil
+}
+
+// statsNodesCommand aggregates pipeline node runs across runs by node id.
+func statsNodesCommand(cwd string, args []string) error {
+	fs := flag.NewFlagSet("stats nodes", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	var jsonOutput bool
+	var recomputeCost bool
+	fs.BoolVar(&jsonOutput, "json", false, "print node statistics as JSON")
+	fs.BoolVar(&recomputeCost, "recompute-cost", false, recomputeCostUsage)
+	filterFlags := registerStatsFilterFlags(fs)
+
+	positionals, err := parseInterspersedFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positionals) > 0 {
+		return errors.New("stats nodes command does not accept positional arguments")
+	}
+	filter, err := filterFlags.filter(cwd)
+	if err != nil {
+		return err
+	}
+
+	var keep stats.RunPredicate
+	if filter != nil {
+		keep = filter.Matches
+	}
+	runs, err := listPricedRunRecords(cwd, recomputeCost)
+	if err != nil {
+		return err
+	}
+	nodes := stats.NodeRuns(runs, keep)
+
+	if jsonOutput {
+		encoded, err := json.MarshalIndent(nodes, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode node stats JSON: %w", err)
+		}
+		fmt.Fprintln(statsOutputWriter, string(encoded))
+		return nil
+	}
+
+	if filter != nil {
+		fmt.Fprintf(statsOutputWriter, "Filters: %s\n\n", formatRunFilter(filter))
+	}
+	if len(nodes) == 0 {
+		fmt.Fprintln(statsOutputWriter, "No pipeline node runs found.")
+		return nil
+	}
+	rows := make([][]string, 0, len(nodes))
+	for _, node := range nodes {
+		transition := "-"
+		if node.TopTransition != nil {
+			transition = fmt.Sprintf("%s (%d)", node.TopTransition.To, node.TopTransition.Count)
+		}
+		rows = append(rows, []string{
+			node.NodeID,
+			orDash(node.Kind),
+			strconv.Itoa(node.Visits),
+			strconv.Itoa(node.Runs),
+			fmt.Sprintf("%.2f", node.VisitsPerRun),
+			fmt.Sprintf("%.1f%%", node.ErrorRate*100),
+			fmt.Sprintf("%.1f%%", node.TimeoutRate*100),
+			formatDurationMS(node.P50MS),
+			formatDurationMS(node.P95MS),
+			formatCostUSD(node.MeanCostUSD),
+			formatCostUSD(node.TotalCostUSD),
+			formatCostUSD(node.TotalEffectiveCostUSD),
+			transition,
+		})
+	}
+	headers := []string{
+		"Node", "Kind", "Visits", "Runs", "Visits/Run", "Error Rate", "Timeout Rate",
+		"p50", "p95", "Mean Cost(USD)", "Cost(USD)", "Effective Cost(USD)", "Top Transition",
+	}
+	for _, line := range renderTextTable(headers, rows) {
+		fmt.Fprintln(statsOutputWriter, line)
+	}
+	return nil
+}
+
+const recomputeCostUsage = "reprice all saved runs with the current [pricing] config, rewriting their effective costs"
+
+// statsPricing converts the [pricing] config to the prices effective costs are computed with.
+func statsPricing(pricing map[string]config.PricingConfig) stats.Pricing {
+	if len(pricing) == 0 {
+		return nil
+	}
+	result := make(stats.Pricing, len(pricing))
+	for model, price := range pricing {
+		result[model] = stats.ModelPrice{
+			InputPerMTok:      price.InputPerMTok,
+			OutputPerMTok:     price.OutputPerMTok,
+			CacheWritePerMTok: price.CacheWritePerMTok,
+			CacheReadPerMTok:  price.CacheReadPerMTok,
+		}
+	}
+	return result
+}
+
+// recomputeRunCosts rewrites the effective costs of every run of cwd with the current [pricing] config.
+func recomputeRunCosts(cwd string) error {
+	pricing, err := config.LoadPricing(cwd)
+	if err != nil {
+		return fmt.Errorf("--recompute-cost: %w", err)
+	}
+	if len(pricing) == 0 {
+		return fmt.Errorf("--recompute-cost: no [pricing.<model>] tables in %s", config.ConfigPath(cwd))
+	}
+	if _, err := stats.RepriceRunRecords(config.RunsDir(cwd), statsPricing(pricing)); err != nil {
+		return fmt.Errorf("--recompute-cost: %w", err)
+	}
+	return nil
+}
+
+// listPricedRunRecords loads the full run records of cwd, first repricing them with the current config when
+// recompute is set.
+func listPricedRunRecords(cwd string, recompute bool) ([]stats.RunEntry, error) {
+	if recompute {
+		if err := recomputeRunCosts(cwd); err != nil {
+			return nil, err
+		}
+	}
+	runs, _, err := stats.ListRunRecords(config.RunsDir(cwd))
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func printStatsGroups(grouped *stats.GroupedAggregate, jsonOutput bool) error {
+	if jsonOutput {
+		encoded, err := json.MarshalIndent(grouped, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode stats JSON: %w", err)
+		}
+		fmt.Fprintln(statsOutputWriter, string(encoded))
+		return nil
+	}
+
+	if grouped.Filters != nil {
+		fmt.Fprintf(statsOutputWriter, "Filters: %s\n\n", formatRunFilter(grouped.Filters))
+	}
+	if len(grouped.Groups) == 0 {
+		fmt.Fprintln(statsOutputWriter, "No runs found.")
+		printSkippedFiles(statsOutputWriter, grouped.SkippedFiles)
+		return nil
+	}
+	rows := make([][]string, 0, len(grouped.Groups))
+	for _, group := range grouped.Groups {
+		rows = append(rows, []string{
+			orDash(group.Group),
+			strconv.Itoa(group.TotalRuns),
+			strconv.Itoa(group.SuccessRuns),
+			fmt.Sprintf("%.1f%%", group.SuccessRate*100),
+			formatCostUSD(group.Sums.TotalCostUSD),
+			strconv.FormatInt(group.Sums.InputTokens, 10),
+			strconv.FormatInt(group.Sums.OutputTokens, 10),
+			formatDurationMS(group.Sums.DurationMS),
+			formatDurationMS(group.AvgDurationMS),
+			formatDurationMS(int64(group.Distributions.DurationMS.P50)),
+			formatDurationMS(int64(group.Distributions.DurationMS.P90)),
+			formatCostUSD(group.Distributions.CostUSD.P90),
+		})
+	}
+	headers := []string{
+		statsGroupHeader(grouped.GroupBy), "Runs", "Success", "Success Rate", "Cost(USD)",
+		"Input Tokens", "Output Tokens", "Duration", "Avg Duration", "p50 Duration", "p90 Duration", "p90 Cost(USD)",
+	}
+	for _, line := range renderTextTable(headers, rows) {
+		fmt.Fprintln(statsOutputWriter, line)
+	}
+	for _, group := range grouped.Groups {
+		if group.Histogram != nil {
+			printStatsHistogram(statsOutputWriter, fmt.Sprintf("%s: %s", statsGroupHeader(grouped.GroupBy), orDash(group.Group)), group.Histogram)
+		}
+	}
+	printSkippedFiles(statsOutputWriter, grouped.SkippedFiles)
+	return nil
+}
+
+func statsGroupHeader(groupBy stats.GroupBy) string {
+	if key, ok := groupBy.LabelKey(); ok {
+		return "Label " + key
+	}
+	switch groupBy {
+	case stats.GroupByDay:
+		return "Day(UTC)"
+	case stats.GroupByWeek:
+		return "Week(UTC)"
+	case stats.GroupByMonth:
+		return "Month(UTC)"
+	case stats.GroupByErrorType:
+		return "Error Type"
+	case stats.GroupByTerminalNode:
+		return "Terminal"
+	}
+	return strings.ToUpper(string(groupBy[:1])) + string(groupBy[1:])
+}
+
+func printStatsTable(w io.Writer, agg *stats.Aggregate) {
+	if agg.Filters != nil {
+		fmt.Fprintf(w, "Filters: %s\n\n", formatRunFilter(agg.Filters))
+	}
+	fmt.Fprintln(w, "Run Summary")
+	fmt.Fprintf(w, "  Total: %d\n", agg.TotalRuns)
+	fmt.Fprintf(w, "  Success: %d\n", agg.SuccessRuns)
+	fmt.Fprintf(w, "  Errors: %d\n", agg.ErrorRuns)
+	fmt.Fprintf(w, "  Parse Errors: %d\n", agg.ParseErrorRuns)
 
 	if agg.FirstRunAt != nil {
-		fmt.Printf("  First Run: %s\n", agg.FirstRunAt.UTC().Format("2006-01-02T15:04:05Z"))
+		fmt.Fprintf(w, "  First Run: %s\n", agg.FirstRunAt.UTC().Format("2006-01-02T15:04:05Z"))
 	}
 	if agg.LastRunAt != nil {
-		fmt.Printf("  Last Run: %s\n", agg.LastRunAt.UTC().Format("2006-01-02T15:04:05Z"))
+		fmt.Fprintf(w, "  Last Run: %s\n", agg.LastRunAt.UTC().Format("2006-01-02T15:04:05Z"))
 	}
 
-	fmt.Println()
-	fmt.Println("Sums")
-	fmt.Printf("  Duration(ms): %d\n", agg.Sums.DurationMS)
-	fmt.Printf("  API Duration(ms): %d\n", agg.Sums.DurationAPIMS)
-	fmt.Printf("  Turns: %d\n", agg.Sums.NumTurns)
-	fmt.Printf("  Cost(USD): %.6f\n", agg.Sums.TotalCostUSD)
-	fmt.Printf("  Input Tokens: %d\n", agg.Sums.InputTokens)
-	fmt.Printf("  Cache Create Tokens: %d\n", agg.Sums.CacheCreationInputTokens)
-	fmt.Printf("  Cache Read Tokens: %d\n", agg.Sums.CacheReadInputTokens)
-	fmt.Printf("  Output Tokens: %d\n", agg.Sums.OutputTokens)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Sums")
+	fmt.Fprintf(w, "  Duration(ms): %d\n", agg.Sums.DurationMS)
+	fmt.Fprintf(w, "  API Duration(ms): %d\n", agg.Sums.DurationAPIMS)
+	fmt.Fprintf(w, "  Turns: %d\n", agg.Sums.NumTurns)
+	fmt.Fprintf(w, "  Cost(USD): %.6f\n", agg.Sums.TotalCostUSD)
+	fmt.Fprintf(w, "  Effective Cost(USD): %.6f\n", agg.Sums.EffectiveCostUSD)
+	fmt.Fprintf(w, "  Input Tokens: %d\n", agg.Sums.InputTokens)
+	fmt.Fprintf(w, "  Cache Create Tokens: %d\n", agg.Sums.CacheCreationInputTokens)
+	fmt.Fprintf(w, "  Cache Read Tokens: %d\n", agg.Sums.CacheReadInputTokens)
+	fmt.Fprintf(w, "  Output Tokens: %d\n", agg.Sums.OutputTokens)
+
+	if agg.TotalRuns > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Distributions (per run)")
+		distributions := agg.Distributions
+		rows := [][]string{
+			formatDistributionRow("Duration", distributions.DurationMS, formatDurationValue),
+			formatDistributionRow("API Duration", distributions.DurationAPIMS, formatDurationValue),
+			formatDistributionRow("Cost(USD)", distributions.CostUSD, formatCostUSD),
+			formatDistributionRow("Turns", distributions.NumTurns, formatCountValue),
+			formatDistributionRow("Tokens", distributions.TotalTokens, formatCountValue),
+		}
+		for _, line := range renderTextTable([]string{"Metric", "Min", "Mean", "p50", "p90", "p99", "Max"}, rows) {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+
+	if len(agg.Projects) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "By Project")
+		rows := make([][]string, 0, len(agg.Projects))
+		for _, project := range agg.Projects {
+			name := project.Project
+			if project.Missing {
+				name += " (missing)"
+			}
+			lastRun := "-"
+			if project.LastRunAt != nil {
+				lastRun = project.LastRunAt.UTC().Format("2006-01-02 15:04:05")
+			}
+			rows = append(rows, []string{
+				name,
+				strconv.Itoa(project.TotalRuns),
+				fmt.Sprintf("%.1f%%", project.SuccessRate*100),
+				formatCostUSD(project.Sums.TotalCostUSD),
+				strconv.FormatInt(project.Sums.InputTokens, 10),
+				strconv.FormatInt(project.Sums.OutputTokens, 10),
+				formatDurationMS(project.Sums.DurationMS),
+				lastRun,
+			})
+		}
+		headers := []string{"Project", "Runs", "Success Rate", "Cost(USD)", "Input Tokens", "Output Tokens", "Duration", "Last Run(UTC)"}
+		for _, line := range renderTextTable(headers, rows) {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
 
 	if len(agg.ByModel) > 0 {
-		fmt.Println()
-		fmt.Println("By Model")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "By Model")
 		models := make([]string, 0, len(agg.ByModel))
 		for model := range agg.ByModel {
 			models = append(models, model)
@@ -80,21 +566,145 @@ func printStatsTable(agg *stats.Aggregate) {
 
 		for _, model := range models {
 			metric := agg.ByModel[model]
-			fmt.Printf("  %s\n", model)
-			fmt.Printf("    Input Tokens: %d\n", metric.InputTokens)
-			fmt.Printf("    Output Tokens: %d\n", metric.OutputTokens)
-			fmt.Printf("    Cache Read Tokens: %d\n", metric.CacheReadInputTokens)
-			fmt.Printf("    Cache Create Tokens: %d\n", metric.CacheCreationInputTokens)
-			fmt.Printf("    Web Search Requests: %d\n", metric.WebSearchRequests)
-			fmt.Printf("    Cost(USD): %.6f\n", metric.CostUSD)
+			fmt.Fprintf(w, "  %s\n", model)
+			fmt.Fprintf(w, "    Input Tokens: %d\n", metric.InputTokens)
+			fmt.Fprintf(w, "    Output Tokens: %d\n", metric.OutputTokens)
+			fmt.Fprintf(w, "    Cache Read Tokens: %d\n", metric.CacheReadInputTokens)
+			fmt.Fprintf(w, "    Cache Create Tokens: %d\n", metric.CacheCreationInputTokens)
+			fmt.Fprintf(w, "    Web Search Requests: %d\n", metric.WebSearchRequests)
+			fmt.Fprintf(w, "    Cost(USD): %.6f\n", metric.CostUSD)
+			fmt.Fprintf(w, "    Effective Cost(USD): %.6f\n", metric.EffectiveCostUSD)
 		}
 	}
 
-	if len(agg.SkippedFiles) > 0 {
-		fmt.Println()
-		fmt.Println("Skipped Files")
-		for _, name := range agg.SkippedFiles {
-			fmt.Printf("  - %s\n", name)
+	if len(agg.ResumeChains) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Resume Chains")
+		for _, chain := range agg.ResumeChains {
+			fmt.Fprintf(w, "  %s\n", chain.RootRunID)
+			fmt.Fprintf(w, "    Runs: %s\n", strings.Join(chain.RunIDs, " -> "))
+			fmt.Fprintf(w, "    Final Status: %s\n", chain.FinalStatus)
+			fmt.Fprintf(w, "    Duration(ms): %d\n", chain.DurationMS)
+			fmt.Fprintf(w, "    Cost(USD): %.6f\n", chain.TotalCostUSD)
+		}
+	}
+
+	if agg.Histogram != nil {
+		printStatsHistogram(w, "", agg.Histogram)
+	}
+
+	printSkippedFiles(w, agg.SkippedFiles)
+}
+
+// printSkippedFiles lists the run files that could not be read.
+func printSkippedFiles(w io.Writer, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Skipped Files")
+	for _, name := range names {
+		fmt.Fprintf(w, "  - %s\n", name)
+	}
+}
+
+func formatDistributionRow(label string, distribution stats.Distribution, format func(float64) string) []string {
+	return []string{
+		label,
+		format(distribution.Min),
+		format(distribution.Mean),
+		format(distribution.P50),
+		format(distribution.P90),
+		format(distribution.P99),
+		format(distribution.Max),
+	}
+}
+
+func formatDurationValue(durationMS float64) string {
+	return formatDurationMS(int64(math.Round(durationMS)))
+}
+
+func formatCountValue(value float64) string {
+	if value == math.Trunc(value) {
+		return strconv.FormatFloat(value, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64)
+}
+
+// printStatsHistogram draws one bar per bucket, scaled to the fullest bucket. A non-empty scope names
+// the group the histogram covers.
+func printStatsHistogram(w io.Writer, scope string, histogram *stats.Histogram) {
+	fmt.Fprintln(w)
+	if scope != "" {
+		fmt.Fprintf(w, "Histogram (%s) - %s\n", histogram.Metric, scope)
+	} else {
+		fmt.Fprintf(w, "Histogram (%s)\n", histogram.Metric)
+	}
+	if len(histogram.Buckets) == 0 {
+		fmt.Fprintln(w, "  No runs found.")
+		return
+	}
+
+	format := formatCountValue
+	switch histogram.Metric {
+	case stats.MetricDuration, stats.MetricAPIDuration:
+		format = formatDurationValue
+	case stats.MetricCost:
+		format = formatCostUSD
+	}
+	maxCount := 0
+	for _, bucket := range histogram.Buckets {
+		maxCount = max(maxCount, bucket.Count)
+	}
+
+	rows := make([][]string, 0, len(histogram.Buckets))
+	for _, bucket := range histogram.Buckets {
+		bar := strings.Repeat("#", bucket.Count*statsHistogramWidth/maxCount)
+		if bar == "" && bucket.Count > 0 {
+			bar = "#"
 		}
+		rows = append(rows, []string{
+			format(bucket.Lower) + " - " + format(bucket.Upper),
+			strconv.Itoa(bucket.Count),
+			bar,
+		})
+	}
+	for _, line := range renderTextTable([]string{"Range", "Runs", ""}, rows) {
+		fmt.Fprintf(w, "  %s\n", strings.TrimRight(line, " "))
+	}
+}
+
+// formatRunFilter describes the set fields of filter in flag syntax.
+func formatRunFilter(filter *stats.RunFilter) string {
+	parts := make([]string, 0, 8)
+	if filter.Since != nil {
+		parts = append(parts, "since="+filter.Since.UTC().Format("2006-01-02T15:04:05Z"))
+	}
+	if filter.Until != nil {
+		parts = append(parts, "until="+filter.Until.UTC().Format("2006-01-02T15:04:05Z"))
+	}
+	if filter.Status != "" {
+		parts = append(parts, "status="+string(filter.Status))
+	}
+	if filter.ErrorType != "" {
+		parts = append(parts, "error_type="+filter.ErrorType)
+	}
+	if filter.Mode != "" {
+		parts = append(parts, "mode="+filter.Mode)
+	}
+	if filter.Pipeline != "" {
+		parts = append(parts, "pipeline="+filter.Pipeline)
+	}
+	if filter.Model != "" {
+		parts = append(parts, "model="+filter.Model)
+	}
+	keys := make([]string, 0, len(filter.Labels))
+	for key := range filter.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts = append(parts, "label:"+key+"="+filter.Labels[key])
 	}
+	return strings.Join(parts, ", ")
 }
@@ -0,0 +1,592 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-cli/internal/config"
+	"agent-cli/internal/result"
+	"agent-cli/internal/stats"
+)
+
+func TestStatsCommandFiltersRuns(t *testing.T) {
+	cwd := t.TempDir()
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	withRunsNow(t, now)
+	saveStatsTestRuns(t, cwd, now)
+
+	var out bytes.Buffer
+	withStatsOutput(t, &out)
+
+	args := []string{"--json", "--since", "7d", "--mode", "pipeline", "--pipeline", "pipelines/flow.yml", "--label", "team=core"}
+	if err := StatsCommand(cwd, args); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+
+	var agg struct {
+		TotalRuns int `json:"total_runs"`
+		Sums      struct {
+			TotalCostUSD float64 `json:"total_cost_usd"`
+		} `json:"sums"`
+		Filters map[string]any `json:"filters"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &agg); err != nil {
+		t.Fatalf("decode stats JSON: %v", err)
+	}
+	if agg.TotalRuns != 1 || agg.Sums.TotalCostUSD != 2 {
+		t.Fatalf("unexpected aggregate: %+v", agg)
+	}
+	wantFilters := map[string]any{
+		"since":    "2026-03-24T12:00:00Z",
+		"mode":     "pipeline",
+		"pipeline": filepath.Join(cwd, "pipelines", "flow.yml"),
+		"labels":   map[string]any{"team": "core"},
+	}
+	if len(agg.Filters) != len(wantFilters) {
+		t.Fatalf("unexpected filters: %#v", agg.Filters)
+	}
+	for key, want := range wantFilters {
+		got, _ := json.Marshal(agg.Filters[key])
+		wantJSON, _ := json.Marshal(want)
+		if string(got) != string(wantJSON) {
+			t.Fatalf("unexpected filter %s: %s", key, got)
+		}
+	}
+
+	out.Reset()
+	if err := StatsCommand(cwd, []string{"--status", "error", "--model", "sonnet", "--until", "2026-03-30"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	text := out.String()
+	assertContains(t, text, "Filters: until=2026-03-30T00:00:00Z, status=error, model=sonnet")
+	assertContains(t, text, "Total: 1")
+	assertContains(t, text, "Cost(USD): 4.000000")
+
+	out.Reset()
+	if err := StatsCommand(cwd, []string{"--json"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if strings.Contains(out.String(), `"filters"`) || !strings.Contains(out.String(), `"total_runs": 3`) {
+		t.Fatalf("unexpected unfiltered stats: %s", out.String())
+	}
+}
+
+func TestStatsCommandRejectsInvalidFilters(t *testing.T) {
+	t.Parallel()
+
+	cwd := t.TempDir()
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"--since", "soon"}, wantErr: "invalid --since"},
+		{args: []string{"--until", "yesterday"}, wantErr: "invalid --until"},
+		{args: []string{"--since", "2026-03-02", "--until", "2026-03-01"}, wantErr: "--until must be after --since"},
+		{args: []string{"--status", "failed"}, wantErr: `invalid --status "failed"`},
+		{args: []string{"--mode", "batch"}, wantErr: `invalid --mode "batch"`},
+		{args: []string{"--label", "team"}, wantErr: `invalid --label "team"`},
+		{args: []string{"extra"}, wantErr: "stats command does not accept positional arguments"},
+	}
+	for _, tt := range tests {
+		err := StatsCommand(cwd, tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("unexpected error for %q: %v", tt.args, err)
+		}
+	}
+}
+
+func saveStatsTestRuns(t *testing.T, cwd string, now time.Time) {
+	t.Helper()
+
+	for _, record := range []*stats.RunRecord{
+		{
+			RunID:        "pipeline-core",
+			Timestamp:    now.AddDate(0, 0, -2),
+			Status:       stats.RunStatusSuccess,
+			Model:        "opus",
+			PipelinePath: filepath.Join(cwd, "pipelines", "flow.yml"),
+			Labels:       map[string]string{"team": "core"},
+			Pipeline:     &stats.PipelineRunRecord{},
+			Normalized:   result.NormalizedMetrics{TotalCostUSD: 2},
+		},
+		{
+			RunID:      "prompt-failed",
+			Timestamp:  now.AddDate(0, 0, -3),
+			Status:     stats.RunStatusError,
+			Model:      "sonnet",
+			Labels:     map[string]string{"team": "core"},
+			Normalized: result.NormalizedMetrics{TotalCostUSD: 4},
+		},
+		{
+			RunID:        "pipeline-old",
+			Timestamp:    now.AddDate(0, 0, -30),
+			Status:       stats.RunStatusSuccess,
+			Model:        "opus",
+			PipelinePath: filepath.Join(cwd, "pipelines", "flow.yml"),
+			Labels:       map[string]string{"team": "core"},
+			Normalized:   result.NormalizedMetrics{TotalCostUSD: 8},
+		},
+	} {
+		if _, err := stats.SaveRunRecord(config.RunsDir(cwd), record, stats.RetentionPolicy{}); err != nil {
+			t.Fatalf("save record: %v", err)
+		}
+	}
+}
+
+func withStatsOutput(t *testing.T, out *bytes.Buffer) {
+	t.Helper()
+
+	prev := statsOutputWriter
+	statsOutputWriter = out
+	t.Cleanup(func() {
+		statsOutputWriter = prev
+	})
+}
+
+func TestStatsCommandGroupsRuns(t *testing.T) {
+	cwd := t.TempDir()
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	withRunsNow(t, now)
+	saveStatsTestRuns(t, cwd, now)
+
+	var out bytes.Buffer
+	withStatsOutput(t, &out)
+
+	if err := StatsCommand(cwd, []string{"--group-by", "status", "--json"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	var grouped stats.GroupedAggregate
+	if err := json.Unmarshal(out.Bytes(), &grouped); err != nil {
+		t.Fatalf("decode stats JSON: %v", err)
+	}
+	groups := grouped.Groups
+	if grouped.GroupBy != stats.GroupByStatus || grouped.Filters != nil || len(groups) != 2 || groups[0].Group != "error" || groups[1].Group != "success" {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+	if groups[1].TotalRuns != 2 || groups[1].SuccessRate != 1 || groups[1].Sums.TotalCostUSD != 10 {
+		t.Fatalf("unexpected success group: %+v", groups[1])
+	}
+
+	out.Reset()
+	if err := StatsCommand(cwd, []string{"--group-by", "pipeline", "--since", "7d"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	text := out.String()
+	assertContains(t, text, "Filters: since=2026-03-24T12:00:00Z")
+	assertContains(t, text, "Pipeline")
+	assertContains(t, text, "Success Rate")
+	assertContains(t, text, filepath.Join(cwd, "pipelines", "flow.yml")+" | 1    | 1       | 100.0%")
+	assertContains(t, text, "- ")
+
+	if err := StatsCommand(cwd, []string{"--group-by", "model"}); err == nil || !strings.Contains(err.Error(), "invalid --group-by") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestStatsCommandGroupsRunsAsJSONWithFilters(t *testing.T) {
+	cwd := t.TempDir()
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	withRunsNow(t, now)
+	saveStatsTestRuns(t, cwd, now)
+	brokenDir := filepath.Join(config.RunsDir(cwd), "20260101T000000.000000000Z-broken")
+	if err := os.MkdirAll(brokenDir, 0o755); err != nil {
+		t.Fatalf("mkdir broken run: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(brokenDir, "stats.json"), []byte("{"), 0o644); err != nil {
+		t.Fatalf("write broken run: %v", err)
+	}
+
+	var out bytes.Buffer
+	withStatsOutput(t, &out)
+
+	if err := StatsCommand(cwd, []string{"--json", "--group-by", "status", "--since", "7d"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	var grouped stats.GroupedAggregate
+	if err := json.Unmarshal(out.Bytes(), &grouped); err != nil {
+		t.Fatalf("decode stats JSON: %v\n%s", err, out.String())
+	}
+	if grouped.Filters == nil || grouped.Filters.Since == nil || !grouped.Filters.Since.Equal(now.AddDate(0, 0, -7)) {
+		t.Fatalf("expected the since filter to be echoed, got %+v", grouped.Filters)
+	}
+	if len(grouped.SkippedFiles) != 1 || !strings.Contains(grouped.SkippedFiles[0], "broken") {
+		t.Fatalf("unexpected skipped files: %v", grouped.SkippedFiles)
+	}
+	for _, group := range grouped.Groups {
+		if group.Group == "success" && group.TotalRuns != 1 {
+			t.Fatalf("expected the filter to drop the old success run, got %+v", group)
+		}
+	}
+
+	out.Reset()
+	if err := StatsCommand(cwd, []string{"--group-by", "status"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	assertContains(t, out.String(), "Skipped Files")
+}
+
+func TestStatsCommandNodes(t *testing.T) {
+	cwd := t.TempDir()
+	planPath := filepath.Join(cwd, "pipelines", "flow.yml")
+	for _, record := range []*stats.RunRecord{
+		{
+			RunID:        "flow-run",
+			Timestamp:    time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+			Status:       stats.RunStatusSuccess,
+			PipelinePath: planPath,
+			Pipeline: &stats.PipelineRunRecord{
+				NodeRuns: []stats.PipelineNodeRunRecord{
+					{NodeID: "implement", NodeRunID: "1", Kind: "agent", Status: "success", DurationMS: 1500, Normalized: &stats.PipelineNodeRunNormalized{CostUSD: 1.25}},
+					{NodeID: "test", NodeRunID: "1", Kind: "command", Status: "error", TimedOut: true, DurationMS: 60000},
+				},
+				Transitions: []stats.PipelineTransition{{From: "implement", To: "test"}},
+			},
+		},
+		{
+			RunID:        "other-run",
+			Timestamp:    time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC),
+			Status:       stats.RunStatusSuccess,
+			PipelinePath: filepath.Join(cwd, "pipelines", "other.yml"),
+			Pipeline: &stats.PipelineRunRecord{
+				NodeRuns: []stats.PipelineNodeRunRecord{{NodeID: "deploy", NodeRunID: "1", Kind: "command", Status: "success"}},
+			},
+		},
+	} {
+		if _, err := stats.SaveRunRecord(config.RunsDir(cwd), record, stats.RetentionPolicy{}); err != nil {
+			t.Fatalf("save record: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	withStatsOutput(t, &out)
+
+	if err := StatsCommand(cwd, []string{"nodes", "--pipeline", "pipelines/flow.yml"}); err != nil {
+		t.Fatalf("stats nodes: %v", err)
+	}
+	text := out.String()
+	assertContains(t, text, "Filters: pipeline="+planPath)
+	assertContains(t, text, "Top Transition")
+	assertContains(t, text, "implement | agent")
+	assertContains(t, text, "test (1)")
+	assertContains(t, text, "100.0%")
+	if strings.Contains(text, "deploy") {
+		t.Fatalf("unexpected node of another pipeline: %s", text)
+	}
+
+	out.Reset()
+	if err := StatsCommand(cwd, []string{"nodes", "--json"}); err != nil {
+		t.Fatalf("stats nodes: %v", err)
+	}
+	var nodes []stats.NodeAggregate
+	if err := json.Unmarshal(out.Bytes(), &nodes); err != nil {
+		t.Fatalf("decode node stats JSON: %v", err)
+	}
+	if len(nodes) != 3 || nodes[0].NodeID != "implement" || nodes[0].TotalCostUSD != 1.25 {
+		t.Fatalf("unexpected nodes: %+v", nodes)
+	}
+
+	if err := StatsCommand(cwd, []string{"nodes", "extra"}); err == nil || !strings.Contains(err.Error(), "does not accept positional arguments") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestStatsCommandDistributionsAndHistogram(t *testing.T) {
+	cwd := t.TempDir()
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	withRunsNow(t, now)
+	saveStatsTestRuns(t, cwd, now)
+
+	var out bytes.Buffer
+	withStatsOutput(t, &out)
+
+	if err := StatsCommand(cwd, []string{"--histogram", "cost"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	text := out.String()
+	assertContains(t, text, "Distributions (per run)")
+	assertContains(t, text, "Cost(USD)    | 2.000000 | 4.666667 | 4.000000 | 8.000000 | 8.000000 | 8.000000")
+	assertContains(t, text, "Histogram (cost)")
+	assertContains(t, text, "2.000000 - 2.600000 | 1    | "+strings.Repeat("#", statsHistogramWidth))
+	assertContains(t, text, "2.600000 - 3.200000 | 0    |\n")
+
+	out.Reset()
+	if err := StatsCommand(cwd, []string{"--json", "--histogram", "turns"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	var agg stats.Aggregate
+	if err := json.Unmarshal(out.Bytes(), &agg); err != nil {
+		t.Fatalf("decode stats JSON: %v", err)
+	}
+	if agg.Distributions.CostUSD.Max != 8 || agg.Histogram == nil || agg.Histogram.Metric != stats.MetricTurns {
+		t.Fatalf("unexpected aggregate: %+v", agg)
+	}
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"--histogram", "latency"}, wantErr: "invalid --histogram"},
+	}
+	for _, tt := range tests {
+		if err := StatsCommand(cwd, tt.args); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("unexpected error for %q: %v", tt.args, err)
+		}
+	}
+}
+
+func TestStatsCommandHistogramPerGroup(t *testing.T) {
+	cwd := t.TempDir()
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	withRunsNow(t, now)
+	saveStatsTestRuns(t, cwd, now)
+
+	var out bytes.Buffer
+	withStatsOutput(t, &out)
+
+	if err := StatsCommand(cwd, []string{"--group-by", "status", "--histogram", "cost"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	text := out.String()
+	assertContains(t, text, "Histogram (cost) - Status: error\n")
+	assertContains(t, text, "4.000000 - 4.000000 | 1    | "+strings.Repeat("#", statsHistogramWidth))
+	assertContains(t, text, "Histogram (cost) - Status: success\n")
+	assertContains(t, text, "7.400000 - 8.000000 | 1    | "+strings.Repeat("#", statsHistogramWidth))
+	assertNotContains(t, text, "Histogram (cost)\n")
+
+	out.Reset()
+	if err := StatsCommand(cwd, []string{"--group-by", "status", "--histogram", "cost", "--json"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	var grouped stats.GroupedAggregate
+	if err := json.Unmarshal(out.Bytes(), &grouped); err != nil {
+		t.Fatalf("decode grouped stats JSON: %v", err)
+	}
+	if len(grouped.Groups) != 2 {
+		t.Fatalf("unexpected groups: %+v", grouped.Groups)
+	}
+	for _, group := range grouped.Groups {
+		if group.Histogram == nil || group.Histogram.Metric != stats.MetricCost {
+			t.Fatalf("missing histogram for group %q: %+v", group.Group, group)
+		}
+		total := 0
+		for _, bucket := range group.Histogram.Buckets {
+			total += bucket.Count
+		}
+		if total != group.TotalRuns {
+			t.Fatalf("histogram of group %q counts %d runs, want %d", group.Group, total, group.TotalRuns)
+		}
+	}
+}
+
+func TestStatsCommandExport(t *testing.T) {
+	cwd := t.TempDir()
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	withRunsNow(t, now)
+	saveStatsTestRuns(t, cwd, now)
+
+	var out bytes.Buffer
+	withStatsOutput(t, &out)
+
+	if err := StatsCommand(cwd, []string{"export", "--since", "7d"}); err != nil {
+		t.Fatalf("stats export: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "schema_version,run_id,timestamp,status,mode,") {
+		t.Fatalf("unexpected export: %q", lines)
+	}
+	assertContains(t, lines[1], "1,prompt-failed,")
+	assertContains(t, lines[2], "1,pipeline-core,")
+
+	out.Reset()
+	if err := StatsCommand(cwd, []string{"export", "--format", "jsonl", "--status", "error"}); err != nil {
+		t.Fatalf("stats export: %v", err)
+	}
+	var row map[string]any
+	if err := json.Unmarshal(out.Bytes(), &row); err != nil {
+		t.Fatalf("decode export line: %v", err)
+	}
+	if row["run_id"] != "prompt-failed" || row["total_cost_usd"] != float64(4) {
+		t.Fatalf("unexpected export row: %v", row)
+	}
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"export", "--format", "parquet"}, wantErr: "invalid --format"},
+		{args: []string{"export", "--level", "batch"}, wantErr: "invalid --level"},
+		{args: []string{"export", "runs.csv"}, wantErr: "does not accept positional arguments"},
+	}
+	for _, tt := range tests {
+		if err := StatsCommand(cwd, tt.args); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("unexpected error for %q: %v", tt.args, err)
+		}
+	}
+}
+
+func TestStatsCommandAllProjects(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	root := t.TempDir()
+	current := filepath.Join(root, "current")
+	other := filepath.Join(root, "other")
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	withRunsNow(t, now)
+	saveStatsTestRuns(t, current, now)
+	if _, err := stats.SaveRunRecord(config.RunsDir(other), &stats.RunRecord{
+		RunID:      "other-run",
+		Timestamp:  now.AddDate(0, 0, -1),
+		Status:     stats.RunStatusSuccess,
+		Model:      "haiku",
+		Normalized: result.NormalizedMetrics{TotalCostUSD: 16},
+	}, stats.RetentionPolicy{}); err != nil {
+		t.Fatalf("save record: %v", err)
+	}
+
+	var out bytes.Buffer
+	withStatsOutput(t, &out)
+
+	if err := StatsCommand(current, []string{"--all-projects", "--since", "7d", "--json"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	var agg stats.Aggregate
+	if err := json.Unmarshal(out.Bytes(), &agg); err != nil {
+		t.Fatalf("decode stats JSON: %v", err)
+	}
+	if agg.TotalRuns != 3 || agg.Sums.TotalCostUSD != 22 || len(agg.Projects) != 2 {
+		t.Fatalf("unexpected aggregate: %+v", agg)
+	}
+	if agg.Projects[0].Project != current || agg.Projects[0].TotalRuns != 2 || agg.Projects[1].Sums.TotalCostUSD != 16 {
+		t.Fatalf("unexpected projects: %+v", agg.Projects)
+	}
+
+	if err := os.RemoveAll(other); err != nil {
+		t.Fatalf("remove project: %v", err)
+	}
+	out.Reset()
+	if err := StatsCommand(current, []string{"--all-projects"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	text := out.String()
+	assertContains(t, text, "By Project")
+	assertContains(t, text, other+" (missing)")
+	assertContains(t, text, "2026-03-30 12:00:00")
+
+	out.Reset()
+	if err := StatsCommand(current, []string{"--json"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	agg = stats.Aggregate{}
+	if err := json.Unmarshal(out.Bytes(), &agg); err != nil {
+		t.Fatalf("decode stats JSON: %v", err)
+	}
+	if agg.TotalRuns != 3 || agg.Sums.TotalCostUSD != 14 || agg.Projects != nil {
+		t.Fatalf("unexpected aggregate without --all-projects: %+v", agg)
+	}
+}
+
+func TestStatsCommandRecomputeCost(t *testing.T) {
+	cwd := t.TempDir()
+	if _, err := stats.SaveRunRecord(config.RunsDir(cwd), &stats.RunRecord{
+		RunID:     "priced",
+		Timestamp: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		Status:    stats.RunStatusSuccess,
+		Normalized: result.NormalizedMetrics{
+			InputTokens:  1_000_000,
+			TotalCostUSD: 3,
+			ByModel:      map[string]result.ModelMetric{"claude-opus-4-1": {InputTokens: 1_000_000, CostUSD: 3}},
+		},
+		Pipeline: &stats.PipelineRunRecord{
+			NodeRuns: []stats.PipelineNodeRunRecord{
+				{NodeID: "implement", Model: "opus", Normalized: &stats.PipelineNodeRunNormalized{InputTokens: 1_000_000, CostUSD: 3}},
+			},
+		},
+	}, stats.RetentionPolicy{}); err != nil {
+		t.Fatalf("save record: %v", err)
+	}
+
+	var out bytes.Buffer
+	withStatsOutput(t, &out)
+
+	if err := StatsCommand(cwd, []string{"--recompute-cost"}); err == nil || !strings.Contains(err.Error(), "config file not found") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(config.ConfigPath(cwd)), 0o755); err != nil {
+		t.Fatalf("mkdir config dir: %v", err)
+	}
+	if err := os.WriteFile(config.ConfigPath(cwd), []byte("[docker]\nimage = \"claude:go\"\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := StatsCommand(cwd, []string{"--recompute-cost"}); err == nil || !strings.Contains(err.Error(), "no [pricing.<model>] tables") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(config.ConfigPath(cwd), []byte("[pricing.opus]\ninput_per_mtok = 15\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if err := StatsCommand(cwd, []string{"--json"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	var agg stats.Aggregate
+	if err := json.Unmarshal(out.Bytes(), &agg); err != nil {
+		t.Fatalf("decode stats JSON: %v", err)
+	}
+	if agg.Sums.EffectiveCostUSD != 3 {
+		t.Fatalf("unexpected effective cost before recompute: %+v", agg.Sums)
+	}
+
+	out.Reset()
+	if err := StatsCommand(cwd, []string{"--recompute-cost"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	text := out.String()
+	assertContains(t, text, "Cost(USD): 3.000000")
+	assertContains(t, text, "Effective Cost(USD): 15.000000")
+
+	out.Reset()
+	if err := StatsCommand(cwd, []string{"nodes", "--recompute-cost", "--json"}); err != nil {
+		t.Fatalf("stats nodes: %v", err)
+	}
+	var nodes []stats.NodeAggregate
+	if err := json.Unmarshal(out.Bytes(), &nodes); err != nil {
+		t.Fatalf("decode node stats JSON: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].TotalCostUSD != 3 || nodes[0].TotalEffectiveCostUSD != 15 {
+		t.Fatalf("unexpected nodes: %+v", nodes)
+	}
+
+	out.Reset()
+	if err := StatsCommand(cwd, []string{"export", "--recompute-cost", "--format", "jsonl"}); err != nil {
+		t.Fatalf("stats export: %v", err)
+	}
+	assertContains(t, out.String(), `"effective_cost_usd":15`)
+
+	// Recomputing rewrites the saved records and their index entries.
+	records, _, err := stats.ListRunRecords(config.RunsDir(cwd))
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	if cost := records[0].Record.EffectiveCostUSD; cost == nil || *cost != 15 {
+		t.Fatalf("unexpected saved effective cost: %v", cost)
+	}
+	out.Reset()
+	if err := StatsCommand(cwd, []string{"--json"}); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	agg = stats.Aggregate{}
+	if err := json.Unmarshal(out.Bytes(), &agg); err != nil {
+		t.Fatalf("decode stats JSON: %v", err)
+	}
+	if agg.Sums.EffectiveCostUSD != 15 {
+		t.Fatalf("unexpected effective cost after recompute: %+v", agg.Sums)
+	}
+
+	err = StatsCommand(cwd, []string{"--recompute-cost", "--all-projects"})
+	if err == nil || !strings.Contains(err.Error(), "cannot be combined with --all-projects") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+
+	"agent-cli/internal/result"
+	"agent-cli/internal/stats"
+)
+
+// nodeTranscripts splits pipeline stdout events into one transcript per node run. Pipeline events
+// carry their node run directly; agent events are attributed through node_session_bind, and events
+// of a session that is not bound yet are held until the bind arrives.
+type nodeTranscripts struct {
+	lines          map[string][]string
+	pendingSession map[string][]string
+}
+
+func newNodeTranscripts() *nodeTranscripts {
+	return &nodeTranscripts{
+		lines:          map[string][]string{},
+		pendingSession: map[string][]string{},
+	}
+}
+
+// add records line for its node run. It must see an event before bindPipelineNodeRunSession does.
+func (n *nodeTranscripts) add(line string, event *result.StreamEvent, sessionTaskBindings map[string]pipelineNodeRunRef) {
+	if event == nil {
+		return
+	}
+
+	if event.Pipeline != nil {
+		nodeID := strings.TrimSpace(event.Pipeline.NodeID)
+		nodeRunID := strings.TrimSpace(event.Pipeline.NodeRunID)
+		if nodeID == "" || nodeRunID == "" {
+			return
+		}
+		key := buildPipelineNodeRunKey(nodeID, nodeRunID)
+		sessionID := strings.TrimSpace(event.Pipeline.SessionID)
+		if strings.EqualFold(strings.TrimSpace(event.Pipeline.Event), "node_session_bind") && sessionID != "" {
+			// Events of the session streamed before the bind belong in front of it.
+			n.lines[key] = append(n.lines[key], n.pendingSession[sessionID]...)
+			delete(n.pendingSession, sessionID)
+		}
+		n.lines[key] = append(n.lines[key], line)
+		return
+	}
+
+	sessionID := streamEventSessionID(event)
+	if sessionID == "" {
+		return
+	}
+	ref, bound := sessionTaskBindings[sessionID]
+	if !bound {
+		n.pendingSession[sessionID] = append(n.pendingSession[sessionID], line)
+		return
+	}
+	key := buildPipelineNodeRunKey(ref.NodeID, ref.NodeRunID)
+	n.lines[key] = append(n.lines[key], line)
+}
+
+// files returns the transcript content per node run, keyed by the path relative to the run
+// directory, and sets TranscriptFile on the matching node runs of pipeline.
+func (n *nodeTranscripts) files(pipeline *stats.PipelineRunRecord) map[string]string {
+	files := map[string]string{}
+	if pipeline == nil {
+		return files
+	}
+	for index := range pipeline.NodeRuns {
+		nodeRun := &pipeline.NodeRuns[index]
+		lines := n.lines[buildPipelineNodeRunKey(nodeRun.NodeID, nodeRun.NodeRunID)]
+		if len(lines) == 0 {
+			continue
+		}
+		name := filepath.ToSlash(filepath.Join(
+			stats.NodeTranscriptsDirName,
+			nodeTranscriptFileName(nodeRun.NodeID, nodeRun.NodeRunID),
+		))
+		nodeRun.TranscriptFile = name
+		files[name] = strings.Join(lines, "\n") + "\n"
+	}
+	return files
+}
+
+func nodeTranscriptFileName(nodeID string, nodeRunID string) string {
+	replacer := strings.NewReplacer("/", "_", "\\", "_")
+	return replacer.Replace(nodeID) + "-" + replacer.Replace(nodeRunID) + ".ndjson"
+}
+
+func streamEventSessionID(event *result.StreamEvent) string {
+	switch {
+	case event.System != nil:
+		return strings.TrimSpace(event.System.SessionID)
+	case event.Assistant != nil:
+		return strings.TrimSpace(event.Assistant.SessionID)
+	case event.User != nil:
+		return strings.TrimSpace(event.User.SessionID)
+	case event.Result != nil:
+		return strings.TrimSpace(event.Result.SessionID)
+	}
+	return ""
+}
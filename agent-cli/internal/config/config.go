@@ -5,15 +5,20 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
-	"strconv"
+	"sort"
 	"strings"
+
+	"github.com/pelletier/go-toml/v2"
 )
 
 const (
 	configDirName  = ".agent-cli"
 	configFileName = "config.toml"
 
+	profilesSectionPrefix = "profiles."
+
 	DockerModelSonnet  = "sonnet"
 	DockerModelOpus    = "opus"
 	DefaultDockerModel = DockerModelOpus
@@ -32,10 +37,29 @@ const (
 
 // Config is the root configuration for agent-cli.
 type Config struct {
+	DefaultProfile string                   `toml:"default_profile"`
+	Docker         DockerConfig             `toml:"docker"`
+	Auth           AuthConfig               `toml:"auth"`
+	Workspace      WorkspaceConfig          `toml:"workspace"`
+	Git            GitConfig                `toml:"git"`
+	Redact         RedactConfig             `toml:"redact"`
+	Budget         BudgetConfig             `toml:"budget"`
+	Retention      RetentionConfig          `toml:"retention"`
+	Pricing        map[string]PricingConfig `toml:"pricing"`
+	Profiles       map[string]ProfileConfig `toml:"profiles"`
+
+	// Profile is the name of the profile applied on top of the base config, if any.
+	Profile string `toml:"-"`
+}
+
+// ProfileConfig holds keys that overlay the base config when the profile is selected.
+// Empty strings and non-positive integers leave the base value untouched.
+type ProfileConfig struct {
 	Docker    DockerConfig    `toml:"docker"`
 	Auth      AuthConfig      `toml:"auth"`
 	Workspace WorkspaceConfig `toml:"workspace"`
 	Git       GitConfig       `toml:"git"`
+	Budget    BudgetConfig    `toml:"budget"`
 }
 
 type DockerConfig struct {
@@ -47,9 +71,15 @@ type DockerConfig struct {
 	PipelineTaskIdleTimeoutSec int    `toml:"pipeline_task_idle_timeout_sec"`
 }
 
+// AuthConfig holds the tokens passed to the container. Each token may be set inline,
+// as a "${ENV_VAR}" reference, or through a *_cmd or *_file source resolved in LoadProfile.
 type AuthConfig struct {
-	GitHubToken string `toml:"github_token"`
-	ClaudeToken string `toml:"claude_token"`
+	GitHubToken     string `toml:"github_token"`
+	GitHubTokenCmd  string `toml:"github_token_cmd"`
+	GitHubTokenFile string `toml:"github_token_file"`
+	ClaudeToken     string `toml:"claude_token"`
+	ClaudeTokenCmd  string `toml:"claude_token_cmd"`
+	ClaudeTokenFile string `toml:"claude_token_file"`
 }
 
 type WorkspaceConfig struct {
@@ -61,6 +91,49 @@ type GitConfig struct {
 	UserEmail string `toml:"user_email"`
 }
 
+// RedactConfig lists extra regexes masked in run artifacts and output,
+// on top of the configured tokens and built-in token patterns.
+type RedactConfig struct {
+	Patterns []string `toml:"patterns"`
+}
+
+// BudgetConfig caps the spend of a single run. Zero leaves a limit unset.
+// Tokens count input, cache creation, cache read and output tokens.
+type BudgetConfig struct {
+	MaxCostUSD float64 `toml:"max_cost_usd"`
+	MaxTokens  int64   `toml:"max_tokens"`
+	// DailyUSD, WeeklyUSD and MonthlyUSD cap the spend of all runs in the current UTC day, ISO week
+	// and month; a run does not start once a cap is reached.
+	DailyUSD   float64 `toml:"daily_usd"`
+	WeeklyUSD  float64 `toml:"weekly_usd"`
+	MonthlyUSD float64 `toml:"monthly_usd"`
+	// Nodes caps the combined spend of all runs of a pipeline node, keyed by node id.
+	Nodes map[string]NodeBudgetConfig `toml:"nodes"`
+}
+
+type NodeBudgetConfig struct {
+	MaxCostUSD float64 `toml:"max_cost_usd"`
+	MaxTokens  int64   `toml:"max_tokens"`
+}
+
+// RetentionConfig prunes old runs from the runs directory after each run is saved.
+// Zero max_age_days keeps every run.
+type RetentionConfig struct {
+	MaxAgeDays int  `toml:"max_age_days"`
+	KeepFailed bool `toml:"keep_failed"`
+	// Compress gzips the artifacts of old runs instead of deleting the runs.
+	Compress bool `toml:"compress"`
+}
+
+// PricingConfig is the USD price per million tokens of a model. Its [pricing.<model>] key is a
+// model name or part of one, such as opus for claude-opus-4-1; quote keys with dots.
+type PricingConfig struct {
+	InputPerMTok      float64 `toml:"input_per_mtok"`
+	OutputPerMTok     float64 `toml:"output_per_mtok"`
+	CacheWritePerMTok float64 `toml:"cache_write_per_mtok"`
+	CacheReadPerMTok  float64 `toml:"cache_read_per_mtok"`
+}
+
 func ConfigPath(cwd string) string {
 	return filepath.Join(cwd, configDirName, configFileName)
 }
@@ -69,8 +142,72 @@ func RunsDir(cwd string) string {
 	return filepath.Join(cwd, configDirName, "runs")
 }
 
+func BatchesDir(cwd string) string {
+	return filepath.Join(cwd, configDirName, "batches")
+}
+
+// PipelineStateDir holds the state directories mounted into pipeline runs in progress.
+func PipelineStateDir(cwd string) string {
+	return filepath.Join(cwd, configDirName, "state")
+}
+
+// ActiveRunsDir holds a marker per run in progress, see stats.ActiveRun.
+func ActiveRunsDir(cwd string) string {
+	return filepath.Join(cwd, configDirName, "active")
+}
+
 func Load(cwd string) (*Config, error) {
-	path := ConfigPath(cwd)
+	return LoadProfile(cwd, "")
+}
+
+// LoadProfile loads the config, overlays the named profile on the base config and resolves its
+// auth tokens. An empty profile name falls back to default_profile.
+func LoadProfile(cwd string, profile string) (*Config, error) {
+	cfg, err := ReadProfile(cwd, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.ResolveSecrets(cwd); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// ReadProfile is LoadProfile without resolving the auth tokens, so no token command runs and no
+// token file is read. Commands call ResolveSecrets once they are about to start a container.
+func ReadProfile(cwd string, profile string) (*Config, error) {
+	cfg, err := readConfigFile(ConfigPath(cwd))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.ApplyProfile(profile); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// LoadPricing returns the [pricing] tables of the config without resolving tokens or
+// validating the rest of the file, so stats can reprice runs in any checkout.
+func LoadPricing(cwd string) (map[string]PricingConfig, error) {
+	cfg, err := readConfigFile(ConfigPath(cwd))
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePricing(cfg.Pricing); err != nil {
+		return nil, err
+	}
+	return cfg.Pricing, nil
+}
+
+func readConfigFile(path string) (*Config, error) {
 	if _, err := os.Stat(path); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil, fmt.Errorf("config file not found: %s", path)
@@ -87,11 +224,6 @@ func Load(cwd string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("decode config TOML: %w", err)
 	}
-
-	if err := cfg.Validate(); err != nil {
-		return nil, err
-	}
-
 	return cfg, nil
 }
 
@@ -141,11 +273,11 @@ func (c *Config) Validate() error {
 		c.Docker.PipelineTaskIdleTimeoutSec = DefaultPipelineTaskIdleTimeoutSec
 	}
 
-	if strings.TrimSpace(c.Auth.GitHubToken) == "" {
-		missing = append(missing, "auth.github_token")
-	}
-	if strings.TrimSpace(c.Auth.ClaudeToken) == "" {
-		missing = append(missing, "auth.claude_token")
+	// Tokens may still be unresolved here: any of their sources counts.
+	for _, source := range c.Auth.secretSources() {
+		if !source.configured() {
+			missing = append(missing, "auth."+source.name)
+		}
 	}
 	if strings.TrimSpace(c.Workspace.SourceWorkspaceDir) == "" {
 		missing = append(missing, "workspace.source_workspace_dir")
@@ -165,162 +297,274 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("workspace.source_workspace_dir must be an absolute path: %q", c.Workspace.SourceWorkspaceDir)
 	}
 
+	if err := c.Budget.validate("budget"); err != nil {
+		return err
+	}
+
+	if c.Retention.MaxAgeDays < 0 {
+		return errors.New("retention.max_age_days must not be negative")
+	}
+
+	if err := validatePricing(c.Pricing); err != nil {
+		return err
+	}
+
+	for i, pattern := range c.Redact.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("redact.patterns[%d] must be a valid regular expression: %w", i, err)
+		}
+	}
+
 	return nil
 }
 
-func parseConfigTOML(content string) (*Config, error) {
-	cfg := &Config{}
-	section := ""
+func (b BudgetConfig) validate(section string) error {
+	if b.MaxCostUSD < 0 {
+		return fmt.Errorf("%s.max_cost_usd must not be negative", section)
+	}
+	if b.MaxTokens < 0 {
+		return fmt.Errorf("%s.max_tokens must not be negative", section)
+	}
+	if b.DailyUSD < 0 {
+		return fmt.Errorf("%s.daily_usd must not be negative", section)
+	}
+	if b.WeeklyUSD < 0 {
+		return fmt.Errorf("%s.weekly_usd must not be negative", section)
+	}
+	if b.MonthlyUSD < 0 {
+		return fmt.Errorf("%s.monthly_usd must not be negative", section)
+	}
 
-	lines := strings.Split(content, "\n")
-	for i, raw := range lines {
-		line := strings.TrimSpace(raw)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	nodeIDs := make([]string, 0, len(b.Nodes))
+	for nodeID := range b.Nodes {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+	for _, nodeID := range nodeIDs {
+		node := b.Nodes[nodeID]
+		if node.MaxCostUSD < 0 {
+			return fmt.Errorf("%s.nodes.%s.max_cost_usd must not be negative", section, nodeID)
 		}
-
-		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			section = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "["), "]"))
-			continue
+		if node.MaxTokens < 0 {
+			return fmt.Errorf("%s.nodes.%s.max_tokens must not be negative", section, nodeID)
 		}
+	}
+	return nil
+}
 
-		key, value, ok := strings.Cut(line, "=")
-		if !ok {
-			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+func validatePricing(pricing map[string]PricingConfig) error {
+	models := make([]string, 0, len(pricing))
+	for model := range pricing {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		if strings.TrimSpace(model) == "" {
+			return errors.New("pricing model names must not be empty")
 		}
-
-		key = strings.TrimSpace(key)
-		value = strings.TrimSpace(stripInlineComment(value))
-		parsedValue, err := parseStringValue(value)
-		if err != nil {
-			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		price := pricing[model]
+		for _, field := range []struct {
+			key   string
+			value float64
+		}{
+			{"input_per_mtok", price.InputPerMTok},
+			{"output_per_mtok", price.OutputPerMTok},
+			{"cache_write_per_mtok", price.CacheWritePerMTok},
+			{"cache_read_per_mtok", price.CacheReadPerMTok},
+		} {
+			if field.value < 0 {
+				return fmt.Errorf("pricing.%s.%s must not be negative", model, field.key)
+			}
 		}
+	}
+	return nil
+}
 
-		if err := setConfigField(cfg, section, key, parsedValue); err != nil {
-			return nil, fmt.Errorf("line %d: %w", i+1, err)
+// ApplyProfile overlays the named profile (or default_profile when name is empty) on the base config.
+func (c *Config) ApplyProfile(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = strings.TrimSpace(c.DefaultProfile)
+	}
+	if name == "" {
+		return nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		available := make([]string, 0, len(c.Profiles))
+		for key := range c.Profiles {
+			available = append(available, key)
+		}
+		sort.Strings(available)
+		if len(available) == 0 {
+			return fmt.Errorf("profile %q is not defined: config has no profiles", name)
 		}
+		return fmt.Errorf("profile %q is not defined: available profiles: %s", name, strings.Join(available, ", "))
 	}
 
-	return cfg, nil
+	overlayDockerConfig(&c.Docker, profile.Docker)
+	overlayAuthConfig(&c.Auth, profile.Auth)
+	overlayString(&c.Workspace.SourceWorkspaceDir, profile.Workspace.SourceWorkspaceDir)
+	overlayString(&c.Git.UserName, profile.Git.UserName)
+	overlayString(&c.Git.UserEmail, profile.Git.UserEmail)
+	overlayBudgetConfig(&c.Budget, profile.Budget)
+	c.Profile = name
+	return nil
 }
 
-func stripInlineComment(value string) string {
-	inQuotes := false
-	escaped := false
+func overlayDockerConfig(target *DockerConfig, overlay DockerConfig) {
+	overlayString(&target.Image, overlay.Image)
+	overlayString(&target.Model, overlay.Model)
+	overlayString(&target.Mode, overlay.Mode)
+	overlayString(&target.DinDStorageDriver, overlay.DinDStorageDriver)
+	overlayPositiveInt(&target.RunIdleTimeoutSec, overlay.RunIdleTimeoutSec)
+	overlayPositiveInt(&target.PipelineTaskIdleTimeoutSec, overlay.PipelineTaskIdleTimeoutSec)
+}
 
-	for i, r := range value {
-		if escaped {
-			escaped = false
-			continue
-		}
-		if r == '\\' && inQuotes {
-			escaped = true
-			continue
-		}
-		if r == '"' {
-			inQuotes = !inQuotes
-			continue
-		}
-		if r == '#' && !inQuotes {
-			return strings.TrimSpace(value[:i])
-		}
+func overlayBudgetConfig(target *BudgetConfig, overlay BudgetConfig) {
+	if overlay.MaxCostUSD > 0 {
+		target.MaxCostUSD = overlay.MaxCostUSD
+	}
+	if overlay.MaxTokens > 0 {
+		target.MaxTokens = overlay.MaxTokens
+	}
+	if overlay.DailyUSD > 0 {
+		target.DailyUSD = overlay.DailyUSD
+	}
+	if overlay.WeeklyUSD > 0 {
+		target.WeeklyUSD = overlay.WeeklyUSD
+	}
+	if overlay.MonthlyUSD > 0 {
+		target.MonthlyUSD = overlay.MonthlyUSD
+	}
+	if len(overlay.Nodes) == 0 {
+		return
 	}
 
-	return value
+	nodes := make(map[string]NodeBudgetConfig, len(target.Nodes)+len(overlay.Nodes))
+	for nodeID, node := range target.Nodes {
+		nodes[nodeID] = node
+	}
+	for nodeID, node := range overlay.Nodes {
+		nodes[nodeID] = node
+	}
+	target.Nodes = nodes
 }
 
-func parseStringValue(raw string) (string, error) {
-	// Support quoted TOML string values used by agent-cli config.
-	if strings.HasPrefix(raw, "\"") && strings.HasSuffix(raw, "\"") {
-		value, err := strconv.Unquote(raw)
-		if err != nil {
-			return "", fmt.Errorf("invalid quoted string: %w", err)
-		}
-		return value, nil
+func overlayString(target *string, value string) {
+	if strings.TrimSpace(value) != "" {
+		*target = value
+	}
+}
+
+func overlayPositiveInt(target *int, value int) {
+	if value > 0 {
+		*target = value
 	}
-	return raw, nil
 }
 
-func setConfigField(cfg *Config, section, key, value string) error {
-	switch section {
-	case "docker":
-		if key == "image" {
-			cfg.Docker.Image = value
-			return nil
+func parseConfigTOML(content string) (*Config, error) {
+	cfg := &Config{}
+	decoder := toml.NewDecoder(strings.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, formatTOMLError(err)
+	}
+
+	if err := validateExplicitPositiveInts(content); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// formatTOMLError rewrites decoder errors as "line L, column C: message" entries.
+func formatTOMLError(err error) error {
+	var strictErr *toml.StrictMissingError
+	if errors.As(err, &strictErr) {
+		messages := make([]string, 0, len(strictErr.Errors))
+		for i := range strictErr.Errors {
+			decodeErr := &strictErr.Errors[i]
+			messages = append(messages, formatTOMLPosition(decodeErr, describeUnknownTOMLKey(decodeErr)))
 		}
-		if key == "model" {
-			cfg.Docker.Model = value
-			return nil
+		return errors.New(strings.Join(messages, "; "))
+	}
+
+	var decodeErr *toml.DecodeError
+	if errors.As(err, &decodeErr) {
+		message := strings.TrimPrefix(decodeErr.Error(), "toml: ")
+		if key := decodeErr.Key(); len(key) > 0 {
+			message = fmt.Sprintf("invalid %s: %s", strings.Join(key, "."), message)
 		}
-		if key == "mode" {
-			cfg.Docker.Mode = value
-			return nil
+		return errors.New(formatTOMLPosition(decodeErr, message))
+	}
+
+	return err
+}
+
+func formatTOMLPosition(decodeErr *toml.DecodeError, message string) string {
+	line, column := decodeErr.Position()
+	return fmt.Sprintf("line %d, column %d: %s", line, column, message)
+}
+
+func describeUnknownTOMLKey(decodeErr *toml.DecodeError) string {
+	key := decodeErr.Key()
+	if strings.Contains(decodeErr.Error(), "missing table") {
+		return fmt.Sprintf("unknown section %q", strings.Join(key, "."))
+	}
+	if len(key) == 0 {
+		return strings.TrimPrefix(decodeErr.Error(), "toml: ")
+	}
+	if len(key) == 1 {
+		return fmt.Sprintf("unknown top-level key %q", key[0])
+	}
+	return fmt.Sprintf("unknown key %q in section %q", key[len(key)-1], strings.Join(key[:len(key)-1], "."))
+}
+
+// validateExplicitPositiveInts rejects non-positive timeouts that are set explicitly.
+// Omitted keys decode to zero and are replaced with defaults in Validate.
+func validateExplicitPositiveInts(content string) error {
+	var raw map[string]any
+	if err := toml.Unmarshal([]byte(content), &raw); err != nil {
+		return formatTOMLError(err)
+	}
+
+	sections := []string{"docker"}
+	tables := []any{raw["docker"]}
+	if profiles, ok := raw["profiles"].(map[string]any); ok {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
 		}
-		if key == "dind_storage_driver" {
-			cfg.Docker.DinDStorageDriver = value
-			return nil
+		sort.Strings(names)
+		for _, name := range names {
+			profile, _ := profiles[name].(map[string]any)
+			sections = append(sections, profilesSectionPrefix+name+".docker")
+			tables = append(tables, profile["docker"])
 		}
-		if key == "run_idle_timeout_sec" {
-			timeoutSec, err := parsePositiveIntValue(value)
-			if err != nil {
-				return fmt.Errorf("invalid docker.run_idle_timeout_sec: %w", err)
-			}
-			cfg.Docker.RunIdleTimeoutSec = timeoutSec
-			return nil
+	}
+
+	for i, table := range tables {
+		values, ok := table.(map[string]any)
+		if !ok {
+			continue
 		}
-		if key == "pipeline_task_idle_timeout_sec" {
-			timeoutSec, err := parsePositiveIntValue(value)
-			if err != nil {
-				return fmt.Errorf("invalid docker.pipeline_task_idle_timeout_sec: %w", err)
+		for _, key := range []string{"run_idle_timeout_sec", "pipeline_task_idle_timeout_sec"} {
+			value, ok := values[key].(int64)
+			if ok && value <= 0 {
+				return fmt.Errorf("invalid %s.%s: expected positive integer, got %d", sections[i], key, value)
 			}
-			cfg.Docker.PipelineTaskIdleTimeoutSec = timeoutSec
-			return nil
-		}
-	case "auth":
-		if key == "github_token" {
-			cfg.Auth.GitHubToken = value
-			return nil
-		}
-		if key == "claude_token" {
-			cfg.Auth.ClaudeToken = value
-			return nil
-		}
-	case "workspace":
-		if key == "source_workspace_dir" {
-			cfg.Workspace.SourceWorkspaceDir = value
-			return nil
-		}
-	case "git":
-		if key == "user_name" {
-			cfg.Git.UserName = value
-			return nil
 		}
-		if key == "user_email" {
-			cfg.Git.UserEmail = value
-			return nil
-		}
-	default:
-		return fmt.Errorf("unknown section %q", section)
 	}
-	return fmt.Errorf("unknown key %q in section %q", key, section)
+
+	return nil
 }
 
 func normalizeDockerModel(model string) string {
 	return strings.ToLower(strings.TrimSpace(model))
 }
 
-func parsePositiveIntValue(raw string) (int, error) {
-	value := strings.TrimSpace(raw)
-	parsed, err := strconv.Atoi(value)
-	if err != nil {
-		return 0, fmt.Errorf("expected positive integer, got %q", raw)
-	}
-	if parsed <= 0 {
-		return 0, fmt.Errorf("expected positive integer, got %q", raw)
-	}
-	return parsed, nil
-}
-
 func IsValidDockerModel(model string) bool {
 	switch normalizeDockerModel(model) {
 	case DockerModelSonnet, DockerModelOpus:
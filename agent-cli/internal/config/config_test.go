@@ -383,7 +383,7 @@ func TestLoadInvalidPipelineTaskIdleTimeout(t *testing.T) {
 
 	content := `[docker]
 image = "claude:go"
-pipeline_task_idle_timeout_sec = nope
+pipeline_task_idle_timeout_sec = "nope"
 
 [auth]
 github_token = "gh-token"
@@ -408,3 +408,464 @@ user_email = "test@example.com"
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestLoadProfileOverlaysBaseConfig(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeProfilesConfig(t, "")
+
+	cfg, err := LoadProfile(cwd, "heavy")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	if cfg.Profile != "heavy" {
+		t.Fatalf("unexpected profile: %q", cfg.Profile)
+	}
+	if cfg.Docker.Image != "claude:go" {
+		t.Fatalf("expected base image to be kept, got %q", cfg.Docker.Image)
+	}
+	if cfg.Docker.Model != DockerModelOpus {
+		t.Fatalf("unexpected model: %q", cfg.Docker.Model)
+	}
+	if cfg.Docker.Mode != DockerModeDinD {
+		t.Fatalf("unexpected docker mode: %q", cfg.Docker.Mode)
+	}
+	if cfg.Docker.RunIdleTimeoutSec != 14400 {
+		t.Fatalf("unexpected run idle timeout: %d", cfg.Docker.RunIdleTimeoutSec)
+	}
+	if cfg.Docker.PipelineTaskIdleTimeoutSec != 60 {
+		t.Fatalf("expected base pipeline task idle timeout, got %d", cfg.Docker.PipelineTaskIdleTimeoutSec)
+	}
+	if cfg.Git.UserEmail != "heavy@example.com" {
+		t.Fatalf("unexpected git email: %q", cfg.Git.UserEmail)
+	}
+	if cfg.Git.UserName != "Test User" {
+		t.Fatalf("expected base git user name, got %q", cfg.Git.UserName)
+	}
+}
+
+func TestLoadProfileUsesDefaultProfile(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeProfilesConfig(t, `default_profile = "cheap"`)
+
+	cfg, err := Load(cwd)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Profile != "cheap" {
+		t.Fatalf("unexpected profile: %q", cfg.Profile)
+	}
+	if cfg.Docker.Model != DockerModelSonnet {
+		t.Fatalf("unexpected model: %q", cfg.Docker.Model)
+	}
+	if cfg.Docker.RunIdleTimeoutSec != 600 {
+		t.Fatalf("unexpected run idle timeout: %d", cfg.Docker.RunIdleTimeoutSec)
+	}
+
+	override, err := LoadProfile(cwd, "heavy")
+	if err != nil {
+		t.Fatalf("load config with explicit profile: %v", err)
+	}
+	if override.Profile != "heavy" {
+		t.Fatalf("expected explicit profile to win, got %q", override.Profile)
+	}
+}
+
+func TestLoadWithoutProfileKeepsBaseConfig(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeProfilesConfig(t, "")
+
+	cfg, err := Load(cwd)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Profile != "" {
+		t.Fatalf("unexpected profile: %q", cfg.Profile)
+	}
+	if cfg.Docker.Model != DockerModelSonnet {
+		t.Fatalf("unexpected model: %q", cfg.Docker.Model)
+	}
+	if cfg.Docker.Mode != DockerModeNone {
+		t.Fatalf("unexpected docker mode: %q", cfg.Docker.Mode)
+	}
+	if len(cfg.Profiles) != 2 {
+		t.Fatalf("expected two parsed profiles, got %d", len(cfg.Profiles))
+	}
+}
+
+func TestLoadProfileUnknownName(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeProfilesConfig(t, "")
+
+	_, err := LoadProfile(cwd, "missing")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), `profile "missing" is not defined: available profiles: cheap, heavy`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadProfileValidatesMergedConfig(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeProfilesConfig(t, `
+[profiles.broken.docker]
+mode = "bogus"`)
+
+	if _, err := Load(cwd); err != nil {
+		t.Fatalf("unselected broken profile must not fail load: %v", err)
+	}
+
+	_, err := LoadProfile(cwd, "broken")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "docker.mode must be one of") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadProfileUnknownSection(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeProfilesConfig(t, `
+[profiles.heavy.limits]
+max = 1`)
+
+	_, err := Load(cwd)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), `unknown section "profiles.heavy.limits"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func writeProfilesConfig(t *testing.T, extra string) string {
+	t.Helper()
+
+	cwd := t.TempDir()
+	path := filepath.Join(cwd, ".agent-cli", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir config dir: %v", err)
+	}
+
+	header := ""
+	trailer := extra
+	if strings.HasPrefix(strings.TrimSpace(extra), "default_profile") {
+		header = extra + "\n\n"
+		trailer = ""
+	}
+
+	content := header + `[docker]
+image = "claude:go"
+model = "sonnet"
+pipeline_task_idle_timeout_sec = 60
+
+[auth]
+github_token = "gh-token"
+claude_token = "claude-token"
+
+[workspace]
+source_workspace_dir = "/workspace-source"
+
+[git]
+user_name = "Test User"
+user_email = "test@example.com"
+
+[profiles.cheap.docker]
+model = "sonnet"
+mode = "none"
+run_idle_timeout_sec = 600
+
+[profiles.heavy.docker]
+model = "opus"
+mode = "dind"
+run_idle_timeout_sec = 14400
+
+[profiles.heavy.git]
+user_email = "heavy@example.com"
+` + trailer + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return cwd
+}
+
+func TestLoadReportsSyntaxErrorPosition(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeProfilesConfig(t, `
+[workspace]
+source_workspace_dir = /workspace-source`)
+
+	_, err := Load(cwd)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "decode config TOML: line ") || !strings.Contains(err.Error(), ", column ") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadUnknownTopLevelKey(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeProfilesConfig(t, "default_profile = \"heavy\"\nextra = true")
+
+	_, err := Load(cwd)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), `unknown top-level key "extra"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadAcceptsFullTOMLSyntax(t *testing.T) {
+	t.Parallel()
+
+	cwd := t.TempDir()
+	path := filepath.Join(cwd, ".agent-cli", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir config dir: %v", err)
+	}
+
+	content := `# literal and multi-line strings, inline tables and dotted keys
+docker = { image = 'claude:go', run_idle_timeout_sec = 900 }
+
+[auth]
+github_token = """
+gh-token"""
+claude_token = "claude-token"
+
+[workspace]
+source_workspace_dir = "/workspace-source" # trailing comment
+
+[git]
+user_name = "Test User"
+user_email = "test@example.com"
+
+[profiles.heavy]
+docker.image = "claude:heavy"
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadProfile(cwd, "heavy")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Docker.Image != "claude:heavy" {
+		t.Fatalf("unexpected image: %q", cfg.Docker.Image)
+	}
+	if cfg.Docker.RunIdleTimeoutSec != 900 {
+		t.Fatalf("unexpected run idle timeout: %d", cfg.Docker.RunIdleTimeoutSec)
+	}
+	if cfg.Auth.GitHubToken != "gh-token" {
+		t.Fatalf("unexpected github token: %q", cfg.Auth.GitHubToken)
+	}
+	if cfg.Workspace.SourceWorkspaceDir != "/workspace-source" {
+		t.Fatalf("unexpected source workspace dir: %q", cfg.Workspace.SourceWorkspaceDir)
+	}
+}
+
+func TestLoadInvalidRedactPattern(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeProfilesConfig(t, `
+[redact]
+patterns = ["ok-[0-9]+", "("]`)
+
+	_, err := Load(cwd)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "redact.patterns[1] must be a valid regular expression") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadBudgetWithProfileOverlay(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeProfilesConfig(t, `
+[budget]
+max_cost_usd = 20
+max_tokens = 5000000
+
+[budget.nodes.reviewer]
+max_cost_usd = 2.5
+
+[profiles.cheap.budget]
+max_cost_usd = 3
+
+[profiles.cheap.budget.nodes.implementer]
+max_tokens = 100000`)
+
+	cfg, err := Load(cwd)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Budget.MaxCostUSD != 20 || cfg.Budget.MaxTokens != 5000000 || cfg.Budget.Nodes["reviewer"].MaxCostUSD != 2.5 {
+		t.Fatalf("unexpected budget: %+v", cfg.Budget)
+	}
+
+	cfg, err = LoadProfile(cwd, "cheap")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Budget.MaxCostUSD != 3 || cfg.Budget.MaxTokens != 5000000 {
+		t.Fatalf("unexpected budget: %+v", cfg.Budget)
+	}
+	if cfg.Budget.Nodes["reviewer"].MaxCostUSD != 2.5 || cfg.Budget.Nodes["implementer"].MaxTokens != 100000 {
+		t.Fatalf("unexpected node budgets: %+v", cfg.Budget.Nodes)
+	}
+}
+
+func TestLoadSpendCapsWithProfileOverlay(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeProfilesConfig(t, `
+[budget]
+daily_usd = 10
+monthly_usd = 200
+
+[profiles.cheap.budget]
+weekly_usd = 25
+monthly_usd = 50`)
+
+	cfg, err := Load(cwd)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Budget.DailyUSD != 10 || cfg.Budget.WeeklyUSD != 0 || cfg.Budget.MonthlyUSD != 200 {
+		t.Fatalf("unexpected budget: %+v", cfg.Budget)
+	}
+
+	cfg, err = LoadProfile(cwd, "cheap")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Budget.DailyUSD != 10 || cfg.Budget.WeeklyUSD != 25 || cfg.Budget.MonthlyUSD != 50 {
+		t.Fatalf("unexpected budget: %+v", cfg.Budget)
+	}
+}
+
+func TestLoadInvalidBudget(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"[budget]\nmax_cost_usd = -1":              "budget.max_cost_usd must not be negative",
+		"[budget.nodes.reviewer]\nmax_tokens = -5": "budget.nodes.reviewer.max_tokens must not be negative",
+		"[budget]\nweekly_usd = -3":                "budget.weekly_usd must not be negative",
+		"[budget.nodes.reviewer]\nmax_cost = 1":    `unknown key "max_cost" in section "budget.nodes.reviewer"`,
+	}
+	for extra, wantErr := range tests {
+		_, err := Load(writeProfilesConfig(t, extra))
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("unexpected error for %q: %v", extra, err)
+		}
+	}
+}
+
+func TestLoadRetention(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Load(writeProfilesConfig(t, "[retention]\nmax_age_days = 30\nkeep_failed = true\ncompress = true"))
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	want := RetentionConfig{MaxAgeDays: 30, KeepFailed: true, Compress: true}
+	if cfg.Retention != want {
+		t.Fatalf("unexpected retention: %+v", cfg.Retention)
+	}
+
+	tests := map[string]string{
+		"[retention]\nmax_age_days = -1": "retention.max_age_days must not be negative",
+		"[retention]\nmax_runs = 10":     `unknown key "max_runs" in section "retention"`,
+	}
+	for extra, wantErr := range tests {
+		_, err := Load(writeProfilesConfig(t, extra))
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("unexpected error for %q: %v", extra, err)
+		}
+	}
+}
+
+func TestLoadPricing(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeProfilesConfig(t, `
+[pricing.opus]
+input_per_mtok = 15
+output_per_mtok = 75
+cache_write_per_mtok = 18.75
+cache_read_per_mtok = 1.5
+
+[pricing."claude-sonnet-4.5"]
+input_per_mtok = 3
+output_per_mtok = 15`)
+
+	cfg, err := Load(cwd)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	want := PricingConfig{InputPerMTok: 15, OutputPerMTok: 75, CacheWritePerMTok: 18.75, CacheReadPerMTok: 1.5}
+	if len(cfg.Pricing) != 2 || cfg.Pricing["opus"] != want || cfg.Pricing["claude-sonnet-4.5"].OutputPerMTok != 15 {
+		t.Fatalf("unexpected pricing: %+v", cfg.Pricing)
+	}
+
+	pricing, err := LoadPricing(cwd)
+	if err != nil {
+		t.Fatalf("load pricing: %v", err)
+	}
+	if pricing["opus"] != want {
+		t.Fatalf("unexpected pricing: %+v", pricing)
+	}
+
+	tests := map[string]string{
+		"[pricing.opus]\noutput_per_mtok = -1": "pricing.opus.output_per_mtok must not be negative",
+		"[pricing.opus]\ninput = 1":            `unknown key "input" in section "pricing.opus"`,
+	}
+	for extra, wantErr := range tests {
+		_, err := Load(writeProfilesConfig(t, extra))
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("unexpected error for %q: %v", extra, err)
+		}
+		_, err = LoadPricing(writeProfilesConfig(t, extra))
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("unexpected pricing error for %q: %v", extra, err)
+		}
+	}
+}
+
+func TestLoadPricingWithoutTokens(t *testing.T) {
+	t.Parallel()
+
+	cwd := t.TempDir()
+	path := ConfigPath(cwd)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir config dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("[pricing.opus]\ninput_per_mtok = 15\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	pricing, err := LoadPricing(cwd)
+	if err != nil {
+		t.Fatalf("load pricing: %v", err)
+	}
+	if pricing["opus"].InputPerMTok != 15 {
+		t.Fatalf("unexpected pricing: %+v", pricing)
+	}
+	if _, err := LoadPricing(t.TempDir()); err == nil || !strings.Contains(err.Error(), "config file not found") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const secretCommandTimeout = 30 * time.Second
+
+var envSecretReferencePattern = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// secretSource describes the alternative ways one auth token can be configured.
+type secretSource struct {
+	name    string
+	value   *string
+	command *string
+	file    *string
+}
+
+func (a *AuthConfig) secretSources() []secretSource {
+	return []secretSource{
+		{name: "github_token", value: &a.GitHubToken, command: &a.GitHubTokenCmd, file: &a.GitHubTokenFile},
+		{name: "claude_token", value: &a.ClaudeToken, command: &a.ClaudeTokenCmd, file: &a.ClaudeTokenFile},
+	}
+}
+
+// configured reports whether the token, its command or its file is set.
+func (s secretSource) configured() bool {
+	return strings.TrimSpace(*s.value) != "" ||
+		strings.TrimSpace(*s.command) != "" ||
+		strings.TrimSpace(*s.file) != ""
+}
+
+// overlayAuthConfig replaces a token only when the profile configures one of its sources.
+// The profile source then wins over every source set in the base config.
+func overlayAuthConfig(target *AuthConfig, overlay AuthConfig) {
+	targetSources := target.secretSources()
+	overlaySources := overlay.secretSources()
+	for i, source := range overlaySources {
+		if !source.configured() {
+			continue
+		}
+		*targetSources[i].value = *source.value
+		*targetSources[i].command = *source.command
+		*targetSources[i].file = *source.file
+	}
+}
+
+// ResolveSecrets replaces env references, token commands and token files with the resolved tokens.
+// Relative token file paths are resolved against cwd, which is also the working directory of token commands.
+// Resolved values live only in memory and are never included in returned errors.
+func (c *Config) ResolveSecrets(cwd string) error {
+	for _, source := range c.Auth.secretSources() {
+		resolved, err := resolveSecret(cwd, source)
+		if err != nil {
+			return err
+		}
+		*source.value = resolved
+		*source.command = ""
+		*source.file = ""
+	}
+	return nil
+}
+
+func resolveSecret(cwd string, source secretSource) (string, error) {
+	value := strings.TrimSpace(*source.value)
+	command := strings.TrimSpace(*source.command)
+	file := strings.TrimSpace(*source.file)
+
+	configured := 0
+	for _, item := range []string{value, command, file} {
+		if item != "" {
+			configured++
+		}
+	}
+	if configured > 1 {
+		return "", fmt.Errorf(
+			"auth.%s: set only one of %s, %s_cmd, %s_file",
+			source.name,
+			source.name,
+			source.name,
+			source.name,
+		)
+	}
+
+	switch {
+	case command != "":
+		return runSecretCommand(cwd, "auth."+source.name+"_cmd", command)
+	case file != "":
+		return readSecretFile(cwd, "auth."+source.name+"_file", file)
+	default:
+		return expandEnvSecretReference("auth."+source.name, value)
+	}
+}
+
+// expandEnvSecretReference resolves values of the form "${NAME}" from the environment.
+func expandEnvSecretReference(field string, value string) (string, error) {
+	match := envSecretReferencePattern.FindStringSubmatch(value)
+	if match == nil {
+		return value, nil
+	}
+
+	resolved, ok := os.LookupEnv(match[1])
+	if !ok || strings.TrimSpace(resolved) == "" {
+		return "", fmt.Errorf("%s references environment variable %s, which is not set", field, match[1])
+	}
+	return strings.TrimSpace(resolved), nil
+}
+
+func runSecretCommand(cwd string, field string, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = cwd
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("run %s: timed out after %s", field, secretCommandTimeout)
+		}
+		if details := strings.TrimSpace(stderr.String()); details != "" {
+			return "", fmt.Errorf("run %s: %w: %s", field, err, details)
+		}
+		return "", fmt.Errorf("run %s: %w", field, err)
+	}
+
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", fmt.Errorf("run %s: command produced no output", field)
+	}
+	return token, nil
+}
+
+func readSecretFile(cwd string, field string, path string) (string, error) {
+	path = expandHomeDir(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cwd, path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("stat %s: %w", field, err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s must be a regular file: %s", field, path)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return "", fmt.Errorf(
+			"%s %s is accessible by group or others (mode %04o): run chmod 600 %s",
+			field,
+			path,
+			info.Mode().Perm(),
+			path,
+		)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", field, err)
+	}
+
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("%s is empty: %s", field, path)
+	}
+	return token, nil
+}
+
+func expandHomeDir(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
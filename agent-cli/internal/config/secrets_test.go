@@ -0,0 +1,223 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLoadResolvesTokenCommand(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeAuthConfig(t, `github_token_cmd = "printf 'gh-from-cmd\n'"
+claude_token = "claude-token"`, "")
+
+	cfg, err := Load(cwd)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Auth.GitHubToken != "gh-from-cmd" {
+		t.Fatalf("unexpected github token: %q", cfg.Auth.GitHubToken)
+	}
+	if cfg.Auth.GitHubTokenCmd != "" {
+		t.Fatalf("unexpected github token cmd after resolve: %q", cfg.Auth.GitHubTokenCmd)
+	}
+}
+
+func TestLoadTokenCommandFailureHidesStdout(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeAuthConfig(t, `github_token_cmd = "echo leaked-secret; echo boom >&2; exit 3"
+claude_token = "claude-token"`, "")
+
+	_, err := Load(cwd)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "run auth.github_token_cmd") || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(err.Error(), "leaked-secret") {
+		t.Fatalf("error leaks command output: %v", err)
+	}
+}
+
+func TestLoadResolvesTokenFileRelativeToProject(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeAuthConfig(t, `github_token = "gh-token"
+claude_token_file = ".agent-cli/claude_token"`, "")
+	if err := os.WriteFile(filepath.Join(cwd, ".agent-cli", "claude_token"), []byte("claude-from-file\n"), 0o600); err != nil {
+		t.Fatalf("write token file: %v", err)
+	}
+
+	cfg, err := Load(cwd)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Auth.ClaudeToken != "claude-from-file" {
+		t.Fatalf("unexpected claude token: %q", cfg.Auth.ClaudeToken)
+	}
+}
+
+func TestLoadRejectsWorldReadableTokenFile(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("file permission bits are not enforced on windows")
+	}
+
+	cwd := writeAuthConfig(t, `github_token = "gh-token"
+claude_token_file = "claude_token"`, "")
+	tokenPath := filepath.Join(cwd, "claude_token")
+	if err := os.WriteFile(tokenPath, []byte("claude-from-file"), 0o600); err != nil {
+		t.Fatalf("write token file: %v", err)
+	}
+	if err := os.Chmod(tokenPath, 0o644); err != nil {
+		t.Fatalf("chmod token file: %v", err)
+	}
+
+	_, err := Load(cwd)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "accessible by group or others (mode 0644)") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadRejectsMultipleTokenSources(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeAuthConfig(t, `github_token = "gh-token"
+github_token_cmd = "printf other"
+claude_token = "claude-token"`, "")
+
+	_, err := Load(cwd)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "auth.github_token: set only one of github_token, github_token_cmd, github_token_file") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadResolvesEnvReference(t *testing.T) {
+	t.Setenv("AGENT_CLI_TEST_GH_TOKEN", "gh-from-env")
+
+	cwd := writeAuthConfig(t, `github_token = "${AGENT_CLI_TEST_GH_TOKEN}"
+claude_token = "claude-token"`, "")
+
+	cfg, err := Load(cwd)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Auth.GitHubToken != "gh-from-env" {
+		t.Fatalf("unexpected github token: %q", cfg.Auth.GitHubToken)
+	}
+}
+
+func TestLoadMissingEnvReference(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeAuthConfig(t, `github_token = "gh-token"
+claude_token = "${AGENT_CLI_TEST_UNSET_TOKEN}"`, "")
+
+	_, err := Load(cwd)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "auth.claude_token references environment variable AGENT_CLI_TEST_UNSET_TOKEN") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadProfileTokenSourceReplacesBaseToken(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeAuthConfig(t, `github_token = "gh-token"
+claude_token = "claude-token"`, `
+[profiles.ci.auth]
+github_token_cmd = "printf gh-ci"`)
+
+	cfg, err := LoadProfile(cwd, "ci")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Auth.GitHubToken != "gh-ci" {
+		t.Fatalf("unexpected github token: %q", cfg.Auth.GitHubToken)
+	}
+	if cfg.Auth.ClaudeToken != "claude-token" {
+		t.Fatalf("unexpected claude token: %q", cfg.Auth.ClaudeToken)
+	}
+}
+
+func TestLoadSkipsTokenCommandsOfUnselectedProfiles(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeAuthConfig(t, `github_token = "gh-token"
+claude_token = "claude-token"`, `
+[profiles.ci.auth]
+github_token_cmd = "exit 1"`)
+
+	cfg, err := Load(cwd)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Auth.GitHubToken != "gh-token" {
+		t.Fatalf("unexpected github token: %q", cfg.Auth.GitHubToken)
+	}
+}
+
+func TestReadProfileLeavesTokenSourcesUnresolved(t *testing.T) {
+	t.Parallel()
+
+	cwd := writeAuthConfig(t, `github_token_cmd = "exit 1"
+claude_token = "${AGENT_CLI_TEST_UNSET_TOKEN}"`, "")
+
+	cfg, err := ReadProfile(cwd, "")
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if cfg.Auth.GitHubTokenCmd != "exit 1" || cfg.Auth.ClaudeToken != "${AGENT_CLI_TEST_UNSET_TOKEN}" {
+		t.Fatalf("unexpected auth config: %+v", cfg.Auth)
+	}
+	if err := cfg.ResolveSecrets(cwd); err == nil || !strings.Contains(err.Error(), "run auth.github_token_cmd") {
+		t.Fatalf("unexpected resolve error: %v", err)
+	}
+
+	cwd = writeAuthConfig(t, `claude_token = "claude-token"`, "")
+	if _, err := ReadProfile(cwd, ""); err == nil || !strings.Contains(err.Error(), "auth.github_token") {
+		t.Fatalf("expected a missing github token source, got %v", err)
+	}
+}
+
+func writeAuthConfig(t *testing.T, auth string, extra string) string {
+	t.Helper()
+
+	cwd := t.TempDir()
+	path := filepath.Join(cwd, ".agent-cli", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir config dir: %v", err)
+	}
+
+	content := `[docker]
+image = "claude:go"
+
+[auth]
+` + auth + `
+
+[workspace]
+source_workspace_dir = "/workspace-source"
+
+[git]
+user_name = "Test User"
+user_email = "test@example.com"
+` + extra + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return cwd
+}
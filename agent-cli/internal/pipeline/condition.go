@@ -0,0 +1,544 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Condition grammar mirrors images/entrypoint/src/lib/condition-eval.ts:
+//
+//	expr       := or
+//	or         := and ("||" and)*
+//	and        := comparison ("&&" comparison)*
+//	comparison := primary (("==" | "!=" | ">" | ">=" | "<" | "<=" | "in") primary)*
+//	primary    := "(" expr ")" | string | number | true | false | null | path
+//	path       := identifier ("." identifier)*
+
+type conditionTokenType string
+
+const (
+	tokenIdentifier conditionTokenType = "identifier"
+	tokenNumber     conditionTokenType = "number"
+	tokenString     conditionTokenType = "string"
+	tokenBoolean    conditionTokenType = "boolean"
+	tokenNull       conditionTokenType = "null"
+	tokenOperator   conditionTokenType = "operator"
+	tokenParenOpen  conditionTokenType = "paren_open"
+	tokenParenClose conditionTokenType = "paren_close"
+	tokenDot        conditionTokenType = "dot"
+	tokenComma      conditionTokenType = "comma"
+)
+
+type conditionToken struct {
+	Type     conditionTokenType
+	Value    string
+	Position int
+}
+
+// ConditionNodeType identifies the kind of a parsed condition AST node.
+type ConditionNodeType string
+
+const (
+	ConditionLiteral ConditionNodeType = "literal"
+	ConditionPath    ConditionNodeType = "path"
+	ConditionBinary  ConditionNodeType = "binary"
+)
+
+// ConditionNode is one node of a compiled transition condition.
+// Literal values are string, float64, bool or nil.
+type ConditionNode struct {
+	Type     ConditionNodeType
+	Value    any
+	Segments []string
+	Operator string
+	Left     *ConditionNode
+	Right    *ConditionNode
+}
+
+// Condition is a compiled transition `when` expression.
+type Condition struct {
+	Raw  string
+	Root *ConditionNode
+}
+
+var (
+	conditionOperators   = []string{"==", "!=", ">=", "<=", "&&", "||", ">", "<"}
+	comparisonOperators  = []string{"==", "!=", ">", ">=", "<", "<=", "in"}
+	conditionNumberShape = regexp.MustCompile(`^(?:0|[1-9][0-9]*)(?:\.[0-9]+)?$`)
+)
+
+// CompileCondition parses a transition `when` expression.
+func CompileCondition(condition string) (*Condition, error) {
+	raw := strings.TrimSpace(condition)
+	if raw == "" {
+		return nil, errors.New("condition must not be empty")
+	}
+
+	tokens, err := tokenizeCondition(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &conditionParser{tokens: tokens}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.index != len(tokens) {
+		next := tokens[parser.index]
+		return nil, fmt.Errorf("unexpected token after expression: %s:%s", next.Type, next.Value)
+	}
+
+	return &Condition{Raw: raw, Root: root}, nil
+}
+
+// ContainsRunStatusErrorCheck reports whether the condition compares run.status with "error".
+// Nodes without such a transition get an implicit `run.status == "error" -> fail` fallback.
+func (c *Condition) ContainsRunStatusErrorCheck() bool {
+	return containsRunStatusErrorCheck(c.Root)
+}
+
+func containsRunStatusErrorCheck(node *ConditionNode) bool {
+	if node == nil || node.Type != ConditionBinary {
+		return false
+	}
+	if node.Operator == "==" &&
+		((isRunStatusPath(node.Left) && isErrorLiteral(node.Right)) ||
+			(isErrorLiteral(node.Left) && isRunStatusPath(node.Right))) {
+		return true
+	}
+	return containsRunStatusErrorCheck(node.Left) || containsRunStatusErrorCheck(node.Right)
+}
+
+func isRunStatusPath(node *ConditionNode) bool {
+	return node.Type == ConditionPath && len(node.Segments) == 2 &&
+		node.Segments[0] == "run" && node.Segments[1] == "status"
+}
+
+func isErrorLiteral(node *ConditionNode) bool {
+	value, ok := node.Value.(string)
+	return node.Type == ConditionLiteral && ok && value == "error"
+}
+
+func tokenizeCondition(input string) ([]conditionToken, error) {
+	tokens := make([]conditionToken, 0, 16)
+	i := 0
+	for i < len(input) {
+		ch := input[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == '\f' || ch == '\v':
+			i++
+			continue
+		case ch == '(':
+			tokens = append(tokens, conditionToken{Type: tokenParenOpen, Value: "(", Position: i})
+			i++
+			continue
+		case ch == ')':
+			tokens = append(tokens, conditionToken{Type: tokenParenClose, Value: ")", Position: i})
+			i++
+			continue
+		case ch == '.':
+			tokens = append(tokens, conditionToken{Type: tokenDot, Value: ".", Position: i})
+			i++
+			continue
+		case ch == ',':
+			tokens = append(tokens, conditionToken{Type: tokenComma, Value: ",", Position: i})
+			i++
+			continue
+		}
+
+		if i+2 <= len(input) && slices.Contains(conditionOperators, input[i:i+2]) {
+			tokens = append(tokens, conditionToken{Type: tokenOperator, Value: input[i : i+2], Position: i})
+			i += 2
+			continue
+		}
+		if slices.Contains(conditionOperators, string(ch)) {
+			tokens = append(tokens, conditionToken{Type: tokenOperator, Value: string(ch), Position: i})
+			i++
+			continue
+		}
+
+		switch {
+		case ch == '"' || ch == '\'':
+			token, next, err := tokenizeConditionString(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token)
+			i = next
+		case ch >= '0' && ch <= '9':
+			j := i + 1
+			for j < len(input) && ((input[j] >= '0' && input[j] <= '9') || input[j] == '.') {
+				j++
+			}
+			raw := input[i:j]
+			if !conditionNumberShape.MatchString(raw) {
+				return nil, fmt.Errorf("invalid number at position %d", i)
+			}
+			tokens = append(tokens, conditionToken{Type: tokenNumber, Value: raw, Position: i})
+			i = j
+		case isIdentifierStart(ch):
+			j := i + 1
+			for j < len(input) && isIdentifierPart(input[j]) {
+				j++
+			}
+			ident := input[i:j]
+			tokenType := tokenIdentifier
+			switch ident {
+			case "true", "false":
+				tokenType = tokenBoolean
+			case "null":
+				tokenType = tokenNull
+			case "in":
+				tokenType = tokenOperator
+			}
+			tokens = append(tokens, conditionToken{Type: tokenType, Value: ident, Position: i})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected token %q at position %d", string(ch), i)
+		}
+	}
+	return tokens, nil
+}
+
+func tokenizeConditionString(input string, start int) (conditionToken, int, error) {
+	quote := input[start]
+	j := start + 1
+	escaped := false
+	for j < len(input) {
+		current := input[j]
+		if escaped {
+			escaped = false
+			j++
+			continue
+		}
+		if current == '\\' {
+			escaped = true
+			j++
+			continue
+		}
+		if current == quote {
+			break
+		}
+		j++
+	}
+	if j >= len(input) || input[j] != quote {
+		return conditionToken{}, 0, fmt.Errorf("unterminated string literal at position %d", start)
+	}
+
+	raw := input[start : j+1]
+	if quote == '\'' {
+		body := raw[1 : len(raw)-1]
+		body = strings.ReplaceAll(body, `\`, `\\`)
+		body = strings.ReplaceAll(body, `"`, `\"`)
+		body = strings.ReplaceAll(body, `'`, `\'`)
+		raw = `"` + body + `"`
+	}
+
+	var parsed string
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return conditionToken{}, 0, fmt.Errorf("invalid string literal at position %d: %w", start, err)
+	}
+	return conditionToken{Type: tokenString, Value: parsed, Position: start}, j + 1, nil
+}
+
+func isIdentifierStart(ch byte) bool {
+	return ch == '_' || (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z')
+}
+
+func isIdentifierPart(ch byte) bool {
+	return isIdentifierStart(ch) || (ch >= '0' && ch <= '9')
+}
+
+type conditionParser struct {
+	tokens []conditionToken
+	index  int
+}
+
+func (p *conditionParser) peek() *conditionToken {
+	if p.index >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.index]
+}
+
+func (p *conditionParser) consume() *conditionToken {
+	token := p.peek()
+	if token != nil {
+		p.index++
+	}
+	return token
+}
+
+func (p *conditionParser) expect(tokenType conditionTokenType) (*conditionToken, error) {
+	token := p.consume()
+	if token == nil || token.Type != tokenType {
+		received := "<eof>"
+		if token != nil {
+			received = string(token.Type) + ":" + token.Value
+		}
+		return nil, fmt.Errorf("expected %s, got %s", tokenType, received)
+	}
+	return token, nil
+}
+
+func (p *conditionParser) parseOr() (*ConditionNode, error) {
+	return p.parseBinary([]string{"||"}, p.parseAnd)
+}
+
+func (p *conditionParser) parseAnd() (*ConditionNode, error) {
+	return p.parseBinary([]string{"&&"}, p.parseComparison)
+}
+
+func (p *conditionParser) parseComparison() (*ConditionNode, error) {
+	return p.parseBinary(comparisonOperators, p.parsePrimary)
+}
+
+func (p *conditionParser) parseBinary(
+	operators []string,
+	operand func() (*ConditionNode, error),
+) (*ConditionNode, error) {
+	node, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		next := p.peek()
+		if next == nil || next.Type != tokenOperator || !slices.Contains(operators, next.Value) {
+			return node, nil
+		}
+		p.consume()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		node = &ConditionNode{Type: ConditionBinary, Operator: next.Value, Left: node, Right: right}
+	}
+}
+
+func (p *conditionParser) parsePrimary() (*ConditionNode, error) {
+	token := p.peek()
+	if token == nil {
+		return nil, errors.New("unexpected end of expression")
+	}
+
+	switch token.Type {
+	case tokenParenOpen:
+		p.consume()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenParenClose); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	case tokenString:
+		p.consume()
+		return &ConditionNode{Type: ConditionLiteral, Value: token.Value}, nil
+	case tokenNumber:
+		p.consume()
+		value, err := strconv.ParseFloat(token.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number at position %d", token.Position)
+		}
+		return &ConditionNode{Type: ConditionLiteral, Value: value}, nil
+	case tokenBoolean:
+		p.consume()
+		return &ConditionNode{Type: ConditionLiteral, Value: token.Value == "true"}, nil
+	case tokenNull:
+		p.consume()
+		return &ConditionNode{Type: ConditionLiteral, Value: nil}, nil
+	case tokenIdentifier:
+		return p.parsePath()
+	case tokenOperator, tokenParenClose, tokenDot, tokenComma:
+	}
+
+	return nil, fmt.Errorf("unexpected token %s:%s at position %d", token.Type, token.Value, token.Position)
+}
+
+func (p *conditionParser) parsePath() (*ConditionNode, error) {
+	first, err := p.expect(tokenIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	segments := []string{first.Value}
+	for {
+		dot := p.peek()
+		if dot == nil || dot.Type != tokenDot {
+			break
+		}
+		p.consume()
+		segment, err := p.expect(tokenIdentifier)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment.Value)
+	}
+	return &ConditionNode{Type: ConditionPath, Segments: segments}, nil
+}
+
+// Evaluate reports whether the condition holds for scope, with the entrypoint's semantics:
+// missing paths are null, == compares by type then by JSON encoding, ordering compares numbers
+// numerically and everything else as strings, `in` tests array membership or substring,
+// and the result is truthy when non-null, non-zero and non-empty.
+// Scope values must be JSON-shaped (nil, bool, float64, string, []any, map[string]any).
+func (c *Condition) Evaluate(scope map[string]any) bool {
+	return isTruthy(evaluateConditionNode(c.Root, scope))
+}
+
+func evaluateConditionNode(node *ConditionNode, scope map[string]any) any {
+	switch node.Type {
+	case ConditionLiteral:
+		return node.Value
+	case ConditionPath:
+		return resolveConditionPath(scope, node.Segments)
+	case ConditionBinary:
+	}
+
+	left := evaluateConditionNode(node.Left, scope)
+	switch node.Operator {
+	case "&&":
+		return isTruthy(left) && isTruthy(evaluateConditionNode(node.Right, scope))
+	case "||":
+		return isTruthy(left) || isTruthy(evaluateConditionNode(node.Right, scope))
+	}
+
+	right := evaluateConditionNode(node.Right, scope)
+	switch node.Operator {
+	case "==":
+		return conditionValuesEqual(left, right)
+	case "!=":
+		return !conditionValuesEqual(left, right)
+	case ">", ">=", "<", "<=":
+		return compareConditionValues(node.Operator, left, right)
+	case "in":
+		switch candidates := right.(type) {
+		case []any:
+			for _, candidate := range candidates {
+				if conditionValuesEqual(left, candidate) {
+					return true
+				}
+			}
+			return false
+		case string:
+			return strings.Contains(candidates, stringifyComparable(left))
+		}
+		return false
+	}
+	return false
+}
+
+func resolveConditionPath(scope map[string]any, segments []string) any {
+	var current any = scope
+	for _, segment := range segments {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = object[segment]
+	}
+	return current
+}
+
+func compareConditionValues(operator string, left any, right any) bool {
+	var cmp int
+	leftNumber, leftIsNumber := left.(float64)
+	rightNumber, rightIsNumber := right.(float64)
+	if leftIsNumber && rightIsNumber {
+		switch {
+		case leftNumber < rightNumber:
+			cmp = -1
+		case leftNumber > rightNumber:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(stringifyComparable(left), stringifyComparable(right))
+	}
+
+	switch operator {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	default:
+		return cmp <= 0
+	}
+}
+
+func conditionValuesEqual(left any, right any) bool {
+	switch left.(type) {
+	case float64, string, bool:
+		if sameScalarType(left, right) {
+			return left == right
+		}
+	case nil:
+		return right == nil
+	}
+	if right == nil {
+		return false
+	}
+	return jsonText(left) == jsonText(right)
+}
+
+func sameScalarType(left any, right any) bool {
+	switch left.(type) {
+	case float64:
+		_, ok := right.(float64)
+		return ok
+	case string:
+		_, ok := right.(string)
+		return ok
+	case bool:
+		_, ok := right.(bool)
+		return ok
+	}
+	return false
+}
+
+func stringifyComparable(value any) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(typed)
+	}
+	return jsonText(value)
+}
+
+func jsonText(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func isTruthy(value any) bool {
+	switch typed := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return typed
+	case float64:
+		return typed != 0
+	case string:
+		return typed != ""
+	case []any:
+		return len(typed) > 0
+	case map[string]any:
+		return len(typed) > 0
+	}
+	return true
+}
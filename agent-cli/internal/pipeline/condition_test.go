@@ -0,0 +1,118 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileConditionValid(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{
+		`run.exit_code == 0`,
+		`decision.status == "done" && pipeline.iteration < 10`,
+		`(run.status == 'error' || run.timed_out) && node.attempt >= 2`,
+		`"bug" in decision.labels`,
+		`decision.score > 0.5 || decision.reason != null`,
+		`true`,
+	} {
+		if _, err := CompileCondition(expr); err != nil {
+			t.Fatalf("compile %q: %v", expr, err)
+		}
+	}
+}
+
+func TestCompileConditionErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"":                         "condition must not be empty",
+		"run.exit_code ==":         "unexpected end of expression",
+		`decision.status == "done`: "unterminated string literal at position 19",
+		"run.exit_code == 01":      "invalid number at position 17",
+		"run.exit_code = 0":        `unexpected token "=" at position 14`,
+		"(run.exit_code == 0":      "expected paren_close, got <eof>",
+		"run.exit_code 0":          "unexpected token after expression: number:0",
+		"decision.":                "expected identifier, got <eof>",
+	}
+	for expr, wantErr := range tests {
+		_, err := CompileCondition(expr)
+		if err == nil {
+			t.Fatalf("expected error for %q", expr)
+		}
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("unexpected error for %q: %v", expr, err)
+		}
+	}
+}
+
+func TestConditionContainsRunStatusErrorCheck(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]bool{
+		`run.status == "error"`:                          true,
+		`"error" == run.status`:                          true,
+		`decision.ok && (run.status == 'error' || true)`: true,
+		`run.status != "error"`:                          false,
+		`run.exit_code != 0`:                             false,
+	}
+	for expr, want := range tests {
+		condition, err := CompileCondition(expr)
+		if err != nil {
+			t.Fatalf("compile %q: %v", expr, err)
+		}
+		if got := condition.ContainsRunStatusErrorCheck(); got != want {
+			t.Fatalf("unexpected result for %q: %v", expr, got)
+		}
+	}
+}
+
+func TestConditionEvaluate(t *testing.T) {
+	t.Parallel()
+
+	scope := map[string]any{
+		"decision": map[string]any{
+			"status": "done",
+			"score":  0.75,
+			"labels": []any{"bug", "ui"},
+			"notes":  "",
+			"meta":   map[string]any{"retries": float64(2)},
+		},
+		"run":      map[string]any{"exit_code": float64(0), "signal": "", "timed_out": false, "status": "success"},
+		"node":     map[string]any{"id": "review", "kind": "agent", "attempt": float64(3), "run_id": "review-7"},
+		"pipeline": map[string]any{"iteration": float64(7), "total_node_runs": float64(7)},
+	}
+
+	tests := map[string]bool{
+		`decision.status == "done"`:                          true,
+		`decision.status == 'done' && run.exit_code == 0`:    true,
+		`run.status == "error" || node.attempt >= 3`:         true,
+		`decision.score > 0.5`:                               true,
+		`decision.meta.retries < 2`:                          false,
+		`decision.missing == null`:                           true,
+		`decision.missing.deeper == null`:                    true,
+		`decision.notes`:                                     false,
+		`decision.labels`:                                    true,
+		`decision.meta`:                                      true,
+		`run.timed_out`:                                      false,
+		`"bug" in decision.labels`:                           true,
+		`"api" in decision.labels`:                           false,
+		`"view" in node.run_id`:                              true,
+		`7 in node.run_id`:                                   true,
+		`run.exit_code == "0"`:                               false,
+		`run.exit_code == false`:                             false,
+		`node.kind > "action"`:                               true,
+		`decision.status != null && pipeline.iteration <= 7`: true,
+		`(decision.status == "x" || decision.score >= 0.75) && pipeline.total_node_runs != 1`: true,
+	}
+
+	for expr, want := range tests {
+		condition, err := CompileCondition(expr)
+		if err != nil {
+			t.Fatalf("compile %q: %v", expr, err)
+		}
+		if got := condition.Evaluate(scope); got != want {
+			t.Fatalf("unexpected result for %q: %v", expr, got)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GraphOverlay marks the path a recorded run took through the plan.
+type GraphOverlay struct {
+	// NodeVisits counts node runs per node id.
+	NodeVisits map[string]int
+	// EdgeVisits counts hops between consecutive nodes, keyed by GraphEdgeKey(from, to).
+	EdgeVisits map[string]int
+}
+
+// GraphEdgeKey identifies a from -> to hop in GraphOverlay.EdgeVisits.
+func GraphEdgeKey(from string, to string) string {
+	return from + "\x00" + to
+}
+
+type graphEdge struct {
+	From       string
+	To         string
+	Transition Transition
+	// Visits is set on the first transition of a from -> to pair only, since runs record hops, not transitions.
+	Visits int
+}
+
+// graphNodeIDs lists plan nodes in file order followed by built-in nodes that are transition targets.
+func graphNodeIDs(plan *Plan) []string {
+	ids := append([]string{}, plan.NodeOrder...)
+	for _, builtinID := range []string{BuiltinSuccessNodeID, BuiltinFailNodeID} {
+		node := plan.Nodes[builtinID]
+		if node == nil || !node.Builtin {
+			continue
+		}
+		for _, edge := range graphEdges(plan, nil) {
+			if edge.To == builtinID {
+				ids = append(ids, builtinID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+func graphEdges(plan *Plan, overlay *GraphOverlay) []graphEdge {
+	edges := make([]graphEdge, 0)
+	for _, nodeID := range plan.NodeOrder {
+		seen := map[string]bool{}
+		for _, transition := range plan.Nodes[nodeID].Transitions {
+			edge := graphEdge{From: nodeID, To: transition.To, Transition: transition}
+			if overlay != nil && !seen[transition.To] {
+				edge.Visits = overlay.EdgeVisits[GraphEdgeKey(nodeID, transition.To)]
+			}
+			seen[transition.To] = true
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+func graphNodeDetail(node *Node) string {
+	switch {
+	case node.Terminal:
+		return fmt.Sprintf("terminal: %s (exit %d)", node.TerminalStatus, node.ExitCode)
+	case node.Run != nil && node.Run.Kind == RunKindAgent:
+		return "agent: " + node.Run.Model
+	default:
+		return RunKindCommand
+	}
+}
+
+func graphEdgeLabel(edge graphEdge) string {
+	label := edge.Transition.When
+	if edge.Transition.Implicit {
+		label += " (implicit)"
+	}
+	if edge.Visits > 0 {
+		label += fmt.Sprintf(" ×%d", edge.Visits)
+	}
+	return label
+}
+
+func graphNodeVisits(overlay *GraphOverlay, nodeID string) int {
+	if overlay == nil {
+		return 0
+	}
+	return overlay.NodeVisits[nodeID]
+}
+
+// RenderMermaid renders the plan as a Mermaid flowchart. overlay may be nil.
+func RenderMermaid(plan *Plan, overlay *GraphOverlay) string {
+	nodeIDs := graphNodeIDs(plan)
+	aliases := make(map[string]string, len(nodeIDs))
+	for index, nodeID := range nodeIDs {
+		aliases[nodeID] = fmt.Sprintf("n%d", index)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	b.WriteString("  entry((start))\n")
+	for _, nodeID := range nodeIDs {
+		node := plan.Nodes[nodeID]
+		label := mermaidText(nodeID) + "<br/>" + mermaidText(graphNodeDetail(node))
+		if visits := graphNodeVisits(overlay, nodeID); visits > 0 {
+			label += fmt.Sprintf("<br/>visits: %d", visits)
+		}
+		open, closing := "[\"", "\"]"
+		switch {
+		case node.Terminal:
+			open, closing = "([\"", "\"])"
+		case node.Run != nil && node.Run.Kind == RunKindCommand:
+			open, closing = "[[\"", "\"]]"
+		}
+		fmt.Fprintf(&b, "  %s%s%s%s\n", aliases[nodeID], open, label, closing)
+	}
+
+	b.WriteString("  entry --> " + aliases[plan.EntryNode] + "\n")
+	linkIndex := 1
+	takenLinks := make([]int, 0)
+	implicitLinks := make([]int, 0)
+	for _, edge := range graphEdges(plan, overlay) {
+		arrow := "-->"
+		if edge.Transition.Implicit {
+			arrow = "-.->"
+			implicitLinks = append(implicitLinks, linkIndex)
+		}
+		if edge.Visits > 0 {
+			takenLinks = append(takenLinks, linkIndex)
+		}
+		fmt.Fprintf(&b, "  %s %s|\"%s\"| %s\n", aliases[edge.From], arrow, mermaidText(graphEdgeLabel(edge)), aliases[edge.To])
+		linkIndex++
+	}
+
+	b.WriteString("  classDef terminal fill:#f5f5f5,stroke:#616161\n")
+	for _, nodeID := range nodeIDs {
+		if plan.Nodes[nodeID].Terminal {
+			fmt.Fprintf(&b, "  class %s terminal\n", aliases[nodeID])
+		}
+	}
+	if overlay != nil {
+		b.WriteString("  classDef visited fill:#d4edda,stroke:#28a745,stroke-width:2px\n")
+		for _, nodeID := range nodeIDs {
+			if graphNodeVisits(overlay, nodeID) > 0 {
+				fmt.Fprintf(&b, "  class %s visited\n", aliases[nodeID])
+			}
+		}
+	}
+	if len(implicitLinks) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:#9e9e9e\n", joinInts(implicitLinks))
+	}
+	if len(takenLinks) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:#28a745,stroke-width:3px\n", joinInts(takenLinks))
+	}
+	return b.String()
+}
+
+// RenderDOT renders the plan as a Graphviz digraph. overlay may be nil.
+func RenderDOT(plan *Plan, overlay *GraphOverlay) string {
+	var b strings.Builder
+	b.WriteString("digraph pipeline {\n")
+	b.WriteString("  rankdir=TB;\n")
+	b.WriteString("  node [fontname=\"Helvetica\", fontsize=11];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=9];\n")
+	b.WriteString("  \"__entry\" [shape=circle, label=\"start\", width=0.5];\n")
+
+	for _, nodeID := range graphNodeIDs(plan) {
+		node := plan.Nodes[nodeID]
+		label := dotText(nodeID) + "\\n" + dotText(graphNodeDetail(node))
+		attrs := []string{}
+		switch {
+		case node.Terminal:
+			attrs = append(attrs, "shape=box", "style=\"rounded,filled\"", "fillcolor=\"#f5f5f5\"")
+		case node.Run != nil && node.Run.Kind == RunKindCommand:
+			attrs = append(attrs, "shape=box", "peripheries=2")
+		default:
+			attrs = append(attrs, "shape=box")
+		}
+		if visits := graphNodeVisits(overlay, nodeID); visits > 0 {
+			label += fmt.Sprintf("\\nvisits: %d", visits)
+			attrs = append(attrs, "color=\"#28a745\"", "penwidth=2")
+			if !node.Terminal {
+				attrs = append(attrs, "style=filled", "fillcolor=\"#d4edda\"")
+			}
+		}
+		attrs = append([]string{"label=\"" + label + "\""}, attrs...)
+		fmt.Fprintf(&b, "  \"%s\" [%s];\n", dotText(nodeID), strings.Join(attrs, ", "))
+	}
+
+	fmt.Fprintf(&b, "  \"__entry\" -> \"%s\";\n", dotText(plan.EntryNode))
+	for _, edge := range graphEdges(plan, overlay) {
+		attrs := []string{"label=\"" + dotText(graphEdgeLabel(edge)) + "\""}
+		if edge.Transition.Implicit {
+			attrs = append(attrs, "style=dashed", "color=\"#9e9e9e\"", "fontcolor=\"#9e9e9e\"")
+		}
+		if edge.Visits > 0 {
+			attrs = append(attrs, "color=\"#28a745\"", "penwidth=2.5")
+		}
+		fmt.Fprintf(&b, "  \"%s\" -> \"%s\" [%s];\n", dotText(edge.From), dotText(edge.To), strings.Join(attrs, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// mermaidText escapes text for a quoted Mermaid label.
+func mermaidText(text string) string {
+	replacer := strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "|", "#124;")
+	return replacer.Replace(text)
+}
+
+// dotText escapes text for a quoted DOT string.
+func dotText(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return replacer.Replace(text)
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		parts = append(parts, fmt.Sprintf("%d", value))
+	}
+	return strings.Join(parts, ",")
+}
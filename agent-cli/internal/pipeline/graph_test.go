@@ -0,0 +1,108 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderMermaidMarksNodeKindsAndImplicitEdges(t *testing.T) {
+	t.Parallel()
+
+	plan := parseGraphTestPlan(t)
+
+	got := RenderMermaid(plan, nil)
+	for _, want := range []string{
+		"flowchart TD\n",
+		`  n0[["build<br/>command"]]`,
+		`  n1["review<br/>agent: sonnet"]`,
+		`  n2(["blocked<br/>terminal: blocked (exit 3)"])`,
+		`  n3(["success<br/>terminal: success (exit 0)"])`,
+		`  n4(["fail<br/>terminal: failed (exit 1)"])`,
+		"  entry --> n0\n",
+		`  n0 -->|"run.exit_code == 0"| n1`,
+		`  n0 -.->|"run.status == #quot;error#quot; (implicit)"| n4`,
+		`  n1 -->|"decision.status == #quot;done#quot;"| n3`,
+		"  linkStyle 2 stroke:#9e9e9e\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in mermaid output:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "visited") {
+		t.Fatalf("unexpected overlay styles without overlay:\n%s", got)
+	}
+}
+
+func TestRenderDOTWithOverlay(t *testing.T) {
+	t.Parallel()
+
+	plan := parseGraphTestPlan(t)
+	overlay := &GraphOverlay{
+		NodeVisits: map[string]int{"build": 2, "review": 1, "success": 1},
+		EdgeVisits: map[string]int{
+			GraphEdgeKey("build", "review"):   1,
+			GraphEdgeKey("review", "build"):   1,
+			GraphEdgeKey("review", "success"): 1,
+		},
+	}
+
+	got := RenderDOT(plan, overlay)
+	for _, want := range []string{
+		`  "build" [label="build\ncommand\nvisits: 2", shape=box, peripheries=2, color="#28a745", penwidth=2, ` +
+			`style=filled, fillcolor="#d4edda"];`,
+		`  "__entry" -> "build";`,
+		`  "build" -> "review" [label="run.exit_code == 0 ×1", color="#28a745", penwidth=2.5];`,
+		`  "build" -> "fail" [label="run.status == \"error\" (implicit)", style=dashed, color="#9e9e9e", ` +
+			`fontcolor="#9e9e9e"];`,
+		`  "review" -> "build" [label="decision.status == \"fixes\" ×1", color="#28a745", penwidth=2.5];`,
+		`  "review" -> "build" [label="decision.status == \"retry\""];`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in dot output:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `"blocked" -> `) || strings.Contains(got, `label="blocked\nterminal: blocked (exit 3)\nvisits`) {
+		t.Fatalf("unexpected blocked overlay:\n%s", got)
+	}
+}
+
+func parseGraphTestPlan(t *testing.T) *Plan {
+	t.Helper()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "schema.json"), []byte(`{"type":"object"}`), 0o644); err != nil {
+		t.Fatalf("write schema: %v", err)
+	}
+	plan, err := ParsePlan([]byte(graphTestPlan), LoadOptions{WorkspaceDir: workspace})
+	if err != nil {
+		t.Fatalf("parse plan: %v", err)
+	}
+	return plan
+}
+
+const graphTestPlan = `version: v2
+entry: build
+nodes:
+  build:
+    run: {kind: command, cmd: make}
+    transitions:
+      - when: run.exit_code == 0
+        to: review
+  review:
+    run: {kind: agent, prompt: review, model: sonnet, decision: {schema_file: schema.json}}
+    transitions:
+      - when: run.status == "error"
+        to: blocked
+      - when: decision.status == "fixes"
+        to: build
+      - when: decision.status == "retry"
+        to: build
+      - when: decision.status == "done"
+        to: success
+  blocked:
+    terminal: true
+    terminal_status: blocked
+    exit_code: 3
+`
@@ -0,0 +1,273 @@
+package pipeline
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Lint rule identifiers reported in Finding.Rule.
+const (
+	RuleUnreachableNode  = "unreachable-node"
+	RuleNoTerminalPath   = "no-terminal-path"
+	RuleUnhandledEnum    = "unhandled-enum-value"
+	RuleUnknownEnumValue = "unknown-enum-value"
+)
+
+// Finding is one semantic problem in a validated plan.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	NodeID   string   `json:"node_id"`
+	// Line is the plan file line the finding points at; 0 when unknown.
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Lint reports semantic problems that structural validation accepts:
+// nodes unreachable from the entry, nodes that can only leave a loop through the
+// iteration limit or an error, and decision schema enum values not matched by transitions.
+func Lint(plan *Plan) []Finding {
+	findings := make([]Finding, 0)
+	reachable := reachableNodes(plan, plan.EntryNode, func(Transition) bool { return true })
+
+	for _, nodeID := range plan.NodeOrder {
+		node := plan.Nodes[nodeID]
+		if !reachable[nodeID] {
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Rule:     RuleUnreachableNode,
+				NodeID:   nodeID,
+				Line:     node.Line,
+				Message:  fmt.Sprintf("node %s is not reachable from entry node %s", nodeID, plan.EntryNode),
+			})
+			continue
+		}
+		if node.Terminal {
+			continue
+		}
+
+		if !canReachTerminal(plan, nodeID) {
+			findings = append(findings, Finding{
+				Severity: SeverityError,
+				Rule:     RuleNoTerminalPath,
+				NodeID:   nodeID,
+				Line:     node.Line,
+				Message: fmt.Sprintf(
+					"node %s cannot reach a terminal node except on run errors; it loops until limits.max_iterations (%d)",
+					nodeID,
+					plan.Limits.MaxIterations,
+				),
+			})
+		}
+		findings = append(findings, lintDecisionEnums(node)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// canReachTerminal ignores transitions that can only fire when the run failed.
+func canReachTerminal(plan *Plan, nodeID string) bool {
+	reachable := reachableNodes(plan, nodeID, func(transition Transition) bool {
+		return !requiresRunStatusError(transition.Condition.Root)
+	})
+	for id := range reachable {
+		if plan.Nodes[id].Terminal {
+			return true
+		}
+	}
+	return false
+}
+
+func reachableNodes(plan *Plan, start string, follow func(Transition) bool) map[string]bool {
+	visited := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		current := plan.Nodes[queue[0]]
+		queue = queue[1:]
+		for _, transition := range current.Transitions {
+			if visited[transition.To] || !follow(transition) {
+				continue
+			}
+			visited[transition.To] = true
+			queue = append(queue, transition.To)
+		}
+	}
+	return visited
+}
+
+// requiresRunStatusError reports whether the condition can only be true when run.status == "error".
+func requiresRunStatusError(node *ConditionNode) bool {
+	if node == nil || node.Type != ConditionBinary {
+		return false
+	}
+	switch node.Operator {
+	case "==":
+		return (isRunStatusPath(node.Left) && isErrorLiteral(node.Right)) ||
+			(isErrorLiteral(node.Left) && isRunStatusPath(node.Right))
+	case "&&":
+		return requiresRunStatusError(node.Left) || requiresRunStatusError(node.Right)
+	case "||":
+		return requiresRunStatusError(node.Left) && requiresRunStatusError(node.Right)
+	}
+	return false
+}
+
+func lintDecisionEnums(node *Node) []Finding {
+	if node.Run == nil || node.Run.Schema == nil {
+		return nil
+	}
+	enums := map[string][]string{}
+	collectSchemaEnums(node.Run.Schema, "decision", enums)
+	if len(enums) == 0 {
+		return nil
+	}
+
+	findings := make([]Finding, 0)
+	handled := map[string]map[string]bool{}
+	fullyHandled := map[string]bool{}
+	catchAll := false
+	for _, transition := range node.Transitions {
+		if transition.Implicit {
+			continue
+		}
+		if isAlwaysTrue(transition.Condition.Root) {
+			catchAll = true
+		}
+		for _, comparison := range decisionComparisons(transition.Condition.Root) {
+			values, ok := enums[comparison.path]
+			if !ok {
+				continue
+			}
+			if comparison.operator == "!=" {
+				fullyHandled[comparison.path] = true
+			}
+			if !slices.Contains(values, comparison.value) {
+				findings = append(findings, Finding{
+					Severity: SeverityError,
+					Rule:     RuleUnknownEnumValue,
+					NodeID:   node.ID,
+					Line:     transition.Line,
+					Message: fmt.Sprintf(
+						"transition %q compares %s with %q, which is not in the schema enum (%s)",
+						transition.When,
+						comparison.path,
+						comparison.value,
+						strings.Join(values, ", "),
+					),
+				})
+				continue
+			}
+			if handled[comparison.path] == nil {
+				handled[comparison.path] = map[string]bool{}
+			}
+			handled[comparison.path][comparison.value] = true
+		}
+	}
+	if catchAll {
+		return findings
+	}
+
+	paths := make([]string, 0, len(enums))
+	for path := range enums {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		// Enums never referenced by a transition are informational fields, not routing keys.
+		if fullyHandled[path] || handled[path] == nil {
+			continue
+		}
+		for _, value := range enums[path] {
+			if handled[path][value] {
+				continue
+			}
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Rule:     RuleUnhandledEnum,
+				NodeID:   node.ID,
+				Line:     node.Line,
+				Message: fmt.Sprintf(
+					"%s value %q is not handled by any transition of node %s",
+					path,
+					value,
+					node.ID,
+				),
+			})
+		}
+	}
+	return findings
+}
+
+// collectSchemaEnums maps condition paths (decision.status, decision.review.verdict, ...) to string enum values.
+func collectSchemaEnums(schema map[string]any, path string, enums map[string][]string) {
+	if rawEnum, ok := schema["enum"].([]any); ok {
+		values := make([]string, 0, len(rawEnum))
+		for _, value := range rawEnum {
+			if text, ok := value.(string); ok {
+				values = append(values, text)
+			}
+		}
+		if len(values) > 0 {
+			enums[path] = values
+		}
+	}
+
+	properties, ok := schema["properties"].(map[string]any)
+	if !ok {
+		return
+	}
+	for name, property := range properties {
+		if child, ok := property.(map[string]any); ok {
+			collectSchemaEnums(child, path+"."+name, enums)
+		}
+	}
+}
+
+type decisionComparison struct {
+	path     string
+	operator string
+	value    string
+}
+
+// decisionComparisons lists `decision.<path> ==/!= "literal"` comparisons in either operand order.
+func decisionComparisons(node *ConditionNode) []decisionComparison {
+	if node == nil || node.Type != ConditionBinary {
+		return nil
+	}
+	if node.Operator == "==" || node.Operator == "!=" {
+		path, value, ok := pathLiteralOperands(node.Left, node.Right)
+		if !ok {
+			path, value, ok = pathLiteralOperands(node.Right, node.Left)
+		}
+		if ok && len(path) > 1 && path[0] == "decision" {
+			return []decisionComparison{{path: strings.Join(path, "."), operator: node.Operator, value: value}}
+		}
+		return nil
+	}
+	return append(decisionComparisons(node.Left), decisionComparisons(node.Right)...)
+}
+
+func pathLiteralOperands(pathNode *ConditionNode, literalNode *ConditionNode) ([]string, string, bool) {
+	if pathNode.Type != ConditionPath || literalNode.Type != ConditionLiteral {
+		return nil, "", false
+	}
+	value, ok := literalNode.Value.(string)
+	return pathNode.Segments, value, ok
+}
+
+func isAlwaysTrue(node *ConditionNode) bool {
+	value, ok := node.Value.(bool)
+	return node.Type == ConditionLiteral && ok && value
+}
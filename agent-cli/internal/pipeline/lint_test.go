@@ -0,0 +1,152 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintRepoGoFlowHasNoFindings(t *testing.T) {
+	t.Parallel()
+
+	repoRoot := filepath.Join("..", "..", "..")
+	plan, err := LoadPlanFile(filepath.Join(repoRoot, "pipelines", "go-flow.yml"), LoadOptions{
+		WorkspaceDir:             repoRoot,
+		KeepTemplatePlaceholders: true,
+	})
+	if err != nil {
+		t.Fatalf("load plan: %v", err)
+	}
+
+	if findings := Lint(plan); len(findings) != 0 {
+		t.Fatalf("unexpected findings: %+v", findings)
+	}
+}
+
+func TestLintUnreachableNode(t *testing.T) {
+	t.Parallel()
+
+	plan := commandPlan(`"true"`, "run.exit_code == 0", "success") +
+		"  orphan:\n    run: {kind: command, cmd: x}\n    transitions: [{when: 'true', to: success}]\n"
+	findings := lintPlan(t, t.TempDir(), plan)
+
+	assertFindings(t, findings, []Finding{{
+		Severity: SeverityWarning,
+		Rule:     RuleUnreachableNode,
+		NodeID:   "orphan",
+		Line:     11,
+		Message:  "node orphan is not reachable from entry node a",
+	}})
+}
+
+func TestLintDeadEndLoop(t *testing.T) {
+	t.Parallel()
+
+	plan := "version: v2\nentry: implement\nnodes:\n" +
+		"  implement:\n    run: {kind: command, cmd: x}\n" +
+		"    transitions:\n      - when: 'run.exit_code == 0'\n        to: review\n" +
+		"  review:\n    run: {kind: command, cmd: y}\n" +
+		"    transitions:\n" +
+		"      - when: 'run.status == \"error\" && node.attempt > 3'\n        to: fail\n" +
+		"      - when: 'true'\n        to: implement\n"
+	findings := lintPlan(t, t.TempDir(), plan)
+
+	if len(findings) != 2 {
+		t.Fatalf("unexpected findings: %+v", findings)
+	}
+	for index, want := range []struct {
+		nodeID string
+		line   int
+	}{{"implement", 4}, {"review", 9}} {
+		finding := findings[index]
+		if finding.Rule != RuleNoTerminalPath || finding.Severity != SeverityError ||
+			finding.NodeID != want.nodeID || finding.Line != want.line {
+			t.Fatalf("unexpected finding: %+v", finding)
+		}
+		if !strings.Contains(finding.Message, "loops until limits.max_iterations (100)") {
+			t.Fatalf("unexpected message: %q", finding.Message)
+		}
+	}
+}
+
+func TestLintDecisionEnumCoverage(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	schema := `{"type":"object","properties":{` +
+		`"status":{"type":"string","enum":["done","fixes_needed","failed"]},` +
+		`"reason":{"type":"string","enum":["a","b"]}}}`
+	if err := os.WriteFile(filepath.Join(workspace, "review.json"), []byte(schema), 0o644); err != nil {
+		t.Fatalf("write schema: %v", err)
+	}
+
+	plan := "version: v2\nentry: review\nnodes:\n" +
+		"  review:\n    run:\n      kind: agent\n      prompt: review\n      decision: {schema_file: review.json}\n" +
+		"    transitions:\n" +
+		"      - when: 'decision.status == \"done\"'\n        to: success\n" +
+		"      - when: '\"failed\" == decision.status || decision.status == \"rejected\"'\n        to: fail\n"
+	findings := lintPlan(t, workspace, plan)
+
+	assertFindings(t, findings, []Finding{
+		{
+			Severity: SeverityWarning,
+			Rule:     RuleUnhandledEnum,
+			NodeID:   "review",
+			Line:     4,
+			Message:  `decision.status value "fixes_needed" is not handled by any transition of node review`,
+		},
+		{
+			Severity: SeverityError,
+			Rule:     RuleUnknownEnumValue,
+			NodeID:   "review",
+			Line:     12,
+			Message: `transition "\"failed\" == decision.status || decision.status == \"rejected\"" compares ` +
+				`decision.status with "rejected", which is not in the schema enum (done, fixes_needed, failed)`,
+		},
+	})
+}
+
+func TestLintDecisionEnumCoveredByCatchAll(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	schema := `{"type":"object","properties":{"status":{"enum":["done","retry","failed"]}}}`
+	if err := os.WriteFile(filepath.Join(workspace, "schema.json"), []byte(schema), 0o644); err != nil {
+		t.Fatalf("write schema: %v", err)
+	}
+
+	for _, fallback := range []string{`'true'`, `'decision.status != "done"'`} {
+		plan := "version: v2\nentry: a\nnodes:\n" +
+			"  a:\n    run:\n      kind: agent\n      prompt: hi\n      decision: {schema_file: schema.json}\n" +
+			"    transitions:\n" +
+			"      - when: 'decision.status == \"done\"'\n        to: success\n" +
+			"      - when: " + fallback + "\n        to: fail\n"
+		if findings := lintPlan(t, workspace, plan); len(findings) != 0 {
+			t.Fatalf("unexpected findings for %s: %+v", fallback, findings)
+		}
+	}
+}
+
+func lintPlan(t *testing.T, workspace string, content string) []Finding {
+	t.Helper()
+
+	plan, err := ParsePlan([]byte(content), LoadOptions{WorkspaceDir: workspace})
+	if err != nil {
+		t.Fatalf("parse plan: %v", err)
+	}
+	return Lint(plan)
+}
+
+func assertFindings(t *testing.T, got []Finding, want []Finding) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("unexpected findings: got=%+v want=%+v", got, want)
+	}
+	for index := range want {
+		if got[index] != want[index] {
+			t.Fatalf("unexpected finding %d:\n got=%+v\nwant=%+v", index, got[index], want[index])
+		}
+	}
+}
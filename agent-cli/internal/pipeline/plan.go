@@ -0,0 +1,754 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Plan validation mirrors images/entrypoint/src/lib/pipeline-plan.ts so that plan errors
+// surface on the host before a container starts.
+
+const (
+	Version = "v2"
+
+	BuiltinSuccessNodeID = "success"
+	BuiltinFailNodeID    = "fail"
+
+	RunKindAgent   = "agent"
+	RunKindCommand = "command"
+
+	TerminalStatusSuccess  = "success"
+	TerminalStatusBlocked  = "blocked"
+	TerminalStatusFailed   = "failed"
+	TerminalStatusCanceled = "canceled"
+
+	DefaultModel               = "opus"
+	DefaultAgentIdleTimeoutSec = 1800
+	DefaultCommandTimeoutSec   = 1800
+	DefaultMaxIterations       = 100
+	DefaultMaxSameNodeHits     = 25
+
+	ContainerWorkspaceDir    = "/workspace"
+	DefaultErrorFallbackWhen = `run.status == "error"`
+
+	maxExitCode = 255
+)
+
+var templatePlaceholderPattern = regexp.MustCompile(`\{\{([A-Z][A-Z0-9_]*)\}\}`)
+
+// Plan is a validated v2 pipeline plan.
+type Plan struct {
+	Version   string
+	EntryNode string
+	Defaults  Defaults
+	Limits    Limits
+	// NodeOrder lists node ids in plan file order; implicit built-in nodes are not included.
+	NodeOrder []string
+	Nodes     map[string]*Node
+}
+
+type Defaults struct {
+	Model               string
+	AgentIdleTimeoutSec int
+	CommandTimeoutSec   int
+}
+
+type Limits struct {
+	MaxIterations   int
+	MaxSameNodeHits int
+}
+
+// Node is either terminal (Terminal=true) or executable (Run and Transitions set).
+type Node struct {
+	ID             string
+	Terminal       bool
+	TerminalStatus string
+	ExitCode       int
+	Message        string
+	// Builtin is true for success/fail nodes added because the plan did not define them.
+	Builtin     bool
+	Run         *NodeRun
+	Transitions []Transition
+	// Line is the plan file line of the node key; 0 for implicit built-in nodes.
+	Line int
+}
+
+type NodeRun struct {
+	Kind string
+
+	// Agent runs.
+	Model          string
+	PromptText     string
+	PromptFile     string
+	IdleTimeoutSec int
+	SchemaFile     string
+	Schema         map[string]any
+
+	// Command runs.
+	Cmd        string
+	CWD        string
+	TimeoutSec int
+}
+
+type Transition struct {
+	When      string
+	To        string
+	Condition *Condition
+	// Implicit is true for the `run.status == "error" -> fail` fallback added by the loader.
+	Implicit bool
+	// Line is the plan file line of the transition entry; 0 for implicit transitions.
+	Line int
+}
+
+// LoadOptions controls how plan files are resolved and validated.
+type LoadOptions struct {
+	// WorkspaceDir is the host directory mounted as the container workspace.
+	// prompt_file and decision.schema_file paths are resolved against it.
+	WorkspaceDir  string
+	TemplateVars  map[string]string
+	FallbackModel string
+	// KeepTemplatePlaceholders leaves {{VAR}} placeholders without a value in place instead of failing.
+	KeepTemplatePlaceholders bool
+}
+
+// LoadPlanFile reads, parses and validates a pipeline plan file.
+func LoadPlanFile(planPath string, opts LoadOptions) (*Plan, error) {
+	content, err := os.ReadFile(planPath)
+	if err != nil {
+		return nil, fmt.Errorf("read pipeline file %s: %w", planPath, err)
+	}
+	if strings.TrimSpace(string(content)) == "" {
+		return nil, fmt.Errorf("pipeline file is empty: %s", planPath)
+	}
+	return ParsePlan(content, opts)
+}
+
+// ParsePlan parses and validates pipeline plan YAML.
+func ParsePlan(content []byte, opts LoadOptions) (*Plan, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML plan: %w", err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("pipeline plan root must be a YAML mapping/object")
+	}
+	root := document.Content[0]
+
+	var raw map[string]any
+	if err := root.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML plan: %w", err)
+	}
+
+	loader := &planLoader{
+		opts:             opts,
+		usedTemplateVars: map[string]bool{},
+	}
+	plan, err := loader.load(raw, mappingKeyOrder(root, "nodes"))
+	if err != nil {
+		return nil, err
+	}
+	annotateSourceLines(plan, root)
+	return plan, nil
+}
+
+type planLoader struct {
+	opts             LoadOptions
+	defaults         Defaults
+	usedTemplateVars map[string]bool
+}
+
+func (l *planLoader) load(raw map[string]any, nodeOrder []string) (*Plan, error) {
+	version, err := requireNonEmptyString(valueOr(raw["version"], ""), "version")
+	if err != nil {
+		return nil, err
+	}
+	if version != Version {
+		return nil, fmt.Errorf("unsupported plan version: %s. Expected %s", version, Version)
+	}
+
+	entryNode, err := requireNonEmptyString(raw["entry"], "entry")
+	if err != nil {
+		return nil, err
+	}
+	if isBuiltinNodeID(entryNode) {
+		return nil, fmt.Errorf("entry must not be built-in terminal node: %s", entryNode)
+	}
+
+	l.defaults, err = resolveDefaults(raw, l.opts.FallbackModel)
+	if err != nil {
+		return nil, err
+	}
+	limits := resolveLimits(raw)
+
+	rawNodes, err := requireObject(raw["nodes"], "nodes")
+	if err != nil {
+		return nil, err
+	}
+	if len(rawNodes) == 0 {
+		return nil, errors.New("nodes must define at least one node")
+	}
+
+	plan := &Plan{
+		Version:   Version,
+		EntryNode: entryNode,
+		Defaults:  l.defaults,
+		Limits:    limits,
+		NodeOrder: make([]string, 0, len(rawNodes)),
+		Nodes:     map[string]*Node{},
+	}
+
+	for _, rawID := range nodeOrder {
+		nodeID, err := requireNonEmptyString(rawID, "node id")
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := plan.Nodes[nodeID]; exists {
+			return nil, fmt.Errorf("duplicate node id: %s", nodeID)
+		}
+		plan.NodeOrder = append(plan.NodeOrder, nodeID)
+
+		node, err := l.resolveNode(nodeID, rawNodes[rawID])
+		if err != nil {
+			return nil, err
+		}
+		if isBuiltinNodeID(nodeID) && !node.Terminal {
+			return nil, fmt.Errorf("nodes.%s must be a terminal node when overriding built-in node", nodeID)
+		}
+		plan.Nodes[nodeID] = node
+	}
+
+	if _, ok := plan.Nodes[entryNode]; !ok {
+		return nil, fmt.Errorf("entry node is not defined in nodes: %s", entryNode)
+	}
+
+	ensureBuiltinTerminalNodes(plan.Nodes)
+
+	for _, nodeID := range plan.NodeOrder {
+		node := plan.Nodes[nodeID]
+		if node.Terminal {
+			continue
+		}
+		ensureErrorFallbackTransition(node)
+		for _, transition := range node.Transitions {
+			if _, ok := plan.Nodes[transition.To]; !ok {
+				return nil, fmt.Errorf("nodes.%s.transitions has unknown target node: %s", node.ID, transition.To)
+			}
+		}
+	}
+
+	unused := make([]string, 0)
+	for name := range l.opts.TemplateVars {
+		if !l.usedTemplateVars[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return nil, fmt.Errorf("unused template vars: %s", strings.Join(unused, ", "))
+	}
+
+	return plan, nil
+}
+
+func (l *planLoader) resolveNode(nodeID string, rawNode any) (*Node, error) {
+	node, err := requireObject(rawNode, "nodes."+nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if terminal, ok := node["terminal"].(bool); ok && terminal {
+		return resolveTerminalNode(nodeID, node)
+	}
+
+	if value, present := node["terminal"]; present {
+		if terminal, ok := value.(bool); !ok || terminal {
+			return nil, fmt.Errorf("nodes.%s.terminal must be boolean", nodeID)
+		}
+	}
+
+	for _, key := range []string{"exit_code", "terminal_status", "message"} {
+		if _, present := node[key]; present {
+			return nil, fmt.Errorf("nodes.%s terminal fields are allowed only when terminal=true", nodeID)
+		}
+	}
+
+	rawRun, err := requireObject(node["run"], "nodes."+nodeID+".run")
+	if err != nil {
+		return nil, err
+	}
+	kind, err := requireNonEmptyString(rawRun["kind"], "nodes."+nodeID+".run.kind")
+	if err != nil {
+		return nil, err
+	}
+
+	var run *NodeRun
+	switch strings.ToLower(kind) {
+	case RunKindAgent:
+		run, err = l.resolveAgentRun(nodeID, rawRun)
+	case RunKindCommand:
+		run, err = l.resolveCommandRun(nodeID, rawRun)
+	default:
+		err = fmt.Errorf("nodes.%s.run.kind must be one of: agent, command", nodeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	transitions, err := parseTransitions(node["transitions"], "nodes."+nodeID+".transitions")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Node{
+		ID:          nodeID,
+		Run:         run,
+		Transitions: transitions,
+	}, nil
+}
+
+func resolveTerminalNode(nodeID string, node map[string]any) (*Node, error) {
+	if _, present := node["run"]; present {
+		return nil, fmt.Errorf("nodes.%s.run is forbidden for terminal node", nodeID)
+	}
+	if _, present := node["transitions"]; present {
+		return nil, fmt.Errorf("nodes.%s.transitions is forbidden for terminal node", nodeID)
+	}
+
+	status, err := normalizeTerminalStatus(node["terminal_status"], "nodes."+nodeID+".terminal_status")
+	if err != nil {
+		return nil, err
+	}
+	exitCode, err := parseExitCode(node["exit_code"], "nodes."+nodeID+".exit_code")
+	if err != nil {
+		return nil, err
+	}
+	message, _ := node["message"].(string)
+
+	return &Node{
+		ID:             nodeID,
+		Terminal:       true,
+		TerminalStatus: status,
+		ExitCode:       exitCode,
+		Message:        strings.TrimSpace(message),
+	}, nil
+}
+
+func (l *planLoader) resolveAgentRun(nodeID string, rawRun map[string]any) (*NodeRun, error) {
+	prompt, _ := rawRun["prompt"].(string)
+	prompt = strings.TrimSpace(prompt)
+	promptFile, _ := rawRun["prompt_file"].(string)
+	promptFile = strings.TrimSpace(promptFile)
+
+	if (prompt == "") == (promptFile == "") {
+		return nil, fmt.Errorf("nodes.%s.run must contain exactly one of: prompt, prompt_file", nodeID)
+	}
+
+	run := &NodeRun{Kind: RunKindAgent}
+	if promptFile != "" {
+		normalized, err := normalizeWorkspaceFilePath(promptFile, "nodes."+nodeID+".run.prompt_file")
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(l.hostPath(normalized))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt_file for %s (%s): %w", nodeID, normalized, err)
+		}
+		run.PromptFile = normalized
+		run.PromptText = strings.TrimSpace(string(content))
+	} else {
+		text, err := l.applyInlineTemplate(prompt, nodeID)
+		if err != nil {
+			return nil, err
+		}
+		run.PromptText = text
+	}
+	if strings.TrimSpace(run.PromptText) == "" {
+		return nil, fmt.Errorf("prompt is empty for node %s", nodeID)
+	}
+
+	decision, err := requireObject(rawRun["decision"], "nodes."+nodeID+".run.decision")
+	if err != nil {
+		return nil, err
+	}
+	schemaFile, err := requireNonEmptyString(decision["schema_file"], "nodes."+nodeID+".run.decision.schema_file")
+	if err != nil {
+		return nil, err
+	}
+	run.SchemaFile, err = normalizeWorkspaceFilePath(schemaFile, "nodes."+nodeID+".run.decision.schema_file")
+	if err != nil {
+		return nil, err
+	}
+	run.Schema, err = l.parseDecisionSchema(run.SchemaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	run.Model, err = normalizeModel(rawRun["model"], "nodes."+nodeID+".run.model", l.defaults.Model)
+	if err != nil {
+		return nil, err
+	}
+	run.IdleTimeoutSec = parsePositiveInteger(rawRun["idle_timeout_sec"], l.defaults.AgentIdleTimeoutSec)
+	return run, nil
+}
+
+func (l *planLoader) resolveCommandRun(nodeID string, rawRun map[string]any) (*NodeRun, error) {
+	rawCommand, err := requireNonEmptyString(rawRun["cmd"], "nodes."+nodeID+".run.cmd")
+	if err != nil {
+		return nil, err
+	}
+	command, err := l.applyInlineTemplate(rawCommand, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("command is empty for node %s", nodeID)
+	}
+
+	cwd, err := resolveCommandCWD(rawRun["cwd"], "nodes."+nodeID+".run.cwd")
+	if err != nil {
+		return nil, err
+	}
+
+	return &NodeRun{
+		Kind:       RunKindCommand,
+		Cmd:        command,
+		CWD:        cwd,
+		TimeoutSec: parsePositiveInteger(rawRun["timeout_sec"], l.defaults.CommandTimeoutSec),
+	}, nil
+}
+
+func (l *planLoader) applyInlineTemplate(value string, nodeID string) (string, error) {
+	missing := map[string]bool{}
+	replaced := templatePlaceholderPattern.ReplaceAllStringFunc(value, func(match string) string {
+		name := strings.TrimSuffix(strings.TrimPrefix(match, "{{"), "}}")
+		l.usedTemplateVars[name] = true
+		replacement, ok := l.opts.TemplateVars[name]
+		if !ok {
+			missing[name] = true
+			return match
+		}
+		return replacement
+	})
+
+	if len(missing) > 0 && !l.opts.KeepTemplatePlaceholders {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("missing template vars for %s: %s", nodeID, strings.Join(names, ", "))
+	}
+	return replaced, nil
+}
+
+func (l *planLoader) parseDecisionSchema(schemaFile string) (map[string]any, error) {
+	content, err := os.ReadFile(l.hostPath(schemaFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema file %s: %w", schemaFile, err)
+	}
+	if strings.TrimSpace(string(content)) == "" {
+		return nil, fmt.Errorf("schema file is empty: %s", schemaFile)
+	}
+
+	var parsed any
+	if err := json.Unmarshal(content, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid JSON in schema file %s: %w", schemaFile, err)
+	}
+	schema, ok := parsed.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("schema file must contain a JSON object: %s", schemaFile)
+	}
+	return schema, nil
+}
+
+// hostPath maps a workspace-relative path to the host workspace directory.
+func (l *planLoader) hostPath(workspaceRelative string) string {
+	return filepath.Join(l.opts.WorkspaceDir, filepath.FromSlash(workspaceRelative))
+}
+
+func parseTransitions(rawTransitions any, fieldName string) ([]Transition, error) {
+	items, ok := rawTransitions.([]any)
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("%s must be a non-empty array", fieldName)
+	}
+
+	transitions := make([]Transition, 0, len(items)+1)
+	for index, item := range items {
+		itemField := fmt.Sprintf("%s[%d]", fieldName, index)
+		transition, err := requireObject(item, itemField)
+		if err != nil {
+			return nil, err
+		}
+		when, err := requireNonEmptyString(transition["when"], itemField+".when")
+		if err != nil {
+			return nil, err
+		}
+		to, err := requireNonEmptyString(transition["to"], itemField+".to")
+		if err != nil {
+			return nil, err
+		}
+		condition, err := CompileCondition(when)
+		if err != nil {
+			return nil, fmt.Errorf("%s.when is invalid: %w", itemField, err)
+		}
+		transitions = append(transitions, Transition{When: when, To: to, Condition: condition})
+	}
+	return transitions, nil
+}
+
+func ensureBuiltinTerminalNodes(nodes map[string]*Node) {
+	if _, ok := nodes[BuiltinSuccessNodeID]; !ok {
+		nodes[BuiltinSuccessNodeID] = &Node{
+			ID:             BuiltinSuccessNodeID,
+			Terminal:       true,
+			TerminalStatus: TerminalStatusSuccess,
+			ExitCode:       0,
+			Builtin:        true,
+		}
+	}
+	if _, ok := nodes[BuiltinFailNodeID]; !ok {
+		nodes[BuiltinFailNodeID] = &Node{
+			ID:             BuiltinFailNodeID,
+			Terminal:       true,
+			TerminalStatus: TerminalStatusFailed,
+			ExitCode:       1,
+			Builtin:        true,
+		}
+	}
+}
+
+func ensureErrorFallbackTransition(node *Node) {
+	for _, transition := range node.Transitions {
+		if transition.Condition.ContainsRunStatusErrorCheck() {
+			return
+		}
+	}
+	condition, _ := CompileCondition(DefaultErrorFallbackWhen)
+	node.Transitions = append(node.Transitions, Transition{
+		When:      DefaultErrorFallbackWhen,
+		To:        BuiltinFailNodeID,
+		Condition: condition,
+		Implicit:  true,
+	})
+}
+
+func isBuiltinNodeID(nodeID string) bool {
+	return nodeID == BuiltinSuccessNodeID || nodeID == BuiltinFailNodeID
+}
+
+func resolveDefaults(raw map[string]any, fallbackModel string) (Defaults, error) {
+	rawDefaults, _ := raw["defaults"].(map[string]any)
+	if strings.TrimSpace(fallbackModel) == "" {
+		fallbackModel = DefaultModel
+	}
+
+	model, err := normalizeModel(rawDefaults["model"], "defaults.model", fallbackModel)
+	if err != nil {
+		return Defaults{}, err
+	}
+	return Defaults{
+		Model:               model,
+		AgentIdleTimeoutSec: parsePositiveInteger(rawDefaults["agent_idle_timeout_sec"], DefaultAgentIdleTimeoutSec),
+		CommandTimeoutSec:   parsePositiveInteger(rawDefaults["command_timeout_sec"], DefaultCommandTimeoutSec),
+	}, nil
+}
+
+func resolveLimits(raw map[string]any) Limits {
+	rawLimits, _ := raw["limits"].(map[string]any)
+	return Limits{
+		MaxIterations:   parsePositiveInteger(rawLimits["max_iterations"], DefaultMaxIterations),
+		MaxSameNodeHits: parsePositiveInteger(rawLimits["max_same_node_hits"], DefaultMaxSameNodeHits),
+	}
+}
+
+func normalizeModel(value any, fieldName string, fallback string) (string, error) {
+	model := strings.ToLower(strings.TrimSpace(stringify(value)))
+	if model == "" {
+		model = strings.ToLower(strings.TrimSpace(fallback))
+	}
+	if model == "sonnet" || model == "opus" {
+		return model, nil
+	}
+	return "", fmt.Errorf("%s must be one of: sonnet, opus", fieldName)
+}
+
+func normalizeTerminalStatus(value any, fieldName string) (string, error) {
+	status, err := requireNonEmptyString(value, fieldName)
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToLower(status) {
+	case TerminalStatusSuccess, TerminalStatusBlocked, TerminalStatusFailed, TerminalStatusCanceled:
+		return strings.ToLower(status), nil
+	default:
+		return "", fmt.Errorf("%s must be one of: success, blocked, failed, canceled", fieldName)
+	}
+}
+
+func parseExitCode(value any, fieldName string) (int, error) {
+	parsed, ok := parseLeadingInteger(value)
+	if !ok || parsed < 0 || parsed > maxExitCode {
+		return 0, fmt.Errorf("%s must be an integer in range 0..255", fieldName)
+	}
+	return parsed, nil
+}
+
+func resolveCommandCWD(value any, fieldName string) (string, error) {
+	if value == nil || strings.TrimSpace(stringify(value)) == "" {
+		return ContainerWorkspaceDir, nil
+	}
+
+	raw, err := requireNonEmptyString(value, fieldName)
+	if err != nil {
+		return "", err
+	}
+	raw = strings.ReplaceAll(raw, `\`, "/")
+
+	resolved := path.Clean(raw)
+	if !path.IsAbs(raw) {
+		resolved = path.Join(ContainerWorkspaceDir, raw)
+	}
+	if resolved != ContainerWorkspaceDir && !strings.HasPrefix(resolved, ContainerWorkspaceDir+"/") {
+		return "", fmt.Errorf("%s must stay within %s: %s", fieldName, ContainerWorkspaceDir, raw)
+	}
+	return resolved, nil
+}
+
+// normalizeWorkspaceFilePath validates a path relative to the workspace and returns it normalized.
+func normalizeWorkspaceFilePath(value string, fieldName string) (string, error) {
+	trimmed := strings.ReplaceAll(strings.TrimSpace(value), `\`, "/")
+	if trimmed == "" {
+		return "", fmt.Errorf("%s must not be empty", fieldName)
+	}
+	if path.IsAbs(trimmed) {
+		return "", fmt.Errorf("%s must be relative to %s: %s", fieldName, ContainerWorkspaceDir, trimmed)
+	}
+
+	normalized := path.Clean(trimmed)
+	if normalized == "." || normalized == ".." || strings.HasPrefix(normalized, "../") {
+		return "", fmt.Errorf("%s must stay within %s: %s", fieldName, ContainerWorkspaceDir, trimmed)
+	}
+	return normalized, nil
+}
+
+func requireObject(value any, fieldName string) (map[string]any, error) {
+	object, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object", fieldName)
+	}
+	return object, nil
+}
+
+func requireNonEmptyString(value any, fieldName string) (string, error) {
+	text, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", fieldName)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("%s must not be empty", fieldName)
+	}
+	return text, nil
+}
+
+// parsePositiveInteger follows the entrypoint: invalid or non-positive values fall back silently.
+func parsePositiveInteger(value any, fallback int) int {
+	parsed, ok := parseLeadingInteger(value)
+	if !ok || parsed <= 0 {
+		return fallback
+	}
+	return parsed
+}
+
+var leadingIntegerPattern = regexp.MustCompile(`^[+-]?[0-9]+`)
+
+// parseLeadingInteger mimics JavaScript parseInt(String(value), 10).
+func parseLeadingInteger(value any) (int, bool) {
+	match := leadingIntegerPattern.FindString(strings.TrimSpace(stringify(value)))
+	if match == "" {
+		return 0, false
+	}
+	parsed, err := strconv.Atoi(match)
+	if err != nil {
+		return 0, false
+	}
+	return parsed, true
+}
+
+func stringify(value any) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	default:
+		return fmt.Sprint(typed)
+	}
+}
+
+func valueOr(value any, fallback any) any {
+	if value == nil {
+		return fallback
+	}
+	return value
+}
+
+// mappingKeyOrder returns the keys of the mapping stored under key in plan file order.
+func mappingKeyOrder(root *yaml.Node, key string) []string {
+	mapping := mappingValue(root, key)
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	keys := make([]string, 0, len(mapping.Content)/2)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keys = append(keys, mapping.Content[i].Value)
+	}
+	return keys
+}
+
+// annotateSourceLines records plan file line numbers for nodes and their explicit transitions.
+func annotateSourceLines(plan *Plan, root *yaml.Node) {
+	nodes := mappingValue(root, "nodes")
+	if nodes == nil || nodes.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(nodes.Content); i += 2 {
+		node, ok := plan.Nodes[nodes.Content[i].Value]
+		if !ok {
+			continue
+		}
+		node.Line = nodes.Content[i].Line
+
+		transitions := mappingValue(nodes.Content[i+1], "transitions")
+		if transitions == nil || transitions.Kind != yaml.SequenceNode {
+			continue
+		}
+		for index, item := range transitions.Content {
+			if index < len(node.Transitions) && !node.Transitions[index].Implicit {
+				node.Transitions[index].Line = item.Line
+			}
+		}
+	}
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
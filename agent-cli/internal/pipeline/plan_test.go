@@ -0,0 +1,224 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPlanFileRepoGoFlow(t *testing.T) {
+	t.Parallel()
+
+	repoRoot := filepath.Join("..", "..", "..")
+	plan, err := LoadPlanFile(filepath.Join(repoRoot, "pipelines", "go-flow.yml"), LoadOptions{
+		WorkspaceDir: repoRoot,
+		TemplateVars: map[string]string{"TASK": "42"},
+	})
+	if err != nil {
+		t.Fatalf("load plan: %v", err)
+	}
+
+	if plan.EntryNode != "prepare_branch" {
+		t.Fatalf("unexpected entry node: %q", plan.EntryNode)
+	}
+	if strings.Join(plan.NodeOrder, ",") != "prepare_branch,issue_gate,planner,implementer,reviewer" {
+		t.Fatalf("unexpected node order: %v", plan.NodeOrder)
+	}
+	if plan.Limits.MaxIterations != 300 || plan.Limits.MaxSameNodeHits != 200 {
+		t.Fatalf("unexpected limits: %+v", plan.Limits)
+	}
+	if plan.Nodes["prepare_branch"].Run.Cmd != "node ./pipelines/scripts/prepare-branch.js 42" {
+		t.Fatalf("unexpected cmd: %q", plan.Nodes["prepare_branch"].Run.Cmd)
+	}
+	if plan.Nodes["prepare_branch"].Run.CWD != "/workspace" {
+		t.Fatalf("unexpected cwd: %q", plan.Nodes["prepare_branch"].Run.CWD)
+	}
+	if plan.Nodes["reviewer"].Run.SchemaFile != "pipelines/schemas/reviewer.schema.json" {
+		t.Fatalf("unexpected schema file: %q", plan.Nodes["reviewer"].Run.SchemaFile)
+	}
+	if !plan.Nodes[BuiltinSuccessNodeID].Builtin || !plan.Nodes[BuiltinFailNodeID].Builtin {
+		t.Fatal("expected built-in terminal nodes")
+	}
+
+	planner := plan.Nodes["planner"]
+	if last := planner.Transitions[len(planner.Transitions)-1]; last.Implicit {
+		t.Fatalf("planner already checks run.status, got implicit fallback: %+v", last)
+	}
+	reviewer := plan.Nodes["reviewer"]
+	last := reviewer.Transitions[len(reviewer.Transitions)-1]
+	if !last.Implicit || last.To != BuiltinFailNodeID || last.When != DefaultErrorFallbackWhen {
+		t.Fatalf("unexpected fallback transition: %+v", last)
+	}
+}
+
+func TestParsePlanErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		plan    string
+		vars    map[string]string
+		wantErr string
+	}{
+		{
+			name:    "version",
+			plan:    "version: v1\nentry: a\nnodes: {}\n",
+			wantErr: "unsupported plan version: v1",
+		},
+		{
+			name:    "builtin entry",
+			plan:    "version: v2\nentry: success\nnodes: {}\n",
+			wantErr: "entry must not be built-in terminal node: success",
+		},
+		{
+			name:    "missing entry node",
+			plan:    "version: v2\nentry: a\nnodes:\n  b:\n    terminal: true\n    terminal_status: success\n    exit_code: 0\n",
+			wantErr: "entry node is not defined in nodes: a",
+		},
+		{
+			name:    "unknown target",
+			plan:    commandPlan(`"true"`, "run.exit_code == 0", "nowhere"),
+			wantErr: "nodes.a.transitions has unknown target node: nowhere",
+		},
+		{
+			name:    "bad when",
+			plan:    commandPlan(`"true"`, "run.exit_code ==", "success"),
+			wantErr: "nodes.a.transitions[0].when is invalid: unexpected end of expression",
+		},
+		{
+			name:    "missing var",
+			plan:    commandPlan(`"echo {{TASK}} {{OTHER}}"`, "run.exit_code == 0", "success"),
+			vars:    map[string]string{"TASK": "1"},
+			wantErr: "missing template vars for a: OTHER",
+		},
+		{
+			name:    "unused var",
+			plan:    commandPlan(`"echo {{TASK}}"`, "run.exit_code == 0", "success"),
+			vars:    map[string]string{"TASK": "1", "EXTRA": "x", "ALSO": "y"},
+			wantErr: "unused template vars: ALSO, EXTRA",
+		},
+		{
+			name: "terminal status",
+			plan: "version: v2\nentry: a\nnodes:\n  a:\n    terminal: true\n" +
+				"    terminal_status: done\n    exit_code: 0\n",
+			wantErr: "nodes.a.terminal_status must be one of: success, blocked, failed, canceled",
+		},
+		{
+			name: "exit code range",
+			plan: "version: v2\nentry: a\nnodes:\n  a:\n    terminal: true\n" +
+				"    terminal_status: failed\n    exit_code: 256\n",
+			wantErr: "nodes.a.exit_code must be an integer in range 0..255",
+		},
+		{
+			name: "terminal with run",
+			plan: "version: v2\nentry: a\nnodes:\n  a:\n    terminal: true\n" +
+				"    terminal_status: failed\n    exit_code: 1\n    run: {kind: command, cmd: x}\n",
+			wantErr: "nodes.a.run is forbidden for terminal node",
+		},
+		{
+			name:    "non terminal builtin override",
+			plan:    commandPlan(`"true"`, "run.exit_code == 0", "success") + "  success:\n    run: {kind: command, cmd: x}\n    transitions: [{when: 'true', to: fail}]\n",
+			wantErr: "nodes.success must be a terminal node when overriding built-in node",
+		},
+		{
+			name:    "terminal fields on executable node",
+			plan:    commandPlan(`"true"`, "run.exit_code == 0", "success") + "    exit_code: 3\n",
+			wantErr: "nodes.a terminal fields are allowed only when terminal=true",
+		},
+		{
+			name: "schema outside workspace",
+			plan: "version: v2\nentry: a\nnodes:\n  a:\n    run:\n      kind: agent\n      prompt: hi\n" +
+				"      decision: {schema_file: ../schema.json}\n    transitions: [{when: 'true', to: success}]\n",
+			wantErr: "nodes.a.run.decision.schema_file must stay within /workspace: ../schema.json",
+		},
+		{
+			name: "missing schema file",
+			plan: "version: v2\nentry: a\nnodes:\n  a:\n    run:\n      kind: agent\n      prompt: hi\n" +
+				"      decision: {schema_file: missing.json}\n    transitions: [{when: 'true', to: success}]\n",
+			wantErr: "failed to read schema file missing.json",
+		},
+		{
+			name: "prompt and prompt_file",
+			plan: "version: v2\nentry: a\nnodes:\n  a:\n    run:\n      kind: agent\n      prompt: hi\n" +
+				"      prompt_file: p.md\n    transitions: [{when: 'true', to: success}]\n",
+			wantErr: "nodes.a.run must contain exactly one of: prompt, prompt_file",
+		},
+		{
+			name: "command cwd outside workspace",
+			plan: "version: v2\nentry: a\nnodes:\n  a:\n    run: {kind: command, cmd: x, cwd: /tmp}\n" +
+				"    transitions: [{when: 'true', to: success}]\n",
+			wantErr: "nodes.a.run.cwd must stay within /workspace: /tmp",
+		},
+		{
+			name:    "duplicate node id",
+			plan:    commandPlan(`"true"`, "run.exit_code == 0", "success") + "  a:\n    terminal: true\n",
+			wantErr: `mapping key "a" already defined`,
+		},
+		{
+			name:    "empty transitions",
+			plan:    "version: v2\nentry: a\nnodes:\n  a:\n    run: {kind: command, cmd: x}\n    transitions: []\n",
+			wantErr: "nodes.a.transitions must be a non-empty array",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParsePlan([]byte(tt.plan), LoadOptions{WorkspaceDir: t.TempDir(), TemplateVars: tt.vars})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestParsePlanSchemaMustBeJSONObject(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "schema.json"), []byte(`["status"]`), 0o644); err != nil {
+		t.Fatalf("write schema: %v", err)
+	}
+
+	plan := "version: v2\nentry: a\nnodes:\n  a:\n    run:\n      kind: agent\n      prompt: hi\n" +
+		"      decision: {schema_file: ./schema.json}\n    transitions: [{when: 'true', to: success}]\n"
+	_, err := ParsePlan([]byte(plan), LoadOptions{WorkspaceDir: workspace})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "schema file must contain a JSON object: schema.json") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParsePlanOverriddenBuiltinTerminal(t *testing.T) {
+	t.Parallel()
+
+	plan := commandPlan(`"true"`, "run.exit_code == 0", "success") +
+		"  success:\n    terminal: true\n    terminal_status: blocked\n    exit_code: \"3\"\n    message: ' waiting '\n"
+	parsed, err := ParsePlan([]byte(plan), LoadOptions{WorkspaceDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("parse plan: %v", err)
+	}
+
+	success := parsed.Nodes[BuiltinSuccessNodeID]
+	if success.Builtin || success.TerminalStatus != TerminalStatusBlocked || success.ExitCode != 3 {
+		t.Fatalf("unexpected success node: %+v", success)
+	}
+	if success.Message != "waiting" {
+		t.Fatalf("unexpected message: %q", success.Message)
+	}
+	if parsed.Limits.MaxIterations != DefaultMaxIterations || parsed.Defaults.Model != DefaultModel {
+		t.Fatalf("unexpected defaults: %+v %+v", parsed.Limits, parsed.Defaults)
+	}
+}
+
+func commandPlan(cmd string, when string, to string) string {
+	return "version: v2\nentry: a\nnodes:\n  a:\n    run:\n      kind: command\n      cmd: " + cmd +
+		"\n    transitions:\n      - when: '" + when + "'\n        to: " + to + "\n"
+}
@@ -0,0 +1,118 @@
+package pipeline
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ValidateDecision checks a decision payload against the JSON Schema subset the entrypoint enforces
+// (type, enum, required, properties, additionalProperties=false, items). value must be JSON-shaped.
+// Errors are returned in a stable order.
+func ValidateDecision(schema map[string]any, value any) []string {
+	errs := make([]string, 0)
+	validateSchemaNode(schema, value, "decision", &errs)
+	return errs
+}
+
+func validateSchemaNode(schemaNode any, value any, pathName string, errs *[]string) {
+	node, ok := schemaNode.(map[string]any)
+	if !ok {
+		*errs = append(*errs, pathName+": schema node must be an object")
+		return
+	}
+
+	schemaType, _ := node["type"].(string)
+	if schemaType != "" {
+		if message, ok := schemaTypeMismatch(schemaType, value); ok {
+			*errs = append(*errs, pathName+": "+message)
+			return
+		}
+	}
+
+	if candidates, ok := node["enum"].([]any); ok {
+		matched := false
+		for _, candidate := range candidates {
+			if jsonText(candidate) == jsonText(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			*errs = append(*errs, pathName+": value is not in enum")
+			return
+		}
+	}
+
+	switch schemaType {
+	case "object":
+		object := value.(map[string]any)
+		required, _ := node["required"].([]any)
+		for _, field := range required {
+			name, ok := field.(string)
+			if !ok {
+				continue
+			}
+			if _, present := object[name]; !present {
+				*errs = append(*errs, pathName+"."+name+": missing required field")
+			}
+		}
+
+		properties, _ := node["properties"].(map[string]any)
+		for _, name := range sortedKeys(properties) {
+			if fieldValue, present := object[name]; present {
+				validateSchemaNode(properties[name], fieldValue, pathName+"."+name, errs)
+			}
+		}
+
+		if additional, ok := node["additionalProperties"].(bool); ok && !additional {
+			for _, name := range sortedKeys(object) {
+				if _, allowed := properties[name]; !allowed {
+					*errs = append(*errs, pathName+"."+name+": additional property is not allowed")
+				}
+			}
+		}
+	case "array":
+		if items, ok := node["items"].(map[string]any); ok {
+			for index, item := range value.([]any) {
+				validateSchemaNode(items, item, pathName+"["+strconv.Itoa(index)+"]", errs)
+			}
+		}
+	}
+}
+
+// schemaTypeMismatch returns the error message when value does not match schemaType.
+func schemaTypeMismatch(schemaType string, value any) (string, bool) {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]any)
+		return "expected object", !ok
+	case "array":
+		_, ok := value.([]any)
+		return "expected array", !ok
+	case "string":
+		_, ok := value.(string)
+		return "expected string", !ok
+	case "number":
+		_, ok := value.(float64)
+		return "expected number", !ok
+	case "integer":
+		number, ok := value.(float64)
+		return "expected integer", !ok || number != math.Trunc(number)
+	case "boolean":
+		_, ok := value.(bool)
+		return "expected boolean", !ok
+	case "null":
+		return "expected null", value != nil
+	}
+	return "unsupported schema type " + schemaType, true
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
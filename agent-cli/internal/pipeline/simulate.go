@@ -0,0 +1,423 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Pipeline exit codes for runs that end without reaching a terminal node, as set by the entrypoint executor.
+const (
+	ExitCodeNoTransition  = 3
+	ExitCodeLimitReached  = 4
+	ExitCodeNodeExecution = 5
+)
+
+const (
+	nodeRunStatusSuccess = "success"
+	nodeRunStatusError   = "error"
+)
+
+// SimulationScript is a table of scenarios for Simulate, loaded from YAML.
+type SimulationScript struct {
+	Scenarios []SimulationScenario `yaml:"scenarios"`
+}
+
+// SimulationScenario scripts node run outcomes for one walk through a plan.
+type SimulationScenario struct {
+	Name string `yaml:"name"`
+	// Nodes maps node ids to outcomes consumed one per visit.
+	Nodes  map[string]SimulatedOutcomes `yaml:"nodes"`
+	Expect *SimulationExpectation       `yaml:"expect"`
+}
+
+// SimulatedOutcomes is either a single outcome used for every visit (YAML mapping)
+// or a list with one outcome per visit (YAML sequence).
+type SimulatedOutcomes struct {
+	Visits []SimulatedOutcome
+	Repeat bool
+}
+
+// SimulatedOutcome replaces one node run: a command exit or an agent's structured decision.
+type SimulatedOutcome struct {
+	ExitCode int            `yaml:"exit_code"`
+	Signal   string         `yaml:"signal"`
+	TimedOut bool           `yaml:"timed_out"`
+	Decision map[string]any `yaml:"decision"`
+	// Status forces run.status (success|error); by default it is derived like the executor does.
+	Status string `yaml:"status"`
+}
+
+// SimulationExpectation is checked against the SimulationResult; empty fields are not checked.
+type SimulationExpectation struct {
+	Terminal       string   `yaml:"terminal"`
+	TerminalStatus string   `yaml:"terminal_status"`
+	ExitCode       *int     `yaml:"exit_code"`
+	Path           []string `yaml:"path"`
+	// Error is a substring of the expected limit/no-transition error.
+	Error string `yaml:"error"`
+}
+
+// SimulationStep is one simulated node run and the transition it took.
+type SimulationStep struct {
+	Iteration int            `json:"iteration"`
+	NodeID    string         `json:"node_id"`
+	NodeRunID string         `json:"node_run_id"`
+	Kind      string         `json:"kind"`
+	Attempt   int            `json:"attempt"`
+	Status    string         `json:"status"`
+	ExitCode  int            `json:"exit_code"`
+	Signal    string         `json:"signal,omitempty"`
+	TimedOut  bool           `json:"timed_out,omitempty"`
+	Decision  map[string]any `json:"decision,omitempty"`
+	Errors    []string       `json:"errors,omitempty"`
+	When      string         `json:"when,omitempty"`
+	To        string         `json:"to,omitempty"`
+}
+
+// SimulationResult is the outcome of one scenario.
+type SimulationResult struct {
+	Name           string           `json:"name"`
+	Steps          []SimulationStep `json:"steps"`
+	Path           []string         `json:"path"`
+	Iterations     int              `json:"iterations"`
+	TerminalNode   string           `json:"terminal_node,omitempty"`
+	TerminalStatus string           `json:"terminal_status,omitempty"`
+	ExitCode       int              `json:"exit_code"`
+	Error          string           `json:"error,omitempty"`
+	// Failures lists unmet expectations and script errors.
+	Failures []string `json:"failures"`
+}
+
+func (r *SimulationResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+var simulatedOutcomeFields = []string{"exit_code", "signal", "timed_out", "decision", "status"}
+
+func (o *SimulatedOutcomes) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.MappingNode:
+		outcome, err := decodeSimulatedOutcome(value)
+		if err != nil {
+			return err
+		}
+		o.Visits = []SimulatedOutcome{outcome}
+		o.Repeat = true
+		return nil
+	case yaml.SequenceNode:
+		o.Visits = make([]SimulatedOutcome, 0, len(value.Content))
+		for _, item := range value.Content {
+			outcome, err := decodeSimulatedOutcome(item)
+			if err != nil {
+				return err
+			}
+			o.Visits = append(o.Visits, outcome)
+		}
+		return nil
+	}
+	return fmt.Errorf("line %d: node outcomes must be a mapping or a list of mappings", value.Line)
+}
+
+// decodeSimulatedOutcome decodes one outcome, rejecting unknown fields (custom unmarshalers
+// do not inherit the decoder's KnownFields setting).
+func decodeSimulatedOutcome(value *yaml.Node) (SimulatedOutcome, error) {
+	if value.Kind != yaml.MappingNode {
+		return SimulatedOutcome{}, fmt.Errorf("line %d: node outcome must be a mapping", value.Line)
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key := value.Content[i]
+		if !slices.Contains(simulatedOutcomeFields, key.Value) {
+			return SimulatedOutcome{}, fmt.Errorf(
+				"line %d: field %s not found in node outcome (expected %s)",
+				key.Line,
+				key.Value,
+				strings.Join(simulatedOutcomeFields, ", "),
+			)
+		}
+	}
+
+	var outcome SimulatedOutcome
+	err := value.Decode(&outcome)
+	return outcome, err
+}
+
+// LoadSimulationScript reads a simulation file. A file without `scenarios` is a single scenario.
+func LoadSimulationScript(path string) (*SimulationScript, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read simulation script %s: %w", path, err)
+	}
+	return ParseSimulationScript(content)
+}
+
+// ParseSimulationScript parses simulation YAML; unknown fields are rejected.
+func ParseSimulationScript(content []byte) (*SimulationScript, error) {
+	var raw struct {
+		SimulationScenario `yaml:",inline"`
+		Scenarios          []SimulationScenario `yaml:"scenarios"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse simulation script: %w", err)
+	}
+
+	script := &SimulationScript{Scenarios: raw.Scenarios}
+	single := raw.SimulationScenario
+	if len(single.Nodes) > 0 || single.Expect != nil || single.Name != "" {
+		if len(script.Scenarios) > 0 {
+			return nil, errors.New("simulation script must define either scenarios or a single top-level scenario, not both")
+		}
+		script.Scenarios = []SimulationScenario{single}
+	}
+	if len(script.Scenarios) == 0 {
+		return nil, errors.New("simulation script must define at least one scenario")
+	}
+
+	for index := range script.Scenarios {
+		scenario := &script.Scenarios[index]
+		if strings.TrimSpace(scenario.Name) == "" {
+			scenario.Name = fmt.Sprintf("scenario %d", index+1)
+		}
+		for nodeID, outcomes := range scenario.Nodes {
+			for visit := range outcomes.Visits {
+				if err := normalizeSimulatedOutcome(&outcomes.Visits[visit]); err != nil {
+					return nil, fmt.Errorf("%s: nodes.%s[%d]: %w", scenario.Name, nodeID, visit, err)
+				}
+			}
+		}
+	}
+	return script, nil
+}
+
+func normalizeSimulatedOutcome(outcome *SimulatedOutcome) error {
+	switch outcome.Status {
+	case "", nodeRunStatusSuccess, nodeRunStatusError:
+	default:
+		return fmt.Errorf("status must be one of: success, error")
+	}
+	if outcome.Decision == nil {
+		return nil
+	}
+
+	// Round-trip through JSON so numbers are float64 like decisions parsed from agent output.
+	encoded, err := json.Marshal(outcome.Decision)
+	if err != nil {
+		return fmt.Errorf("decision must be JSON-compatible: %w", err)
+	}
+	outcome.Decision = nil
+	return json.Unmarshal(encoded, &outcome.Decision)
+}
+
+// Simulate walks the plan's state machine like the entrypoint executor, replacing node runs with
+// scripted outcomes, and checks the scenario's expectations.
+func Simulate(plan *Plan, scenario SimulationScenario) *SimulationResult {
+	result := &SimulationResult{
+		Name:     scenario.Name,
+		Steps:    make([]SimulationStep, 0),
+		Path:     make([]string, 0),
+		Failures: make([]string, 0),
+	}
+	if err := checkScenarioNodes(plan, scenario); err != nil {
+		result.Failures = append(result.Failures, err.Error())
+		return result
+	}
+
+	hits := map[string]int{}
+	currentNodeID := plan.EntryNode
+	for {
+		node := plan.Nodes[currentNodeID]
+		if node.Terminal {
+			result.Path = append(result.Path, node.ID)
+			result.TerminalNode = node.ID
+			result.TerminalStatus = node.TerminalStatus
+			result.ExitCode = node.ExitCode
+			break
+		}
+		if result.Iterations >= plan.Limits.MaxIterations {
+			result.ExitCode = ExitCodeLimitReached
+			result.Error = fmt.Sprintf("max_iterations exceeded: %d", plan.Limits.MaxIterations)
+			break
+		}
+		result.Iterations++
+
+		hits[node.ID]++
+		attempt := hits[node.ID]
+		if attempt > plan.Limits.MaxSameNodeHits {
+			result.ExitCode = ExitCodeLimitReached
+			result.Error = fmt.Sprintf("max_same_node_hits exceeded for %s: %d", node.ID, plan.Limits.MaxSameNodeHits)
+			break
+		}
+
+		outcome, err := scriptedOutcome(scenario, node.ID, attempt)
+		if err != nil {
+			result.Failures = append(result.Failures, err.Error())
+			return result
+		}
+
+		step := simulateNodeRun(node, outcome, attempt, result.Iterations, len(result.Steps)+1)
+		result.Path = append(result.Path, node.ID)
+		scope := conditionScope(step, result.Iterations, len(result.Steps)+1)
+
+		var matched *Transition
+		for index := range node.Transitions {
+			if node.Transitions[index].Condition.Evaluate(scope) {
+				matched = &node.Transitions[index]
+				break
+			}
+		}
+		if matched == nil {
+			result.Steps = append(result.Steps, step)
+			result.ExitCode = ExitCodeNoTransition
+			if step.Status == nodeRunStatusError {
+				result.ExitCode = ExitCodeNodeExecution
+			}
+			result.Error = "no transition matched for node " + node.ID
+			break
+		}
+
+		step.When = matched.When
+		step.To = matched.To
+		result.Steps = append(result.Steps, step)
+		currentNodeID = matched.To
+	}
+
+	if scenario.Expect != nil {
+		result.Failures = append(result.Failures, scenario.Expect.check(result)...)
+	}
+	return result
+}
+
+func checkScenarioNodes(plan *Plan, scenario SimulationScenario) error {
+	for _, nodeID := range sortedKeys(scenario.Nodes) {
+		node, ok := plan.Nodes[nodeID]
+		if !ok {
+			return fmt.Errorf("script references unknown node %s", nodeID)
+		}
+		if node.Terminal {
+			return fmt.Errorf("script references terminal node %s, which does not run", nodeID)
+		}
+		if node.Run.Kind == RunKindCommand {
+			for _, outcome := range scenario.Nodes[nodeID].Visits {
+				if outcome.Decision != nil {
+					return fmt.Errorf("script sets a decision for command node %s", nodeID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func scriptedOutcome(scenario SimulationScenario, nodeID string, attempt int) (SimulatedOutcome, error) {
+	outcomes, ok := scenario.Nodes[nodeID]
+	if !ok || len(outcomes.Visits) == 0 {
+		return SimulatedOutcome{}, fmt.Errorf("script has no outcome for node %s", nodeID)
+	}
+	if outcomes.Repeat {
+		return outcomes.Visits[0], nil
+	}
+	if attempt > len(outcomes.Visits) {
+		return SimulatedOutcome{}, fmt.Errorf(
+			"script has no outcome for visit %d of node %s (%d scripted)",
+			attempt,
+			nodeID,
+			len(outcomes.Visits),
+		)
+	}
+	return outcomes.Visits[attempt-1], nil
+}
+
+func simulateNodeRun(node *Node, outcome SimulatedOutcome, attempt int, iteration int, sequence int) SimulationStep {
+	step := SimulationStep{
+		Iteration: iteration,
+		NodeID:    node.ID,
+		NodeRunID: fmt.Sprintf("%s-%d", node.ID, sequence),
+		Kind:      node.Run.Kind,
+		Attempt:   attempt,
+		ExitCode:  outcome.ExitCode,
+		Signal:    outcome.Signal,
+		TimedOut:  outcome.TimedOut,
+		Decision:  map[string]any{},
+		Status:    nodeRunStatusSuccess,
+	}
+
+	failed := outcome.ExitCode != 0 || outcome.Signal != "" || outcome.TimedOut
+	if node.Run.Kind == RunKindAgent {
+		if outcome.Decision != nil {
+			step.Decision = outcome.Decision
+		}
+		step.Errors = ValidateDecision(node.Run.Schema, step.Decision)
+		failed = failed || len(step.Errors) > 0
+	}
+	if failed {
+		step.Status = nodeRunStatusError
+	}
+	if outcome.Status != "" {
+		step.Status = outcome.Status
+	}
+	return step
+}
+
+func conditionScope(step SimulationStep, iteration int, totalNodeRuns int) map[string]any {
+	return map[string]any{
+		"decision": step.Decision,
+		"run": map[string]any{
+			"exit_code": float64(step.ExitCode),
+			"signal":    step.Signal,
+			"timed_out": step.TimedOut,
+			"status":    step.Status,
+		},
+		"node": map[string]any{
+			"id":      step.NodeID,
+			"kind":    step.Kind,
+			"attempt": float64(step.Attempt),
+			"run_id":  step.NodeRunID,
+		},
+		"pipeline": map[string]any{
+			"iteration":       float64(iteration),
+			"total_node_runs": float64(totalNodeRuns),
+		},
+	}
+}
+
+func (e *SimulationExpectation) check(result *SimulationResult) []string {
+	failures := make([]string, 0)
+	if e.Terminal != "" && e.Terminal != result.TerminalNode {
+		failures = append(failures, fmt.Sprintf("terminal: expected %s, got %s", e.Terminal, orNone(result.TerminalNode)))
+	}
+	if e.TerminalStatus != "" && e.TerminalStatus != result.TerminalStatus {
+		failures = append(failures, fmt.Sprintf(
+			"terminal_status: expected %s, got %s",
+			e.TerminalStatus,
+			orNone(result.TerminalStatus),
+		))
+	}
+	if e.ExitCode != nil && *e.ExitCode != result.ExitCode {
+		failures = append(failures, fmt.Sprintf("exit_code: expected %d, got %d", *e.ExitCode, result.ExitCode))
+	}
+	if e.Path != nil && !slices.Equal(e.Path, result.Path) {
+		failures = append(failures, fmt.Sprintf(
+			"path: expected %s, got %s",
+			strings.Join(e.Path, " -> "),
+			strings.Join(result.Path, " -> "),
+		))
+	}
+	if e.Error != "" && !strings.Contains(result.Error, e.Error) {
+		failures = append(failures, fmt.Sprintf("error: expected %q, got %q", e.Error, result.Error))
+	}
+	return failures
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,257 @@
+package pipeline
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSimulateRepoGoFlowScenarios(t *testing.T) {
+	t.Parallel()
+
+	repoRoot := filepath.Join("..", "..", "..")
+	plan, err := LoadPlanFile(filepath.Join(repoRoot, "pipelines", "go-flow.yml"), LoadOptions{
+		WorkspaceDir:             repoRoot,
+		KeepTemplatePlaceholders: true,
+	})
+	if err != nil {
+		t.Fatalf("load plan: %v", err)
+	}
+
+	script, err := ParseSimulationScript([]byte(`
+scenarios:
+  - name: approved after one round of fixes
+    nodes:
+      prepare_branch: {exit_code: 0}
+      issue_gate: {exit_code: 0}
+      planner: {decision: {status: created}}
+      implementer: {decision: {status: done}}
+      reviewer:
+        - decision: {status: fixes_needed, reason: missing tests}
+        - decision: {status: done}
+    expect:
+      terminal: success
+      exit_code: 0
+      path: [prepare_branch, issue_gate, planner, implementer, reviewer, implementer, reviewer, success]
+  - name: planner output violates schema
+    nodes:
+      prepare_branch: {exit_code: 0}
+      issue_gate: {exit_code: 0}
+      planner: {decision: {status: bogus}}
+    expect:
+      terminal: fail
+      terminal_status: failed
+      exit_code: 1
+  - name: implementer never finishes
+    nodes:
+      prepare_branch: {exit_code: 0}
+      issue_gate: {exit_code: 0}
+      planner: {decision: {status: done}}
+      implementer: {decision: {status: todo_not_empty}}
+    expect:
+      exit_code: 4
+      error: "max_same_node_hits exceeded for implementer: 200"
+`))
+	if err != nil {
+		t.Fatalf("parse script: %v", err)
+	}
+
+	results := make([]*SimulationResult, 0, len(script.Scenarios))
+	for _, scenario := range script.Scenarios {
+		result := Simulate(plan, scenario)
+		if !result.Passed() {
+			t.Fatalf("scenario %q failed: %v", scenario.Name, result.Failures)
+		}
+		results = append(results, result)
+	}
+
+	planner := results[1].Steps[2]
+	if planner.Status != "error" || !slices.Equal(planner.Errors, []string{"decision.status: value is not in enum"}) {
+		t.Fatalf("unexpected planner step: %+v", planner)
+	}
+	if planner.When != `run.status == "error"` || planner.To != "fail" {
+		t.Fatalf("unexpected planner transition: %+v", planner)
+	}
+	if results[2].Iterations != 204 {
+		t.Fatalf("unexpected iterations: %d", results[2].Iterations)
+	}
+}
+
+func TestSimulateRepoGoFlowScript(t *testing.T) {
+	t.Parallel()
+
+	repoRoot := filepath.Join("..", "..", "..")
+	plan, err := LoadPlanFile(filepath.Join(repoRoot, "pipelines", "go-flow.yml"), LoadOptions{
+		WorkspaceDir:             repoRoot,
+		KeepTemplatePlaceholders: true,
+	})
+	if err != nil {
+		t.Fatalf("load plan: %v", err)
+	}
+	script, err := LoadSimulationScript(filepath.Join(repoRoot, "pipelines", "simulations", "go-flow.yml"))
+	if err != nil {
+		t.Fatalf("load script: %v", err)
+	}
+
+	for _, scenario := range script.Scenarios {
+		if result := Simulate(plan, scenario); !result.Passed() {
+			t.Fatalf("scenario %q failed: %v", scenario.Name, result.Failures)
+		}
+	}
+}
+
+func TestSimulateReportsExpectationFailures(t *testing.T) {
+	t.Parallel()
+
+	plan, err := ParsePlan([]byte(commandPlan(`"true"`, "run.exit_code == 0", "success")), LoadOptions{
+		WorkspaceDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("parse plan: %v", err)
+	}
+	script, err := ParseSimulationScript([]byte(`
+nodes:
+  a: {exit_code: 2}
+expect:
+  terminal: success
+  path: [a, success]
+`))
+	if err != nil {
+		t.Fatalf("parse script: %v", err)
+	}
+
+	result := Simulate(plan, script.Scenarios[0])
+	if result.TerminalNode != "fail" || result.ExitCode != 1 || result.Steps[0].To != "fail" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	want := []string{
+		"terminal: expected success, got fail",
+		"path: expected a -> success, got a -> fail",
+	}
+	if !slices.Equal(result.Failures, want) {
+		t.Fatalf("unexpected failures: %q", result.Failures)
+	}
+}
+
+func TestSimulateNoTransitionAndScriptErrors(t *testing.T) {
+	t.Parallel()
+
+	plan, err := ParsePlan([]byte(commandPlan(`"true"`, "node.attempt > 1", "success")+
+		"      - when: 'run.exit_code == 0'\n        to: a\n"+
+		"      - when: 'run.status == \"error\" && node.attempt > 5'\n        to: fail\n"), LoadOptions{
+		WorkspaceDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("parse plan: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		outcomes    []SimulatedOutcome
+		wantError   string
+		wantFailure string
+		wantExit    int
+	}{
+		{
+			name:      "no transition after failed run",
+			outcomes:  []SimulatedOutcome{{ExitCode: 7}},
+			wantError: "no transition matched for node a",
+			wantExit:  ExitCodeNodeExecution,
+		},
+		{
+			name:      "no transition after forced success",
+			outcomes:  []SimulatedOutcome{{ExitCode: 7, Status: "success"}},
+			wantError: "no transition matched for node a",
+			wantExit:  ExitCodeNoTransition,
+		},
+		{
+			name:        "missing visit",
+			outcomes:    []SimulatedOutcome{{ExitCode: 0}},
+			wantFailure: "script has no outcome for visit 2 of node a (1 scripted)",
+		},
+		{
+			name:        "decision on command node",
+			outcomes:    []SimulatedOutcome{{Decision: map[string]any{}}},
+			wantFailure: "script sets a decision for command node a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := Simulate(plan, SimulationScenario{
+				Name:  tt.name,
+				Nodes: map[string]SimulatedOutcomes{"a": {Visits: tt.outcomes}},
+			})
+			if result.Error != tt.wantError || result.ExitCode != tt.wantExit {
+				t.Fatalf("unexpected result: %+v", result)
+			}
+			if tt.wantFailure == "" && !result.Passed() {
+				t.Fatalf("unexpected failures: %q", result.Failures)
+			}
+			if tt.wantFailure != "" && !slices.Equal(result.Failures, []string{tt.wantFailure}) {
+				t.Fatalf("unexpected failures: %q", result.Failures)
+			}
+		})
+	}
+
+	result := Simulate(plan, SimulationScenario{Nodes: map[string]SimulatedOutcomes{"b": {Visits: []SimulatedOutcome{{}}}}})
+	if !slices.Equal(result.Failures, []string{"script references unknown node b"}) {
+		t.Fatalf("unexpected failures: %q", result.Failures)
+	}
+}
+
+func TestParseSimulationScriptErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"nodes: {a: {exit_code: 0}}\nscenarios: [{name: x}]\n": "either scenarios or a single top-level scenario",
+		"expect: {terminal: success}\nnodes: {a: {exit: 1}}\n": "field exit not found in node outcome",
+		"nodes: {a: {status: broken}}\n":                       "nodes.a[0]: status must be one of: success, error",
+		"nodes: {a: 3}\n":                                      "node outcomes must be a mapping or a list of mappings",
+		"{}\n":                                                 "must define at least one scenario",
+	}
+	for content, wantErr := range tests {
+		_, err := ParseSimulationScript([]byte(content))
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("unexpected error for %q: %v", content, err)
+		}
+	}
+}
+
+func TestValidateDecision(t *testing.T) {
+	t.Parallel()
+
+	schema := map[string]any{
+		"type":                 "object",
+		"required":             []any{"status", "count"},
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"status": map[string]any{"type": "string", "enum": []any{"done", "failed"}},
+			"count":  map[string]any{"type": "integer"},
+			"tags":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+	}
+
+	errs := ValidateDecision(schema, map[string]any{
+		"status": "done",
+		"tags":   []any{"a", float64(1)},
+		"extra":  true,
+	})
+	want := []string{
+		"decision.count: missing required field",
+		"decision.tags[1]: expected string",
+		"decision.extra: additional property is not allowed",
+	}
+	if !slices.Equal(errs, want) {
+		t.Fatalf("unexpected errors: %q", errs)
+	}
+
+	if errs := ValidateDecision(schema, map[string]any{"status": "failed", "count": 1.5}); !slices.Equal(
+		errs,
+		[]string{"decision.count: expected integer"},
+	) {
+		t.Fatalf("unexpected errors: %q", errs)
+	}
+}
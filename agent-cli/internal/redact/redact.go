@@ -0,0 +1,91 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Mask replaces every redacted value.
+const Mask = "[REDACTED]"
+
+// minSecretLength skips configured values too short to mask without destroying unrelated output.
+const minSecretLength = 6
+
+// builtinPatterns match well-known token formats even when they are not configured values.
+var builtinPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{20,}`),
+	regexp.MustCompile(`github_pat_[A-Za-z0-9_]{20,}`),
+	regexp.MustCompile(`sk-ant-[A-Za-z0-9_-]{10,}`),
+}
+
+// Redactor masks secret values and pattern matches in text. It is safe for concurrent use.
+type Redactor struct {
+	secrets  []string
+	patterns []*regexp.Regexp
+}
+
+// New builds a redactor for the given literal secrets and user-defined regexes.
+// Built-in token patterns are always included.
+func New(secrets []string, patterns []string) (*Redactor, error) {
+	r := &Redactor{
+		patterns: append([]*regexp.Regexp(nil), builtinPatterns...),
+	}
+
+	seen := map[string]bool{}
+	for _, secret := range secrets {
+		secret = strings.TrimSpace(secret)
+		if len(secret) < minSecretLength || seen[secret] {
+			continue
+		}
+		seen[secret] = true
+		r.secrets = append(r.secrets, secret)
+	}
+	// Longer secrets first so a secret containing another is masked whole.
+	sort.SliceStable(r.secrets, func(i, j int) bool {
+		return len(r.secrets[i]) > len(r.secrets[j])
+	})
+
+	for i, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact.patterns[%d]: %w", i, err)
+		}
+		r.patterns = append(r.patterns, compiled)
+	}
+
+	return r, nil
+}
+
+// Redact returns text with secrets masked and the number of masked occurrences.
+func (r *Redactor) Redact(text string) (string, int) {
+	if r == nil || text == "" {
+		return text, 0
+	}
+
+	count := 0
+	for _, secret := range r.secrets {
+		if n := strings.Count(text, secret); n > 0 {
+			count += n
+			text = strings.ReplaceAll(text, secret, Mask)
+		}
+	}
+	for _, pattern := range r.patterns {
+		text = pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if match == Mask || match == "" {
+				return match
+			}
+			count++
+			return Mask
+		})
+	}
+
+	return text, count
+}
+
+// String returns text with secrets masked.
+func (r *Redactor) String(text string) string {
+	redacted, _ := r.Redact(text)
+	return redacted
+}
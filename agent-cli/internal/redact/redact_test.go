@@ -0,0 +1,85 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactMasksConfiguredSecrets(t *testing.T) {
+	t.Parallel()
+
+	r, err := New([]string{"claude-secret-value", "", "short"}, nil)
+	if err != nil {
+		t.Fatalf("new redactor: %v", err)
+	}
+
+	got, count := r.Redact("token=claude-secret-value again claude-secret-value short")
+	if got != "token=[REDACTED] again [REDACTED] short" {
+		t.Fatalf("unexpected redacted text: %q", got)
+	}
+	if count != 2 {
+		t.Fatalf("unexpected count: %d", count)
+	}
+}
+
+func TestRedactMasksBuiltinTokenPatterns(t *testing.T) {
+	t.Parallel()
+
+	r, err := New(nil, nil)
+	if err != nil {
+		t.Fatalf("new redactor: %v", err)
+	}
+
+	input := strings.Join([]string{
+		"ghp_" + strings.Repeat("a", 36),
+		"github_pat_" + strings.Repeat("B", 22) + "_" + strings.Repeat("c", 59),
+		"sk-ant-oat01-" + strings.Repeat("x", 40),
+		"ghp_short",
+	}, " ")
+	got, count := r.Redact(input)
+	if got != "[REDACTED] [REDACTED] [REDACTED] ghp_short" {
+		t.Fatalf("unexpected redacted text: %q", got)
+	}
+	if count != 3 {
+		t.Fatalf("unexpected count: %d", count)
+	}
+}
+
+func TestRedactMasksUserPatterns(t *testing.T) {
+	t.Parallel()
+
+	r, err := New(nil, []string{`corp-[0-9]{4}`})
+	if err != nil {
+		t.Fatalf("new redactor: %v", err)
+	}
+
+	got, count := r.Redact(`{"text":"key corp-1234 and corp-12"}`)
+	if got != `{"text":"key [REDACTED] and corp-12"}` {
+		t.Fatalf("unexpected redacted text: %q", got)
+	}
+	if count != 1 {
+		t.Fatalf("unexpected count: %d", count)
+	}
+}
+
+func TestNewRejectsInvalidPattern(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(nil, []string{"ok", "("})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "redact.patterns[1]") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestNilRedactorIsNoop(t *testing.T) {
+	t.Parallel()
+
+	var r *Redactor
+	got, count := r.Redact("ghp_" + strings.Repeat("a", 36))
+	if count != 0 || !strings.HasPrefix(got, "ghp_") {
+		t.Fatalf("unexpected redaction: %q (%d)", got, count)
+	}
+}
@@ -34,6 +34,8 @@ const (
 	defaultRunIdleTimeoutSeconds    = 7200
 	defaultPipelineTaskIdleTimeout  = 1800
 	containerWorkspaceDir           = "/workspace"
+	containerStateDir               = "/run/agent-cli/state"
+	containerResumeDir              = "/run/agent-cli/resume"
 	hostDockerSocketPath            = "/var/run/docker.sock"
 	containerStopTimeoutSeconds     = 10
 	containerCleanupTimeout         = 15 * time.Second
@@ -92,27 +94,56 @@ type dockerAPI interface {
 }
 
 type runSpec struct {
-	HostDir           string
-	Model             string
-	CommandArgs       []string
-	Env               []string
-	Labels            map[string]string
-	CWDHash           string
-	DockerMode        string
-	DinDStorageDriver string
+	HostDir               string
+	Model                 string
+	CommandArgs           []string
+	Env                   []string
+	Labels                map[string]string
+	CWDHash               string
+	DockerMode            string
+	DinDStorageDriver     string
+	NetworkMode           string
+	Privileged            bool
+	Binds                 []string
+	ContainerPipelinePath string
+}
+
+// ContainerSpec is the container configuration RunDockerStreaming would create for a request.
+type ContainerSpec struct {
+	Image                 string            `json:"image"`
+	Cmd                   []string          `json:"cmd"`
+	Env                   []string          `json:"env"`
+	Binds                 []string          `json:"binds"`
+	Labels                map[string]string `json:"labels"`
+	NetworkMode           string            `json:"network_mode"`
+	Privileged            bool              `json:"privileged"`
+	AutoRemove            bool              `json:"auto_remove"`
+	Model                 string            `json:"model"`
+	DockerMode            string            `json:"docker_mode"`
+	DinDStorageDriver     string            `json:"dind_storage_driver"`
+	ContainerPipelinePath string            `json:"container_pipeline_path,omitempty"`
+	TemplateVars          map[string]string `json:"template_vars,omitempty"`
 }
 
 type RunRequest struct {
-	Image                      string
-	CWD                        string
-	SourceWorkspaceDir         string
-	GitHubToken                string
-	ClaudeToken                string
-	GitUserName                string
-	GitUserEmail               string
-	Prompt                     string
-	Pipeline                   string
-	TemplateVars               map[string]string
+	Image              string
+	CWD                string
+	SourceWorkspaceDir string
+	GitHubToken        string
+	ClaudeToken        string
+	GitUserName        string
+	GitUserEmail       string
+	Prompt             string
+	Pipeline           string
+	TemplateVars       map[string]string
+	// StateDir is a host directory the entrypoint exports pipeline resume state into.
+	StateDir string
+	// ResumeDir is a host directory holding the resume state of the run being resumed.
+	ResumeDir string
+	// StartNode overrides the plan entry node when resuming.
+	StartNode string
+	// Labels are extra container labels; they never override the labels agent-cli manages itself.
+	Labels                     map[string]string
 	Model                      string
 	Debug                      bool
 	DockerMode                 string
@@ -133,6 +164,39 @@ type StreamHooks struct {
 	OnStderrLine func(line string)
 }
 
+// ResolveContainerSpec validates the request and returns the resolved container spec
+// without contacting the Docker daemon.
+func ResolveContainerSpec(req RunRequest) (ContainerSpec, error) {
+	spec, err := buildRunSpec(req)
+	if err != nil {
+		return ContainerSpec{}, err
+	}
+
+	var templateVars map[string]string
+	if spec.ContainerPipelinePath != "" && len(req.TemplateVars) > 0 {
+		templateVars = make(map[string]string, len(req.TemplateVars))
+		for key, value := range req.TemplateVars {
+			templateVars[key] = value
+		}
+	}
+
+	return ContainerSpec{
+		Image:                 req.Image,
+		Cmd:                   append([]string(nil), spec.CommandArgs...),
+		Env:                   append([]string(nil), spec.Env...),
+		Binds:                 append([]string(nil), spec.Binds...),
+		Labels:                spec.Labels,
+		NetworkMode:           spec.NetworkMode,
+		Privileged:            spec.Privileged,
+		AutoRemove:            true,
+		Model:                 spec.Model,
+		DockerMode:            spec.DockerMode,
+		DinDStorageDriver:     spec.DinDStorageDriver,
+		ContainerPipelinePath: spec.ContainerPipelinePath,
+		TemplateVars:          templateVars,
+	}, nil
+}
+
 func RunDockerStreaming(ctx context.Context, req RunRequest, hooks StreamHooks) (RunOutput, error) {
 	spec, err := buildRunSpec(req)
 	if err != nil {
@@ -210,28 +274,11 @@ func RunDockerStreaming(ctx context.Context, req RunRequest, hooks StreamHooks)
 		Labels:       spec.Labels,
 	}
 
-	networkMode := container.NetworkMode("host")
-	privileged := false
-	binds := []string{
-		fmt.Sprintf("%s:%s:ro", spec.HostDir, req.SourceWorkspaceDir),
-	}
-
-	if spec.DockerMode == dockerModeDinD {
-		// DinD daemon should not share host network namespace; otherwise it can mutate
-		// host iptables rules and break host-side docker compose.
-		networkMode = container.NetworkMode("bridge")
-		privileged = true
-	}
-
-	if spec.DockerMode == dockerModeDooD {
-		binds = append(binds, fmt.Sprintf("%s:%s", hostDockerSocketPath, hostDockerSocketPath))
-	}
-
 	hostConfig := &container.HostConfig{
-		NetworkMode: networkMode,
+		NetworkMode: container.NetworkMode(spec.NetworkMode),
 		AutoRemove:  true,
-		Privileged:  privileged,
-		Binds:       binds,
+		Privileged:  spec.Privileged,
+		Binds:       spec.Binds,
 	}
 
 	createResp, err := dockerClient.ContainerCreate(runCtx, containerConfig, hostConfig, nil, nil, "")
@@ -262,7 +309,11 @@ func RunDockerStreaming(ctx context.Context, req RunRequest, hooks StreamHooks)
 		return output, fmt.Errorf("start container: %w", err)
 	}
 
-	logsReader, err := dockerClient.ContainerLogs(runCtx, containerID, container.LogsOptions{
+	// The log stream outlives an interrupt: stopping the container lets a pipeline emit its result
+	// and export its state, and the stream ends once the container is gone.
+	logsCtx, cancelLogs := context.WithCancel(context.WithoutCancel(runCtx))
+	defer cancelLogs()
+	logsReader, err := dockerClient.ContainerLogs(logsCtx, containerID, container.LogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
 		Follow:     true,
@@ -359,6 +410,9 @@ func buildRunSpec(req RunRequest) (runSpec, error) {
 	if modeCount > 1 {
 		return runSpec{}, errors.New("use exactly one input source: prompt or pipeline")
 	}
+	if pipeline == "" && (req.StateDir != "" || req.ResumeDir != "" || req.StartNode != "") {
+		return runSpec{}, errors.New("state, resume and start node options require pipeline mode")
+	}
 
 	model := strings.ToLower(strings.TrimSpace(req.Model))
 	if model == "" {
@@ -403,6 +457,11 @@ func buildRunSpec(req RunRequest) (runSpec, error) {
 		managedContainerLabelKey:        managedContainerLabelValue,
 		managedContainerCWDHashLabelKey: cwdHash,
 	}
+	for key, value := range req.Labels {
+		if _, managed := labels[key]; !managed {
+			labels[key] = value
+		}
+	}
 	pipelineNodeTimeoutSec := resolvePipelineTaskIdleTimeoutSec(req.PipelineTaskIdleTimeoutSec)
 	env := []string{
 		"GH_TOKEN=" + req.GitHubToken,
@@ -416,6 +475,7 @@ func buildRunSpec(req RunRequest) (runSpec, error) {
 	}
 
 	var commandArgs []string
+	var containerPipelinePath string
 	baseArgs := []string{"--model", model}
 	if req.Debug {
 		baseArgs = append(baseArgs, "--debug")
@@ -425,7 +485,7 @@ func buildRunSpec(req RunRequest) (runSpec, error) {
 	case prompt != "":
 		commandArgs = append(baseArgs, "-vv", "-v", prompt)
 	case pipeline != "":
-		containerPipelinePath, err := resolveContainerPipelineFilePath(hostDir, pipeline)
+		containerPipelinePath, err = resolveContainerPipelineFilePath(hostDir, pipeline)
 		if err != nil {
 			return runSpec{}, err
 		}
@@ -442,21 +502,52 @@ func buildRunSpec(req RunRequest) (runSpec, error) {
 				commandArgs = append(commandArgs, "--var", key+"="+req.TemplateVars[key])
 			}
 		}
+		if startNode := strings.TrimSpace(req.StartNode); startNode != "" {
+			commandArgs = append(commandArgs, "--start-node", startNode)
+		}
+		if req.ResumeDir != "" {
+			commandArgs = append(commandArgs, "--resume-dir", containerResumeDir)
+		}
+	}
+
+	networkMode := "host"
+	privileged := false
+	binds := []string{
+		fmt.Sprintf("%s:%s:ro", hostDir, req.SourceWorkspaceDir),
+	}
+	if req.StateDir != "" {
+		env = append(env, "PIPELINE_STATE_DIR="+containerStateDir)
+		binds = append(binds, fmt.Sprintf("%s:%s", req.StateDir, containerStateDir))
+	}
+	if req.ResumeDir != "" {
+		binds = append(binds, fmt.Sprintf("%s:%s:ro", req.ResumeDir, containerResumeDir))
 	}
 
 	if dockerMode == dockerModeDinD {
 		env = append(env, "ENABLE_DIND=1", "DIND_STORAGE_DRIVER="+dindStorageDriver)
+		// DinD daemon should not share host network namespace; otherwise it can mutate
+		// host iptables rules and break host-side docker compose.
+		networkMode = "bridge"
+		privileged = true
+	}
+
+	if dockerMode == dockerModeDooD {
+		binds = append(binds, fmt.Sprintf("%s:%s", hostDockerSocketPath, hostDockerSocketPath))
 	}
 
 	return runSpec{
-		HostDir:           hostDir,
-		Model:             model,
-		CommandArgs:       commandArgs,
-		Env:               env,
-		Labels:            labels,
-		CWDHash:           cwdHash,
-		DockerMode:        dockerMode,
-		DinDStorageDriver: dindStorageDriver,
+		HostDir:               hostDir,
+		Model:                 model,
+		CommandArgs:           commandArgs,
+		Env:                   env,
+		Labels:                labels,
+		CWDHash:               cwdHash,
+		DockerMode:            dockerMode,
+		DinDStorageDriver:     dindStorageDriver,
+		NetworkMode:           networkMode,
+		Privileged:            privileged,
+		Binds:                 binds,
+		ContainerPipelinePath: containerPipelinePath,
 	}, nil
 }
 
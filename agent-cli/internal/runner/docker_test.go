@@ -7,6 +7,7 @@ import (
 	"io"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -377,6 +378,80 @@ func TestBuildDockerArgsPipelineIncludesDebugFlag(t *testing.T) {
 	}
 }
 
+func TestResolveContainerSpecPipelineResume(t *testing.T) {
+	cwd := t.TempDir()
+	planPath := filepath.Join(cwd, "pipelines", "flow.yml")
+	stateDir := filepath.Join(t.TempDir(), "state")
+	resumeDir := filepath.Join(cwd, ".agent-cli", "runs", "20260216T000000-abc", "state")
+
+	spec, err := ResolveContainerSpec(RunRequest{
+		Image:              "claude:go",
+		CWD:                cwd,
+		SourceWorkspaceDir: "/workspace-source",
+		Pipeline:           planPath,
+		Model:              "sonnet",
+		StateDir:           stateDir,
+		ResumeDir:          resumeDir,
+		StartNode:          "reviewer",
+	})
+	if err != nil {
+		t.Fatalf("resolve spec: %v", err)
+	}
+
+	joined := strings.Join(spec.Cmd, " ")
+	want := "--model sonnet --pipeline /workspace/pipelines/flow.yml --start-node reviewer --resume-dir /run/agent-cli/resume"
+	if joined != want {
+		t.Fatalf("unexpected args: %q", joined)
+	}
+	wantBinds := []string{
+		cwd + ":/workspace-source:ro",
+		stateDir + ":/run/agent-cli/state",
+		resumeDir + ":/run/agent-cli/resume:ro",
+	}
+	if !slices.Equal(spec.Binds, wantBinds) {
+		t.Fatalf("unexpected binds: %#v", spec.Binds)
+	}
+	if !slices.Contains(spec.Env, "PIPELINE_STATE_DIR=/run/agent-cli/state") {
+		t.Fatalf("unexpected env: %#v", spec.Env)
+	}
+
+	_, err = ResolveContainerSpec(RunRequest{
+		Image:              "claude:go",
+		CWD:                cwd,
+		SourceWorkspaceDir: "/workspace-source",
+		Prompt:             "hello",
+		StartNode:          "reviewer",
+	})
+	if err == nil || !strings.Contains(err.Error(), "require pipeline mode") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestResolveContainerSpecExtraLabels(t *testing.T) {
+	cwd := t.TempDir()
+
+	spec, err := ResolveContainerSpec(RunRequest{
+		Image:              "claude:go",
+		CWD:                cwd,
+		SourceWorkspaceDir: "/workspace-source",
+		Prompt:             "hello",
+		Labels: map[string]string{
+			"agent-cli.batch_id":     "batch-1",
+			managedContainerLabelKey: "false",
+		},
+	})
+	if err != nil {
+		t.Fatalf("resolve spec: %v", err)
+	}
+
+	if spec.Labels["agent-cli.batch_id"] != "batch-1" {
+		t.Fatalf("missing extra label: %#v", spec.Labels)
+	}
+	if spec.Labels[managedContainerLabelKey] != managedContainerLabelValue {
+		t.Fatalf("managed label was overridden: %#v", spec.Labels)
+	}
+}
+
 func TestBuildDockerArgsPipelineRejectsOutsideCWD(t *testing.T) {
 	cwd := t.TempDir()
 	outside := filepath.Join(t.TempDir(), "pipeline.yaml")
@@ -395,6 +470,66 @@ func TestBuildDockerArgsPipelineRejectsOutsideCWD(t *testing.T) {
 	}
 }
 
+func TestResolveContainerSpecPipelineDinD(t *testing.T) {
+	cwd := t.TempDir()
+	planPath := filepath.Join(cwd, "pipelines", "flow.yml")
+
+	spec, err := ResolveContainerSpec(RunRequest{
+		Image:              "claude:go",
+		CWD:                cwd,
+		SourceWorkspaceDir: "/workspace-source",
+		GitHubToken:        "gh-token",
+		Pipeline:           planPath,
+		TemplateVars:       map[string]string{"ISSUE": "42"},
+		DockerMode:         "dind",
+		DinDStorageDriver:  "vfs",
+	})
+	if err != nil {
+		t.Fatalf("resolve spec: %v", err)
+	}
+
+	if spec.NetworkMode != "bridge" || !spec.Privileged || !spec.AutoRemove {
+		t.Fatalf("unexpected host config: network=%q privileged=%v auto_remove=%v", spec.NetworkMode, spec.Privileged, spec.AutoRemove)
+	}
+	if spec.ContainerPipelinePath != "/workspace/pipelines/flow.yml" {
+		t.Fatalf("unexpected container pipeline path: %q", spec.ContainerPipelinePath)
+	}
+	if spec.TemplateVars["ISSUE"] != "42" {
+		t.Fatalf("unexpected template vars: %#v", spec.TemplateVars)
+	}
+	if len(spec.Binds) != 1 || spec.Binds[0] != cwd+":/workspace-source:ro" {
+		t.Fatalf("unexpected binds: %#v", spec.Binds)
+	}
+	if !slices.Contains(spec.Env, "ENABLE_DIND=1") || !slices.Contains(spec.Env, "GH_TOKEN=gh-token") {
+		t.Fatalf("unexpected env: %#v", spec.Env)
+	}
+}
+
+func TestResolveContainerSpecDooDBindsSocket(t *testing.T) {
+	cwd := t.TempDir()
+
+	spec, err := ResolveContainerSpec(RunRequest{
+		Image:              "claude:go",
+		CWD:                cwd,
+		SourceWorkspaceDir: "/workspace-source",
+		Prompt:             "hello",
+		DockerMode:         "dood",
+	})
+	if err != nil {
+		t.Fatalf("resolve spec: %v", err)
+	}
+
+	if spec.NetworkMode != "host" || spec.Privileged {
+		t.Fatalf("unexpected host config: network=%q privileged=%v", spec.NetworkMode, spec.Privileged)
+	}
+	if !slices.Contains(spec.Binds, "/var/run/docker.sock:/var/run/docker.sock") {
+		t.Fatalf("unexpected binds: %#v", spec.Binds)
+	}
+	if spec.ContainerPipelinePath != "" || spec.TemplateVars != nil {
+		t.Fatalf("unexpected pipeline fields: %q %#v", spec.ContainerPipelinePath, spec.TemplateVars)
+	}
+}
+
 func TestBuildDockerArgsRejectsMixedPromptAndPipeline(t *testing.T) {
 	_, err := buildDockerArgsForTest(RunRequest{
 		Image:              "claude:go",
@@ -0,0 +1,109 @@
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ActiveRun marks a run in progress, so spend caps count its cost before its record is saved.
+type ActiveRun struct {
+	RunID     string    `json:"run_id"`
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	// CostUSD is the cost the run has reported so far.
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// WriteActiveRun creates or updates the marker of run in activeDir.
+func WriteActiveRun(activeDir string, run ActiveRun) error {
+	if strings.TrimSpace(run.RunID) == "" {
+		return errors.New("active run id is empty")
+	}
+	if err := os.MkdirAll(activeDir, 0o755); err != nil {
+		return fmt.Errorf("create active runs directory: %w", err)
+	}
+	content, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal active run: %w", err)
+	}
+	content = append(content, '\n')
+
+	// Readers must never see a partly written marker.
+	path := activeRunPath(activeDir, run.RunID)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0o644); err != nil {
+		return fmt.Errorf("write active run: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("write active run: %w", err)
+	}
+	return nil
+}
+
+// RemoveActiveRun deletes the marker of runID; a missing marker is not an error.
+func RemoveActiveRun(activeDir string, runID string) error {
+	err := os.Remove(activeRunPath(activeDir, runID))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove active run: %w", err)
+	}
+	return nil
+}
+
+// ListActiveRuns returns the runs in activeDir ordered by start time. Unreadable markers and
+// markers left behind by processes that are gone are skipped.
+func ListActiveRuns(activeDir string) ([]ActiveRun, error) {
+	entries, err := os.ReadDir(activeDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read active runs directory: %w", err)
+	}
+
+	runs := make([]ActiveRun, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(activeDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var run ActiveRun
+		if err := json.Unmarshal(content, &run); err != nil || !processAlive(run.PID) {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.Before(runs[j].StartedAt) })
+	return runs, nil
+}
+
+func activeRunPath(activeDir string, runID string) string {
+	return filepath.Join(activeDir, sanitizeID(runID)+".json")
+}
+
+// processAlive reports whether pid names a running process.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	if pid == os.Getpid() {
+		return true
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	// EPERM means the process exists but belongs to another user.
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -2,45 +2,36 @@ package stats
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
-	"sort"
 )
 
-func AggregateStats(runsDir string) (*Aggregate, error) {
-	agg := &Aggregate{
-		ByModel:      map[string]ModelAggregate{},
-		SkippedFiles: []string{},
-	}
-
-	entries, err := os.ReadDir(runsDir)
+// AggregateStats sums the runs in runsDir accepted by keep; a nil keep includes every run.
+// It reads run summaries from the run index, see ListRunSummaries.
+func AggregateStats(runsDir string, keep RunPredicate) (*Aggregate, error) {
+	runs, skipped, err := ListRunSummaries(runsDir)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return agg, nil
-		}
-		return nil, fmt.Errorf("read runs directory: %w", err)
+		return nil, err
 	}
+	return AggregateRuns(runs, skipped, keep), nil
+}
 
-	files := make([]string, 0, len(entries))
-	for _, entry := range entries {
-		if entry.IsDir() {
-			files = append(files, filepath.Join(runsDir, entry.Name(), statsFileName))
-		}
+// AggregateRuns sums runs accepted by keep and reports skipped as unreadable files. runs must be in run order.
+func AggregateRuns(runs []RunEntry, skipped []string, keep RunPredicate) *Aggregate {
+	agg := &Aggregate{
+		ByModel:      map[string]ModelAggregate{},
+		ResumeChains: []ResumeChain{},
+		SkippedFiles: []string{},
 	}
-	sort.Strings(files)
+	agg.SkippedFiles = append(agg.SkippedFiles, skipped...)
 
-	for _, path := range files {
-		record, err := LoadRunRecord(path)
-		if err != nil {
-			agg.SkippedFiles = append(
-				agg.SkippedFiles,
-				filepath.ToSlash(filepath.Join(filepath.Base(filepath.Dir(path)), filepath.Base(path))),
-			)
+	records := make([]*RunRecord, 0, len(runs))
+	samples := &distributionSamples{}
+	for _, run := range runs {
+		record := run.Record
+		if keep != nil && !keep(record) {
 			continue
 		}
-
 		agg.TotalRuns++
 		if record.Status == RunStatusSuccess {
 			agg.SuccessRuns++
@@ -62,9 +53,63 @@ func AggregateStats(runsDir string) (*Aggregate, error) {
 
 		mergeMetrics(&agg.Sums, record)
 		mergeByModel(agg.ByModel, record)
+		samples.add(record)
+		records = append(records, record)
+	}
+
+	agg.ResumeChains = buildResumeChains(records)
+	agg.Distributions = samples.distributions()
+	return agg
+}
+
+// buildResumeChains groups runs linked by parent_run_id and sums their cost per chain.
+// Runs without a parent or child are not chains and are left out. records must be in run order.
+func buildResumeChains(records []*RunRecord) []ResumeChain {
+	byID := make(map[string]*RunRecord, len(records))
+	for _, record := range records {
+		byID[record.RunID] = record
 	}
 
-	return agg, nil
+	rootOf := func(record *RunRecord) string {
+		current := record
+		seen := map[string]bool{}
+		for current.ParentRunID != "" && !seen[current.RunID] {
+			seen[current.RunID] = true
+			parent := byID[current.ParentRunID]
+			if parent == nil {
+				break
+			}
+			current = parent
+		}
+		return current.RunID
+	}
+
+	chains := map[string]*ResumeChain{}
+	order := make([]string, 0)
+	for _, record := range records {
+		if record.RunID == "" {
+			continue
+		}
+		rootID := rootOf(record)
+		chain := chains[rootID]
+		if chain == nil {
+			chain = &ResumeChain{RootRunID: rootID}
+			chains[rootID] = chain
+			order = append(order, rootID)
+		}
+		chain.RunIDs = append(chain.RunIDs, record.RunID)
+		chain.FinalStatus = record.Status
+		chain.DurationMS += record.Normalized.DurationMS
+		chain.TotalCostUSD += record.Normalized.TotalCostUSD
+	}
+
+	result := make([]ResumeChain, 0)
+	for _, rootID := range order {
+		if chain := chains[rootID]; len(chain.RunIDs) > 1 {
+			result = append(result, *chain)
+		}
+	}
+	return result
 }
 
 func LoadRunRecord(path string) (*RunRecord, error) {
@@ -85,6 +130,7 @@ func mergeMetrics(target *AggregateMetrics, record *RunRecord) {
 	target.DurationAPIMS += record.Normalized.DurationAPIMS
 	target.NumTurns += record.Normalized.NumTurns
 	target.TotalCostUSD += record.Normalized.TotalCostUSD
+	target.EffectiveCostUSD += EffectiveCostUSD(record)
 	target.InputTokens += record.Normalized.InputTokens
 	target.CacheCreationInputTokens += record.Normalized.CacheCreationInputTokens
 	target.CacheReadInputTokens += record.Normalized.CacheReadInputTokens
@@ -100,6 +146,7 @@ func mergeByModel(target map[string]ModelAggregate, record *RunRecord) {
 		current.CacheCreationInputTokens += metric.CacheCreationInputTokens
 		current.WebSearchRequests += metric.WebSearchRequests
 		current.CostUSD += metric.CostUSD
+		current.EffectiveCostUSD += modelEffectiveCostUSD(record, model)
 		target[model] = current
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -41,7 +42,7 @@ func TestAggregateStatsMixedRuns(t *testing.T) {
 				},
 			},
 		},
-	})
+	}, RetentionPolicy{})
 	if err != nil {
 		t.Fatalf("save first record: %v", err)
 	}
@@ -64,7 +65,7 @@ func TestAggregateStatsMixedRuns(t *testing.T) {
 				},
 			},
 		},
-	})
+	}, RetentionPolicy{})
 	if err != nil {
 		t.Fatalf("save second record: %v", err)
 	}
@@ -80,7 +81,7 @@ func TestAggregateStatsMixedRuns(t *testing.T) {
 		t.Fatalf("write legacy file: %v", err)
 	}
 
-	agg, err := AggregateStats(dir)
+	agg, err := AggregateStats(dir, nil)
 	if err != nil {
 		t.Fatalf("aggregate stats: %v", err)
 	}
@@ -114,10 +115,45 @@ func TestAggregateStatsMixedRuns(t *testing.T) {
 	}
 }
 
+func TestAggregateStatsResumeChains(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	base := time.Date(2026, 2, 12, 10, 0, 0, 0, time.UTC)
+	records := []*RunRecord{
+		{RunID: "root", Status: RunStatusError, Normalized: result.NormalizedMetrics{DurationMS: 100, TotalCostUSD: 2}},
+		{RunID: "alone", Status: RunStatusSuccess, Normalized: result.NormalizedMetrics{TotalCostUSD: 5}},
+		{RunID: "retry-1", ParentRunID: "root", Status: RunStatusError, Normalized: result.NormalizedMetrics{DurationMS: 20, TotalCostUSD: 0.5}},
+		{RunID: "retry-2", ParentRunID: "retry-1", Status: RunStatusSuccess, Normalized: result.NormalizedMetrics{DurationMS: 30, TotalCostUSD: 1}},
+		{RunID: "orphan", ParentRunID: "deleted", Status: RunStatusSuccess},
+	}
+	for index, record := range records {
+		record.Timestamp = base.Add(time.Duration(index) * time.Minute)
+		if _, err := SaveRunRecord(dir, record, RetentionPolicy{}); err != nil {
+			t.Fatalf("save record %s: %v", record.RunID, err)
+		}
+	}
+
+	agg, err := AggregateStats(dir, nil)
+	if err != nil {
+		t.Fatalf("aggregate stats: %v", err)
+	}
+	if len(agg.ResumeChains) != 1 {
+		t.Fatalf("unexpected resume chains: %+v", agg.ResumeChains)
+	}
+	chain := agg.ResumeChains[0]
+	if chain.RootRunID != "root" || strings.Join(chain.RunIDs, ",") != "root,retry-1,retry-2" {
+		t.Fatalf("unexpected chain runs: %+v", chain)
+	}
+	if chain.FinalStatus != RunStatusSuccess || chain.DurationMS != 150 || chain.TotalCostUSD != 3.5 {
+		t.Fatalf("unexpected chain totals: %+v", chain)
+	}
+}
+
 func TestAggregateStatsMissingDirectory(t *testing.T) {
 	t.Parallel()
 
-	agg, err := AggregateStats(filepath.Join(t.TempDir(), "missing"))
+	agg, err := AggregateStats(filepath.Join(t.TempDir(), "missing"), nil)
 	if err != nil {
 		t.Fatalf("aggregate stats: %v", err)
 	}
@@ -153,7 +189,7 @@ func TestAggregateStatsReadsTimestampPrefixedRunDirectory(t *testing.T) {
 		t.Fatalf("write stats file: %v", err)
 	}
 
-	agg, err := AggregateStats(dir)
+	agg, err := AggregateStats(dir, nil)
 	if err != nil {
 		t.Fatalf("aggregate stats: %v", err)
 	}
@@ -200,7 +236,7 @@ func TestAggregateStatsAcceptsLegacyPromptField(t *testing.T) {
 		t.Fatalf("write stats file: %v", err)
 	}
 
-	agg, err := AggregateStats(dir)
+	agg, err := AggregateStats(dir, nil)
 	if err != nil {
 		t.Fatalf("aggregate stats: %v", err)
 	}
@@ -214,3 +250,23 @@ func TestAggregateStatsAcceptsLegacyPromptField(t *testing.T) {
 		t.Fatalf("expected no skipped files, got %#v", agg.SkippedFiles)
 	}
 }
+
+func TestAggregateStatsAppliesPredicate(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	saveTestRun(t, dir, "ok", RunStatusSuccess, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	saveTestRun(t, dir, "failed", RunStatusError, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC))
+
+	filter := &RunFilter{Status: RunStatusError}
+	agg, err := AggregateStats(dir, filter.Matches)
+	if err != nil {
+		t.Fatalf("aggregate stats: %v", err)
+	}
+	if agg.TotalRuns != 1 || agg.ErrorRuns != 1 || agg.SuccessRuns != 0 {
+		t.Fatalf("unexpected aggregate: %+v", agg)
+	}
+	if !agg.FirstRunAt.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected first run: %v", agg.FirstRunAt)
+	}
+}
@@ -0,0 +1,178 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Metric names a per-run value that has a distribution and a histogram.
+type Metric string
+
+const (
+	MetricDuration    Metric = "duration"
+	MetricAPIDuration Metric = "api_duration"
+	MetricCost        Metric = "cost"
+	MetricTurns       Metric = "turns"
+	MetricTokens      Metric = "tokens"
+)
+
+// Distribution summarizes the values of one metric. Percentiles are nearest-rank.
+type Distribution struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// RunDistributions holds the per-run distribution of each metric.
+type RunDistributions struct {
+	DurationMS    Distribution `json:"duration_ms"`
+	DurationAPIMS Distribution `json:"duration_api_ms"`
+	CostUSD       Distribution `json:"cost_usd"`
+	NumTurns      Distribution `json:"num_turns"`
+	TotalTokens   Distribution `json:"total_tokens"`
+}
+
+// Histogram counts the runs per equal-width bucket of a metric.
+type Histogram struct {
+	Metric  Metric            `json:"metric"`
+	Buckets []HistogramBucket `json:"buckets"`
+}
+
+// HistogramBucket covers [Lower, Upper); the last bucket includes Upper.
+type HistogramBucket struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int     `json:"count"`
+}
+
+// ParseMetric validates a metric name.
+func ParseMetric(value string) (Metric, error) {
+	metric := Metric(strings.ToLower(strings.TrimSpace(value)))
+	switch metric {
+	case MetricDuration, MetricAPIDuration, MetricCost, MetricTurns, MetricTokens:
+		return metric, nil
+	}
+	return "", fmt.Errorf("invalid metric %q: expected duration, api_duration, cost, turns or tokens", value)
+}
+
+// Value returns the metric of record.
+func (m Metric) Value(record *RunRecord) float64 {
+	switch m {
+	case MetricDuration:
+		return float64(record.Normalized.DurationMS)
+	case MetricAPIDuration:
+		return float64(record.Normalized.DurationAPIMS)
+	case MetricCost:
+		return record.Normalized.TotalCostUSD
+	case MetricTurns:
+		return float64(record.Normalized.NumTurns)
+	case MetricTokens:
+		return float64(recordTotalTokens(record))
+	}
+	return 0
+}
+
+// HistogramRuns buckets metric over the runs accepted by keep.
+func HistogramRuns(runs []RunEntry, keep RunPredicate, metric Metric, buckets int) *Histogram {
+	values := make([]float64, 0, len(runs))
+	for _, run := range runs {
+		if keep != nil && !keep(run.Record) {
+			continue
+		}
+		values = append(values, metric.Value(run.Record))
+	}
+	return &Histogram{Metric: metric, Buckets: buildHistogram(values, buckets)}
+}
+
+func buildHistogram(values []float64, buckets int) []HistogramBucket {
+	if len(values) == 0 || buckets < 1 {
+		return []HistogramBucket{}
+	}
+	lower, upper := slices.Min(values), slices.Max(values)
+	if lower == upper {
+		return []HistogramBucket{{Lower: lower, Upper: upper, Count: len(values)}}
+	}
+
+	width := (upper - lower) / float64(buckets)
+	result := make([]HistogramBucket, buckets)
+	for index := range result {
+		result[index].Lower = lower + float64(index)*width
+		result[index].Upper = lower + float64(index+1)*width
+	}
+	result[buckets-1].Upper = upper
+	for _, value := range values {
+		index := min(int((value-lower)/width), buckets-1)
+		result[index].Count++
+	}
+	return result
+}
+
+// distributionSamples collects the per-run metric values of an aggregate.
+type distributionSamples struct {
+	durationMS    []float64
+	durationAPIMS []float64
+	costUSD       []float64
+	numTurns      []float64
+	totalTokens   []float64
+}
+
+func (s *distributionSamples) add(record *RunRecord) {
+	s.durationMS = append(s.durationMS, MetricDuration.Value(record))
+	s.durationAPIMS = append(s.durationAPIMS, MetricAPIDuration.Value(record))
+	s.costUSD = append(s.costUSD, MetricCost.Value(record))
+	s.numTurns = append(s.numTurns, MetricTurns.Value(record))
+	s.totalTokens = append(s.totalTokens, MetricTokens.Value(record))
+}
+
+func (s *distributionSamples) distributions() RunDistributions {
+	return RunDistributions{
+		DurationMS:    newDistribution(s.durationMS),
+		DurationAPIMS: newDistribution(s.durationAPIMS),
+		CostUSD:       newDistribution(s.costUSD),
+		NumTurns:      newDistribution(s.numTurns),
+		TotalTokens:   newDistribution(s.totalTokens),
+	}
+}
+
+func newDistribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	sum := 0.0
+	for _, value := range sorted {
+		sum += value
+	}
+	return Distribution{
+		Min:  sorted[0],
+		Mean: sum / float64(len(sorted)),
+		P50:  percentile(sorted, 50),
+		P90:  percentile(sorted, 90),
+		P99:  percentile(sorted, 99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted values, or 0 when there are none.
+func percentile[T int64 | float64](sorted []T, p float64) T {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = min(max(rank, 1), len(sorted))
+	return sorted[rank-1]
+}
+
+func recordTotalTokens(record *RunRecord) int64 {
+	return record.Normalized.InputTokens +
+		record.Normalized.CacheCreationInputTokens +
+		record.Normalized.CacheReadInputTokens +
+		record.Normalized.OutputTokens
+}
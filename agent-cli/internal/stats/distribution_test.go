@@ -0,0 +1,118 @@
+package stats
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-cli/internal/result"
+)
+
+func TestAggregateStatsDistributions(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for index, cost := range []float64{4, 1, 3, 2} {
+		record := &RunRecord{
+			RunID:     string(rune('a' + index)),
+			Timestamp: base.Add(time.Duration(index) * time.Hour),
+			Status:    RunStatusSuccess,
+			Normalized: result.NormalizedMetrics{
+				TotalCostUSD: cost,
+				DurationMS:   int64(cost * 1000),
+				NumTurns:     int64(cost),
+				InputTokens:  int64(cost * 10),
+				OutputTokens: int64(cost * 5),
+			},
+		}
+		if _, err := SaveRunRecord(dir, record, RetentionPolicy{}); err != nil {
+			t.Fatalf("save record: %v", err)
+		}
+	}
+
+	agg, err := AggregateStats(dir, nil)
+	if err != nil {
+		t.Fatalf("aggregate stats: %v", err)
+	}
+	want := Distribution{Min: 1, Mean: 2.5, P50: 2, P90: 4, P99: 4, Max: 4}
+	if agg.Distributions.CostUSD != want {
+		t.Fatalf("unexpected cost distribution: %+v", agg.Distributions.CostUSD)
+	}
+	if agg.Distributions.DurationMS.P50 != 2000 || agg.Distributions.NumTurns.Max != 4 {
+		t.Fatalf("unexpected distributions: %+v", agg.Distributions)
+	}
+	if agg.Distributions.TotalTokens.Min != 15 || agg.Distributions.TotalTokens.Max != 60 {
+		t.Fatalf("unexpected token distribution: %+v", agg.Distributions.TotalTokens)
+	}
+
+	runs, _, err := ListRunSummaries(dir)
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	groups := GroupRuns(runs, nil, GroupByStatus)
+	if len(groups) != 1 || groups[0].Distributions.CostUSD != want {
+		t.Fatalf("unexpected group distributions: %+v", groups)
+	}
+
+	histogram := HistogramRuns(runs, nil, MetricCost, 3)
+	counts := make([]int, 0, len(histogram.Buckets))
+	for _, bucket := range histogram.Buckets {
+		counts = append(counts, bucket.Count)
+	}
+	if histogram.Metric != MetricCost || len(counts) != 3 || counts[0] != 1 || counts[1] != 1 || counts[2] != 2 {
+		t.Fatalf("unexpected histogram: %+v", histogram)
+	}
+	if histogram.Buckets[0].Lower != 1 || histogram.Buckets[2].Upper != 4 {
+		t.Fatalf("unexpected histogram bounds: %+v", histogram.Buckets)
+	}
+}
+
+func TestBuildHistogramEdgeCases(t *testing.T) {
+	t.Parallel()
+
+	if buckets := buildHistogram(nil, 10); len(buckets) != 0 {
+		t.Fatalf("unexpected empty histogram: %+v", buckets)
+	}
+	buckets := buildHistogram([]float64{5, 5, 5}, 10)
+	if len(buckets) != 1 || buckets[0] != (HistogramBucket{Lower: 5, Upper: 5, Count: 3}) {
+		t.Fatalf("unexpected constant histogram: %+v", buckets)
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	t.Parallel()
+
+	for _, value := range []string{"duration", "api_duration", "COST", "turns", "tokens"} {
+		if _, err := ParseMetric(value); err != nil {
+			t.Fatalf("parse %q: %v", value, err)
+		}
+	}
+	if _, err := ParseMetric("latency"); err == nil || !strings.Contains(err.Error(), `invalid metric "latency"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+
+	values := []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	tests := []struct {
+		p    float64
+		want int64
+	}{
+		{p: 0, want: 10},
+		{p: 50, want: 50},
+		{p: 95, want: 100},
+		{p: 100, want: 100},
+	}
+	for _, tt := range tests {
+		if got := percentile(values, tt.p); got != tt.want {
+			t.Fatalf("unexpected p%v: %d", tt.p, got)
+		}
+	}
+	if got := percentile([]int64(nil), 50); got != 0 {
+		t.Fatalf("unexpected empty percentile: %d", got)
+	}
+}
@@ -0,0 +1,166 @@
+package stats
+
+import "sort"
+
+const (
+	// EstimateBasisPlan estimates from past runs of the same plan file.
+	EstimateBasisPlan = "plan"
+	// EstimateBasisNodes estimates from past pipeline runs of other plans that ran nodes with the same ids.
+	EstimateBasisNodes = "nodes"
+)
+
+// PipelineEstimate is the expected cost and duration of a pipeline run, from past runs.
+type PipelineEstimate struct {
+	Pipeline string `json:"pipeline"`
+	// Basis is EstimateBasisPlan or EstimateBasisNodes, and empty when there was no past run to go by.
+	Basis string `json:"basis,omitempty"`
+	// Runs is the number of past runs the estimate is based on.
+	Runs int `json:"runs"`
+	// CostUSD and DurationMS are the ranges of the sum over the plan's node runs per past run.
+	CostUSD    CostRange      `json:"cost_usd"`
+	DurationMS DurationRange  `json:"duration_ms"`
+	Nodes      []NodeEstimate `json:"nodes"`
+}
+
+// NodeEstimate is the expected cost and duration of one node, summed over its visits in a run.
+type NodeEstimate struct {
+	NodeID string `json:"node_id"`
+	// Runs counts the past runs that visited the node; VisitRate is their share of all past runs.
+	// The ranges only cover those runs.
+	Runs       int           `json:"runs"`
+	VisitRate  float64       `json:"visit_rate"`
+	CostUSD    CostRange     `json:"cost_usd"`
+	DurationMS DurationRange `json:"duration_ms"`
+}
+
+// CostRange is the median and 90th percentile of a USD cost.
+type CostRange struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+}
+
+// DurationRange is the median and 90th percentile of a duration in milliseconds.
+type DurationRange struct {
+	P50 int64 `json:"p50"`
+	P90 int64 `json:"p90"`
+}
+
+// EstimatePipeline estimates a run of the plan at planPath, whose nodes are nodeIDs, from the runs in runsDir.
+// It reads the run summaries, so it does not load every stats.json.
+func EstimatePipeline(runsDir string, planPath string, nodeIDs []string) (*PipelineEstimate, error) {
+	runs, _, err := ListRunSummaries(runsDir)
+	if err != nil {
+		return nil, err
+	}
+	return EstimatePipelineRuns(runs, planPath, nodeIDs), nil
+}
+
+// EstimatePipelineRuns is EstimatePipeline over already loaded runs, either full records or summaries.
+// Runs of the same plan are used when there are any, else pipeline runs that visited one of nodeIDs.
+// Resumed runs only cover part of a path and are left out. Costs are effective costs, see ApplyPricing.
+func EstimatePipelineRuns(runs []RunEntry, planPath string, nodeIDs []string) *PipelineEstimate {
+	estimate := &PipelineEstimate{
+		Pipeline: planPath,
+		Nodes:    make([]NodeEstimate, 0, len(nodeIDs)),
+	}
+	planNodes := make(map[string]bool, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		planNodes[nodeID] = true
+	}
+
+	samePlan := (&RunFilter{Pipeline: planPath}).Matches
+	candidates := make([]*RunRecord, 0, len(runs))
+	for _, run := range runs {
+		record := run.Record
+		if record.Pipeline != nil && record.ParentRunID == "" {
+			candidates = append(candidates, record)
+		}
+	}
+	history := make([]*RunRecord, 0, len(candidates))
+	for _, record := range candidates {
+		if planPath != "" && samePlan(record) {
+			history = append(history, record)
+		}
+	}
+	basis := EstimateBasisPlan
+	if len(history) == 0 {
+		for _, record := range candidates {
+			if visitsAnyNode(record, planNodes) {
+				history = append(history, record)
+			}
+		}
+		basis = EstimateBasisNodes
+	}
+	if len(history) > 0 {
+		estimate.Basis = basis
+	}
+	estimate.Runs = len(history)
+
+	nodeCosts := map[string][]float64{}
+	nodeDurations := map[string][]int64{}
+	runCosts := make([]float64, 0, len(history))
+	runDurations := make([]int64, 0, len(history))
+	for _, record := range history {
+		var runCost float64
+		var runDuration int64
+		for _, total := range pipelineNodeTotals(record.Pipeline) {
+			if !planNodes[total.NodeID] {
+				continue
+			}
+			nodeCosts[total.NodeID] = append(nodeCosts[total.NodeID], total.EffectiveCostUSD)
+			nodeDurations[total.NodeID] = append(nodeDurations[total.NodeID], total.DurationMS)
+			runCost += total.EffectiveCostUSD
+			runDuration += total.DurationMS
+		}
+		runCosts = append(runCosts, runCost)
+		runDurations = append(runDurations, runDuration)
+	}
+
+	estimate.CostUSD = newCostRange(runCosts)
+	estimate.DurationMS = newDurationRange(runDurations)
+	for _, nodeID := range nodeIDs {
+		node := NodeEstimate{
+			NodeID:     nodeID,
+			Runs:       len(nodeCosts[nodeID]),
+			CostUSD:    newCostRange(nodeCosts[nodeID]),
+			DurationMS: newDurationRange(nodeDurations[nodeID]),
+		}
+		if estimate.Runs > 0 {
+			node.VisitRate = float64(node.Runs) / float64(estimate.Runs)
+		}
+		estimate.Nodes = append(estimate.Nodes, node)
+	}
+	return estimate
+}
+
+// Node returns the estimate of nodeID, or nil when it is not a node of the plan.
+func (e *PipelineEstimate) Node(nodeID string) *NodeEstimate {
+	if e == nil {
+		return nil
+	}
+	for index := range e.Nodes {
+		if e.Nodes[index].NodeID == nodeID {
+			return &e.Nodes[index]
+		}
+	}
+	return nil
+}
+
+func visitsAnyNode(record *RunRecord, nodeIDs map[string]bool) bool {
+	for _, total := range pipelineNodeTotals(record.Pipeline) {
+		if nodeIDs[total.NodeID] {
+			return true
+		}
+	}
+	return false
+}
+
+func newCostRange(values []float64) CostRange {
+	sort.Float64s(values)
+	return CostRange{P50: percentile(values, 50), P90: percentile(values, 90)}
+}
+
+func newDurationRange(values []int64) DurationRange {
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return DurationRange{P50: percentile(values, 50), P90: percentile(values, 90)}
+}
@@ -0,0 +1,125 @@
+package stats
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEstimatePipelineRuns(t *testing.T) {
+	t.Parallel()
+
+	nodeRun := func(nodeID string, costUSD float64, durationMS int64) PipelineNodeRunRecord {
+		return PipelineNodeRunRecord{
+			NodeID:     nodeID,
+			DurationMS: durationMS,
+			Normalized: &PipelineNodeRunNormalized{CostUSD: costUSD},
+		}
+	}
+	pipelineRun := func(planPath string, parentRunID string, nodeRuns ...PipelineNodeRunRecord) RunEntry {
+		return RunEntry{Record: &RunRecord{
+			PipelinePath: planPath,
+			ParentRunID:  parentRunID,
+			Pipeline:     &PipelineRunRecord{NodeRuns: nodeRuns},
+		}}
+	}
+	lintNode := PipelineNodeRunRecord{NodeID: "lint", DurationMS: 500}
+
+	runs := []RunEntry{
+		// The implement -> review loop ran twice in the first run.
+		pipelineRun("/work/go-flow.yml", "", nodeRun("implement", 1, 1000), nodeRun("review", 0.5, 400), nodeRun("implement", 1, 1000), nodeRun("review", 0.5, 400), lintNode),
+		pipelineRun("/work/go-flow.yml", "", nodeRun("implement", 1.5, 3000), lintNode),
+		pipelineRun("/work/go-flow.yml", "", nodeRun("implement", 0.5, 2000), nodeRun("review", 0.25, 200), lintNode),
+		// Resumed runs are left out.
+		pipelineRun("/work/go-flow.yml", "r1", nodeRun("review", 9, 9000)),
+		pipelineRun("/work/other.yml", "", nodeRun("implement", 4, 8000)),
+		{Record: &RunRecord{PipelinePath: "/work/go-flow.yml"}},
+	}
+	nodeIDs := []string{"implement", "review", "lint", "deploy"}
+
+	estimate := EstimatePipelineRuns(runs, "/work/./go-flow.yml", nodeIDs)
+	if estimate.Basis != EstimateBasisPlan || estimate.Runs != 3 || len(estimate.Nodes) != 4 {
+		t.Fatalf("unexpected estimate: %+v", estimate)
+	}
+	// Per run: 3, 1.5 and 0.75 USD; 3300, 3500 and 2700 ms.
+	assertCost(t, "total p50", estimate.CostUSD.P50, 1.5)
+	assertCost(t, "total p90", estimate.CostUSD.P90, 3)
+	if estimate.DurationMS != (DurationRange{P50: 3300, P90: 3500}) {
+		t.Fatalf("unexpected total duration: %+v", estimate.DurationMS)
+	}
+
+	implement := estimate.Node("implement")
+	if implement.Runs != 3 || implement.VisitRate != 1 || implement.DurationMS != (DurationRange{P50: 2000, P90: 3000}) {
+		t.Fatalf("unexpected implement estimate: %+v", implement)
+	}
+	assertCost(t, "implement p50", implement.CostUSD.P50, 1.5)
+	assertCost(t, "implement p90", implement.CostUSD.P90, 2)
+
+	review := estimate.Node("review")
+	if review.Runs != 2 || review.VisitRate != 2.0/3 || review.DurationMS != (DurationRange{P50: 200, P90: 800}) {
+		t.Fatalf("unexpected review estimate: %+v", review)
+	}
+	lint := estimate.Node("lint")
+	if lint.Runs != 3 || lint.CostUSD != (CostRange{}) || lint.DurationMS.P50 != 500 {
+		t.Fatalf("unexpected lint estimate: %+v", lint)
+	}
+	if deploy := estimate.Node("deploy"); deploy.Runs != 0 || deploy.VisitRate != 0 {
+		t.Fatalf("unexpected deploy estimate: %+v", deploy)
+	}
+	if estimate.Node("missing") != nil {
+		t.Fatal("unexpected estimate for a node outside the plan")
+	}
+
+	// A new plan falls back to past runs of any plan with the same node ids.
+	estimate = EstimatePipelineRuns(runs, "/work/new-flow.yml", []string{"implement"})
+	if estimate.Basis != EstimateBasisNodes || estimate.Runs != 4 {
+		t.Fatalf("unexpected node-based estimate: %+v", estimate)
+	}
+	assertCost(t, "node-based p90", estimate.CostUSD.P90, 4)
+
+	estimate = EstimatePipelineRuns(runs, "/work/new-flow.yml", []string{"deploy"})
+	if estimate.Basis != "" || estimate.Runs != 0 || len(estimate.Nodes) != 1 {
+		t.Fatalf("unexpected estimate without history: %+v", estimate)
+	}
+}
+
+func TestEstimatePipelineFromRunSummaries(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for index, costUSD := range []float64{1, 2, 4} {
+		record := &RunRecord{
+			RunID:        fmt.Sprintf("r%d", index),
+			Timestamp:    base.Add(time.Duration(index) * time.Hour),
+			PipelinePath: "/work/go-flow.yml",
+			Pipeline: &PipelineRunRecord{NodeRuns: []PipelineNodeRunRecord{
+				{NodeID: "implement", DurationMS: 1000, Normalized: &PipelineNodeRunNormalized{CostUSD: costUSD}},
+				{NodeID: "review", DurationMS: 500, Normalized: &PipelineNodeRunNormalized{CostUSD: 0.5}},
+				{NodeID: "implement", DurationMS: 1000, Normalized: &PipelineNodeRunNormalized{CostUSD: costUSD}},
+			}},
+		}
+		if _, err := SaveRunRecord(dir, record, RetentionPolicy{}); err != nil {
+			t.Fatalf("save record: %v", err)
+		}
+	}
+	nodeIDs := []string{"implement", "review"}
+
+	estimate, err := EstimatePipeline(dir, "/work/go-flow.yml", nodeIDs)
+	if err != nil {
+		t.Fatalf("estimate pipeline: %v", err)
+	}
+	runs, _, err := ListRunRecords(dir)
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	if want := EstimatePipelineRuns(runs, "/work/go-flow.yml", nodeIDs); !reflect.DeepEqual(estimate, want) {
+		t.Fatalf("summary estimate %+v differs from full record estimate %+v", estimate, want)
+	}
+	assertCost(t, "implement p50", estimate.Node("implement").CostUSD.P50, 4)
+	if estimate.DurationMS.P50 != 2500 {
+		t.Fatalf("unexpected duration: %+v", estimate.DurationMS)
+	}
+}
@@ -0,0 +1,349 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"agent-cli/internal/result"
+)
+
+// ExportSchemaVersion is written as the first column of every exported row. It changes
+// whenever a column is renamed, removed or changes meaning; new columns are only appended.
+const ExportSchemaVersion = 1
+
+// ExportFormat is the file format of an export.
+type ExportFormat string
+
+const (
+	ExportFormatCSV   ExportFormat = "csv"
+	ExportFormatJSONL ExportFormat = "jsonl"
+)
+
+// ExportLevel selects what one exported row is: a run, a pipeline node run or a model of a run.
+type ExportLevel string
+
+const (
+	ExportLevelRun   ExportLevel = "run"
+	ExportLevelNode  ExportLevel = "node"
+	ExportLevelModel ExportLevel = "model"
+)
+
+// ParseExportFormat validates an export format name.
+func ParseExportFormat(value string) (ExportFormat, error) {
+	format := ExportFormat(strings.ToLower(strings.TrimSpace(value)))
+	switch format {
+	case ExportFormatCSV, ExportFormatJSONL:
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid format %q: expected csv or jsonl", value)
+}
+
+// ParseExportLevel validates an export level name.
+func ParseExportLevel(value string) (ExportLevel, error) {
+	level := ExportLevel(strings.ToLower(strings.TrimSpace(value)))
+	switch level {
+	case ExportLevelRun, ExportLevelNode, ExportLevelModel:
+		return level, nil
+	}
+	return "", fmt.Errorf("invalid level %q: expected run, node or model", value)
+}
+
+type exportColumn[T any] struct {
+	name  string
+	value func(row T) any
+}
+
+type nodeExportRow struct {
+	record  *RunRecord
+	nodeRun *PipelineNodeRunRecord
+}
+
+type modelExportRow struct {
+	record *RunRecord
+	model  string
+	metric result.ModelMetric
+}
+
+// runKeyColumns lead every level so rows can be joined on run_id.
+func runKeyColumns[T any](record func(T) *RunRecord) []exportColumn[T] {
+	return []exportColumn[T]{
+		{name: "schema_version", value: func(T) any { return ExportSchemaVersion }},
+		{name: "run_id", value: func(row T) any { return record(row).RunID }},
+		{name: "timestamp", value: func(row T) any { return record(row).Timestamp.UTC().Format(time.RFC3339Nano) }},
+	}
+}
+
+var runExportColumns = append(runKeyColumns(func(record *RunRecord) *RunRecord { return record }), []exportColumn[*RunRecord]{
+	{name: "status", value: func(record *RunRecord) any { return string(record.Status) }},
+	{name: "mode", value: func(record *RunRecord) any { return RunMode(record) }},
+	{name: "error_type", value: func(record *RunRecord) any { return record.ErrorType }},
+	{name: "model", value: func(record *RunRecord) any { return record.Model }},
+	{name: "profile", value: func(record *RunRecord) any { return record.Profile }},
+	{name: "pipeline_path", value: func(record *RunRecord) any { return record.PipelinePath }},
+	{name: "batch_id", value: func(record *RunRecord) any { return record.BatchID }},
+	{name: "parent_run_id", value: func(record *RunRecord) any { return record.ParentRunID }},
+	{name: "docker_exit_code", value: func(record *RunRecord) any { return record.DockerExitCode }},
+	{name: "terminal_node", value: func(record *RunRecord) any {
+		return pipelineField(record, func(p *PipelineRunRecord) any { return p.TerminalNode })
+	}},
+	{name: "terminal_status", value: func(record *RunRecord) any {
+		return pipelineField(record, func(p *PipelineRunRecord) any { return p.TerminalStatus })
+	}},
+	{name: "iterations", value: func(record *RunRecord) any {
+		return pipelineField(record, func(p *PipelineRunRecord) any { return p.Iterations })
+	}},
+	{name: "node_run_count", value: func(record *RunRecord) any {
+		return pipelineField(record, func(p *PipelineRunRecord) any { return p.NodeRunCount })
+	}},
+	{name: "failed_node_count", value: func(record *RunRecord) any {
+		return pipelineField(record, func(p *PipelineRunRecord) any { return p.FailedNodeCount })
+	}},
+	{name: "duration_ms", value: func(record *RunRecord) any { return record.Normalized.DurationMS }},
+	{name: "duration_api_ms", value: func(record *RunRecord) any { return record.Normalized.DurationAPIMS }},
+	{name: "num_turns", value: func(record *RunRecord) any { return record.Normalized.NumTurns }},
+	{name: "input_tokens", value: func(record *RunRecord) any { return record.Normalized.InputTokens }},
+	{name: "cache_creation_input_tokens", value: func(record *RunRecord) any { return record.Normalized.CacheCreationInputTokens }},
+	{name: "cache_read_input_tokens", value: func(record *RunRecord) any { return record.Normalized.CacheReadInputTokens }},
+	{name: "output_tokens", value: func(record *RunRecord) any { return record.Normalized.OutputTokens }},
+	{name: "total_tokens", value: func(record *RunRecord) any { return recordTotalTokens(record) }},
+	{name: "total_cost_usd", value: func(record *RunRecord) any { return record.Normalized.TotalCostUSD }},
+	{name: "budget_exceeded", value: func(record *RunRecord) any { return record.BudgetExceeded != nil }},
+	{name: "labels", value: func(record *RunRecord) any { return record.Labels }},
+	{name: "effective_cost_usd", value: func(record *RunRecord) any { return EffectiveCostUSD(record) }},
+}...)
+
+var nodeExportColumns = append(runKeyColumns(func(row nodeExportRow) *RunRecord { return row.record }), []exportColumn[nodeExportRow]{
+	{name: "node_id", value: func(row nodeExportRow) any { return row.nodeRun.NodeID }},
+	{name: "node_run_id", value: func(row nodeExportRow) any { return row.nodeRun.NodeRunID }},
+	{name: "kind", value: func(row nodeExportRow) any { return row.nodeRun.Kind }},
+	{name: "status", value: func(row nodeExportRow) any { return row.nodeRun.Status }},
+	{name: "model", value: func(row nodeExportRow) any { return row.nodeRun.Model }},
+	{name: "exit_code", value: func(row nodeExportRow) any { return row.nodeRun.ExitCode }},
+	{name: "timed_out", value: func(row nodeExportRow) any { return row.nodeRun.TimedOut }},
+	{name: "started_at", value: func(row nodeExportRow) any { return formatExportTime(row.nodeRun.StartedAt) }},
+	{name: "finished_at", value: func(row nodeExportRow) any { return formatExportTime(row.nodeRun.FinishedAt) }},
+	{name: "duration_ms", value: func(row nodeExportRow) any { return row.nodeRun.DurationMS }},
+	{name: "input_tokens", value: func(row nodeExportRow) any {
+		return nodeUsage(row, func(n *PipelineNodeRunNormalized) any { return n.InputTokens })
+	}},
+	{name: "cache_creation_input_tokens", value: func(row nodeExportRow) any {
+		return nodeUsage(row, func(n *PipelineNodeRunNormalized) any { return n.CacheCreationInputTokens })
+	}},
+	{name: "cache_read_input_tokens", value: func(row nodeExportRow) any {
+		return nodeUsage(row, func(n *PipelineNodeRunNormalized) any { return n.CacheReadInputTokens })
+	}},
+	{name: "output_tokens", value: func(row nodeExportRow) any {
+		return nodeUsage(row, func(n *PipelineNodeRunNormalized) any { return n.OutputTokens })
+	}},
+	{name: "cost_usd", value: func(row nodeExportRow) any {
+		return nodeUsage(row, func(n *PipelineNodeRunNormalized) any { return n.CostUSD })
+	}},
+	{name: "web_search_requests", value: func(row nodeExportRow) any {
+		return nodeUsage(row, func(n *PipelineNodeRunNormalized) any { return n.WebSearchRequests })
+	}},
+	{name: "error_message", value: func(row nodeExportRow) any { return row.nodeRun.ErrorMessage }},
+	{name: "effective_cost_usd", value: func(row nodeExportRow) any {
+		return nodeUsage(row, func(n *PipelineNodeRunNormalized) any { return nodeEffectiveCostUSD(n) })
+	}},
+}...)
+
+var modelExportColumns = append(runKeyColumns(func(row modelExportRow) *RunRecord { return row.record }), []exportColumn[modelExportRow]{
+	{name: "status", value: func(row modelExportRow) any { return string(row.record.Status) }},
+	{name: "model", value: func(row modelExportRow) any { return row.model }},
+	{name: "input_tokens", value: func(row modelExportRow) any { return row.metric.InputTokens }},
+	{name: "cache_creation_input_tokens", value: func(row modelExportRow) any { return row.metric.CacheCreationInputTokens }},
+	{name: "cache_read_input_tokens", value: func(row modelExportRow) any { return row.metric.CacheReadInputTokens }},
+	{name: "output_tokens", value: func(row modelExportRow) any { return row.metric.OutputTokens }},
+	{name: "web_search_requests", value: func(row modelExportRow) any { return row.metric.WebSearchRequests }},
+	{name: "cost_usd", value: func(row modelExportRow) any { return row.metric.CostUSD }},
+	{name: "effective_cost_usd", value: func(row modelExportRow) any { return modelEffectiveCostUSD(row.record, row.model) }},
+}...)
+
+// ExportColumns returns the column names of level in export order.
+func ExportColumns(level ExportLevel) []string {
+	switch level {
+	case ExportLevelNode:
+		return exportColumnNames(nodeExportColumns)
+	case ExportLevelModel:
+		return exportColumnNames(modelExportColumns)
+	}
+	return exportColumnNames(runExportColumns)
+}
+
+// ExportRunEntries writes the runs accepted by keep to w, one row per level item in run order. The
+// records must include node runs, as ListRunRecords loads them. CSV output always has a header row,
+// even without runs.
+func ExportRunEntries(w io.Writer, runs []RunEntry, keep RunPredicate, format ExportFormat, level ExportLevel) error {
+	records := make([]*RunRecord, 0, len(runs))
+	for _, run := range runs {
+		if keep == nil || keep(run.Record) {
+			records = append(records, run.Record)
+		}
+	}
+
+	var rows [][]any
+	switch level {
+	case ExportLevelNode:
+		items := make([]nodeExportRow, 0)
+		for _, record := range records {
+			if record.Pipeline == nil {
+				continue
+			}
+			for index := range record.Pipeline.NodeRuns {
+				items = append(items, nodeExportRow{record: record, nodeRun: &record.Pipeline.NodeRuns[index]})
+			}
+		}
+		rows = exportRowValues(nodeExportColumns, items)
+	case ExportLevelModel:
+		items := make([]modelExportRow, 0)
+		for _, record := range records {
+			models := make([]string, 0, len(record.Normalized.ByModel))
+			for model := range record.Normalized.ByModel {
+				models = append(models, model)
+			}
+			sort.Strings(models)
+			for _, model := range models {
+				items = append(items, modelExportRow{record: record, model: model, metric: record.Normalized.ByModel[model]})
+			}
+		}
+		rows = exportRowValues(modelExportColumns, items)
+	default:
+		rows = exportRowValues(runExportColumns, records)
+	}
+
+	columns := ExportColumns(level)
+	if format == ExportFormatJSONL {
+		return writeExportJSONL(w, columns, rows)
+	}
+	return writeExportCSV(w, columns, rows)
+}
+
+func exportColumnNames[T any](columns []exportColumn[T]) []string {
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, column.name)
+	}
+	return names
+}
+
+func exportRowValues[T any](columns []exportColumn[T], items []T) [][]any {
+	rows := make([][]any, 0, len(items))
+	for _, item := range items {
+		row := make([]any, 0, len(columns))
+		for _, column := range columns {
+			row = append(row, column.value(item))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func writeExportCSV(w io.Writer, columns []string, rows [][]any) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return fmt.Errorf("write export header: %w", err)
+	}
+	for _, row := range rows {
+		cells := make([]string, 0, len(row))
+		for _, value := range row {
+			cell, err := formatExportCell(value)
+			if err != nil {
+				return err
+			}
+			cells = append(cells, cell)
+		}
+		if err := writer.Write(cells); err != nil {
+			return fmt.Errorf("write export row: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+	return nil
+}
+
+// writeExportJSONL writes one object per row with keys in column order.
+func writeExportJSONL(w io.Writer, columns []string, rows [][]any) error {
+	var line bytes.Buffer
+	for _, row := range rows {
+		line.Reset()
+		line.WriteByte('{')
+		for index, value := range row {
+			if index > 0 {
+				line.WriteByte(',')
+			}
+			key, _ := json.Marshal(columns[index])
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("encode export column %s: %w", columns[index], err)
+			}
+			line.Write(key)
+			line.WriteByte(':')
+			line.Write(encoded)
+		}
+		line.WriteString("}\n")
+		if _, err := w.Write(line.Bytes()); err != nil {
+			return fmt.Errorf("write export row: %w", err)
+		}
+	}
+	return nil
+}
+
+// formatExportCell renders a column value as CSV text. Maps are written as JSON objects.
+func formatExportCell(value any) (string, error) {
+	switch typed := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return typed, nil
+	case bool:
+		return strconv.FormatBool(typed), nil
+	case int:
+		return strconv.Itoa(typed), nil
+	case int64:
+		return strconv.FormatInt(typed, 10), nil
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64), nil
+	case map[string]string:
+		if len(typed) == 0 {
+			return "", nil
+		}
+		encoded, err := json.Marshal(typed)
+		if err != nil {
+			return "", fmt.Errorf("encode export cell: %w", err)
+		}
+		return string(encoded), nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+func formatExportTime(value time.Time) any {
+	if value.IsZero() {
+		return nil
+	}
+	return value.UTC().Format(time.RFC3339Nano)
+}
+
+// pipelineField returns nil for prompt runs so they export empty pipeline columns.
+func pipelineField(record *RunRecord, field func(*PipelineRunRecord) any) any {
+	if record.Pipeline == nil {
+		return nil
+	}
+	return field(record.Pipeline)
+}
+
+// nodeUsage returns nil for node runs without usage, such as command nodes.
+func nodeUsage(row nodeExportRow, field func(*PipelineNodeRunNormalized) any) any {
+	if row.nodeRun.Normalized == nil {
+		return nil
+	}
+	return field(row.nodeRun.Normalized)
+}
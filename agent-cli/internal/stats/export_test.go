@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-cli/internal/result"
+)
+
+func TestExportRunEntries(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, record := range []*RunRecord{
+		{
+			RunID:        "pipeline-run",
+			Timestamp:    base,
+			Status:       RunStatusSuccess,
+			PipelinePath: "/work/flow.yml",
+			Labels:       map[string]string{"team": "core"},
+			Pipeline: &PipelineRunRecord{
+				TerminalNode: "done",
+				NodeRuns: []PipelineNodeRunRecord{
+					{NodeID: "implement", NodeRunID: "1", Kind: "agent", Status: "success", Normalized: &PipelineNodeRunNormalized{CostUSD: 0.25}},
+					{NodeID: "test", NodeRunID: "1", Kind: "command", Status: "error", TimedOut: true},
+				},
+			},
+			Normalized: result.NormalizedMetrics{
+				TotalCostUSD: 0.25,
+				InputTokens:  10,
+				OutputTokens: 5,
+				ByModel: map[string]result.ModelMetric{
+					"claude-sonnet": {InputTokens: 4, CostUSD: 0.1},
+					"claude-opus":   {InputTokens: 6, CostUSD: 0.15},
+				},
+			},
+		},
+		{
+			RunID:        "prompt-run",
+			Timestamp:    base.Add(time.Hour),
+			Status:       RunStatusError,
+			ErrorType:    "timeout",
+			ErrorMessage: "agent timed out, \"idle\"",
+		},
+	} {
+		if _, err := SaveRunRecord(dir, record, RetentionPolicy{}); err != nil {
+			t.Fatalf("save record: %v", err)
+		}
+	}
+
+	runs, _, err := ListRunRecords(dir)
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	var out bytes.Buffer
+	if err := ExportRunEntries(&out, runs, nil, ExportFormatCSV, ExportLevelRun); err != nil {
+		t.Fatalf("export runs: %v", err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != strings.Join(ExportColumns(ExportLevelRun), ",") {
+		t.Fatalf("unexpected CSV rows: %q", rows)
+	}
+	run := exportRowMap(rows[0], rows[1])
+	if run["schema_version"] != "1" || run["run_id"] != "pipeline-run" || run["timestamp"] != "2026-03-01T10:00:00Z" {
+		t.Fatalf("unexpected run key columns: %v", run)
+	}
+	if run["mode"] != "pipeline" || run["terminal_node"] != "done" || run["total_tokens"] != "15" || run["total_cost_usd"] != "0.25" {
+		t.Fatalf("unexpected run columns: %v", run)
+	}
+	if run["labels"] != `{"team":"core"}` || run["budget_exceeded"] != "false" {
+		t.Fatalf("unexpected run columns: %v", run)
+	}
+	prompt := exportRowMap(rows[0], rows[2])
+	if prompt["mode"] != "prompt" || prompt["terminal_node"] != "" || prompt["error_type"] != "timeout" || prompt["labels"] != "" {
+		t.Fatalf("unexpected prompt run columns: %v", prompt)
+	}
+
+	out.Reset()
+	if err := ExportRunEntries(&out, runs, nil, ExportFormatJSONL, ExportLevelNode); err != nil {
+		t.Fatalf("export nodes: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], `{"schema_version":1,"run_id":"pipeline-run",`) {
+		t.Fatalf("unexpected node lines: %q", lines)
+	}
+	var node map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &node); err != nil {
+		t.Fatalf("decode node line: %v", err)
+	}
+	if node["node_id"] != "test" || node["timed_out"] != true || node["cost_usd"] != nil || node["started_at"] != nil {
+		t.Fatalf("unexpected node columns: %v", node)
+	}
+
+	out.Reset()
+	keep := func(record *RunRecord) bool { return record.Status == RunStatusSuccess }
+	if err := ExportRunEntries(&out, runs, keep, ExportFormatCSV, ExportLevelModel); err != nil {
+		t.Fatalf("export models: %v", err)
+	}
+	rows, err = csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+	if len(rows) != 3 || exportRowMap(rows[0], rows[1])["model"] != "claude-opus" || exportRowMap(rows[0], rows[2])["cost_usd"] != "0.1" {
+		t.Fatalf("unexpected model rows: %q", rows)
+	}
+}
+
+func TestParseExportFormatAndLevel(t *testing.T) {
+	t.Parallel()
+
+	if format, err := ParseExportFormat("JSONL"); err != nil || format != ExportFormatJSONL {
+		t.Fatalf("unexpected format: %q, %v", format, err)
+	}
+	if _, err := ParseExportFormat("parquet"); err == nil || !strings.Contains(err.Error(), `invalid format "parquet"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if level, err := ParseExportLevel("node"); err != nil || level != ExportLevelNode {
+		t.Fatalf("unexpected level: %q, %v", level, err)
+	}
+	if _, err := ParseExportLevel("batch"); err == nil || !strings.Contains(err.Error(), `invalid level "batch"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func exportRowMap(header []string, row []string) map[string]string {
+	values := make(map[string]string, len(header))
+	for index, name := range header {
+		values[name] = row[index]
+	}
+	return values
+}
@@ -0,0 +1,97 @@
+package stats
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	RunModePrompt   = "prompt"
+	RunModePipeline = "pipeline"
+)
+
+// RunPredicate reports whether a run record is included in an aggregation.
+type RunPredicate func(record *RunRecord) bool
+
+// RunFilter selects runs by their record fields. Zero fields match every run; the filter is
+// echoed as JSON so aggregated output states what it covers.
+type RunFilter struct {
+	Since     *time.Time        `json:"since,omitempty"`
+	Until     *time.Time        `json:"until,omitempty"`
+	Status    RunStatus         `json:"status,omitempty"`
+	ErrorType string            `json:"error_type,omitempty"`
+	Mode      string            `json:"mode,omitempty"`
+	Pipeline  string            `json:"pipeline,omitempty"`
+	Model     string            `json:"model,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// IsZero reports whether the filter matches every run.
+func (f *RunFilter) IsZero() bool {
+	return f == nil || (f.Since == nil &&
+		f.Until == nil &&
+		f.Status == "" &&
+		f.ErrorType == "" &&
+		f.Mode == "" &&
+		f.Pipeline == "" &&
+		f.Model == "" &&
+		len(f.Labels) == 0)
+}
+
+// Matches reports whether record passes every set field. Since is inclusive and Until exclusive.
+func (f *RunFilter) Matches(record *RunRecord) bool {
+	if f == nil {
+		return true
+	}
+	if f.Since != nil && record.Timestamp.Before(*f.Since) {
+		return false
+	}
+	if f.Until != nil && !record.Timestamp.Before(*f.Until) {
+		return false
+	}
+	if f.Status != "" && record.Status != f.Status {
+		return false
+	}
+	if f.ErrorType != "" && record.ErrorType != f.ErrorType {
+		return false
+	}
+	if f.Mode != "" && RunMode(record) != f.Mode {
+		return false
+	}
+	if f.Pipeline != "" && filepath.Clean(record.PipelinePath) != filepath.Clean(f.Pipeline) {
+		return false
+	}
+	if f.Model != "" && !runUsedModel(record, f.Model) {
+		return false
+	}
+	for key, value := range f.Labels {
+		if actual, ok := record.Labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// RunMode returns RunModePipeline for pipeline runs and RunModePrompt otherwise.
+func RunMode(record *RunRecord) string {
+	if record.Pipeline != nil || record.PipelinePath != "" {
+		return RunModePipeline
+	}
+	return RunModePrompt
+}
+
+// runUsedModel matches the configured model alias (sonnet, opus) or a substring of a model
+// that reported usage, such as "opus-4" for "claude-opus-4-1".
+func runUsedModel(record *RunRecord, model string) bool {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if strings.EqualFold(record.Model, model) {
+		return true
+	}
+	for name := range record.Normalized.ByModel {
+		if strings.Contains(strings.ToLower(name), model) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"agent-cli/internal/result"
+)
+
+func TestRunFilterMatches(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	record := &RunRecord{
+		Timestamp:    time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC),
+		Status:       RunStatusError,
+		ErrorType:    "pipeline_error",
+		Model:        "opus",
+		PipelinePath: "/work/pipelines/go-flow.yml",
+		Labels:       map[string]string{"team": "core", "agent-cli.batch_id": "b1"},
+		Pipeline:     &PipelineRunRecord{},
+		Normalized: result.NormalizedMetrics{
+			ByModel: map[string]result.ModelMetric{"claude-sonnet-4-5": {}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		filter *RunFilter
+		want   bool
+	}{
+		{name: "nil", filter: nil, want: true},
+		{name: "empty", filter: &RunFilter{}, want: true},
+		{name: "in range", filter: &RunFilter{Since: &since, Until: &until}, want: true},
+		{name: "until is exclusive", filter: &RunFilter{Until: &record.Timestamp}, want: false},
+		{name: "since is inclusive", filter: &RunFilter{Since: &record.Timestamp}, want: true},
+		{name: "status", filter: &RunFilter{Status: RunStatusError, ErrorType: "pipeline_error"}, want: true},
+		{name: "other status", filter: &RunFilter{Status: RunStatusSuccess}, want: false},
+		{name: "other error type", filter: &RunFilter{ErrorType: "timeout"}, want: false},
+		{name: "pipeline mode", filter: &RunFilter{Mode: RunModePipeline}, want: true},
+		{name: "prompt mode", filter: &RunFilter{Mode: RunModePrompt}, want: false},
+		{name: "pipeline path", filter: &RunFilter{Pipeline: "/work/pipelines/../pipelines/go-flow.yml"}, want: true},
+		{name: "other pipeline", filter: &RunFilter{Pipeline: "/work/pipelines/other.yml"}, want: false},
+		{name: "configured model", filter: &RunFilter{Model: "opus"}, want: true},
+		{name: "reported model", filter: &RunFilter{Model: "sonnet-4"}, want: true},
+		{name: "unused model", filter: &RunFilter{Model: "haiku"}, want: false},
+		{name: "labels", filter: &RunFilter{Labels: map[string]string{"team": "core", "agent-cli.batch_id": "b1"}}, want: true},
+		{name: "label value", filter: &RunFilter{Labels: map[string]string{"team": "web"}}, want: false},
+		{name: "missing label", filter: &RunFilter{Labels: map[string]string{"owner": ""}}, want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Matches(record); got != tt.want {
+			t.Fatalf("%s: Matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
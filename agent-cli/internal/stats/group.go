@@ -0,0 +1,143 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GroupBy names the run dimension GroupRuns splits runs by.
+type GroupBy string
+
+const (
+	GroupByDay          GroupBy = "day"
+	GroupByWeek         GroupBy = "week"
+	GroupByMonth        GroupBy = "month"
+	GroupByStatus       GroupBy = "status"
+	GroupByErrorType    GroupBy = "error_type"
+	GroupByPipeline     GroupBy = "pipeline"
+	GroupByTerminalNode GroupBy = "terminal_node"
+	GroupByProfile      GroupBy = "profile"
+
+	groupByLabelPrefix = "label:"
+)
+
+// GroupAggregate sums the runs that share one group key.
+type GroupAggregate struct {
+	Group         string           `json:"group"`
+	TotalRuns     int              `json:"total_runs"`
+	SuccessRuns   int              `json:"success_runs"`
+	ErrorRuns     int              `json:"error_runs"`
+	SuccessRate   float64          `json:"success_rate"`
+	AvgDurationMS int64            `json:"avg_duration_ms"`
+	Sums          AggregateMetrics `json:"sums"`
+	Distributions RunDistributions `json:"distributions"`
+	// Histogram is set by stats --histogram, over the runs of the group only.
+	Histogram *Histogram `json:"histogram,omitempty"`
+}
+
+// GroupedAggregate is the result of stats --group-by.
+type GroupedAggregate struct {
+	GroupBy      GroupBy          `json:"group_by"`
+	Groups       []GroupAggregate `json:"groups"`
+	SkippedFiles []string         `json:"skipped_files"`
+	// Filters echoes the run filters the groups were built with, when any.
+	Filters *RunFilter `json:"filters,omitempty"`
+}
+
+// ParseGroupBy validates a --group-by value: a fixed dimension or label:<key>.
+func ParseGroupBy(value string) (GroupBy, error) {
+	value = strings.TrimSpace(value)
+	switch GroupBy(strings.ToLower(value)) {
+	case GroupByDay, GroupByWeek, GroupByMonth, GroupByStatus, GroupByErrorType,
+		GroupByPipeline, GroupByTerminalNode, GroupByProfile:
+		return GroupBy(strings.ToLower(value)), nil
+	}
+	if key, ok := strings.CutPrefix(value, groupByLabelPrefix); ok && strings.TrimSpace(key) != "" {
+		return GroupBy(groupByLabelPrefix + strings.TrimSpace(key)), nil
+	}
+	return "", fmt.Errorf(
+		"invalid group %q: expected day, week, month, status, error_type, pipeline, terminal_node, profile or label:<key>",
+		value,
+	)
+}
+
+// LabelKey returns the label key of a label:<key> grouping.
+func (g GroupBy) LabelKey() (string, bool) {
+	return strings.CutPrefix(string(g), groupByLabelPrefix)
+}
+
+// Key returns the group of record. Runs without a value for the dimension share the empty group.
+func (g GroupBy) Key(record *RunRecord) string {
+	timestamp := record.Timestamp.UTC()
+	switch g {
+	case GroupByDay:
+		return timestamp.Format("2006-01-02")
+	case GroupByWeek:
+		year, week := timestamp.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	case GroupByMonth:
+		return timestamp.Format("2006-01")
+	case GroupByStatus:
+		return string(record.Status)
+	case GroupByErrorType:
+		return record.ErrorType
+	case GroupByPipeline:
+		return record.PipelinePath
+	case GroupByTerminalNode:
+		if record.Pipeline == nil {
+			return ""
+		}
+		return record.Pipeline.TerminalNode
+	case GroupByProfile:
+		return record.Profile
+	}
+	if key, ok := g.LabelKey(); ok {
+		return record.Labels[key]
+	}
+	return ""
+}
+
+// GroupRuns sums runs accepted by keep per group of by, ordered by group key. Time groups sort
+// chronologically because their keys are zero-padded.
+func GroupRuns(runs []RunEntry, keep RunPredicate, by GroupBy) []GroupAggregate {
+	groups := map[string]*GroupAggregate{}
+	samples := map[string]*distributionSamples{}
+	for _, run := range runs {
+		record := run.Record
+		if keep != nil && !keep(record) {
+			continue
+		}
+		key := by.Key(record)
+		group := groups[key]
+		if group == nil {
+			group = &GroupAggregate{Group: key}
+			groups[key] = group
+			samples[key] = &distributionSamples{}
+		}
+		group.TotalRuns++
+		if record.Status == RunStatusSuccess {
+			group.SuccessRuns++
+		} else {
+			group.ErrorRuns++
+		}
+		mergeMetrics(&group.Sums, record)
+		samples[key].add(record)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]GroupAggregate, 0, len(keys))
+	for _, key := range keys {
+		group := groups[key]
+		group.SuccessRate = float64(group.SuccessRuns) / float64(group.TotalRuns)
+		group.AvgDurationMS = group.Sums.DurationMS / int64(group.TotalRuns)
+		group.Distributions = samples[key].distributions()
+		result = append(result, *group)
+	}
+	return result
+}
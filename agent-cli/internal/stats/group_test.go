@@ -0,0 +1,109 @@
+package stats
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-cli/internal/result"
+)
+
+func TestGroupRuns(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, record := range []*RunRecord{
+		{
+			RunID:      "a",
+			Timestamp:  base,
+			Status:     RunStatusSuccess,
+			Labels:     map[string]string{"team": "core"},
+			Normalized: result.NormalizedMetrics{TotalCostUSD: 1, DurationMS: 1000, InputTokens: 10},
+		},
+		{
+			RunID:      "b",
+			Timestamp:  base.Add(2 * time.Hour),
+			Status:     RunStatusError,
+			ErrorType:  "timeout",
+			Normalized: result.NormalizedMetrics{TotalCostUSD: 2, DurationMS: 3000, InputTokens: 20},
+		},
+		{
+			RunID:      "c",
+			Timestamp:  base.AddDate(0, 0, 14),
+			Status:     RunStatusSuccess,
+			Labels:     map[string]string{"team": "web"},
+			Normalized: result.NormalizedMetrics{TotalCostUSD: 4, DurationMS: 5000, InputTokens: 40},
+		},
+	} {
+		if _, err := SaveRunRecord(dir, record, RetentionPolicy{}); err != nil {
+			t.Fatalf("save record: %v", err)
+		}
+	}
+
+	runs, _, err := ListRunSummaries(dir)
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	groups := GroupRuns(runs, nil, GroupByDay)
+	if len(groups) != 2 || groups[0].Group != "2026-03-01" || groups[1].Group != "2026-03-15" {
+		t.Fatalf("unexpected day groups: %+v", groups)
+	}
+	first := groups[0]
+	if first.TotalRuns != 2 || first.SuccessRuns != 1 || first.ErrorRuns != 1 || first.SuccessRate != 0.5 {
+		t.Fatalf("unexpected run counts: %+v", first)
+	}
+	if first.Sums.TotalCostUSD != 3 || first.Sums.InputTokens != 30 || first.AvgDurationMS != 2000 {
+		t.Fatalf("unexpected sums: %+v", first)
+	}
+
+	groups = GroupRuns(runs, func(record *RunRecord) bool { return record.RunID != "c" }, GroupBy("label:team"))
+	if len(groups) != 2 || groups[0].Group != "" || groups[1].Group != "core" || groups[1].TotalRuns != 1 {
+		t.Fatalf("unexpected label groups: %+v", groups)
+	}
+}
+
+func TestParseGroupBy(t *testing.T) {
+	t.Parallel()
+
+	record := &RunRecord{
+		Timestamp:    time.Date(2026, 1, 1, 23, 0, 0, 0, time.FixedZone("CET", 3600)),
+		Status:       RunStatusError,
+		ErrorType:    "timeout",
+		Profile:      "heavy",
+		PipelinePath: "/work/flow.yml",
+		Pipeline:     &PipelineRunRecord{TerminalNode: "review"},
+		Labels:       map[string]string{"team": "core"},
+	}
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "day", want: "2026-01-01"},
+		{value: "WEEK", want: "2026-W01"},
+		{value: "month", want: "2026-01"},
+		{value: "status", want: "error"},
+		{value: "error_type", want: "timeout"},
+		{value: "pipeline", want: "/work/flow.yml"},
+		{value: "terminal_node", want: "review"},
+		{value: "profile", want: "heavy"},
+		{value: "label:team", want: "core"},
+		{value: "label:missing", want: ""},
+	}
+	for _, tt := range tests {
+		groupBy, err := ParseGroupBy(tt.value)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.value, err)
+		}
+		if got := groupBy.Key(record); got != tt.want {
+			t.Fatalf("unexpected key for %q: %q", tt.value, got)
+		}
+	}
+
+	for _, value := range []string{"", "year", "label:", "model"} {
+		if _, err := ParseGroupBy(value); err == nil || !strings.Contains(err.Error(), "invalid group") {
+			t.Fatalf("unexpected error for %q: %v", value, err)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// RunIndexFileName is the run summary index kept next to the run directories.
+	RunIndexFileName = "index.jsonl"
+
+	// runIndexVersion is bumped whenever the summary fields change; older entries are rebuilt.
+	runIndexVersion = 2
+)
+
+// runIndexMu serializes index appends and rewrites within the process, e.g. parallel batch rows.
+var runIndexMu sync.Mutex
+
+// runIndexEntry is one line of the run index: the summary of a run record and the stats.json
+// modification time and size it was read at.
+type runIndexEntry struct {
+	Version int        `json:"v"`
+	Dir     string     `json:"dir"`
+	ModTime int64      `json:"mtime_ns"`
+	Size    int64      `json:"size"`
+	Record  *RunRecord `json:"record"`
+}
+
+// ListRunSummaries returns the runs of ListRunRecords with summary records, which lack the
+// per-node pipeline detail (node runs, transitions, resume inputs) and the agent result. Pipeline
+// summaries carry the per-node totals of their node runs instead.
+//
+// The summaries come from runs/index.jsonl. Runs missing from the index are read and appended;
+// when the index has drifted (a run was removed or rewritten, or a line is unreadable) it is
+// rebuilt from the run directories. The index is a cache: failing to update it is not an error.
+func ListRunSummaries(runsDir string) ([]RunEntry, []string, error) {
+	names, err := listRunDirNames(runsDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if names == nil {
+		return []RunEntry{}, []string{}, nil
+	}
+
+	runIndexMu.Lock()
+	defer runIndexMu.Unlock()
+
+	indexPath := filepath.Join(runsDir, RunIndexFileName)
+	indexed, drift := readRunIndex(indexPath)
+
+	runs := make([]RunEntry, 0, len(names))
+	skipped := make([]string, 0)
+	entries := make([]runIndexEntry, 0, len(names))
+	added := make([]runIndexEntry, 0)
+	present := make(map[string]bool, len(names))
+	for _, name := range names {
+		present[name] = true
+		runDir := filepath.Join(runsDir, name)
+		entry, ok := indexed[name]
+
+		info, err := os.Stat(filepath.Join(runDir, statsFileName))
+		if err != nil {
+			drift = drift || ok
+			skipped = append(skipped, filepath.ToSlash(filepath.Join(name, statsFileName)))
+			continue
+		}
+		if !ok || entry.ModTime != info.ModTime().UnixNano() || entry.Size != info.Size() {
+			drift = drift || ok
+			record, err := LoadRunRecord(filepath.Join(runDir, statsFileName))
+			if err != nil {
+				skipped = append(skipped, filepath.ToSlash(filepath.Join(name, statsFileName)))
+				continue
+			}
+			entry = newRunIndexEntry(name, info, record)
+			added = append(added, entry)
+		}
+		entries = append(entries, entry)
+		runs = append(runs, RunEntry{Dir: runDir, Record: entry.Record})
+	}
+	for name := range indexed {
+		if !present[name] {
+			drift = true
+		}
+	}
+
+	switch {
+	case drift:
+		_ = writeRunIndex(indexPath, entries)
+	case len(added) > 0:
+		_ = appendRunIndex(indexPath, added)
+	}
+	return runs, skipped, nil
+}
+
+// RebuildRunIndex rewrites the run index from every run record in runsDir and returns the
+// number of indexed runs and the unreadable records it skipped.
+func RebuildRunIndex(runsDir string) (int, []string, error) {
+	names, err := listRunDirNames(runsDir)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	runIndexMu.Lock()
+	defer runIndexMu.Unlock()
+
+	entries := make([]runIndexEntry, 0, len(names))
+	skipped := make([]string, 0)
+	for _, name := range names {
+		path := filepath.Join(runsDir, name, statsFileName)
+		info, statErr := os.Stat(path)
+		record, loadErr := LoadRunRecord(path)
+		if statErr != nil || loadErr != nil {
+			skipped = append(skipped, filepath.ToSlash(filepath.Join(name, statsFileName)))
+			continue
+		}
+		entries = append(entries, newRunIndexEntry(name, info, record))
+	}
+
+	if err := os.MkdirAll(runsDir, 0o755); err != nil {
+		return 0, nil, fmt.Errorf("create runs directory: %w", err)
+	}
+	if err := writeRunIndex(filepath.Join(runsDir, RunIndexFileName), entries); err != nil {
+		return 0, nil, err
+	}
+	return len(entries), skipped, nil
+}
+
+// indexRunRecord appends the record just written to path to the run index of runsDir.
+func indexRunRecord(runsDir string, path string, record *RunRecord) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat run record: %w", err)
+	}
+
+	runIndexMu.Lock()
+	defer runIndexMu.Unlock()
+	entry := newRunIndexEntry(filepath.Base(filepath.Dir(path)), info, record)
+	return appendRunIndex(filepath.Join(runsDir, RunIndexFileName), []runIndexEntry{entry})
+}
+
+func newRunIndexEntry(name string, info os.FileInfo, record *RunRecord) runIndexEntry {
+	return runIndexEntry{
+		Version: runIndexVersion,
+		Dir:     name,
+		ModTime: info.ModTime().UnixNano(),
+		Size:    info.Size(),
+		Record:  summarizeRunRecord(record),
+	}
+}
+
+// summarizeRunRecord copies the fields stats, filters and run listings read and drops the rest.
+func summarizeRunRecord(record *RunRecord) *RunRecord {
+	summary := *record
+	summary.AgentResult = nil
+	summary.ErrorMessage = ""
+	summary.TemplateVars = nil
+	if record.Pipeline != nil {
+		pipeline := *record.Pipeline
+		pipeline.NodeTotals = pipelineNodeTotals(record.Pipeline)
+		pipeline.NodeRuns = nil
+		pipeline.Transitions = nil
+		pipeline.LastDecision = nil
+		pipeline.Workspace = nil
+		summary.Pipeline = &pipeline
+	}
+	return &summary
+}
+
+// pipelineNodeTotals sums the node runs of pipeline per node, in order of first visit. Summaries,
+// which have no node runs, return their recorded totals.
+func pipelineNodeTotals(pipeline *PipelineRunRecord) []PipelineNodeTotal {
+	if pipeline.NodeTotals != nil {
+		return pipeline.NodeTotals
+	}
+	if len(pipeline.NodeRuns) == 0 {
+		return nil
+	}
+	totals := make([]PipelineNodeTotal, 0, len(pipeline.NodeRuns))
+	positions := map[string]int{}
+	for _, nodeRun := range pipeline.NodeRuns {
+		nodeID := strings.TrimSpace(nodeRun.NodeID)
+		position, ok := positions[nodeID]
+		if !ok {
+			position = len(totals)
+			positions[nodeID] = position
+			totals = append(totals, PipelineNodeTotal{NodeID: nodeID})
+		}
+		// Command nodes have no usage but still count as a visit.
+		total := &totals[position]
+		total.Visits++
+		total.DurationMS += nodeRun.DurationMS
+		if nodeRun.Normalized != nil {
+			total.EffectiveCostUSD += nodeEffectiveCostUSD(nodeRun.Normalized)
+		}
+	}
+	return totals
+}
+
+// readRunIndex returns the entries of the index by run directory name, later lines taking
+// precedence. drift reports a missing index or lines that are unreadable, outdated or repeated.
+func readRunIndex(path string) (map[string]runIndexEntry, bool) {
+	entries := map[string]runIndexEntry{}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return entries, true
+	}
+
+	drift := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry runIndexEntry
+		if err := json.Unmarshal(line, &entry); err != nil || entry.Version != runIndexVersion || entry.Dir == "" || entry.Record == nil {
+			drift = true
+			continue
+		}
+		if _, ok := entries[entry.Dir]; ok {
+			drift = true
+		}
+		entries[entry.Dir] = entry
+	}
+	if scanner.Err() != nil {
+		drift = true
+	}
+	return entries, drift
+}
+
+// appendRunIndex appends entries with a single O_APPEND write, so concurrent writers do not
+// interleave lines. A line lost to a concurrent rewrite is restored by the next read.
+func appendRunIndex(path string, entries []runIndexEntry) error {
+	content, err := encodeRunIndex(entries)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open run index: %w", err)
+	}
+	_, writeErr := file.Write(content)
+	closeErr := file.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		return fmt.Errorf("append run index: %w", err)
+	}
+	return nil
+}
+
+// writeRunIndex replaces the index through a temporary file and a rename.
+func writeRunIndex(path string, entries []runIndexEntry) error {
+	content, err := encodeRunIndex(entries)
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), RunIndexFileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create run index: %w", err)
+	}
+	_, writeErr := temp.Write(content)
+	closeErr := temp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		_ = os.Remove(temp.Name())
+		return fmt.Errorf("write run index: %w", err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		_ = os.Remove(temp.Name())
+		return fmt.Errorf("replace run index: %w", err)
+	}
+	return nil
+}
+
+func encodeRunIndex(entries []runIndexEntry) ([]byte, error) {
+	var content bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return nil, fmt.Errorf("encode run index entry %s: %w", entry.Dir, err)
+		}
+		content.Write(line)
+		content.WriteByte('\n')
+	}
+	return content.Bytes(), nil
+}
@@ -0,0 +1,185 @@
+package stats
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-cli/internal/result"
+)
+
+func TestSaveRunRecordAppendsRunIndex(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	saveTestRun(t, dir, "first", RunStatusSuccess, base)
+	saveTestRun(t, dir, "second", RunStatusError, base.Add(time.Hour))
+
+	entries := readTestRunIndex(t, dir)
+	if len(entries) != 2 || entries[0].Record.RunID != "first" || entries[1].Record.RunID != "second" {
+		t.Fatalf("unexpected index entries: %+v", entries)
+	}
+
+	// A matching entry is used as is, without reading stats.json.
+	entries[0].Record.Normalized.TotalCostUSD = 42
+	writeTestRunIndex(t, dir, entries)
+	runs, skipped, err := ListRunSummaries(dir)
+	if err != nil {
+		t.Fatalf("list run summaries: %v", err)
+	}
+	if len(runs) != 2 || len(skipped) != 0 || runs[0].Record.Normalized.TotalCostUSD != 42 {
+		t.Fatalf("unexpected summaries: %+v", runs)
+	}
+	if runs[0].Dir != filepath.Join(dir, entries[0].Dir) {
+		t.Fatalf("unexpected run dir: %s", runs[0].Dir)
+	}
+}
+
+func TestListRunSummariesRepairsDrift(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	saveTestRun(t, dir, "kept", RunStatusSuccess, base)
+	removedDir := saveTestRun(t, dir, "removed", RunStatusSuccess, base.Add(time.Hour))
+	rewrittenDir := saveTestRun(t, dir, "rewritten", RunStatusSuccess, base.Add(2*time.Hour))
+
+	// A run written without the index is appended.
+	unindexed := &RunRecord{
+		RunID:     "unindexed",
+		Timestamp: base.Add(3 * time.Hour),
+		Status:    RunStatusSuccess,
+		Pipeline: &PipelineRunRecord{
+			TerminalNode: "done",
+			NodeRuns:     []PipelineNodeRunRecord{{NodeID: "implement", NodeRunID: "1"}},
+		},
+	}
+	writeTestRunRecordFile(t, filepath.Join(dir, "20260301T130000.000000000Z-unindexed"), unindexed)
+
+	runs, _, err := ListRunSummaries(dir)
+	if err != nil {
+		t.Fatalf("list run summaries: %v", err)
+	}
+	if len(runs) != 4 || len(readTestRunIndex(t, dir)) != 4 {
+		t.Fatalf("unexpected runs after append: %d", len(runs))
+	}
+	last := runs[3].Record
+	if last.RunID != "unindexed" || last.Pipeline == nil || last.Pipeline.TerminalNode != "done" || last.Pipeline.NodeRuns != nil {
+		t.Fatalf("unexpected summary: %+v", last)
+	}
+	if totals := last.Pipeline.NodeTotals; len(totals) != 1 || totals[0] != (PipelineNodeTotal{NodeID: "implement", Visits: 1}) {
+		t.Fatalf("unexpected summary node totals: %+v", totals)
+	}
+
+	if err := os.RemoveAll(removedDir); err != nil {
+		t.Fatalf("remove run: %v", err)
+	}
+	writeTestRunRecordFile(t, rewrittenDir, &RunRecord{
+		RunID:      "rewritten",
+		Timestamp:  base.Add(2 * time.Hour),
+		Status:     RunStatusError,
+		Normalized: result.NormalizedMetrics{TotalCostUSD: 1.5},
+	})
+
+	runs, _, err = ListRunSummaries(dir)
+	if err != nil {
+		t.Fatalf("list run summaries: %v", err)
+	}
+	if len(runs) != 3 || runs[1].Record.RunID != "rewritten" || runs[1].Record.Status != RunStatusError {
+		t.Fatalf("unexpected runs after drift: %+v", runs)
+	}
+	entries := readTestRunIndex(t, dir)
+	if len(entries) != 3 || entries[1].Record.Normalized.TotalCostUSD != 1.5 {
+		t.Fatalf("index was not rebuilt: %+v", entries)
+	}
+
+	// Unreadable lines rebuild the index too.
+	indexPath := filepath.Join(dir, RunIndexFileName)
+	content, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("read index: %v", err)
+	}
+	if err := os.WriteFile(indexPath, append(content, []byte("{not json\n")...), 0o644); err != nil {
+		t.Fatalf("write index: %v", err)
+	}
+	if _, _, err := ListRunSummaries(dir); err != nil {
+		t.Fatalf("list run summaries: %v", err)
+	}
+	content, err = os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("read index: %v", err)
+	}
+	if strings.Contains(string(content), "not json") || strings.Count(string(content), "\n") != 3 {
+		t.Fatalf("unexpected index content: %s", content)
+	}
+}
+
+func TestRebuildRunIndex(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	saveTestRun(t, dir, "only", RunStatusSuccess, time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC))
+	brokenDir := filepath.Join(dir, "20260301T110000.000000000Z-broken")
+	if err := os.MkdirAll(brokenDir, 0o755); err != nil {
+		t.Fatalf("create run dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, RunIndexFileName), []byte("garbage\n"), 0o644); err != nil {
+		t.Fatalf("write index: %v", err)
+	}
+
+	indexed, skipped, err := RebuildRunIndex(dir)
+	if err != nil {
+		t.Fatalf("rebuild run index: %v", err)
+	}
+	if indexed != 1 || len(skipped) != 1 || skipped[0] != "20260301T110000.000000000Z-broken/stats.json" {
+		t.Fatalf("unexpected rebuild result: %d %v", indexed, skipped)
+	}
+	if entries := readTestRunIndex(t, dir); len(entries) != 1 || entries[0].Record.RunID != "only" {
+		t.Fatalf("unexpected index entries: %+v", entries)
+	}
+}
+
+func readTestRunIndex(t *testing.T, dir string) []runIndexEntry {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join(dir, RunIndexFileName))
+	if err != nil {
+		t.Fatalf("read index: %v", err)
+	}
+	entries := make([]runIndexEntry, 0)
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var entry runIndexEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("decode index line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func writeTestRunIndex(t *testing.T, dir string, entries []runIndexEntry) {
+	t.Helper()
+
+	if err := writeRunIndex(filepath.Join(dir, RunIndexFileName), entries); err != nil {
+		t.Fatalf("write index: %v", err)
+	}
+}
+
+func writeTestRunRecordFile(t *testing.T, runDir string, record *RunRecord) {
+	t.Helper()
+
+	content, err := json.Marshal(record)
+	if err != nil {
+		t.Fatalf("marshal record: %v", err)
+	}
+	if err := os.MkdirAll(runDir, 0o755); err != nil {
+		t.Fatalf("create run dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, statsFileName), content, 0o644); err != nil {
+		t.Fatalf("write record: %v", err)
+	}
+}
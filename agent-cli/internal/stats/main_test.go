@@ -0,0 +1,24 @@
+package stats
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestMain keeps the user-level run registry of saved test runs out of the real state directory.
+func TestMain(m *testing.M) {
+	stateHome, err := os.MkdirTemp("", "agent-cli-state-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "create state directory: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.Setenv("XDG_STATE_HOME", stateHome); err != nil {
+		fmt.Fprintf(os.Stderr, "set XDG_STATE_HOME: %v\n", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(stateHome)
+	os.Exit(code)
+}
@@ -0,0 +1,139 @@
+package stats
+
+import (
+	"sort"
+	"strings"
+)
+
+// NodeAggregate sums the runs of one pipeline node across the pipeline runs that visited it.
+type NodeAggregate struct {
+	NodeID string `json:"node_id"`
+	Kind   string `json:"kind,omitempty"`
+	// Visits counts node runs; Runs counts the pipeline runs with at least one of them.
+	Visits       int     `json:"visits"`
+	Runs         int     `json:"runs"`
+	VisitsPerRun float64 `json:"visits_per_run"`
+	Errors       int     `json:"errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	Timeouts     int     `json:"timeouts"`
+	TimeoutRate  float64 `json:"timeout_rate"`
+	P50MS        int64   `json:"p50_duration_ms"`
+	P95MS        int64   `json:"p95_duration_ms"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	MeanCostUSD  float64 `json:"mean_cost_usd"`
+	// TotalEffectiveCostUSD sums the effective cost of the node runs, see ApplyPricing.
+	TotalEffectiveCostUSD float64 `json:"total_effective_cost_usd"`
+	// TopTransition is the transition most often taken out of the node, if any was recorded.
+	TopTransition *NodeTransitionCount `json:"top_transition,omitempty"`
+}
+
+// NodeTransitionCount is how often a transition to To was taken out of a node.
+type NodeTransitionCount struct {
+	To    string `json:"to"`
+	Count int    `json:"count"`
+}
+
+type nodeAccumulator struct {
+	aggregate   NodeAggregate
+	durations   []int64
+	runIDs      map[string]bool
+	transitions map[string]int
+}
+
+// NodeRuns aggregates the node runs of the pipeline runs accepted by keep by node id, ordered by
+// total cost, highest first. The records must include node runs, so runs come from ListRunRecords.
+func NodeRuns(runs []RunEntry, keep RunPredicate) []NodeAggregate {
+	nodes := map[string]*nodeAccumulator{}
+	nodeFor := func(nodeID string) *nodeAccumulator {
+		node := nodes[nodeID]
+		if node == nil {
+			node = &nodeAccumulator{
+				aggregate:   NodeAggregate{NodeID: nodeID},
+				runIDs:      map[string]bool{},
+				transitions: map[string]int{},
+			}
+			nodes[nodeID] = node
+		}
+		return node
+	}
+
+	for _, run := range runs {
+		record := run.Record
+		if record.Pipeline == nil || (keep != nil && !keep(record)) {
+			continue
+		}
+		// Records without a run id still count as one run each.
+		runKey := record.RunID
+		if runKey == "" {
+			runKey = run.Dir
+		}
+
+		for _, nodeRun := range record.Pipeline.NodeRuns {
+			nodeID := strings.TrimSpace(nodeRun.NodeID)
+			if nodeID == "" {
+				continue
+			}
+			node := nodeFor(nodeID)
+			node.aggregate.Visits++
+			node.runIDs[runKey] = true
+			if node.aggregate.Kind == "" {
+				node.aggregate.Kind = nodeRun.Kind
+			}
+			if strings.EqualFold(strings.TrimSpace(nodeRun.Status), "error") {
+				node.aggregate.Errors++
+			}
+			if nodeRun.TimedOut {
+				node.aggregate.Timeouts++
+			}
+			node.durations = append(node.durations, nodeRun.DurationMS)
+			if nodeRun.Normalized != nil {
+				node.aggregate.TotalCostUSD += nodeRun.Normalized.CostUSD
+				node.aggregate.TotalEffectiveCostUSD += nodeEffectiveCostUSD(nodeRun.Normalized)
+			}
+		}
+		for _, transition := range record.Pipeline.Transitions {
+			if transition.From == "" || transition.To == "" {
+				continue
+			}
+			nodeFor(transition.From).transitions[transition.To]++
+		}
+	}
+
+	result := make([]NodeAggregate, 0, len(nodes))
+	for _, node := range nodes {
+		aggregate := node.aggregate
+		if aggregate.Visits == 0 {
+			// Only seen as a transition source, without a recorded node run.
+			continue
+		}
+		aggregate.Runs = len(node.runIDs)
+		visits := float64(aggregate.Visits)
+		aggregate.VisitsPerRun = visits / float64(aggregate.Runs)
+		aggregate.ErrorRate = float64(aggregate.Errors) / visits
+		aggregate.TimeoutRate = float64(aggregate.Timeouts) / visits
+		aggregate.MeanCostUSD = aggregate.TotalCostUSD / visits
+		sort.Slice(node.durations, func(i, j int) bool { return node.durations[i] < node.durations[j] })
+		aggregate.P50MS = percentile(node.durations, 50)
+		aggregate.P95MS = percentile(node.durations, 95)
+		aggregate.TopTransition = topTransition(node.transitions)
+		result = append(result, aggregate)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalCostUSD != result[j].TotalCostUSD {
+			return result[i].TotalCostUSD > result[j].TotalCostUSD
+		}
+		return result[i].NodeID < result[j].NodeID
+	})
+	return result
+}
+
+// topTransition returns the most common target, breaking ties by target name.
+func topTransition(counts map[string]int) *NodeTransitionCount {
+	var top *NodeTransitionCount
+	for to, count := range counts {
+		if top == nil || count > top.Count || (count == top.Count && to < top.To) {
+			top = &NodeTransitionCount{To: to, Count: count}
+		}
+	}
+	return top
+}
@@ -0,0 +1,90 @@
+package stats
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNodeRuns(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	nodeRun := func(nodeID string, runID string, status string, durationMS int64, costUSD float64) PipelineNodeRunRecord {
+		return PipelineNodeRunRecord{
+			NodeID:     nodeID,
+			NodeRunID:  runID,
+			Kind:       "agent",
+			Status:     status,
+			DurationMS: durationMS,
+			Normalized: &PipelineNodeRunNormalized{CostUSD: costUSD},
+		}
+	}
+	for index, pipeline := range []*PipelineRunRecord{
+		{
+			NodeRuns: []PipelineNodeRunRecord{
+				nodeRun("implement", "1", "success", 1000, 1),
+				nodeRun("review", "1", "success", 200, 0.5),
+				nodeRun("implement", "2", "success", 3000, 1),
+				nodeRun("review", "2", "success", 400, 0.5),
+			},
+			Transitions: []PipelineTransition{
+				{From: "implement", To: "review"},
+				{From: "review", To: "implement"},
+				{From: "implement", To: "review"},
+				{From: "review", To: "done"},
+			},
+		},
+		{
+			NodeRuns: []PipelineNodeRunRecord{
+				nodeRun("implement", "1", "error", 2000, 2),
+			},
+		},
+		nil,
+	} {
+		record := &RunRecord{
+			RunID:     string(rune('a' + index)),
+			Timestamp: base.Add(time.Duration(index) * time.Hour),
+			Status:    RunStatusSuccess,
+			Pipeline:  pipeline,
+		}
+		if _, err := SaveRunRecord(dir, record, RetentionPolicy{}); err != nil {
+			t.Fatalf("save record: %v", err)
+		}
+	}
+
+	runs, _, err := ListRunRecords(dir)
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	nodes := NodeRuns(runs, nil)
+	if len(nodes) != 2 || nodes[0].NodeID != "implement" || nodes[1].NodeID != "review" {
+		t.Fatalf("unexpected nodes: %+v", nodes)
+	}
+
+	implement := nodes[0]
+	if implement.Visits != 3 || implement.Runs != 2 || implement.VisitsPerRun != 1.5 || implement.Errors != 1 {
+		t.Fatalf("unexpected implement visits: %+v", implement)
+	}
+	if implement.P50MS != 2000 || implement.P95MS != 3000 || implement.TotalCostUSD != 4 {
+		t.Fatalf("unexpected implement duration or cost: %+v", implement)
+	}
+	if implement.TopTransition == nil || *implement.TopTransition != (NodeTransitionCount{To: "review", Count: 2}) {
+		t.Fatalf("unexpected implement transition: %+v", implement.TopTransition)
+	}
+
+	review := nodes[1]
+	if review.Visits != 2 || review.Runs != 1 || review.MeanCostUSD != 0.5 || review.ErrorRate != 0 {
+		t.Fatalf("unexpected review node: %+v", review)
+	}
+	// Ties go to the first target by name.
+	if review.TopTransition == nil || review.TopTransition.To != "done" {
+		t.Fatalf("unexpected review transition: %+v", review.TopTransition)
+	}
+
+	nodes = NodeRuns(runs, func(record *RunRecord) bool { return record.RunID == "b" })
+	if len(nodes) != 1 || nodes[0].ErrorRate != 1 || nodes[0].TopTransition != nil {
+		t.Fatalf("unexpected filtered nodes: %+v", nodes)
+	}
+}
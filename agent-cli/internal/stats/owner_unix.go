@@ -0,0 +1,14 @@
+//go:build unix
+
+package stats
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownedByCurrentUser reports whether info describes a file owned by the user running agent-cli.
+func ownedByCurrentUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return !ok || int(stat.Uid) == os.Getuid()
+}
@@ -0,0 +1,163 @@
+package stats
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ModelPrice is the USD price per million tokens of one model.
+type ModelPrice struct {
+	InputPerMTok      float64
+	OutputPerMTok     float64
+	CacheWritePerMTok float64
+	CacheReadPerMTok  float64
+}
+
+// Pricing maps model names, or parts of model names, to their prices.
+type Pricing map[string]ModelPrice
+
+// Lookup returns the price of model: the entry named exactly like it, otherwise the longest entry
+// contained in its name, so opus prices claude-opus-4-1. Names compare case-insensitively.
+func (p Pricing) Lookup(model string) (ModelPrice, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if model == "" {
+		return ModelPrice{}, false
+	}
+	match := ""
+	var price ModelPrice
+	for name, candidate := range p {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if key == "" {
+			continue
+		}
+		if key == model {
+			return candidate, true
+		}
+		if strings.Contains(model, key) && (len(key) > len(match) || (len(key) == len(match) && key < match)) {
+			match = key
+			price = candidate
+		}
+	}
+	return price, match != ""
+}
+
+// Cost prices the given token counts.
+func (m ModelPrice) Cost(inputTokens, outputTokens, cacheWriteTokens, cacheReadTokens int64) float64 {
+	return (float64(inputTokens)*m.InputPerMTok +
+		float64(outputTokens)*m.OutputPerMTok +
+		float64(cacheWriteTokens)*m.CacheWritePerMTok +
+		float64(cacheReadTokens)*m.CacheReadPerMTok) / 1_000_000
+}
+
+// ApplyPricing sets the effective costs of record, its models and its node runs from their token
+// counts. Usage of a model without a price keeps its reported cost. Usage without a per-model
+// breakdown is priced with the run or node model. An empty pricing leaves record untouched.
+func ApplyPricing(record *RunRecord, pricing Pricing) {
+	if len(pricing) == 0 {
+		return
+	}
+
+	normalized := record.Normalized
+	var total float64
+	if len(normalized.ByModel) > 0 {
+		record.EffectiveCostByModel = make(map[string]float64, len(normalized.ByModel))
+		for model, metric := range normalized.ByModel {
+			cost := metric.CostUSD
+			if price, ok := pricing.Lookup(model); ok {
+				cost = price.Cost(metric.InputTokens, metric.OutputTokens, metric.CacheCreationInputTokens, metric.CacheReadInputTokens)
+			}
+			record.EffectiveCostByModel[model] = cost
+			total += cost
+		}
+	} else if price, ok := pricing.Lookup(record.Model); ok {
+		total = price.Cost(normalized.InputTokens, normalized.OutputTokens, normalized.CacheCreationInputTokens, normalized.CacheReadInputTokens)
+	} else {
+		total = normalized.TotalCostUSD
+	}
+	record.EffectiveCostUSD = &total
+
+	if record.Pipeline == nil {
+		return
+	}
+	for index := range record.Pipeline.NodeRuns {
+		nodeRun := &record.Pipeline.NodeRuns[index]
+		if nodeRun.Normalized == nil {
+			continue
+		}
+		cost := nodeRunCost(nodeRun, pricing)
+		nodeRun.Normalized.EffectiveCostUSD = &cost
+	}
+}
+
+func nodeRunCost(nodeRun *PipelineNodeRunRecord, pricing Pricing) float64 {
+	usage := nodeRun.Normalized
+	if len(usage.ByModel) == 0 {
+		if price, ok := pricing.Lookup(nodeRun.Model); ok {
+			return price.Cost(usage.InputTokens, usage.OutputTokens, usage.CacheCreationInputTokens, usage.CacheReadInputTokens)
+		}
+		return usage.CostUSD
+	}
+	var cost float64
+	for model, metric := range usage.ByModel {
+		if price, ok := pricing.Lookup(model); ok {
+			cost += price.Cost(metric.InputTokens, metric.OutputTokens, metric.CacheCreationInputTokens, metric.CacheReadInputTokens)
+		} else {
+			cost += metric.CostUSD
+		}
+	}
+	return cost
+}
+
+// RepriceRuns applies pricing to every run, replacing the effective costs recorded when the runs were saved.
+func RepriceRuns(runs []RunEntry, pricing Pricing) {
+	for _, run := range runs {
+		ApplyPricing(run.Record, pricing)
+	}
+}
+
+// RepriceRunRecords reprices every run in runsDir with pricing and rewrites its stats.json, so later
+// listings, stats and exports report the new effective costs. It returns the number of rewritten runs
+// and refreshes the run index. Unreadable records are left alone.
+func RepriceRunRecords(runsDir string, pricing Pricing) (int, error) {
+	if len(pricing) == 0 {
+		return 0, nil
+	}
+	runs, _, err := ListRunRecords(runsDir)
+	if err != nil {
+		return 0, err
+	}
+	RepriceRuns(runs, pricing)
+	for index, run := range runs {
+		if err := rewriteRunRecord(filepath.Join(run.Dir, statsFileName), run.Record); err != nil {
+			return index, fmt.Errorf("reprice run %s: %w", filepath.Base(run.Dir), err)
+		}
+	}
+	// Listing the summaries re-reads the rewritten records into the index.
+	if _, _, err := ListRunSummaries(runsDir); err != nil {
+		return len(runs), err
+	}
+	return len(runs), nil
+}
+
+// EffectiveCostUSD returns the effective cost of record, or its reported cost when it was saved without pricing.
+func EffectiveCostUSD(record *RunRecord) float64 {
+	if record.EffectiveCostUSD != nil {
+		return *record.EffectiveCostUSD
+	}
+	return record.Normalized.TotalCostUSD
+}
+
+func modelEffectiveCostUSD(record *RunRecord, model string) float64 {
+	if cost, ok := record.EffectiveCostByModel[model]; ok {
+		return cost
+	}
+	return record.Normalized.ByModel[model].CostUSD
+}
+
+func nodeEffectiveCostUSD(usage *PipelineNodeRunNormalized) float64 {
+	if usage.EffectiveCostUSD != nil {
+		return *usage.EffectiveCostUSD
+	}
+	return usage.CostUSD
+}
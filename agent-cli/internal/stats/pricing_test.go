@@ -0,0 +1,200 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"agent-cli/internal/result"
+)
+
+func TestPricingLookup(t *testing.T) {
+	t.Parallel()
+
+	pricing := Pricing{
+		"opus":            {InputPerMTok: 15},
+		"claude-opus-4-1": {InputPerMTok: 12},
+		"Sonnet":          {InputPerMTok: 3},
+	}
+	tests := map[string]float64{
+		"claude-opus-4-1-20250805": 12,
+		"claude-opus-4-20250514":   15,
+		"opus":                     15,
+		"claude-sonnet-4-5":        3,
+	}
+	for model, want := range tests {
+		price, ok := pricing.Lookup(model)
+		if !ok || price.InputPerMTok != want {
+			t.Fatalf("unexpected price for %s: %+v, %v", model, price, ok)
+		}
+	}
+	if _, ok := pricing.Lookup("claude-haiku-4-5"); ok {
+		t.Fatalf("unexpected price for haiku")
+	}
+	if _, ok := pricing.Lookup(""); ok {
+		t.Fatalf("unexpected price for empty model")
+	}
+}
+
+func TestApplyPricing(t *testing.T) {
+	t.Parallel()
+
+	pricing := Pricing{
+		"opus": {InputPerMTok: 10, OutputPerMTok: 50, CacheWritePerMTok: 12, CacheReadPerMTok: 1},
+	}
+	record := &RunRecord{
+		Model: "opus",
+		Normalized: result.NormalizedMetrics{
+			TotalCostUSD: 5,
+			ByModel: map[string]result.ModelMetric{
+				"claude-opus-4-1": {
+					InputTokens:              100_000,
+					OutputTokens:             20_000,
+					CacheCreationInputTokens: 50_000,
+					CacheReadInputTokens:     1_000_000,
+					CostUSD:                  4,
+				},
+				"claude-haiku-4-5": {InputTokens: 10_000, CostUSD: 1},
+			},
+		},
+		Pipeline: &PipelineRunRecord{
+			NodeRuns: []PipelineNodeRunRecord{
+				{NodeID: "implement", Model: "opus", Normalized: &PipelineNodeRunNormalized{InputTokens: 200_000, CostUSD: 3}},
+				{
+					NodeID: "review",
+					Normalized: &PipelineNodeRunNormalized{
+						CostUSD: 2,
+						ByModel: map[string]PipelineNodeRunModelMetric{
+							"claude-opus-4-1":  {OutputTokens: 10_000, CostUSD: 1.5},
+							"claude-haiku-4-5": {InputTokens: 1000, CostUSD: 0.5},
+						},
+					},
+				},
+				{NodeID: "test", Kind: "command"},
+			},
+		},
+	}
+
+	if EffectiveCostUSD(record) != 5 {
+		t.Fatalf("unexpected cost without pricing: %v", EffectiveCostUSD(record))
+	}
+	ApplyPricing(record, nil)
+	if record.EffectiveCostUSD != nil {
+		t.Fatalf("unexpected effective cost without pricing: %v", *record.EffectiveCostUSD)
+	}
+
+	ApplyPricing(record, pricing)
+	// 1 + 1 + 0.6 + 1 for opus; haiku keeps its reported cost.
+	assertCost(t, "run", EffectiveCostUSD(record), 4.6)
+	assertCost(t, "opus", modelEffectiveCostUSD(record, "claude-opus-4-1"), 3.6)
+	assertCost(t, "haiku", modelEffectiveCostUSD(record, "claude-haiku-4-5"), 1)
+	assertCost(t, "implement", nodeEffectiveCostUSD(record.Pipeline.NodeRuns[0].Normalized), 2)
+	assertCost(t, "review", nodeEffectiveCostUSD(record.Pipeline.NodeRuns[1].Normalized), 1)
+	if record.Pipeline.NodeRuns[2].Normalized != nil {
+		t.Fatalf("unexpected usage for command node: %+v", record.Pipeline.NodeRuns[2].Normalized)
+	}
+
+	prompt := &RunRecord{Model: "opus", Normalized: result.NormalizedMetrics{InputTokens: 300_000, TotalCostUSD: 9}}
+	ApplyPricing(prompt, pricing)
+	assertCost(t, "prompt", EffectiveCostUSD(prompt), 3)
+	unpriced := &RunRecord{Model: "sonnet", Normalized: result.NormalizedMetrics{InputTokens: 300_000, TotalCostUSD: 9}}
+	ApplyPricing(unpriced, pricing)
+	assertCost(t, "unpriced", EffectiveCostUSD(unpriced), 9)
+}
+
+func TestAggregateAndExportEffectiveCost(t *testing.T) {
+	t.Parallel()
+
+	priced := &RunRecord{
+		RunID: "priced",
+		Normalized: result.NormalizedMetrics{
+			TotalCostUSD: 2,
+			ByModel:      map[string]result.ModelMetric{"claude-opus-4-1": {InputTokens: 100_000, CostUSD: 2}},
+		},
+	}
+	ApplyPricing(priced, Pricing{"opus": {InputPerMTok: 5}})
+	plain := &RunRecord{
+		RunID: "plain",
+		Normalized: result.NormalizedMetrics{
+			TotalCostUSD: 3,
+			ByModel:      map[string]result.ModelMetric{"claude-opus-4-1": {CostUSD: 3}},
+		},
+	}
+	runs := []RunEntry{{Dir: "priced", Record: priced}, {Dir: "plain", Record: plain}}
+
+	agg := AggregateRuns(runs, nil, nil)
+	assertCost(t, "sums", agg.Sums.EffectiveCostUSD, 3.5)
+	assertCost(t, "model", agg.ByModel["claude-opus-4-1"].EffectiveCostUSD, 3.5)
+	if agg.Sums.TotalCostUSD != 5 {
+		t.Fatalf("unexpected reported cost: %v", agg.Sums.TotalCostUSD)
+	}
+
+	var out bytes.Buffer
+	if err := ExportRunEntries(&out, runs, nil, ExportFormatJSONL, ExportLevelModel); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("unexpected export: %s", out.String())
+	}
+	var row map[string]any
+	if err := json.Unmarshal(lines[0], &row); err != nil {
+		t.Fatalf("decode export row: %v", err)
+	}
+	if row["effective_cost_usd"] != 0.5 || row["cost_usd"] != 2.0 {
+		t.Fatalf("unexpected export row: %v", row)
+	}
+
+	RepriceRuns(runs, Pricing{"opus": {InputPerMTok: 10}})
+	assertCost(t, "repriced", EffectiveCostUSD(priced), 1)
+	assertCost(t, "repriced plain", EffectiveCostUSD(plain), 0)
+}
+
+func TestRepriceRunRecords(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	path, err := SaveRunRecord(dir, &RunRecord{
+		RunID:     "r1",
+		Timestamp: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		Model:     "opus",
+		Normalized: result.NormalizedMetrics{
+			InputTokens:  1_000_000,
+			TotalCostUSD: 3,
+		},
+	}, RetentionPolicy{})
+	if err != nil {
+		t.Fatalf("save record: %v", err)
+	}
+	if _, _, err := ListRunSummaries(dir); err != nil {
+		t.Fatalf("list summaries: %v", err)
+	}
+
+	count, err := RepriceRunRecords(dir, Pricing{"opus": {InputPerMTok: 15}})
+	if err != nil || count != 1 {
+		t.Fatalf("reprice runs: %d, %v", count, err)
+	}
+	record, err := LoadRunRecord(path)
+	if err != nil {
+		t.Fatalf("load record: %v", err)
+	}
+	assertCost(t, "saved", EffectiveCostUSD(record), 15)
+
+	indexed, drift := readRunIndex(filepath.Join(dir, RunIndexFileName))
+	if drift || len(indexed) != 1 {
+		t.Fatalf("unexpected index after repricing: %+v, drift=%v", indexed, drift)
+	}
+	for _, entry := range indexed {
+		assertCost(t, "indexed", EffectiveCostUSD(entry.Record), 15)
+	}
+}
+
+func assertCost(t *testing.T, name string, got float64, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Fatalf("unexpected %s cost: got %v, want %v", name, got, want)
+	}
+}
@@ -0,0 +1,331 @@
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// RegistryFileName lists the runs of every project on this machine, one line per run.
+	RegistryFileName = "registry.jsonl"
+
+	registryVersion = 1
+)
+
+// registryMu serializes registry appends and rewrites within the process.
+var registryMu sync.Mutex
+
+// registryEntry is one line of the run registry.
+type registryEntry struct {
+	Version int        `json:"v"`
+	Project string     `json:"project"`
+	RunDir  string     `json:"run_dir"`
+	Record  *RunRecord `json:"record"`
+}
+
+// ProjectRuns are the runs of one project known to the registry.
+type ProjectRuns struct {
+	Project string
+	RunsDir string
+	// Missing reports that the runs directory is gone, because the project was moved or deleted.
+	// Runs then holds the summaries last registered for it.
+	Missing bool
+	Runs    []RunEntry
+	Skipped []string
+}
+
+// ProjectAggregate sums the runs of one project.
+type ProjectAggregate struct {
+	Project     string           `json:"project"`
+	Missing     bool             `json:"missing,omitempty"`
+	TotalRuns   int              `json:"total_runs"`
+	SuccessRuns int              `json:"success_runs"`
+	ErrorRuns   int              `json:"error_runs"`
+	SuccessRate float64          `json:"success_rate"`
+	LastRunAt   *time.Time       `json:"last_run_at,omitempty"`
+	Sums        AggregateMetrics `json:"sums"`
+}
+
+// RegistryDir returns $XDG_STATE_HOME/agent-cli, or ~/.local/state/agent-cli when XDG_STATE_HOME is unset.
+func RegistryDir() (string, error) {
+	if stateHome := strings.TrimSpace(os.Getenv("XDG_STATE_HOME")); filepath.IsAbs(stateHome) {
+		return filepath.Join(stateHome, "agent-cli"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve state directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "agent-cli"), nil
+}
+
+// registerRunRecord appends the run saved to path to the user-level registry.
+func registerRunRecord(runsDir string, path string, record *RunRecord) error {
+	registryDir, err := RegistryDir()
+	if err != nil {
+		return err
+	}
+	absRunsDir, err := filepath.Abs(runsDir)
+	if err != nil {
+		return fmt.Errorf("resolve runs directory: %w", err)
+	}
+	runDir := filepath.Join(absRunsDir, filepath.Base(filepath.Dir(path)))
+	project := record.CWD
+	if strings.TrimSpace(project) == "" {
+		// runsDir is <project>/.agent-cli/runs.
+		project = filepath.Dir(filepath.Dir(absRunsDir))
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if err := os.MkdirAll(registryDir, 0o755); err != nil {
+		return fmt.Errorf("create registry directory: %w", err)
+	}
+	entry := registryEntry{Version: registryVersion, Project: project, RunDir: runDir, Record: summarizeRunRecord(record)}
+	return appendRegistry(filepath.Join(registryDir, RegistryFileName), []registryEntry{entry})
+}
+
+// ListRegisteredProjects returns the runs of every project in the registry of registryDir,
+// ordered by project path. Projects whose runs directory still exists are read through their
+// run index, which also picks up runs saved before the registry existed; the registry is then
+// rewritten to match, dropping removed runs. Projects whose runs directory is gone keep their
+// registered summaries and are marked Missing.
+func ListRegisteredProjects(registryDir string) ([]ProjectRuns, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	path := filepath.Join(registryDir, RegistryFileName)
+	entries, drift, err := readRegistry(path)
+	if err != nil {
+		return nil, err
+	}
+
+	byRunsDir := map[string]*ProjectRuns{}
+	registered := map[string][]registryEntry{}
+	for _, entry := range entries {
+		runsDir := filepath.Dir(entry.RunDir)
+		if byRunsDir[runsDir] == nil {
+			byRunsDir[runsDir] = &ProjectRuns{Project: entry.Project, RunsDir: runsDir}
+		}
+		registered[runsDir] = append(registered[runsDir], entry)
+	}
+
+	runsDirs := make([]string, 0, len(byRunsDir))
+	for runsDir := range byRunsDir {
+		runsDirs = append(runsDirs, runsDir)
+	}
+	sort.Slice(runsDirs, func(i, j int) bool {
+		left, right := byRunsDir[runsDirs[i]], byRunsDir[runsDirs[j]]
+		if left.Project != right.Project {
+			return left.Project < right.Project
+		}
+		return left.RunsDir < right.RunsDir
+	})
+
+	projects := make([]ProjectRuns, 0, len(runsDirs))
+	current := make([]registryEntry, 0, len(entries))
+	for _, runsDir := range runsDirs {
+		project := byRunsDir[runsDir]
+		if info, err := os.Stat(runsDir); err != nil || !info.IsDir() {
+			project.Missing = true
+			project.Skipped = []string{}
+			for _, entry := range registered[runsDir] {
+				project.Runs = append(project.Runs, RunEntry{Dir: entry.RunDir, Record: entry.Record})
+			}
+			sort.SliceStable(project.Runs, func(i, j int) bool {
+				return filepath.Base(project.Runs[i].Dir) < filepath.Base(project.Runs[j].Dir)
+			})
+			current = append(current, registered[runsDir]...)
+			projects = append(projects, *project)
+			continue
+		}
+
+		runs, skipped, err := ListRunSummaries(runsDir)
+		if err != nil {
+			return nil, err
+		}
+		project.Runs = runs
+		project.Skipped = skipped
+		if len(runs) != len(registered[runsDir]) {
+			drift = true
+		}
+		known := make(map[string]bool, len(registered[runsDir]))
+		for _, entry := range registered[runsDir] {
+			known[entry.RunDir] = true
+		}
+		for _, run := range runs {
+			if !known[run.Dir] {
+				drift = true
+			}
+			current = append(current, registryEntry{
+				Version: registryVersion,
+				Project: project.Project,
+				RunDir:  run.Dir,
+				Record:  run.Record,
+			})
+		}
+		projects = append(projects, *project)
+	}
+
+	if drift {
+		// The registry is rebuilt from the projects; failing to write it only costs the next read.
+		_ = writeRegistry(path, current)
+	}
+	return projects, nil
+}
+
+// AggregateProjects sums the runs of all projects accepted by keep and adds a per-project breakdown.
+// Skipped files are reported relative to their project.
+func AggregateProjects(projects []ProjectRuns, keep RunPredicate) *Aggregate {
+	skipped := make([]string, 0)
+	breakdown := make([]ProjectAggregate, 0, len(projects))
+	for _, project := range projects {
+		for _, name := range project.Skipped {
+			skipped = append(skipped, filepath.ToSlash(filepath.Join(project.Project, name)))
+		}
+
+		summary := ProjectAggregate{Project: project.Project, Missing: project.Missing}
+		for _, run := range project.Runs {
+			record := run.Record
+			if keep != nil && !keep(record) {
+				continue
+			}
+			summary.TotalRuns++
+			if record.Status == RunStatusSuccess {
+				summary.SuccessRuns++
+			} else {
+				summary.ErrorRuns++
+			}
+			if summary.LastRunAt == nil || record.Timestamp.After(*summary.LastRunAt) {
+				ts := record.Timestamp
+				summary.LastRunAt = &ts
+			}
+			mergeMetrics(&summary.Sums, record)
+		}
+		if summary.TotalRuns > 0 {
+			summary.SuccessRate = float64(summary.SuccessRuns) / float64(summary.TotalRuns)
+		}
+		breakdown = append(breakdown, summary)
+	}
+
+	agg := AggregateRuns(AllProjectRuns(projects), skipped, keep)
+	agg.Projects = breakdown
+	return agg
+}
+
+// AllProjectRuns flattens projects into one run list in run order. Run directory names start
+// with the run timestamp, so they order runs across projects too.
+func AllProjectRuns(projects []ProjectRuns) []RunEntry {
+	runs := make([]RunEntry, 0)
+	for _, project := range projects {
+		runs = append(runs, project.Runs...)
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return filepath.Base(runs[i].Dir) < filepath.Base(runs[j].Dir)
+	})
+	return runs
+}
+
+// readRegistry returns the registry entries, later lines for a run directory taking precedence.
+// drift reports unreadable, outdated or repeated lines. A missing registry has no entries.
+func readRegistry(path string) ([]registryEntry, bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []registryEntry{}, false, nil
+		}
+		return nil, false, fmt.Errorf("read run registry: %w", err)
+	}
+
+	drift := false
+	order := make([]string, 0)
+	byRunDir := map[string]registryEntry{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry registryEntry
+		if err := json.Unmarshal(line, &entry); err != nil || entry.Version != registryVersion || entry.RunDir == "" || entry.Record == nil {
+			drift = true
+			continue
+		}
+		if _, ok := byRunDir[entry.RunDir]; ok {
+			drift = true
+		} else {
+			order = append(order, entry.RunDir)
+		}
+		byRunDir[entry.RunDir] = entry
+	}
+	if scanner.Err() != nil {
+		drift = true
+	}
+
+	entries := make([]registryEntry, 0, len(order))
+	for _, runDir := range order {
+		entries = append(entries, byRunDir[runDir])
+	}
+	return entries, drift, nil
+}
+
+func appendRegistry(path string, entries []registryEntry) error {
+	content, err := encodeRegistry(entries)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open run registry: %w", err)
+	}
+	_, writeErr := file.Write(content)
+	closeErr := file.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		return fmt.Errorf("append run registry: %w", err)
+	}
+	return nil
+}
+
+func writeRegistry(path string, entries []registryEntry) error {
+	content, err := encodeRegistry(entries)
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), RegistryFileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create run registry: %w", err)
+	}
+	_, writeErr := temp.Write(content)
+	closeErr := temp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		_ = os.Remove(temp.Name())
+		return fmt.Errorf("write run registry: %w", err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		_ = os.Remove(temp.Name())
+		return fmt.Errorf("replace run registry: %w", err)
+	}
+	return nil
+}
+
+func encodeRegistry(entries []registryEntry) ([]byte, error) {
+	var content bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return nil, fmt.Errorf("encode run registry entry %s: %w", entry.RunDir, err)
+		}
+		content.Write(line)
+		content.WriteByte('\n')
+	}
+	return content.Bytes(), nil
+}
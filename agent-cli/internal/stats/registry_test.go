@@ -0,0 +1,104 @@
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"agent-cli/internal/result"
+)
+
+func TestListRegisteredProjects(t *testing.T) {
+	stateHome := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", stateHome)
+
+	root := t.TempDir()
+	projectA := filepath.Join(root, "a")
+	projectB := filepath.Join(root, "b")
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	save := func(project string, runID string, offset time.Duration, costUSD float64) {
+		t.Helper()
+		record := &RunRecord{
+			RunID:      runID,
+			Timestamp:  base.Add(offset),
+			Status:     RunStatusSuccess,
+			CWD:        project,
+			Normalized: result.NormalizedMetrics{TotalCostUSD: costUSD},
+		}
+		if _, err := SaveRunRecord(filepath.Join(project, ".agent-cli", "runs"), record, RetentionPolicy{}); err != nil {
+			t.Fatalf("save record: %v", err)
+		}
+	}
+	save(projectA, "a1", 0, 1)
+	save(projectB, "b1", time.Hour, 2)
+	save(projectA, "a2", 2*time.Hour, 4)
+
+	registryDir, err := RegistryDir()
+	if err != nil {
+		t.Fatalf("registry dir: %v", err)
+	}
+	if registryDir != filepath.Join(stateHome, "agent-cli") {
+		t.Fatalf("unexpected registry dir: %s", registryDir)
+	}
+
+	// A run saved before the registry existed is picked up from its project.
+	writeTestRunRecordFile(t, filepath.Join(projectA, ".agent-cli", "runs", "20260301T130000.000000000Z-a3"), &RunRecord{
+		RunID:     "a3",
+		Timestamp: base.Add(3 * time.Hour),
+		Status:    RunStatusError,
+		CWD:       projectA,
+	})
+	if _, err := RemoveRun(filepath.Join(projectA, ".agent-cli", "runs"), "a1"); err != nil {
+		t.Fatalf("remove run: %v", err)
+	}
+
+	projects, err := ListRegisteredProjects(registryDir)
+	if err != nil {
+		t.Fatalf("list registered projects: %v", err)
+	}
+	if len(projects) != 2 || projects[0].Project != projectA || projects[1].Project != projectB {
+		t.Fatalf("unexpected projects: %+v", projects)
+	}
+	if len(projects[0].Runs) != 2 || projects[0].Runs[0].Record.RunID != "a2" || projects[0].Runs[1].Record.RunID != "a3" {
+		t.Fatalf("unexpected project runs: %+v", projects[0].Runs)
+	}
+	entries, drift, err := readRegistry(filepath.Join(registryDir, RegistryFileName))
+	if err != nil || drift || len(entries) != 3 {
+		t.Fatalf("registry was not rewritten: %d entries, drift %v, %v", len(entries), drift, err)
+	}
+
+	// A moved project keeps its registered runs and is marked missing.
+	if err := os.Rename(projectB, filepath.Join(root, "b-moved")); err != nil {
+		t.Fatalf("move project: %v", err)
+	}
+	projects, err = ListRegisteredProjects(registryDir)
+	if err != nil {
+		t.Fatalf("list registered projects: %v", err)
+	}
+	if len(projects) != 2 || !projects[1].Missing || len(projects[1].Runs) != 1 || projects[1].Runs[0].Record.RunID != "b1" {
+		t.Fatalf("unexpected missing project: %+v", projects[1])
+	}
+
+	agg := AggregateProjects(projects, func(record *RunRecord) bool { return record.RunID != "a3" })
+	if agg.TotalRuns != 2 || agg.Sums.TotalCostUSD != 6 || len(agg.Projects) != 2 {
+		t.Fatalf("unexpected aggregate: %+v", agg)
+	}
+	if agg.Projects[0].TotalRuns != 1 || agg.Projects[0].Sums.TotalCostUSD != 4 || !agg.Projects[1].Missing {
+		t.Fatalf("unexpected project breakdown: %+v", agg.Projects)
+	}
+
+	runs := AllProjectRuns(projects)
+	if len(runs) != 3 || runs[0].Record.RunID != "b1" || runs[1].Record.RunID != "a2" {
+		t.Fatalf("unexpected run order: %+v", runs)
+	}
+}
+
+func TestListRegisteredProjectsWithoutRegistry(t *testing.T) {
+	t.Parallel()
+
+	projects, err := ListRegisteredProjects(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || len(projects) != 0 {
+		t.Fatalf("unexpected projects: %+v, %v", projects, err)
+	}
+}
@@ -0,0 +1,261 @@
+package stats
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const compressedArtifactSuffix = ".gz"
+
+// RunEntry is a run record together with the directory it was loaded from.
+type RunEntry struct {
+	Dir    string
+	Record *RunRecord
+}
+
+// pruneMu serializes pruning within the process, e.g. parallel batch rows saving their runs.
+var pruneMu sync.Mutex
+
+// RetentionPolicy selects the runs PruneRuns removes or compresses.
+type RetentionPolicy struct {
+	// OlderThan is the minimum age of a run. Zero disables pruning.
+	OlderThan time.Duration
+	// KeepFailed leaves runs that did not succeed untouched.
+	KeepFailed bool
+	// Compress gzips the artifacts of matching runs instead of removing them.
+	Compress bool
+	// DryRun reports the matching runs without changing them.
+	DryRun bool
+}
+
+// PruneResult lists the run directory names PruneRuns removed or compressed.
+type PruneResult struct {
+	Removed    []string `json:"removed"`
+	Compressed []string `json:"compressed"`
+}
+
+// ListRunRecords loads every run record in runsDir in run directory order, which is run order.
+// Directories without a readable stats.json are returned by name in skipped.
+func ListRunRecords(runsDir string) ([]RunEntry, []string, error) {
+	names, err := listRunDirNames(runsDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	runs := make([]RunEntry, 0, len(names))
+	skipped := make([]string, 0)
+	for _, name := range names {
+		runDir := filepath.Join(runsDir, name)
+		record, err := LoadRunRecord(filepath.Join(runDir, statsFileName))
+		if err != nil {
+			skipped = append(skipped, filepath.ToSlash(filepath.Join(name, statsFileName)))
+			continue
+		}
+		runs = append(runs, RunEntry{Dir: runDir, Record: record})
+	}
+	return runs, skipped, nil
+}
+
+// listRunDirNames returns the sorted run directory names in runsDir, or nil when it does not exist.
+func listRunDirNames(runsDir string) ([]string, error) {
+	entries, err := os.ReadDir(runsDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read runs directory: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// RemoveRun deletes the run directory of runID and returns its path.
+func RemoveRun(runsDir string, runID string) (string, error) {
+	_, runDir, err := FindRunRecord(runsDir, runID)
+	if err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(runDir); err != nil {
+		return "", fmt.Errorf("remove run %s: %w", runID, err)
+	}
+	return runDir, nil
+}
+
+// PruneRuns removes, or with policy.Compress compresses, the runs older than policy.OlderThan at now.
+// Runs whose artifacts are already compressed are not reported again.
+func PruneRuns(runsDir string, policy RetentionPolicy, now time.Time) (*PruneResult, error) {
+	result := &PruneResult{
+		Removed:    []string{},
+		Compressed: []string{},
+	}
+	if policy.OlderThan <= 0 {
+		return result, nil
+	}
+
+	pruneMu.Lock()
+	defer pruneMu.Unlock()
+
+	runs, _, err := ListRunSummaries(runsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := now.Add(-policy.OlderThan)
+	for _, run := range runs {
+		if !run.Record.Timestamp.Before(cutoff) {
+			continue
+		}
+		if policy.KeepFailed && run.Record.Status != RunStatusSuccess {
+			continue
+		}
+
+		name := filepath.Base(run.Dir)
+		if policy.Compress {
+			compressed, err := compressRunArtifacts(run.Dir, policy.DryRun)
+			if err != nil {
+				return nil, err
+			}
+			if compressed {
+				result.Compressed = append(result.Compressed, name)
+			}
+			continue
+		}
+
+		if !policy.DryRun {
+			if err := os.RemoveAll(run.Dir); err != nil {
+				return nil, fmt.Errorf("remove run %s: %w", name, err)
+			}
+		}
+		result.Removed = append(result.Removed, name)
+	}
+	return result, nil
+}
+
+// compressRunArtifacts gzips the output logs and node transcripts of runDir in place and points
+// the transcript files of the run record at the compressed files. stats.json and the resume state
+// stay uncompressed so the run can still be listed and resumed.
+func compressRunArtifacts(runDir string, dryRun bool) (bool, error) {
+	paths := []string{
+		filepath.Join(runDir, outputNDJSONFileName),
+		filepath.Join(runDir, outputFileName),
+	}
+	transcripts, err := filepath.Glob(filepath.Join(runDir, NodeTranscriptsDirName, "*.ndjson"))
+	if err != nil {
+		return false, fmt.Errorf("list node transcripts: %w", err)
+	}
+	paths = append(paths, transcripts...)
+
+	compressed := false
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return false, fmt.Errorf("stat run artifact: %w", err)
+		}
+		compressed = true
+		if dryRun {
+			continue
+		}
+		if err := gzipFile(path); err != nil {
+			return false, err
+		}
+	}
+	if len(transcripts) > 0 && !dryRun {
+		if err := renameCompressedTranscripts(runDir); err != nil {
+			return false, err
+		}
+	}
+	return compressed, nil
+}
+
+// renameCompressedTranscripts rewrites the transcript files recorded in the stats.json of runDir
+// whose transcript is now compressed.
+func renameCompressedTranscripts(runDir string) error {
+	path := filepath.Join(runDir, statsFileName)
+	record, err := LoadRunRecord(path)
+	if err != nil {
+		return err
+	}
+	if record.Pipeline == nil {
+		return nil
+	}
+
+	changed := false
+	for index := range record.Pipeline.NodeRuns {
+		nodeRun := &record.Pipeline.NodeRuns[index]
+		if nodeRun.TranscriptFile == "" || strings.HasSuffix(nodeRun.TranscriptFile, compressedArtifactSuffix) {
+			continue
+		}
+		compressed := nodeRun.TranscriptFile + compressedArtifactSuffix
+		if _, err := os.Stat(filepath.Join(runDir, filepath.FromSlash(compressed))); err == nil {
+			nodeRun.TranscriptFile = compressed
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return rewriteRunRecord(path, record)
+}
+
+func gzipFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open run artifact: %w", err)
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(path+compressedArtifactSuffix, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create compressed run artifact: %w", err)
+	}
+	writer := gzip.NewWriter(target)
+	writer.Name = filepath.Base(path)
+	_, copyErr := io.Copy(writer, source)
+	closeErr := writer.Close()
+	fileErr := target.Close()
+	if err := errors.Join(copyErr, closeErr, fileErr); err != nil {
+		_ = os.Remove(path + compressedArtifactSuffix)
+		return fmt.Errorf("compress run artifact %s: %w", filepath.Base(path), err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove compressed run artifact: %w", err)
+	}
+	return nil
+}
+
+// ParseAge parses a run age such as "30d", "12h" or "90m". Days are 24 hours.
+func ParseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil || count < 0 {
+			return 0, fmt.Errorf("invalid age %q: expected a duration like 30d or 12h", value)
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid age %q: expected a duration like 30d or 12h", value)
+	}
+	return duration, nil
+}
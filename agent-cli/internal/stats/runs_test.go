@@ -0,0 +1,243 @@
+package stats
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestListRunRecordsSkipsUnreadableRuns(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	saveTestRun(t, dir, "run-b", RunStatusSuccess, time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC))
+	saveTestRun(t, dir, "run-a", RunStatusError, time.Date(2026, 2, 14, 0, 0, 0, 0, time.UTC))
+	if err := os.MkdirAll(filepath.Join(dir, "broken"), 0o755); err != nil {
+		t.Fatalf("create broken run: %v", err)
+	}
+
+	runs, skipped, err := ListRunRecords(dir)
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	if len(runs) != 2 || runs[0].Record.RunID != "run-a" || runs[1].Record.RunID != "run-b" {
+		t.Fatalf("unexpected runs: %+v", runs)
+	}
+	if !reflect.DeepEqual(skipped, []string{"broken/stats.json"}) {
+		t.Fatalf("unexpected skipped runs: %v", skipped)
+	}
+}
+
+func TestPruneRunsRemovesOldRuns(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	dir := filepath.Join(t.TempDir(), "runs")
+	saveTestRun(t, dir, "old-ok", RunStatusSuccess, now.AddDate(0, 0, -40))
+	saveTestRun(t, dir, "old-failed", RunStatusError, now.AddDate(0, 0, -35))
+	saveTestRun(t, dir, "recent", RunStatusSuccess, now.AddDate(0, 0, -2))
+
+	policy := RetentionPolicy{OlderThan: 30 * 24 * time.Hour, KeepFailed: true, DryRun: true}
+	result, err := PruneRuns(dir, policy, now)
+	if err != nil {
+		t.Fatalf("prune runs: %v", err)
+	}
+	if len(result.Removed) != 1 || !runDirExists(t, dir, "old-ok") {
+		t.Fatalf("unexpected dry run result: %+v", result)
+	}
+
+	policy.DryRun = false
+	if _, err := PruneRuns(dir, policy, now); err != nil {
+		t.Fatalf("prune runs: %v", err)
+	}
+	if runDirExists(t, dir, "old-ok") || !runDirExists(t, dir, "old-failed") || !runDirExists(t, dir, "recent") {
+		t.Fatal("expected only the old successful run to be removed")
+	}
+
+	policy.KeepFailed = false
+	result, err = PruneRuns(dir, policy, now)
+	if err != nil {
+		t.Fatalf("prune runs: %v", err)
+	}
+	if len(result.Removed) != 1 || runDirExists(t, dir, "old-failed") {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestPruneRunsCompressesArtifacts(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	dir := filepath.Join(t.TempDir(), "runs")
+	path, err := SaveRunRecord(dir, &RunRecord{
+		RunID:     "old",
+		Status:    RunStatusSuccess,
+		Timestamp: now.AddDate(0, 0, -40),
+		Pipeline: &PipelineRunRecord{NodeRuns: []PipelineNodeRunRecord{
+			{NodeID: "dev", NodeRunID: "dev-1", TranscriptFile: "nodes/dev-dev-1.ndjson"},
+		}},
+	}, RetentionPolicy{})
+	if err != nil {
+		t.Fatalf("save record: %v", err)
+	}
+	runDir := filepath.Dir(path)
+	if err := SaveRunArtifacts(runDir, "{\"type\":\"result\"}\nplain line\n", ""); err != nil {
+		t.Fatalf("save artifacts: %v", err)
+	}
+	if err := SaveNodeTranscripts(runDir, map[string]string{"nodes/dev-dev-1.ndjson": "{\"type\":\"system\"}\n"}); err != nil {
+		t.Fatalf("save transcripts: %v", err)
+	}
+
+	policy := RetentionPolicy{OlderThan: 30 * 24 * time.Hour, Compress: true}
+	result, err := PruneRuns(dir, policy, now)
+	if err != nil {
+		t.Fatalf("prune runs: %v", err)
+	}
+	if len(result.Compressed) != 1 || len(result.Removed) != 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	for name, want := range map[string]string{
+		outputNDJSONFileName:     "{\"type\":\"result\"}\n",
+		outputFileName:           "plain line\n",
+		"nodes/dev-dev-1.ndjson": "{\"type\":\"system\"}\n",
+	} {
+		if _, err := os.Stat(filepath.Join(runDir, name)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be replaced, got %v", name, err)
+		}
+		if got := readGzipFile(t, filepath.Join(runDir, name+".gz")); got != want {
+			t.Fatalf("unexpected %s content: %q", name, got)
+		}
+	}
+	record, _, err := FindRunRecord(dir, "old")
+	if err != nil {
+		t.Fatalf("find compressed run: %v", err)
+	}
+	if transcript := record.Pipeline.NodeRuns[0].TranscriptFile; transcript != "nodes/dev-dev-1.ndjson.gz" {
+		t.Fatalf("expected the recorded transcript to point at the compressed file, got %q", transcript)
+	}
+	if runs, _, err := ListRunSummaries(dir); err != nil || len(runs) != 1 {
+		t.Fatalf("unexpected summaries after compression: %+v, %v", runs, err)
+	}
+
+	result, err = PruneRuns(dir, policy, now)
+	if err != nil {
+		t.Fatalf("prune runs: %v", err)
+	}
+	if len(result.Compressed) != 0 {
+		t.Fatalf("expected already compressed run to be skipped: %+v", result)
+	}
+}
+
+func TestSaveRunRecordAppliesRetention(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	now := time.Now().UTC()
+	saveTestRun(t, dir, "old-ok", RunStatusSuccess, now.AddDate(0, 0, -40))
+	saveTestRun(t, dir, "old-failed", RunStatusError, now.AddDate(0, 0, -40))
+
+	policy := RetentionPolicy{OlderThan: 30 * 24 * time.Hour, KeepFailed: true}
+	if _, err := SaveRunRecord(dir, &RunRecord{RunID: "new", Status: RunStatusSuccess, Timestamp: now}, policy); err != nil {
+		t.Fatalf("save record: %v", err)
+	}
+	if runDirExists(t, dir, "old-ok") || !runDirExists(t, dir, "old-failed") || !runDirExists(t, dir, "new") {
+		t.Fatal("expected saving a run to remove only the old successful run")
+	}
+
+	// A pruning failure still saves the run.
+	oldDir := saveTestRun(t, dir, "old-broken", RunStatusSuccess, now.AddDate(0, 0, -40))
+	if err := os.WriteFile(filepath.Join(oldDir, outputFileName), []byte("line\n"), 0o644); err != nil {
+		t.Fatalf("write artifact: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(oldDir, outputFileName+".gz"), 0o755); err != nil {
+		t.Fatalf("block compressed artifact: %v", err)
+	}
+	policy.Compress = true
+	path, err := SaveRunRecord(dir, &RunRecord{RunID: "newer", Status: RunStatusSuccess, Timestamp: now}, policy)
+	var retentionErr *RetentionError
+	if !errors.As(err, &retentionErr) || path == "" || !runDirExists(t, dir, "newer") {
+		t.Fatalf("expected a retention error after saving, got path=%q err=%v", path, err)
+	}
+}
+
+func TestRemoveRun(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	runDir := saveTestRun(t, dir, "abc123", RunStatusSuccess, time.Now().UTC())
+
+	removed, err := RemoveRun(dir, "abc")
+	if err != nil {
+		t.Fatalf("remove run: %v", err)
+	}
+	if removed != runDir || runDirExists(t, dir, "abc123") {
+		t.Fatalf("unexpected removed run %q", removed)
+	}
+	if _, err := RemoveRun(dir, "abc"); err == nil {
+		t.Fatal("expected error for a removed run")
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"0d":  0,
+		"12h": 12 * time.Hour,
+		"90m": 90 * time.Minute,
+	}
+	for value, want := range tests {
+		got, err := ParseAge(value)
+		if err != nil || got != want {
+			t.Fatalf("ParseAge(%q) = %v, %v", value, got, err)
+		}
+	}
+	for _, value := range []string{"", "d", "-1d", "1.5d", "soon", "-2h"} {
+		if _, err := ParseAge(value); err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
+
+func saveTestRun(t *testing.T, dir string, runID string, status RunStatus, timestamp time.Time) string {
+	t.Helper()
+
+	path, err := SaveRunRecord(dir, &RunRecord{RunID: runID, Status: status, Timestamp: timestamp}, RetentionPolicy{})
+	if err != nil {
+		t.Fatalf("save record: %v", err)
+	}
+	return filepath.Dir(path)
+}
+
+func runDirExists(t *testing.T, dir string, runID string) bool {
+	t.Helper()
+
+	_, _, err := FindRunRecord(dir, runID)
+	return err == nil
+}
+
+func readGzipFile(t *testing.T, path string) string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("read gzip header: %v", err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read gzip content: %v", err)
+	}
+	return string(content)
+}
@@ -0,0 +1,115 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+)
+
+// SpendWindow is the calendar period a spend cap applies to. Windows are in UTC and weeks start
+// on Monday, like --group-by day, week and month.
+type SpendWindow string
+
+const (
+	SpendWindowDaily   SpendWindow = "daily"
+	SpendWindowWeekly  SpendWindow = "weekly"
+	SpendWindowMonthly SpendWindow = "monthly"
+)
+
+// SpendLimits are the USD caps per window; zero leaves a window uncapped.
+type SpendLimits struct {
+	DailyUSD   float64
+	WeeklyUSD  float64
+	MonthlyUSD float64
+}
+
+// SpendCap is the spend of one capped window.
+type SpendCap struct {
+	Window   SpendWindow `json:"window"`
+	LimitUSD float64     `json:"limit_usd"`
+	Since    time.Time   `json:"since"`
+	// SpentUSD sums the effective cost of the runs saved since Since and the reported cost of the
+	// runs still in progress that started since then.
+	SpentUSD float64 `json:"spent_usd"`
+}
+
+// Start returns the start of the window containing now.
+func (w SpendWindow) Start(now time.Time) time.Time {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch w {
+	case SpendWindowWeekly:
+		// time.Weekday counts from Sunday; ISO weeks start on Monday.
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case SpendWindowMonthly:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// RemainingUSD is what is left of the cap, never below zero.
+func (c SpendCap) RemainingUSD() float64 {
+	return max(c.LimitUSD-c.SpentUSD, 0)
+}
+
+// Reached reports whether the window's spend has hit the cap.
+func (c SpendCap) Reached() bool {
+	return c.SpentUSD >= c.LimitUSD
+}
+
+// Message describes a reached cap, such as "monthly spend cap reached: 51.000000 of 50.000000 USD
+// spent since 2026-10-01".
+func (c SpendCap) Message() string {
+	return fmt.Sprintf(
+		"%s spend cap reached: %.6f of %.6f USD spent since %s",
+		c.Window,
+		c.SpentUSD,
+		c.LimitUSD,
+		c.Since.Format(time.DateOnly),
+	)
+}
+
+// SpendCaps sums the spend of every capped window containing now, ordered daily, weekly, monthly.
+// Saved runs are read from runsDir and runs in progress from activeDir.
+func SpendCaps(runsDir string, activeDir string, limits SpendLimits, now time.Time) ([]SpendCap, error) {
+	windows := []struct {
+		window SpendWindow
+		limit  float64
+	}{
+		{SpendWindowDaily, limits.DailyUSD},
+		{SpendWindowWeekly, limits.WeeklyUSD},
+		{SpendWindowMonthly, limits.MonthlyUSD},
+	}
+
+	caps := make([]SpendCap, 0, len(windows))
+	if limits == (SpendLimits{}) {
+		return caps, nil
+	}
+	active, err := ListActiveRuns(activeDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, window := range windows {
+		if window.limit <= 0 {
+			continue
+		}
+		since := window.window.Start(now)
+		agg, err := AggregateStats(runsDir, (&RunFilter{Since: &since}).Matches)
+		if err != nil {
+			return nil, err
+		}
+		spendCap := SpendCap{
+			Window:   window.window,
+			LimitUSD: window.limit,
+			Since:    since,
+			SpentUSD: agg.Sums.EffectiveCostUSD,
+		}
+		for _, run := range active {
+			if !run.StartedAt.Before(since) {
+				spendCap.SpentUSD += run.CostUSD
+			}
+		}
+		caps = append(caps, spendCap)
+	}
+	return caps, nil
+}
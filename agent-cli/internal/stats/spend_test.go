@@ -0,0 +1,139 @@
+package stats
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-cli/internal/result"
+)
+
+func TestSpendWindowStart(t *testing.T) {
+	t.Parallel()
+
+	// 2026-10-18 is a Sunday, so its ISO week started on Monday 2026-10-12.
+	now := time.Date(2026, 10, 18, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*60*60))
+	tests := map[SpendWindow]time.Time{
+		SpendWindowDaily:   time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		SpendWindowWeekly:  time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		SpendWindowMonthly: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for window, want := range tests {
+		if got := window.Start(now); !got.Equal(want) {
+			t.Fatalf("unexpected %s start: %v", window, got)
+		}
+	}
+
+	sunday := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	if got := SpendWindowWeekly.Start(sunday); !got.Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected weekly start: %v", got)
+	}
+}
+
+func TestSpendCaps(t *testing.T) {
+	t.Parallel()
+
+	runsDir := t.TempDir()
+	activeDir := t.TempDir()
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	effective := 4.0
+	records := []*RunRecord{
+		// Last month.
+		{Timestamp: time.Date(2026, 9, 30, 23, 0, 0, 0, time.UTC), Normalized: result.NormalizedMetrics{TotalCostUSD: 100}},
+		// This month, before this week.
+		{Timestamp: time.Date(2026, 10, 2, 9, 0, 0, 0, time.UTC), Normalized: result.NormalizedMetrics{TotalCostUSD: 10}},
+		// This week, before today; priced runs count their effective cost.
+		{Timestamp: time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC), Normalized: result.NormalizedMetrics{TotalCostUSD: 1}, EffectiveCostUSD: &effective},
+		// Today.
+		{Timestamp: time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC), Normalized: result.NormalizedMetrics{TotalCostUSD: 2}},
+	}
+	for _, record := range records {
+		if _, err := SaveRunRecord(runsDir, record, RetentionPolicy{}); err != nil {
+			t.Fatalf("save run record: %v", err)
+		}
+	}
+
+	active := []ActiveRun{
+		{RunID: "running", PID: os.Getpid(), StartedAt: time.Date(2026, 10, 14, 11, 0, 0, 0, time.UTC), CostUSD: 0.5},
+		{RunID: "crashed", PID: math.MaxInt32, StartedAt: time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC), CostUSD: 50},
+	}
+	for _, run := range active {
+		if err := WriteActiveRun(activeDir, run); err != nil {
+			t.Fatalf("write active run: %v", err)
+		}
+	}
+
+	caps, err := SpendCaps(runsDir, activeDir, SpendLimits{DailyUSD: 2.5, MonthlyUSD: 50}, now)
+	if err != nil {
+		t.Fatalf("spend caps: %v", err)
+	}
+	if len(caps) != 2 || caps[0].Window != SpendWindowDaily || caps[1].Window != SpendWindowMonthly {
+		t.Fatalf("unexpected caps: %+v", caps)
+	}
+	assertCost(t, "daily", caps[0].SpentUSD, 2.5)
+	if !caps[0].Reached() || caps[0].RemainingUSD() != 0 {
+		t.Fatalf("expected daily cap reached: %+v", caps[0])
+	}
+	wantMessage := "daily spend cap reached: 2.500000 of 2.500000 USD spent since 2026-10-14"
+	if caps[0].Message() != wantMessage {
+		t.Fatalf("unexpected message: %s", caps[0].Message())
+	}
+	assertCost(t, "monthly", caps[1].SpentUSD, 16.5)
+	if caps[1].Reached() {
+		t.Fatalf("unexpected monthly cap reached: %+v", caps[1])
+	}
+	assertCost(t, "monthly remaining", caps[1].RemainingUSD(), 33.5)
+
+	caps, err = SpendCaps(runsDir, activeDir, SpendLimits{}, now)
+	if err != nil || len(caps) != 0 {
+		t.Fatalf("unexpected caps without limits: %+v, %v", caps, err)
+	}
+}
+
+func TestActiveRuns(t *testing.T) {
+	t.Parallel()
+
+	activeDir := filepath.Join(t.TempDir(), "active")
+	runs, err := ListActiveRuns(activeDir)
+	if err != nil || len(runs) != 0 {
+		t.Fatalf("unexpected active runs without directory: %+v, %v", runs, err)
+	}
+
+	run := ActiveRun{RunID: "r1", PID: os.Getpid(), StartedAt: time.Date(2026, 10, 14, 11, 0, 0, 0, time.UTC)}
+	if err := WriteActiveRun(activeDir, run); err != nil {
+		t.Fatalf("write active run: %v", err)
+	}
+	run.CostUSD = 1.25
+	if err := WriteActiveRun(activeDir, run); err != nil {
+		t.Fatalf("write active run: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(activeDir, "broken.json"), []byte("{"), 0o644); err != nil {
+		t.Fatalf("write broken marker: %v", err)
+	}
+
+	runs, err = ListActiveRuns(activeDir)
+	if err != nil {
+		t.Fatalf("list active runs: %v", err)
+	}
+	if len(runs) != 1 || runs[0].RunID != "r1" || runs[0].CostUSD != 1.25 {
+		t.Fatalf("unexpected active runs: %+v", runs)
+	}
+
+	if err := RemoveActiveRun(activeDir, "r1"); err != nil {
+		t.Fatalf("remove active run: %v", err)
+	}
+	if err := RemoveActiveRun(activeDir, "r1"); err != nil {
+		t.Fatalf("remove missing active run: %v", err)
+	}
+	runs, err = ListActiveRuns(activeDir)
+	if err != nil || len(runs) != 0 {
+		t.Fatalf("unexpected active runs after remove: %+v, %v", runs, err)
+	}
+
+	if err := WriteActiveRun(activeDir, ActiveRun{}); err == nil || !strings.Contains(err.Error(), "id is empty") {
+		t.Fatalf("unexpected error for empty run id: %v", err)
+	}
+}
@@ -16,10 +16,31 @@ const (
 	statsFileName         = "stats.json"
 	outputFileName        = "output.log"
 	outputNDJSONFileName  = "output.ndjson"
+	stateDirName          = "state"
+	resumeStateFileName   = "resume.json"
 	runDirTimestampFormat = "20060102T150405"
+
+	// NodeTranscriptsDirName is the run subdirectory holding one NDJSON transcript per node run.
+	NodeTranscriptsDirName = "nodes"
 )
 
-func SaveRunRecord(runsDir string, record *RunRecord) (string, error) {
+// RetentionError reports that SaveRunRecord saved the run but pruning by the retention policy failed.
+type RetentionError struct {
+	Err error
+}
+
+func (e *RetentionError) Error() string {
+	return fmt.Sprintf("apply retention: %v", e.Err)
+}
+
+func (e *RetentionError) Unwrap() error {
+	return e.Err
+}
+
+// SaveRunRecord writes record to its run directory in runsDir and returns the path of its stats.json.
+// It then prunes runsDir by retention; a zero policy prunes nothing. A pruning failure is returned as
+// a *RetentionError together with the path, since the run itself is saved.
+func SaveRunRecord(runsDir string, record *RunRecord, retention RetentionPolicy) (string, error) {
 	if record == nil {
 		return "", errors.New("run record is nil")
 	}
@@ -45,19 +66,133 @@ func SaveRunRecord(runsDir string, record *RunRecord) (string, error) {
 	}
 	path := filepath.Join(runDir, statsFileName)
 
+	content, err := encodeRunRecord(record)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return "", fmt.Errorf("write run record: %w", err)
+	}
+	// The index is a cache; a run missing from it is added by the next ListRunSummaries.
+	_ = indexRunRecord(runsDir, path, record)
+	// Likewise, the registry picks up unregistered runs of known projects when it is read.
+	_ = registerRunRecord(runsDir, path, record)
+
+	if _, err := PruneRuns(runsDir, retention, time.Now()); err != nil {
+		return path, &RetentionError{Err: err}
+	}
+	return path, nil
+}
+
+// rewriteRunRecord replaces the stats.json at path through a temporary file and a rename, so
+// readers never see a partial record. The next ListRunSummaries picks up the change.
+func rewriteRunRecord(path string, record *RunRecord) error {
+	content, err := encodeRunRecord(record)
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), statsFileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create run record: %w", err)
+	}
+	_, writeErr := temp.Write(content)
+	closeErr := temp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		_ = os.Remove(temp.Name())
+		return fmt.Errorf("write run record: %w", err)
+	}
+	// CreateTemp files are owner-only; stats.json is as readable as SaveRunRecord leaves it.
+	if err := os.Chmod(temp.Name(), 0o644); err != nil {
+		_ = os.Remove(temp.Name())
+		return fmt.Errorf("write run record: %w", err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		_ = os.Remove(temp.Name())
+		return fmt.Errorf("replace run record: %w", err)
+	}
+	return nil
+}
+
+func encodeRunRecord(record *RunRecord) ([]byte, error) {
+	content, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal run record: %w", err)
+	}
+	return append(content, '\n'), nil
+}
+
+// SaveBatchRecord writes the batch summary to batchesDir and returns its path.
+func SaveBatchRecord(batchesDir string, record *BatchRecord) (string, error) {
+	if record == nil {
+		return "", errors.New("batch record is nil")
+	}
+
+	name, err := runDirName(record.Timestamp, record.BatchID)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(batchesDir, 0o755); err != nil {
+		return "", fmt.Errorf("create batches directory: %w", err)
+	}
+
 	content, err := json.MarshalIndent(record, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("marshal run record: %w", err)
+		return "", fmt.Errorf("marshal batch record: %w", err)
 	}
 	content = append(content, '\n')
 
+	path := filepath.Join(batchesDir, name+".json")
 	if err := os.WriteFile(path, content, 0o644); err != nil {
-		return "", fmt.Errorf("write run record: %w", err)
+		return "", fmt.Errorf("write batch record: %w", err)
 	}
-
 	return path, nil
 }
 
+// FindRunRecord loads the record of the run matching runID, which may be a full run id,
+// a unique run id prefix or a run directory name. It returns the record and its run directory.
+func FindRunRecord(runsDir string, runID string) (*RunRecord, string, error) {
+	runID = strings.TrimSpace(runID)
+	if runID == "" {
+		return nil, "", errors.New("run id is empty")
+	}
+
+	entries, err := os.ReadDir(runsDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, "", fmt.Errorf("read runs directory: %w", err)
+	}
+
+	matches := make([]string, 0, 1)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		_, id, ok := strings.Cut(name, "-")
+		if name == runID || id == sanitizeID(runID) {
+			matches = []string{name}
+			break
+		}
+		if ok && strings.HasPrefix(id, sanitizeID(runID)) {
+			matches = append(matches, name)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, "", fmt.Errorf("run not found: %s", runID)
+	case 1:
+	default:
+		return nil, "", fmt.Errorf("run id %s is ambiguous: matches %s", runID, strings.Join(matches, ", "))
+	}
+
+	runDir := filepath.Join(runsDir, matches[0])
+	record, err := LoadRunRecord(filepath.Join(runDir, statsFileName))
+	if err != nil {
+		return nil, "", err
+	}
+	return record, runDir, nil
+}
+
 func SaveRunArtifacts(runDir string, stdout string, stderr string) error {
 	if strings.TrimSpace(runDir) == "" {
 		return errors.New("run directory is empty")
@@ -81,6 +216,95 @@ func SaveRunArtifacts(runDir string, stdout string, stderr string) error {
 	return nil
 }
 
+// SaveNodeTranscripts writes each transcript in files, keyed by its path relative to runDir.
+func SaveNodeTranscripts(runDir string, files map[string]string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Join(runDir, NodeTranscriptsDirName), 0o755); err != nil {
+		return fmt.Errorf("create node transcripts directory: %w", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(runDir, filepath.FromSlash(name)), []byte(content), 0o644); err != nil {
+			return fmt.Errorf("write node transcript %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// RunStateDir returns the directory holding the resume state exported by the run in runDir.
+func RunStateDir(runDir string) string {
+	return filepath.Join(runDir, stateDirName)
+}
+
+// LoadPipelineResumeState reads resume.json from stateDir. It returns nil without error when the
+// run exported no state. The state is replayed into the next run, so a file owned by another user
+// is refused.
+func LoadPipelineResumeState(stateDir string) (*PipelineResumeState, error) {
+	path := filepath.Join(stateDir, resumeStateFileName)
+	info, err := os.Lstat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read resume state: %w", err)
+	}
+	if !info.Mode().IsRegular() || !ownedByCurrentUser(info) {
+		return nil, fmt.Errorf("read resume state: %s is not a regular file owned by the current user", path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read resume state: %w", err)
+	}
+
+	var state PipelineResumeState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("decode resume state: %w", err)
+	}
+	return &state, nil
+}
+
+// SaveRunState copies the files exported into stateDir to the state directory of runDir.
+// Resuming needs the workspace patch byte for byte, so the files are not redacted and are only
+// readable by the owner. Files owned by another user are refused.
+func SaveRunState(runDir string, stateDir string) error {
+	entries, err := os.ReadDir(stateDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("read state directory: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	target := RunStateDir(runDir)
+	if err := os.MkdirAll(target, 0o700); err != nil {
+		return fmt.Errorf("create run state directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("read state file %s: %w", entry.Name(), err)
+		}
+		if !ownedByCurrentUser(info) {
+			return fmt.Errorf("read state file %s: not owned by the current user", entry.Name())
+		}
+		content, err := os.ReadFile(filepath.Join(stateDir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("read state file %s: %w", entry.Name(), err)
+		}
+		if err := os.WriteFile(filepath.Join(target, entry.Name()), content, 0o600); err != nil {
+			return fmt.Errorf("write state file %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
 func appendArtifactLines(raw string, ndjsonLog *strings.Builder, outputLog *strings.Builder) {
 	for _, line := range strings.SplitAfter(raw, "\n") {
 		if line == "" {
@@ -18,7 +18,7 @@ func TestSaveRunRecordCreatesFile(t *testing.T) {
 		CWD:       "/tmp/work",
 	}
 
-	path, err := SaveRunRecord(dir, record)
+	path, err := SaveRunRecord(dir, record, RetentionPolicy{})
 	if err != nil {
 		t.Fatalf("save record: %v", err)
 	}
@@ -61,11 +61,11 @@ func TestSaveRunRecordUniqueNames(t *testing.T) {
 		Status:    RunStatusSuccess,
 	}
 
-	pathA, err := SaveRunRecord(dir, recordA)
+	pathA, err := SaveRunRecord(dir, recordA, RetentionPolicy{})
 	if err != nil {
 		t.Fatalf("save first: %v", err)
 	}
-	pathB, err := SaveRunRecord(dir, recordB)
+	pathB, err := SaveRunRecord(dir, recordB, RetentionPolicy{})
 	if err != nil {
 		t.Fatalf("save second: %v", err)
 	}
@@ -88,6 +88,35 @@ func TestSaveRunRecordUniqueNames(t *testing.T) {
 	}
 }
 
+func TestFindRunRecordByIDPrefixAndDirName(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	timestamp := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	for _, runID := range []string{"abc123", "abd456"} {
+		if _, err := SaveRunRecord(dir, &RunRecord{RunID: runID, Timestamp: timestamp, Status: RunStatusSuccess}, RetentionPolicy{}); err != nil {
+			t.Fatalf("save record: %v", err)
+		}
+	}
+
+	for _, query := range []string{"abc123", "abc", "20260301T093000-abc123"} {
+		record, runDir, err := FindRunRecord(dir, query)
+		if err != nil {
+			t.Fatalf("find %q: %v", query, err)
+		}
+		if record.RunID != "abc123" || filepath.Base(runDir) != "20260301T093000-abc123" {
+			t.Fatalf("unexpected match for %q: %s %s", query, record.RunID, runDir)
+		}
+	}
+
+	if _, _, err := FindRunRecord(dir, "ab"); err == nil || !strings.Contains(err.Error(), "is ambiguous") {
+		t.Fatalf("unexpected ambiguous error: %v", err)
+	}
+	if _, _, err := FindRunRecord(dir, "zzz"); err == nil || err.Error() != "run not found: zzz" {
+		t.Fatalf("unexpected missing error: %v", err)
+	}
+}
+
 func TestSaveRunArtifactsSplitsJSONObjectsAndOtherLines(t *testing.T) {
 	t.Parallel()
 
@@ -206,6 +235,61 @@ func TestSaveRunArtifactsCreatesBothFilesWhenOneIsEmpty(t *testing.T) {
 	}
 }
 
+func TestSaveBatchRecordWritesSummary(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "batches")
+	record := &BatchRecord{
+		BatchID:   "batch-1",
+		Timestamp: time.Date(2026, 2, 15, 10, 11, 12, 0, time.UTC),
+		Status:    RunStatusError,
+		Runs: []BatchRunRecord{
+			{Row: 1, RunID: "run-1", Status: RunStatusSuccess},
+			{Row: 2, Status: RunStatusExecError, ErrorMessage: "boom"},
+		},
+	}
+
+	path, err := SaveBatchRecord(dir, record)
+	if err != nil {
+		t.Fatalf("save batch record: %v", err)
+	}
+	if filepath.Base(path) != "20260215T101112-batch-1.json" {
+		t.Fatalf("unexpected batch record path: %q", path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read batch record: %v", err)
+	}
+	if !strings.Contains(string(content), `"error_message": "boom"`) {
+		t.Fatalf("unexpected batch record content: %s", content)
+	}
+}
+
+func TestLoadPipelineResumeStateRefusesForeignFiles(t *testing.T) {
+	t.Parallel()
+
+	stateDir := t.TempDir()
+	path := filepath.Join(stateDir, "resume.json")
+	if err := os.WriteFile(path, []byte(`{"last_decision":{"node_id":"plan"}}`), 0o600); err != nil {
+		t.Fatalf("write resume state: %v", err)
+	}
+	state, err := LoadPipelineResumeState(stateDir)
+	if err != nil || state == nil || state.LastDecision.NodeID != "plan" {
+		t.Fatalf("unexpected state: %+v err=%v", state, err)
+	}
+
+	// Only root can hand the file to another user.
+	if err := os.Lchown(path, os.Getuid()+1, -1); err != nil {
+		t.Skipf("cannot change file owner: %v", err)
+	}
+	if _, err := LoadPipelineResumeState(stateDir); err == nil || !strings.Contains(err.Error(), "not a regular file owned by the current user") {
+		t.Fatalf("expected a foreign resume state to be refused, got %v", err)
+	}
+	if err := SaveRunState(t.TempDir(), stateDir); err == nil || !strings.Contains(err.Error(), "not owned by the current user") {
+		t.Fatalf("expected a foreign state file to be refused, got %v", err)
+	}
+}
+
 func TestIsJSONObjectLine(t *testing.T) {
 	t.Parallel()
 
@@ -21,11 +21,24 @@ type RunRecord struct {
 	Status         RunStatus                `json:"status"`
 	DockerExitCode int                      `json:"docker_exit_code"`
 	CWD            string                   `json:"cwd"`
+	Profile        string                   `json:"profile,omitempty"`
+	Model          string                   `json:"model,omitempty"`
+	PipelinePath   string                   `json:"pipeline_path,omitempty"`
+	TemplateVars   map[string]string        `json:"template_vars,omitempty"`
+	Labels         map[string]string        `json:"labels,omitempty"`
+	BatchID        string                   `json:"batch_id,omitempty"`
+	ParentRunID    string                   `json:"parent_run_id,omitempty"`
 	Pipeline       *PipelineRunRecord       `json:"pipeline,omitempty"`
 	AgentResult    *result.AgentResult      `json:"agent_result,omitempty"`
 	Normalized     result.NormalizedMetrics `json:"normalized"`
 	ErrorType      string                   `json:"error_type,omitempty"`
 	ErrorMessage   string                   `json:"error_message,omitempty"`
+	BudgetExceeded *BudgetBreach            `json:"budget_exceeded,omitempty"`
+	RedactionCount int                      `json:"redaction_count"`
+	// EffectiveCostUSD and EffectiveCostByModel price the token counts with the [pricing] config the run
+	// was saved with; they are unset when no pricing was configured.
+	EffectiveCostUSD     *float64           `json:"effective_cost_usd,omitempty"`
+	EffectiveCostByModel map[string]float64 `json:"effective_cost_by_model,omitempty"`
 }
 
 type PipelineRunRecord struct {
@@ -40,6 +53,45 @@ type PipelineRunRecord struct {
 	NodeRunCount    int                     `json:"node_run_count"`
 	FailedNodeCount int                     `json:"failed_node_count"`
 	NodeRuns        []PipelineNodeRunRecord `json:"node_runs"`
+	Transitions     []PipelineTransition    `json:"transitions,omitempty"`
+	StartNode       string                  `json:"start_node,omitempty"`
+	LastDecision    *PipelineLastDecision   `json:"last_decision,omitempty"`
+	Workspace       *WorkspaceSnapshot      `json:"workspace,omitempty"`
+	// NodeTotals is set on run summaries only, in place of NodeRuns.
+	NodeTotals []PipelineNodeTotal `json:"node_totals,omitempty"`
+}
+
+// PipelineNodeTotal sums the visits of one node in a pipeline run.
+type PipelineNodeTotal struct {
+	NodeID     string `json:"node_id"`
+	Visits     int    `json:"visits"`
+	DurationMS int64  `json:"duration_ms"`
+	// EffectiveCostUSD is the effective cost of the visits, or their reported cost without pricing.
+	EffectiveCostUSD float64 `json:"effective_cost_usd"`
+}
+
+// PipelineLastDecision is the decision of the last successful agent node run, carried across resumed runs.
+type PipelineLastDecision struct {
+	NodeID    string         `json:"node_id"`
+	NodeRunID string         `json:"node_run_id"`
+	Decision  map[string]any `json:"decision"`
+}
+
+// WorkspaceSnapshot describes the workspace state a pipeline run exported for resuming.
+// The patch in PatchFile applies on top of BaseCommit; when Pushed is set, BaseCommit is the
+// head of Branch on origin.
+type WorkspaceSnapshot struct {
+	BaseCommit string `json:"base_commit"`
+	HeadCommit string `json:"head_commit"`
+	Branch     string `json:"branch,omitempty"`
+	Pushed     bool   `json:"pushed"`
+	PatchFile  string `json:"patch_file,omitempty"`
+}
+
+// PipelineResumeState is the resume.json document the entrypoint exports after a pipeline run.
+type PipelineResumeState struct {
+	Workspace    *WorkspaceSnapshot    `json:"workspace"`
+	LastDecision *PipelineLastDecision `json:"last_decision"`
 }
 
 type PipelineNodeRunRecord struct {
@@ -60,6 +112,19 @@ type PipelineNodeRunRecord struct {
 	DurationMS   int64                      `json:"duration_ms"`
 	Normalized   *PipelineNodeRunNormalized `json:"normalized,omitempty"`
 	ErrorMessage string                     `json:"error_message,omitempty"`
+	// TranscriptFile is the node run's own events, relative to the run directory.
+	TranscriptFile string `json:"transcript_file,omitempty"`
+	// Decision is the structured output of an agent node run, kept even when it failed schema validation.
+	Decision map[string]any `json:"decision,omitempty"`
+}
+
+// PipelineTransition is one edge taken between node runs, in execution order.
+type PipelineTransition struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	When      string `json:"when"`
+	Iteration int    `json:"iteration"`
+	NodeRunID string `json:"node_run_id"`
 }
 
 type PipelineNodeRunNormalized struct {
@@ -70,6 +135,8 @@ type PipelineNodeRunNormalized struct {
 	CostUSD                  float64                               `json:"cost_usd"`
 	WebSearchRequests        int64                                 `json:"web_search_requests"`
 	ByModel                  map[string]PipelineNodeRunModelMetric `json:"by_model"`
+	// EffectiveCostUSD is the node run's usage priced with the [pricing] config, if any.
+	EffectiveCostUSD *float64 `json:"effective_cost_usd,omitempty"`
 }
 
 type PipelineNodeRunModelMetric struct {
@@ -90,7 +157,63 @@ type Aggregate struct {
 	LastRunAt      *time.Time                `json:"last_run_at,omitempty"`
 	Sums           AggregateMetrics          `json:"sums"`
 	ByModel        map[string]ModelAggregate `json:"by_model"`
+	ResumeChains   []ResumeChain             `json:"resume_chains"`
 	SkippedFiles   []string                  `json:"skipped_files"`
+	Distributions  RunDistributions          `json:"distributions"`
+	// Histogram is set when a histogram of one metric was requested.
+	Histogram *Histogram `json:"histogram,omitempty"`
+	// Projects breaks the aggregate down per project when it covers all registered projects.
+	Projects []ProjectAggregate `json:"projects,omitempty"`
+	// Filters echoes the filter the aggregate was computed with, if any.
+	Filters *RunFilter `json:"filters,omitempty"`
+}
+
+// BudgetBreach records the budget limit that cancelled a run and the spend it had reached at that moment.
+type BudgetBreach struct {
+	// Limit is max_cost_usd or max_tokens.
+	Limit string `json:"limit"`
+	// NodeID is set when a per-node cap was crossed, and empty for run caps.
+	NodeID     string  `json:"node_id,omitempty"`
+	MaxCostUSD float64 `json:"max_cost_usd,omitempty"`
+	MaxTokens  int64   `json:"max_tokens,omitempty"`
+	CostUSD    float64 `json:"cost_usd"`
+	Tokens     int64   `json:"tokens"`
+}
+
+// ResumeChain attributes the cost of a run and the runs that resumed it to a single task.
+type ResumeChain struct {
+	RootRunID    string    `json:"root_run_id"`
+	RunIDs       []string  `json:"run_ids"`
+	FinalStatus  RunStatus `json:"final_status"`
+	DurationMS   int64     `json:"duration_ms"`
+	TotalCostUSD float64   `json:"total_cost_usd"`
+}
+
+// BatchRecord summarizes one agent-cli batch invocation. Each row also has a regular RunRecord
+// carrying the same BatchID.
+type BatchRecord struct {
+	BatchID      string           `json:"batch_id"`
+	Timestamp    time.Time        `json:"timestamp"`
+	CWD          string           `json:"cwd"`
+	Profile      string           `json:"profile,omitempty"`
+	Pipeline     string           `json:"pipeline"`
+	VarsFile     string           `json:"vars_file"`
+	Parallel     int              `json:"parallel"`
+	Status       RunStatus        `json:"status"`
+	DurationMS   int64            `json:"duration_ms"`
+	TotalCostUSD float64          `json:"total_cost_usd"`
+	Runs         []BatchRunRecord `json:"runs"`
+}
+
+// BatchRunRecord is one row of a batch. RunID is empty when the row never produced a run record.
+type BatchRunRecord struct {
+	Row          int               `json:"row"`
+	RunID        string            `json:"run_id,omitempty"`
+	TemplateVars map[string]string `json:"template_vars"`
+	Status       RunStatus         `json:"status"`
+	TerminalNode string            `json:"terminal_node,omitempty"`
+	TotalCostUSD float64           `json:"total_cost_usd"`
+	ErrorMessage string            `json:"error_message,omitempty"`
 }
 
 type AggregateMetrics struct {
@@ -98,6 +221,7 @@ type AggregateMetrics struct {
 	DurationAPIMS            int64   `json:"duration_api_ms"`
 	NumTurns                 int64   `json:"num_turns"`
 	TotalCostUSD             float64 `json:"total_cost_usd"`
+	EffectiveCostUSD         float64 `json:"effective_cost_usd"`
 	InputTokens              int64   `json:"input_tokens"`
 	CacheCreationInputTokens int64   `json:"cache_creation_input_tokens"`
 	CacheReadInputTokens     int64   `json:"cache_read_input_tokens"`
@@ -111,4 +235,5 @@ type ModelAggregate struct {
 	CacheCreationInputTokens int64   `json:"cache_creation_input_tokens"`
 	WebSearchRequests        int64   `json:"web_search_requests"`
 	CostUSD                  float64 `json:"cost_usd"`
+	EffectiveCostUSD         float64 `json:"effective_cost_usd"`
 }
@@ -38,8 +38,16 @@ func run() error {
 		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer stop()
 		return cli.RunCommand(ctx, cwd, args)
+	case "batch":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return cli.BatchCommand(ctx, cwd, args)
 	case "stats":
 		return cli.StatsCommand(cwd, args)
+	case "pipeline":
+		return cli.PipelineCommand(cwd, args)
+	case "runs":
+		return cli.RunsCommand(cwd, args)
 	case "help", "-h", "--help":
 		printUsage()
 		return nil
@@ -51,9 +59,23 @@ func run() error {
 
 func printUsage() {
 	_, _ = os.Stdout.WriteString(`Usage:
-  agent-cli run [--json] [--model sonnet|opus] [--debug] <prompt text>
-  agent-cli run [--json] [--model sonnet|opus] [--debug] --file <path>
-  agent-cli run [--json] [--model sonnet|opus] [--debug] --pipeline <path> [--var KEY=VALUE ...]
-  agent-cli stats [--json]
+  agent-cli run [--json] [--model sonnet|opus] [--profile <name>] [--debug] [--dry-run] [--max-cost-usd <usd>] [--max-tokens <n>] [--force] [--label KEY=VALUE ...] <prompt text>
+  agent-cli run [--json] [--model sonnet|opus] [--profile <name>] [--debug] [--dry-run] [--max-cost-usd <usd>] [--max-tokens <n>] [--force] [--label KEY=VALUE ...] --file <path>
+  agent-cli run [--json] [--model sonnet|opus] [--profile <name>] [--debug] [--dry-run | --estimate] [--max-cost-usd <usd>] [--max-tokens <n>] [--force] [--label KEY=VALUE ...] --pipeline <path> [--var KEY=VALUE ...]
+  agent-cli run [--json] [--model sonnet|opus] [--profile <name>] [--debug] [--dry-run] [--max-cost-usd <usd>] [--max-tokens <n>] [--force] [--label KEY=VALUE ...] --pipeline <path> --resume <run-id> [--from <node>] [--var KEY=VALUE ...]
+  agent-cli batch <plan> --vars-file <tasks.csv|tasks.jsonl> [--parallel N] [--max-cost-usd <usd>] [--max-tokens <n>] [--force] [--json] [--model sonnet|opus] [--profile <name>] [--debug] [--var KEY=VALUE ...] [--label KEY=VALUE ...]
+  agent-cli stats [--json] [--all-projects] [--recompute-cost] [--group-by day|week|month|status|error_type|pipeline|terminal_node|profile|label:<key>] [--histogram duration|api_duration|cost|turns|tokens] [--since <time|age>] [--until <time|age>] [--status <status>] [--error-type <type>] [--mode prompt|pipeline] [--pipeline <path>] [--model <name>] [--label KEY=VALUE ...]
+  agent-cli stats nodes [--json] [--recompute-cost] [--pipeline <path>] [--since <time|age>] [--until <time|age>] [--status <status>] [--label KEY=VALUE ...]
+  agent-cli stats export [--format csv|jsonl] [--level run|node|model] [--recompute-cost] [--since <time|age>] [--until <time|age>] [--status <status>] [--pipeline <path>] [--label KEY=VALUE ...]
+  agent-cli runs list [--status <status>] [--error-type <type>] [--since <age|date>] [--limit N] [--json]
+  agent-cli runs show <run-id> [--timeline] [--json]
+  agent-cli runs rm <run-id> [<run-id> ...]
+  agent-cli runs prune --older-than <age> [--keep-failed] [--compress] [--dry-run] [--json]
+  agent-cli runs reindex
+  agent-cli pipeline validate <plan> [--model sonnet|opus] [--var KEY=VALUE ...]
+  agent-cli pipeline lint <plan> [--json] [--model sonnet|opus] [--var KEY=VALUE ...]
+  agent-cli pipeline graph <plan> [--format mermaid|dot|svg] [--run <run-id>]
+  agent-cli pipeline simulate <plan> --script <file> [--json]
+  agent-cli pipeline estimate <plan> [--json] [--model sonnet|opus] [--var KEY=VALUE ...]
 `)
 }
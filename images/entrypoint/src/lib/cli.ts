@@ -9,6 +9,8 @@ interface CliOptions {
   model?: string;
   pipeline?: string;
   var?: string[];
+  startNode?: string;
+  resumeDir?: string;
 }
 
 function buildCliProgram(): Command {
@@ -31,6 +33,8 @@ function buildCliProgram(): Command {
         .argParser((value: string, previous: string[]) => [...previous, value])
         .default([]),
     )
+    .option("--start-node <id>", "Pipeline node to start at instead of the plan entry (resume mode)")
+    .option("--resume-dir <path>", "Directory with the resume state exported by a previous pipeline run")
     .allowUnknownOption(true)
     .allowExcessArguments(true)
     .argument("[taskArgs...]", "Prompt text (supports -v/-vv before prompt)");
@@ -83,7 +87,21 @@ export function resolveEntrypointArgs(rawArgs: readonly string[]): EntrypointArg
     templateVars: parsedTemplateVars,
   };
 
-  return typeof options.pipeline === "string" ? { ...baseArgs, pipelinePath: options.pipeline } : baseArgs;
+  if (typeof options.pipeline !== "string") {
+    if (options.startNode || options.resumeDir) {
+      throw new Error("--start-node and --resume-dir can only be used together with --pipeline.");
+    }
+    return baseArgs;
+  }
+
+  const pipelineArgs: EntrypointArgs = { ...baseArgs, pipelinePath: options.pipeline };
+  if (typeof options.startNode === "string" && options.startNode.trim()) {
+    pipelineArgs.startNode = options.startNode.trim();
+  }
+  if (typeof options.resumeDir === "string" && options.resumeDir.trim()) {
+    pipelineArgs.resumeDir = options.resumeDir.trim();
+  }
+  return pipelineArgs;
 }
 
 export function resolvePromptRunOptions(args: readonly string[]): PromptRunOptions {
@@ -28,3 +28,7 @@ export const PIPELINE_DEFAULT_MAX_SAME_NODE_HITS = parsePositiveInteger(
   firstNonEmptyEnv(["PIPELINE_MAX_SAME_NODE_HITS"], "25"),
   25,
 );
+
+export const PIPELINE_STATE_DIR = firstNonEmptyEnv(["PIPELINE_STATE_DIR"], "");
+export const PIPELINE_RESUME_STATE_FILE = "resume.json";
+export const PIPELINE_WORKSPACE_PATCH_FILE = "workspace.patch";
@@ -1,10 +1,24 @@
+import os from "node:os";
 import process from "node:process";
 
 import { resolveEntrypointArgs, resolvePromptRunOptions } from "./cli.js";
+import { PIPELINE_STATE_DIR } from "./constants.js";
 import { installDinDSignalHandlers, startDinD, stopDinD } from "./dind.js";
-import { executePipelinePlan, runClaudeProcess } from "./pipeline-executor.js";
+import { executePipelinePlan, interruptPipeline, runClaudeProcess } from "./pipeline-executor.js";
 import { PipelinePlanError, resolvePipelinePlan } from "./pipeline-plan.js";
-import type { ClaudeProcessResult, DinDRuntime, Model } from "./types.js";
+import {
+  currentWorkspaceCommit,
+  exportPipelineState,
+  readPipelineResumeState,
+  restoreWorkspaceSnapshot,
+} from "./pipeline-state.js";
+import type {
+  ClaudeProcessResult,
+  DinDRuntime,
+  Model,
+  PipelineExecutionResult,
+  PipelineLastDecision,
+} from "./types.js";
 import { debugLog, isTruthyEnv } from "./utils.js";
 import {
   configureGit,
@@ -60,9 +74,6 @@ export async function runEntrypoint(): Promise<void> {
 
   if (isTruthyEnv(process.env.ENABLE_DIND)) {
     dindRuntime = startDinD(debugEnabled);
-    installDinDSignalHandlers(() => {
-      stopDinDRuntime();
-    }, debugEnabled);
   }
 
   try {
@@ -78,13 +89,61 @@ export async function runEntrypoint(): Promise<void> {
       throw error;
     }
 
+    if (dindRuntime && plan === null) {
+      // Pipeline runs handle signals themselves and stop DinD once their state is exported.
+      installDinDSignalHandlers(() => {
+        stopDinDRuntime();
+      }, debugEnabled);
+    }
+
     if (plan !== null) {
       if (taskArgs.length > 0) {
         throw new Error("Prompt task arguments cannot be used together with pipeline mode.");
       }
 
-      const pipelineResult = await executePipelinePlan(plan, debugEnabled);
+      let lastDecision: PipelineLastDecision | null = null;
+      if (args.resumeDir) {
+        const resumeState = readPipelineResumeState(args.resumeDir);
+        if (resumeState.workspace) {
+          restoreWorkspaceSnapshot(args.resumeDir, resumeState.workspace, debugEnabled);
+        }
+        lastDecision = resumeState.last_decision;
+      }
+
+      const startCommit = currentWorkspaceCommit();
+      const exportState = (decision: PipelineLastDecision | null): void => {
+        if (PIPELINE_STATE_DIR) {
+          exportPipelineState(PIPELINE_STATE_DIR, startCommit, decision, debugEnabled);
+        }
+      };
+
+      // docker stop only sends SIGTERM, which the entrypoint as PID 1 would otherwise ignore until the
+      // container is killed. Stop the running node instead, so the plan still emits its result and
+      // exports its state before the container exits.
+      const onSignal = (signalName: NodeJS.Signals): void => {
+        debugLog(debugEnabled, `Received ${signalName}, interrupting pipeline...`);
+        interruptPipeline(signalName);
+      };
+      process.on("SIGINT", onSignal);
+      process.on("SIGTERM", onSignal);
+
+      let pipelineResult: PipelineExecutionResult;
+      try {
+        pipelineResult = await executePipelinePlan(plan, debugEnabled, {
+          startNode: args.startNode,
+          lastDecision,
+        });
+      } catch (error: unknown) {
+        exportState(lastDecision);
+        throw error;
+      } finally {
+        process.removeListener("SIGINT", onSignal);
+        process.removeListener("SIGTERM", onSignal);
+      }
+      exportState(pipelineResult.lastDecision ?? lastDecision);
       if (pipelineResult.signal) {
+        stopDinDRuntime();
+        process.exitCode = 128 + os.constants.signals[pipelineResult.signal];
         process.kill(process.pid, pipelineResult.signal);
         return;
       }
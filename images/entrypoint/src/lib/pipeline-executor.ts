@@ -1,5 +1,5 @@
 import process from "node:process";
-import { spawn } from "node:child_process";
+import { spawn, type ChildProcess } from "node:child_process";
 
 import { evaluateCondition, compileCondition } from "./condition-eval.js";
 import { validateDecisionJSONSchema } from "./pipeline-plan.js";
@@ -13,11 +13,14 @@ import type {
   PipelineEventPayloadMap,
   PipelineExecutionResult,
   PipelineExecutableNode,
+  PipelineLastDecision,
   PipelineNode,
   PipelineNodeRunRecord,
   PipelineNodeRunStatus,
   PipelinePlan,
   PipelineResult,
+  PipelineResumeOptions,
+  PipelineTransitionRecord,
   RunClaudeProcessOptions,
   RunCommandProcessOptions,
 } from "./types.js";
@@ -27,6 +30,7 @@ const SYSTEM_ERROR_INVALID_PLAN = 2;
 const SYSTEM_ERROR_NO_TRANSITION = 3;
 const SYSTEM_ERROR_LIMIT_REACHED = 4;
 const SYSTEM_ERROR_NODE_EXECUTION = 5;
+const SYSTEM_ERROR_INTERRUPTED = 130;
 const AUTHENTICATION_FAILED_ERROR_CODE = "authentication_failed";
 const STREAM_LOOP_DETECTED_ERROR_CODE = "stream_loop_detected";
 const STREAM_LOOP_THRESHOLD = 3;
@@ -34,6 +38,28 @@ const STOP_HOOK_FEEDBACK_MARKER = "Stop hook feedback:";
 const STOP_HOOK_STRUCTURED_OUTPUT_MARKER = "You MUST call the StructuredOutput tool";
 const STREAM_LOOP_DETECTED_MESSAGE = `repeated assistant-error/stop-hook cycle (${STREAM_LOOP_THRESHOLD}x)`;
 
+// Children still running, so an interrupt can stop the node in progress.
+const runningChildren = new Set<ChildProcess>();
+let interruptSignal: NodeJS.Signals | "" = "";
+
+// interruptPipeline stops the running node; executePipelinePlan then finishes the plan as interrupted
+// instead of taking a transition.
+export function interruptPipeline(signal: NodeJS.Signals): void {
+  if (interruptSignal) {
+    return;
+  }
+  interruptSignal = signal;
+  for (const child of runningChildren) {
+    child.kill("SIGTERM");
+  }
+}
+
+function trackChild(child: ChildProcess): void {
+  runningChildren.add(child);
+  child.on("close", () => runningChildren.delete(child));
+  child.on("error", () => runningChildren.delete(child));
+}
+
 function emitPipelineEvent<TEvent extends PipelineEventName>(
   event: TEvent,
   payload: PipelineEventPayloadMap[TEvent],
@@ -216,6 +242,7 @@ export function runClaudeProcess(
       stdio: ["inherit", "pipe", "pipe"],
       ...spawnOptions,
     });
+    trackChild(child);
 
     let stdoutBuffer = "";
     let timedOut = false;
@@ -426,6 +453,7 @@ function runCommandProcess(
       stdio: ["inherit", "pipe", "pipe"],
       ...spawnOptions,
     });
+    trackChild(child);
 
     let timedOut = false;
     let closed = false;
@@ -531,6 +559,7 @@ function toDecisionScope(record: PipelineNodeRunRecord, decision: JSONObject, at
 function executeTerminalResult(
   plan: PipelinePlan,
   nodeRuns: PipelineNodeRunRecord[],
+  transitions: PipelineTransitionRecord[],
   startedAtMs: number,
   node: PipelineNode,
   iterations: number,
@@ -557,6 +586,7 @@ function executeTerminalResult(
     node_run_count: nodeRuns.length,
     failed_node_count: failedNodeCount,
     node_runs: nodeRuns,
+    transitions,
   };
 
   emitPipelineEvent("plan_finish", {
@@ -581,6 +611,7 @@ function executeTerminalResult(
 function executeSystemErrorResult(
   plan: PipelinePlan,
   nodeRuns: PipelineNodeRunRecord[],
+  transitions: PipelineTransitionRecord[],
   startedAtMs: number,
   iterations: number,
   exitCode: number,
@@ -600,6 +631,7 @@ function executeSystemErrorResult(
     node_run_count: nodeRuns.length,
     failed_node_count: failedNodeCount,
     node_runs: nodeRuns,
+    transitions,
   };
 
   emitPipelineEvent("plan_finish", {
@@ -764,6 +796,9 @@ async function executeAgentNode(
       ? errorMessage || timeoutMessage || (processResult.signal ? `terminated by ${processResult.signal}` : `exit code ${processResult.code}`)
       : "",
   };
+  if (isPlainObject(finalStructuredOutput)) {
+    record.decision = finalStructuredOutput as JSONObject;
+  }
 
   emitPipelineEvent("node_finish", record);
 
@@ -778,6 +813,7 @@ async function executeCommandNode(
   nodeRunID: string,
   attempt: number,
   iteration: number,
+  lastDecision: PipelineLastDecision | null,
 ): Promise<ExecutedNodeRun> {
   if (node.run.kind !== "command") {
     throw new Error(`executeCommandNode called for non-command node ${node.id}`);
@@ -807,6 +843,10 @@ async function executeCommandNode(
   try {
     processResult = await runCommandProcess(run.cmd, {
       cwd: run.cwd,
+      env: {
+        ...process.env,
+        PIPELINE_LAST_DECISION: lastDecision ? JSON.stringify(lastDecision) : "",
+      },
       timeoutMs: run.timeoutSec * 1000,
       onTimeout: () => {
         emitPipelineEvent("node_timeout", {
@@ -864,56 +904,106 @@ async function executeNodeRun(
   nodeRunID: string,
   attempt: number,
   iteration: number,
+  lastDecision: PipelineLastDecision | null,
 ): Promise<ExecutedNodeRun> {
   if (node.run.kind === "agent") {
     return executeAgentNode(node, nodeRunID, attempt, iteration);
   }
-  return executeCommandNode(node, nodeRunID, attempt, iteration);
+  return executeCommandNode(node, nodeRunID, attempt, iteration, lastDecision);
 }
 
 export async function executePipelinePlan(
   plan: PipelinePlan,
   _debugEnabled: boolean,
+  resume: PipelineResumeOptions = {},
 ): Promise<PipelineExecutionResult> {
   const startedAtMs = Date.now();
   const nodeRuns: PipelineNodeRunRecord[] = [];
+  const transitions: PipelineTransitionRecord[] = [];
   const nodeRunHits = new Map<string, number>();
+  const startNodeID = resume.startNode || plan.entryNode;
+  // The last successful agent decision is carried across resumed runs so the chain keeps it.
+  let lastDecision: PipelineLastDecision | null = resume.lastDecision ?? null;
+  const withLastDecision = (result: PipelineExecutionResult): PipelineExecutionResult => ({
+    ...result,
+    lastDecision,
+  });
 
   emitPipelineEvent("plan_start", {
     version: plan.version,
     started_at: currentTimestamp(),
     entry_node: plan.entryNode,
+    start_node: startNodeID,
     node_count: plan.nodeOrder.length,
   });
 
-  let currentNodeID = plan.entryNode;
-  let iterations = 0;
+  const startNode = plan.nodes[startNodeID];
+  if (startNodeID !== plan.entryNode && (!startNode || ("terminal" in startNode && startNode.terminal))) {
+    return withLastDecision(
+      executeSystemErrorResult(
+        plan,
+        nodeRuns,
+        transitions,
+        startedAtMs,
+        0,
+        SYSTEM_ERROR_INVALID_PLAN,
+        `start node must be a non-terminal plan node: ${startNodeID}`,
+      ),
+    );
+  }
 
-  while (true) {
-    const node = plan.nodes[currentNodeID];
-    if (!node) {
-      return executeSystemErrorResult(
+  let currentNodeID = startNodeID;
+  let iterations = 0;
+  const interruptedResult = (): PipelineExecutionResult => ({
+    ...withLastDecision(
+      executeSystemErrorResult(
         plan,
         nodeRuns,
+        transitions,
         startedAtMs,
         iterations,
-        SYSTEM_ERROR_INVALID_PLAN,
-        `node not found: ${currentNodeID}`,
+        SYSTEM_ERROR_INTERRUPTED,
+        `pipeline interrupted by ${interruptSignal}`,
+      ),
+    ),
+    signal: interruptSignal,
+  });
+
+  while (true) {
+    if (interruptSignal) {
+      return interruptedResult();
+    }
+
+    const node = plan.nodes[currentNodeID];
+    if (!node) {
+      return withLastDecision(
+        executeSystemErrorResult(
+          plan,
+          nodeRuns,
+          transitions,
+          startedAtMs,
+          iterations,
+          SYSTEM_ERROR_INVALID_PLAN,
+          `node not found: ${currentNodeID}`,
+        ),
       );
     }
 
     if ("terminal" in node && node.terminal) {
-      return executeTerminalResult(plan, nodeRuns, startedAtMs, node, iterations);
+      return withLastDecision(executeTerminalResult(plan, nodeRuns, transitions, startedAtMs, node, iterations));
     }
 
     if (iterations >= plan.limits.maxIterations) {
-      return executeSystemErrorResult(
-        plan,
-        nodeRuns,
-        startedAtMs,
-        iterations,
-        SYSTEM_ERROR_LIMIT_REACHED,
-        `max_iterations exceeded: ${plan.limits.maxIterations}`,
+      return withLastDecision(
+        executeSystemErrorResult(
+          plan,
+          nodeRuns,
+          transitions,
+          startedAtMs,
+          iterations,
+          SYSTEM_ERROR_LIMIT_REACHED,
+          `max_iterations exceeded: ${plan.limits.maxIterations}`,
+        ),
       );
     }
     const executableNode = node as PipelineExecutableNode;
@@ -923,19 +1013,33 @@ export async function executePipelinePlan(
     const nodeHitCount = (nodeRunHits.get(executableNode.id) ?? 0) + 1;
     nodeRunHits.set(executableNode.id, nodeHitCount);
     if (nodeHitCount > plan.limits.maxSameNodeHits) {
-      return executeSystemErrorResult(
-        plan,
-        nodeRuns,
-        startedAtMs,
-        iterations,
-        SYSTEM_ERROR_LIMIT_REACHED,
-        `max_same_node_hits exceeded for ${executableNode.id}: ${plan.limits.maxSameNodeHits}`,
+      return withLastDecision(
+        executeSystemErrorResult(
+          plan,
+          nodeRuns,
+          transitions,
+          startedAtMs,
+          iterations,
+          SYSTEM_ERROR_LIMIT_REACHED,
+          `max_same_node_hits exceeded for ${executableNode.id}: ${plan.limits.maxSameNodeHits}`,
+        ),
       );
     }
 
     const nodeRunID = makeNodeRunID(executableNode.id, nodeRuns.length + 1);
-    const executed = await executeNodeRun(executableNode, nodeRunID, nodeHitCount, iterations);
+    const executed = await executeNodeRun(executableNode, nodeRunID, nodeHitCount, iterations, lastDecision);
     nodeRuns.push(executed.record);
+    if (executed.record.kind === "agent" && executed.record.status === "success") {
+      lastDecision = {
+        node_id: executed.record.node_id,
+        node_run_id: executed.record.node_run_id,
+        decision: executed.decision,
+      };
+    }
+    if (interruptSignal) {
+      // The node was stopped, so its result must not pick a transition.
+      return interruptedResult();
+    }
 
     const scope = toDecisionScope(executed.record, executed.decision, nodeHitCount, iterations, nodeRuns.length);
 
@@ -953,16 +1057,26 @@ export async function executePipelinePlan(
 
     if (!matchedTransition) {
       const noTransitionErrorCode = executed.record.status === "error" ? SYSTEM_ERROR_NODE_EXECUTION : SYSTEM_ERROR_NO_TRANSITION;
-      return executeSystemErrorResult(
-        plan,
-        nodeRuns,
-        startedAtMs,
-        iterations,
-        noTransitionErrorCode,
-        `no transition matched for node ${executableNode.id}`,
+      return withLastDecision(
+        executeSystemErrorResult(
+          plan,
+          nodeRuns,
+          transitions,
+          startedAtMs,
+          iterations,
+          noTransitionErrorCode,
+          `no transition matched for node ${executableNode.id}`,
+        ),
       );
     }
 
+    transitions.push({
+      from_node: executableNode.id,
+      to_node: matchedTransition.to,
+      when: matchedTransition.when,
+      iteration: iterations,
+      node_run_id: nodeRunID,
+    });
     emitPipelineEvent("transition_taken", {
       node_id: executableNode.id,
       node_run_id: nodeRunID,
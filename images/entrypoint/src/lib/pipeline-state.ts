@@ -0,0 +1,172 @@
+import fs from "node:fs";
+import os from "node:os";
+import path from "node:path";
+import process from "node:process";
+
+import { PIPELINE_RESUME_STATE_FILE, PIPELINE_WORKSPACE_PATCH_FILE, TARGET_WORKSPACE_DIR } from "./constants.js";
+import type { PipelineLastDecision, PipelineResumeState, WorkspaceSnapshot } from "./types.js";
+import { debugLog, isPlainObject, runSync } from "./utils.js";
+
+function runGitInWorkspace(args: readonly string[], env: NodeJS.ProcessEnv = process.env): string {
+  return runSync("git", args, {
+    cwd: TARGET_WORKSPACE_DIR,
+    env,
+    stdio: ["ignore", "pipe", "pipe"],
+    maxBuffer: 256 * 1024 * 1024,
+  });
+}
+
+function tryGitInWorkspace(args: readonly string[]): string {
+  try {
+    return runGitInWorkspace(args).trim();
+  } catch {
+    return "";
+  }
+}
+
+export function currentWorkspaceCommit(): string {
+  return tryGitInWorkspace(["rev-parse", "--verify", "HEAD"]);
+}
+
+export function readPipelineResumeState(resumeDir: string): PipelineResumeState {
+  const statePath = path.join(resumeDir, PIPELINE_RESUME_STATE_FILE);
+  let parsed: unknown;
+  try {
+    parsed = JSON.parse(fs.readFileSync(statePath, "utf8"));
+  } catch (error: unknown) {
+    const message = error instanceof Error ? error.message : String(error);
+    throw new Error(`Failed to read resume state ${statePath}: ${message}`);
+  }
+  if (!isPlainObject(parsed)) {
+    throw new Error(`Resume state ${statePath} must be a JSON object.`);
+  }
+
+  return {
+    workspace: isPlainObject(parsed.workspace) ? (parsed.workspace as unknown as WorkspaceSnapshot) : null,
+    last_decision: isPlainObject(parsed.last_decision) ? (parsed.last_decision as unknown as PipelineLastDecision) : null,
+  };
+}
+
+// restoreWorkspaceSnapshot resets /workspace to the snapshot commit and re-applies its patch.
+// A pushed branch is fetched from origin; otherwise the branch is recreated at base_commit and
+// commits made by the previous run come back as uncommitted changes from the patch.
+export function restoreWorkspaceSnapshot(resumeDir: string, snapshot: WorkspaceSnapshot, debugEnabled: boolean): void {
+  const branch = String(snapshot.branch ?? "").trim();
+  const baseCommit = String(snapshot.base_commit ?? "").trim();
+  if (!baseCommit) {
+    throw new Error("Resume state has no workspace base_commit.");
+  }
+
+  if (snapshot.pushed && branch) {
+    debugLog(debugEnabled, `Restoring workspace from pushed branch ${branch}...`);
+    runGitInWorkspace(["fetch", "origin", branch]);
+    runGitInWorkspace(["checkout", "-f", "-B", branch, "FETCH_HEAD"]);
+  } else if (branch) {
+    debugLog(debugEnabled, `Restoring workspace branch ${branch} at ${baseCommit}...`);
+    runGitInWorkspace(["checkout", "-f", "-B", branch, baseCommit]);
+  } else {
+    debugLog(debugEnabled, `Restoring detached workspace at ${baseCommit}...`);
+    runGitInWorkspace(["checkout", "-f", "--detach", baseCommit]);
+  }
+  runGitInWorkspace(["clean", "-fd"]);
+
+  const patchFile = String(snapshot.patch_file ?? "").trim();
+  if (!patchFile) {
+    return;
+  }
+  const patchPath = path.join(resumeDir, patchFile);
+  if (!fs.existsSync(patchPath) || fs.statSync(patchPath).size === 0) {
+    return;
+  }
+  debugLog(debugEnabled, `Applying workspace patch ${patchPath}...`);
+  runGitInWorkspace(["apply", "--binary", "--whitespace=nowarn", patchPath]);
+}
+
+function captureWorkspaceSnapshot(stagingDir: string, startCommit: string): WorkspaceSnapshot | null {
+  const headCommit = currentWorkspaceCommit();
+  if (!headCommit) {
+    return null;
+  }
+
+  const abbrevRef = tryGitInWorkspace(["rev-parse", "--abbrev-ref", "HEAD"]);
+  const branch = abbrevRef === "HEAD" ? "" : abbrevRef;
+  const upstreamCommit = branch ? tryGitInWorkspace(["rev-parse", "--verify", "@{u}"]) : "";
+  const pushed = upstreamCommit !== "" && upstreamCommit === headCommit;
+  const baseCommit = pushed || !startCommit ? headCommit : startCommit;
+
+  // Stage everything into a throwaway index so untracked files are part of the patch
+  // without touching the workspace index.
+  const indexDir = fs.mkdtempSync(path.join(os.tmpdir(), "pipeline-state-"));
+  try {
+    const env = { ...process.env, GIT_INDEX_FILE: path.join(indexDir, "index") };
+    runGitInWorkspace(["read-tree", "HEAD"], env);
+    runGitInWorkspace(["add", "-A"], env);
+    const patch = runGitInWorkspace(["diff", "--cached", "--binary", baseCommit], env);
+    fs.writeFileSync(path.join(stagingDir, PIPELINE_WORKSPACE_PATCH_FILE), patch);
+  } finally {
+    fs.rmSync(indexDir, { recursive: true, force: true });
+  }
+
+  return {
+    base_commit: baseCommit,
+    head_commit: headCommit,
+    branch,
+    pushed,
+    patch_file: PIPELINE_WORKSPACE_PATCH_FILE,
+  };
+}
+
+// publishStateFiles moves the files staged in stagingDir into stateDir. The host keeps stateDir
+// private to its own user, which need not be the container user, so the files are then installed
+// as the directory owner through sudo.
+function publishStateFiles(stagingDir: string, stateDir: string): void {
+  let writable = true;
+  try {
+    fs.accessSync(stateDir, fs.constants.W_OK);
+  } catch {
+    writable = false;
+  }
+
+  const owner = fs.statSync(stateDir);
+  for (const name of fs.readdirSync(stagingDir)) {
+    const source = path.join(stagingDir, name);
+    const target = path.join(stateDir, name);
+    if (writable) {
+      fs.copyFileSync(source, target);
+      fs.chmodSync(target, 0o600);
+      continue;
+    }
+    runSync("sudo", ["-n", "install", "-m", "0600", "-o", String(owner.uid), "-g", String(owner.gid), source, target], {
+      stdio: ["ignore", "pipe", "pipe"],
+    });
+  }
+}
+
+// exportPipelineState writes resume.json (and the workspace patch) for a later --resume-dir run.
+export function exportPipelineState(
+  stateDir: string,
+  startCommit: string,
+  lastDecision: PipelineLastDecision | null,
+  debugEnabled: boolean,
+): void {
+  const stagingDir = fs.mkdtempSync(path.join(os.tmpdir(), "pipeline-state-"));
+  try {
+    let workspace: WorkspaceSnapshot | null = null;
+    try {
+      workspace = captureWorkspaceSnapshot(stagingDir, startCommit);
+    } catch (error: unknown) {
+      const message = error instanceof Error ? error.message : String(error);
+      process.stderr.write(`Warning: failed to export workspace state: ${message}\n`);
+    }
+
+    const state: PipelineResumeState = {
+      workspace,
+      last_decision: lastDecision,
+    };
+    fs.writeFileSync(path.join(stagingDir, PIPELINE_RESUME_STATE_FILE), `${JSON.stringify(state, null, 2)}\n`);
+    publishStateFiles(stagingDir, stateDir);
+  } finally {
+    fs.rmSync(stagingDir, { recursive: true, force: true });
+  }
+  debugLog(debugEnabled, `Exported pipeline state to ${stateDir}`);
+}
@@ -22,6 +22,8 @@ export interface EntrypointArgs {
   taskArgs: string[];
   templateVars: Record<string, string>;
   pipelinePath?: string;
+  startNode?: string;
+  resumeDir?: string;
 }
 
 export interface PromptRunOptions {
@@ -119,6 +121,31 @@ export interface CommandProcessResult {
 export interface PipelineExecutionResult {
   exitCode: number;
   signal: NodeJS.Signals | "";
+  lastDecision?: PipelineLastDecision | null;
+}
+
+export interface PipelineLastDecision {
+  node_id: string;
+  node_run_id: string;
+  decision: JSONObject;
+}
+
+export interface PipelineResumeOptions {
+  startNode?: string;
+  lastDecision?: PipelineLastDecision | null;
+}
+
+export interface WorkspaceSnapshot {
+  base_commit: string;
+  head_commit: string;
+  branch: string;
+  pushed: boolean;
+  patch_file: string;
+}
+
+export interface PipelineResumeState {
+  workspace: WorkspaceSnapshot | null;
+  last_decision: PipelineLastDecision | null;
 }
 
 export interface RunClaudeProcessOptions extends Omit<SpawnOptions, "stdio"> {
@@ -155,6 +182,16 @@ export interface PipelineNodeRunRecord {
   finished_at: string;
   duration_ms: number;
   error_message: string;
+  // Agent nodes only: the structured_output object, recorded even when it failed schema validation.
+  decision?: JSONObject;
+}
+
+export interface PipelineTransitionRecord {
+  from_node: string;
+  to_node: string;
+  when: string;
+  iteration: number;
+  node_run_id: string;
 }
 
 export interface PipelineResult {
@@ -170,6 +207,7 @@ export interface PipelineResult {
   node_run_count: number;
   failed_node_count: number;
   node_runs: PipelineNodeRunRecord[];
+  transitions: PipelineTransitionRecord[];
 }
 
 export interface PipelineRuntimeState {
@@ -204,6 +242,7 @@ export interface PipelineEventPayloadMap {
     version: PipelineVersion;
     started_at: string;
     entry_node: string;
+    start_node: string;
     node_count: number;
   };
   node_start: {